@@ -38,13 +38,17 @@ type Handlers struct {
 	Auth          *AuthManager
 }
 
-// Health exposes a simple liveness probe.
+// Health exposes a liveness probe along with search provider health.
 func (h *Handlers) Health(w http.ResponseWriter, _ *http.Request) {
 	if h == nil || h.Store == nil {
 		writeJSON(w, http.StatusInternalServerError, Response{OK: false, Error: "store not initialized"})
 		return
 	}
-	writeJSON(w, http.StatusOK, Response{OK: true, Data: map[string]string{"status": "ok"}})
+	data := map[string]any{"status": "ok"}
+	if h.ServiceBundle != nil && h.ServiceBundle.Search != nil {
+		data["search_providers"] = h.ServiceBundle.Search.ProviderHealth()
+	}
+	writeJSON(w, http.StatusOK, Response{OK: true, Data: data})
 }
 
 // Login 处理前端登录请求。
@@ -169,6 +173,42 @@ func (h *Handlers) DeleteCustomer(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, Response{OK: true})
 }
 
+// CheckWebsite re-crawls the customer's website now and records significant
+// changes as a customer activity. The crawl and summary outlast the server's
+// write timeout on slow sites, so the deadline is lifted.
+func (h *Handlers) CheckWebsite(w http.ResponseWriter, r *http.Request) {
+	customerID, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	liftWriteDeadline(w)
+	result, err := h.ServiceBundle.Monitor.CheckCustomer(r.Context(), customerID)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{OK: true, Data: result})
+}
+
+// VerifyContacts checks whether the customer's contact addresses can receive
+// mail and returns the contacts with their verification status. SMTP probes
+// can outlast the server's write timeout, so the deadline is lifted.
+func (h *Handlers) VerifyContacts(w http.ResponseWriter, r *http.Request) {
+	customerID, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	liftWriteDeadline(w)
+	contacts, err := h.ServiceBundle.Verifier.VerifyCustomerContacts(r.Context(), customerID)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{OK: true, Data: contacts})
+}
+
 // UpdateFollowupStatus toggles whether automated followups can continue sending emails.
 func (h *Handlers) UpdateFollowupStatus(w http.ResponseWriter, r *http.Request) {
 	customerID, err := parseID(chi.URLParam(r, "id"))
@@ -255,6 +295,97 @@ func (h *Handlers) TestLLM(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, Response{OK: true, Data: result})
 }
 
+// LLMUsageReport aggregates recorded LLM token usage by day, step or customer.
+func (h *Handlers) LLMUsageReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	report, err := h.Store.LLMUsageReport(r.Context(), store.LLMUsageReportFilter{
+		GroupBy: query.Get("group_by"),
+		From:    query.Get("from"),
+		To:      query.Get("to"),
+	})
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{OK: true, Data: report})
+}
+
+// ListDomainRules returns the website resolution block/allow list.
+func (h *Handlers) ListDomainRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.Store.ListDomainRules(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, Response{OK: false, Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{OK: true, Data: rules})
+}
+
+// CreateDomainRule adds a blocked or preferred domain.
+func (h *Handlers) CreateDomainRule(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Pattern string `json:"pattern"`
+		Kind    string `json:"kind"`
+		Note    string `json:"note"`
+	}
+	if err := decodeJSON(r, &payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	rule, err := h.Store.CreateDomainRule(r.Context(), store.DomainRule{Pattern: payload.Pattern, Kind: payload.Kind, Note: payload.Note})
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{OK: true, Data: rule})
+}
+
+// UpdateDomainRule switches a rule between block and allow, edits its note or
+// enables/disables it.
+func (h *Handlers) UpdateDomainRule(w http.ResponseWriter, r *http.Request) {
+	ruleID, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	var payload struct {
+		Kind    string `json:"kind"`
+		Note    string `json:"note"`
+		Enabled bool   `json:"enabled"`
+	}
+	if err := decodeJSON(r, &payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	rule, err := h.Store.UpdateDomainRule(r.Context(), ruleID, payload.Kind, payload.Note, payload.Enabled)
+	if err != nil {
+		if errors.Is(err, store.ErrDomainRuleNotFound) {
+			writeJSON(w, http.StatusNotFound, Response{OK: false, Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{OK: true, Data: rule})
+}
+
+// DeleteDomainRule removes a user-defined domain rule.
+func (h *Handlers) DeleteDomainRule(w http.ResponseWriter, r *http.Request) {
+	ruleID, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	if err := h.Store.DeleteDomainRule(r.Context(), ruleID); err != nil {
+		if errors.Is(err, store.ErrDomainRuleNotFound) {
+			writeJSON(w, http.StatusNotFound, Response{OK: false, Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{OK: true})
+}
+
 // TestSMTP sends a test email using the configured SMTP credentials.
 func (h *Handlers) TestSMTP(w http.ResponseWriter, r *http.Request) {
 	var overrides *store.Settings
@@ -289,6 +420,39 @@ func (h *Handlers) TestSearch(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, Response{OK: true, Data: map[string]string{"message": "搜索 API 测试成功"}})
 }
 
+// TestProxy checks every proxy channel with the saved settings.
+func (h *Handlers) TestProxy(w http.ResponseWriter, r *http.Request) {
+	results, err := h.ServiceBundle.Proxy.Test(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{OK: true, Data: results})
+}
+
+// DryRunSearchPlan runs a search plan for a company name and returns every
+// stage's result. Stages in the body override the saved plan without saving it.
+func (h *Handlers) DryRunSearchPlan(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		CompanyName string                  `json:"company_name"`
+		Stages      []store.SearchPlanStage `json:"stages"`
+	}
+	if err := decodeJSON(r, &payload); err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	if strings.TrimSpace(payload.CompanyName) == "" {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: "请输入公司名称"})
+		return
+	}
+	preview, err := h.ServiceBundle.Search.DryRunPlan(r.Context(), payload.CompanyName, payload.Stages)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{OK: true, Data: preview})
+}
+
 // EnqueueTodo accepts a raw query and persists it for background processing.
 func (h *Handlers) EnqueueTodo(w http.ResponseWriter, r *http.Request) {
 	var payload struct {
@@ -433,12 +597,13 @@ func (h *Handlers) ResolveCompany(w http.ResponseWriter, r *http.Request) {
 					src = b
 				}
 				saveReq := &domain.CreateCompanyRequest{
-					Name:       strings.TrimSpace(result.Name),
-					Website:    strings.TrimSpace(result.Website),
-					Country:    strings.TrimSpace(result.Country),
-					Summary:    strings.TrimSpace(result.Summary),
-					Contacts:   result.Contacts,
-					SourceJSON: src,
+					Name:           strings.TrimSpace(result.Name),
+					Website:        strings.TrimSpace(result.Website),
+					Country:        strings.TrimSpace(result.Country),
+					Summary:        strings.TrimSpace(result.Summary),
+					Contacts:       result.Contacts,
+					SourceJSON:     src,
+					WebsiteSignals: result.WebsiteSignals,
 				}
 				if strings.TrimSpace(saveReq.Name) == "" {
 					saveReq.Name = trimmed
@@ -642,6 +807,22 @@ func (h *Handlers) GenerateAnalysis(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, Response{OK: true, Data: resp})
 }
 
+// StreamAnalysis produces entry-point suggestions and streams model output as server-sent events.
+func (h *Handlers) StreamAnalysis(w http.ResponseWriter, r *http.Request) {
+	customerID, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	stream := newSSEWriter(w)
+	resp, err := h.ServiceBundle.Analyst.GenerateStream(r.Context(), customerID, stream.Delta)
+	if err != nil {
+		stream.Fail(err)
+		return
+	}
+	stream.Done(resp)
+}
+
 // UpdateAnalysis allows saving manual edits to the analysis report.
 func (h *Handlers) UpdateAnalysis(w http.ResponseWriter, r *http.Request) {
 	customerID, err := parseID(chi.URLParam(r, "id"))
@@ -677,6 +858,22 @@ func (h *Handlers) GenerateEmailDraft(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, Response{OK: true, Data: resp})
 }
 
+// StreamEmailDraft drafts the initial outreach mail and streams model output as server-sent events.
+func (h *Handlers) StreamEmailDraft(w http.ResponseWriter, r *http.Request) {
+	customerID, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	stream := newSSEWriter(w)
+	resp, err := h.ServiceBundle.EmailComposer.DraftInitialStream(r.Context(), customerID, stream.Delta)
+	if err != nil {
+		stream.Fail(err)
+		return
+	}
+	stream.Done(resp)
+}
+
 // UpdateEmailDraft updates subject and body for an existing draft email.
 func (h *Handlers) UpdateEmailDraft(w http.ResponseWriter, r *http.Request) {
 	emailID, err := parseID(chi.URLParam(r, "id"))
@@ -756,6 +953,102 @@ func (h *Handlers) RunTaskNow(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, Response{OK: true})
 }
 
+// GetCacheStats reports the size of the LLM and search response cache.
+func (h *Handlers) GetCacheStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.ServiceBundle.Cache.Stats(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{OK: true, Data: stats})
+}
+
+// PurgeCache clears one cache namespace (?namespace=llm|search) or the whole cache.
+func (h *Handlers) PurgeCache(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.ServiceBundle.Cache.Purge(r.Context(), r.URL.Query().Get("namespace"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{OK: true, Data: stats})
+}
+
+// ListPrompts returns the active version of every prompt template.
+func (h *Handlers) ListPrompts(w http.ResponseWriter, r *http.Request) {
+	prompts, err := h.ServiceBundle.Prompts.List(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, Response{OK: false, Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{OK: true, Data: prompts})
+}
+
+// ListPromptVersions returns the version history of a prompt template, newest first.
+func (h *Handlers) ListPromptVersions(w http.ResponseWriter, r *http.Request) {
+	versions, err := h.ServiceBundle.Prompts.Versions(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{OK: true, Data: versions})
+}
+
+// SavePrompt stores an edited prompt template as a new version.
+func (h *Handlers) SavePrompt(w http.ResponseWriter, r *http.Request) {
+	var req domain.PromptTemplateRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	prompt, err := h.ServiceBundle.Prompts.Save(r.Context(), chi.URLParam(r, "name"), &req)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{OK: true, Data: prompt})
+}
+
+// RollbackPrompt re-activates an earlier version of a prompt template.
+func (h *Handlers) RollbackPrompt(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Version int `json:"version"`
+	}
+	if err := decodeJSON(r, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	prompt, err := h.ServiceBundle.Prompts.Rollback(r.Context(), chi.URLParam(r, "name"), req.Version)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{OK: true, Data: prompt})
+}
+
+// ResetPrompt drops all stored versions so the built-in template applies again.
+func (h *Handlers) ResetPrompt(w http.ResponseWriter, r *http.Request) {
+	if err := h.ServiceBundle.Prompts.Reset(r.Context(), chi.URLParam(r, "name")); err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{OK: true})
+}
+
+// PreviewPrompt renders a prompt template against a stored customer.
+func (h *Handlers) PreviewPrompt(w http.ResponseWriter, r *http.Request) {
+	var req domain.PromptPreviewRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	preview, err := h.ServiceBundle.Prompts.Preview(r.Context(), chi.URLParam(r, "name"), &req)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, Response{OK: false, Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{OK: true, Data: preview})
+}
+
 func maxInt(a, b int) int {
 	if a > b {
 		return a
@@ -956,3 +1249,9 @@ func parseID(value string) (int64, error) {
 	}
 	return id, nil
 }
+
+// liftWriteDeadline removes the server-wide write timeout for a request that
+// legitimately runs longer, as newSSEWriter does for streams.
+func liftWriteDeadline(w http.ResponseWriter) {
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+}
@@ -7,8 +7,29 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/services"
 )
 
+// cacheBypass lets any API call skip cached LLM and search responses with
+// ?no_cache=1 or a "Cache-Control: no-cache" request header.
+func cacheBypass(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if wantsFreshResponse(r) {
+			r = r.WithContext(services.WithCacheBypass(r.Context()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func wantsFreshResponse(r *http.Request) bool {
+	switch strings.ToLower(strings.TrimSpace(r.URL.Query().Get("no_cache"))) {
+	case "1", "true", "yes":
+		return true
+	}
+	return strings.Contains(strings.ToLower(r.Header.Get("Cache-Control")), "no-cache")
+}
+
 func requestLogger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if skipLogging(r) {
@@ -14,6 +14,7 @@ func Router(h *Handlers) http.Handler {
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
 	r.Use(requestLogger)
+	r.Use(cacheBypass)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.AllowContentType("application/json"))
 
@@ -32,12 +33,29 @@ func Router(h *Handlers) http.Handler {
 			priv.Post("/settings/test-llm", h.TestLLM)
 			priv.Post("/settings/test-smtp", h.TestSMTP)
 			priv.Post("/settings/test-search", h.TestSearch)
+			priv.Post("/settings/test-proxy", h.TestProxy)
+			priv.Post("/search/plan/dry-run", h.DryRunSearchPlan)
+			priv.Get("/llm/usage", h.LLMUsageReport)
+			priv.Get("/domain-rules", h.ListDomainRules)
+			priv.Post("/domain-rules", h.CreateDomainRule)
+			priv.Put("/domain-rules/{id}", h.UpdateDomainRule)
+			priv.Delete("/domain-rules/{id}", h.DeleteDomainRule)
+			priv.Get("/cache", h.GetCacheStats)
+			priv.Delete("/cache", h.PurgeCache)
+			priv.Get("/prompts", h.ListPrompts)
+			priv.Get("/prompts/{name}/versions", h.ListPromptVersions)
+			priv.Put("/prompts/{name}", h.SavePrompt)
+			priv.Post("/prompts/{name}/rollback", h.RollbackPrompt)
+			priv.Post("/prompts/{name}/preview", h.PreviewPrompt)
+			priv.Delete("/prompts/{name}", h.ResetPrompt)
 
 			priv.Post("/todos", h.EnqueueTodo)
 
 			priv.Get("/customers", h.ListCustomers)
 			priv.Get("/customers/{id}", h.GetCustomerDetail)
 			priv.Put("/customers/{id}/followup-flag", h.UpdateFollowupStatus)
+			priv.Post("/customers/{id}/website-check", h.CheckWebsite)
+			priv.Post("/customers/{id}/contacts/verify", h.VerifyContacts)
 			priv.Delete("/customers/{id}", h.DeleteCustomer)
 
 			priv.Post("/companies/resolve", h.ResolveCompany)
@@ -48,8 +66,10 @@ func Router(h *Handlers) http.Handler {
 			priv.Post("/companies/{id}/grade/suggest", h.SuggestGrade)
 			priv.Post("/companies/{id}/grade/confirm", h.ConfirmGrade)
 			priv.Post("/companies/{id}/analysis", h.GenerateAnalysis)
+			priv.Post("/companies/{id}/analysis/stream", h.StreamAnalysis)
 			priv.Put("/companies/{id}/analysis", h.UpdateAnalysis)
 			priv.Post("/companies/{id}/email-draft", h.GenerateEmailDraft)
+			priv.Post("/companies/{id}/email-draft/stream", h.StreamEmailDraft)
 			priv.Put("/emails/{id}", h.UpdateEmailDraft)
 			priv.Post("/companies/{id}/followup/first-save", h.SaveFirstFollowup)
 			priv.Post("/followups/schedule", h.ScheduleFollowup)
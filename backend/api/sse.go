@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// sseWriter emits server-sent events for streaming endpoints.
+type sseWriter struct {
+	mu   sync.Mutex
+	w    http.ResponseWriter
+	ctrl *http.ResponseController
+}
+
+// newSSEWriter prepares the response for event streaming. The server-wide write
+// timeout is lifted because model output can take minutes to complete.
+func newSSEWriter(w http.ResponseWriter) *sseWriter {
+	ctrl := http.NewResponseController(w)
+	_ = ctrl.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	_ = ctrl.Flush()
+	return &sseWriter{w: w, ctrl: ctrl}
+}
+
+// Send writes a single named event with a JSON payload.
+func (s *sseWriter) Send(event string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	return s.ctrl.Flush()
+}
+
+// Delta streams a partial text fragment.
+func (s *sseWriter) Delta(text string) {
+	_ = s.Send("delta", map[string]string{"text": text})
+}
+
+// Done emits the final envelope and ends the stream.
+func (s *sseWriter) Done(data any) {
+	_ = s.Send("done", Response{OK: true, Data: data})
+}
+
+// Fail emits an error envelope and ends the stream.
+func (s *sseWriter) Fail(err error) {
+	_ = s.Send("error", Response{OK: false, Error: err.Error()})
+}
@@ -16,6 +16,7 @@ const (
 	LogDirName       = "logs"
 	CacheDirName     = "cache"
 	ExportsDirName   = "exports"
+	SnapshotsDirName = "snapshots"
 	DefaultHTTPAddr  = "0.0.0.0:25000"
 	httpAddrEnvKey   = "APP_HTTP_ADDR"
 	httpPortEnvKey   = "APP_PORT"
@@ -46,6 +47,8 @@ type Paths struct {
 	LogDir     string
 	CacheDir   string
 	ExportsDir string
+	// SnapshotDir holds the website monitor's page snapshots.
+	SnapshotDir string
 }
 
 // ResolvePaths builds the set of directories under the configured base.
@@ -65,12 +68,13 @@ func buildPaths(root string) (*Paths, error) {
 		return nil, fmt.Errorf("empty root path")
 	}
 	return &Paths{
-		RootDir:    root,
-		ConfigFile: filepath.Join(root, ConfigFileName),
-		DBFile:     filepath.Join(root, DatabaseFile),
-		LogDir:     filepath.Join(root, LogDirName),
-		CacheDir:   filepath.Join(root, CacheDirName),
-		ExportsDir: filepath.Join(root, ExportsDirName),
+		RootDir:     root,
+		ConfigFile:  filepath.Join(root, ConfigFileName),
+		DBFile:      filepath.Join(root, DatabaseFile),
+		LogDir:      filepath.Join(root, LogDirName),
+		CacheDir:    filepath.Join(root, CacheDirName),
+		ExportsDir:  filepath.Join(root, ExportsDirName),
+		SnapshotDir: filepath.Join(root, SnapshotsDirName),
 	}, nil
 }
 
@@ -96,6 +100,7 @@ func Ensure(paths *Paths) error {
 		paths.LogDir,
 		paths.CacheDir,
 		paths.ExportsDir,
+		paths.SnapshotDir,
 	}
 
 	for _, dir := range dirs {
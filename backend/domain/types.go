@@ -29,6 +29,33 @@ type Contact struct {
 	IsKey  bool   `json:"is_key"`
 	// IsKeyDecisionMaker mirrors the JSON field returned by the enrichment model.
 	IsKeyDecisionMaker bool `json:"is_key_decision_maker,omitempty"`
+	// Email verification results, read-only: they come from the stored
+	// verification of the address and are ignored when saving contacts.
+	EmailStatus       string `json:"email_status,omitempty"`
+	EmailStatusReason string `json:"email_status_reason,omitempty"`
+	EmailRole         bool   `json:"email_role,omitempty"`
+	EmailCheckedAt    string `json:"email_checked_at,omitempty"`
+}
+
+// Email verification statuses.
+const (
+	EmailStatusValid    = "valid"     // the mail server accepted the mailbox
+	EmailStatusDomainOK = "domain_ok" // the domain receives mail; the mailbox was not confirmed
+	EmailStatusRisky    = "risky"     // the server accepts any address (catch-all)
+	EmailStatusInvalid  = "invalid"   // bad syntax, disposable domain, no mail server or rejected mailbox
+	EmailStatusUnknown  = "unknown"   // the checks could not complete
+)
+
+// EmailVerification is the stored deliverability check of one address.
+type EmailVerification struct {
+	Email       string `json:"email"`
+	Status      string `json:"status"`
+	Reason      string `json:"reason,omitempty"`
+	Role        bool   `json:"role,omitempty"`
+	Disposable  bool   `json:"disposable,omitempty"`
+	MXHost      string `json:"mx_host,omitempty"`
+	SMTPChecked bool   `json:"smtp_checked"`
+	CheckedAt   string `json:"checked_at"`
 }
 
 // CandidateWebsite represents a possible official website candidate.
@@ -39,6 +66,43 @@ type CandidateWebsite struct {
 	Reason string `json:"reason"`
 }
 
+// CompanyProfile is structured company data published by the website itself
+// through schema.org JSON-LD, microdata or OpenGraph tags.
+type CompanyProfile struct {
+	Name         string   `json:"name,omitempty"`
+	LegalName    string   `json:"legal_name,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	Address      string   `json:"address,omitempty"`
+	Country      string   `json:"country,omitempty"`
+	Phone        string   `json:"phone,omitempty"`
+	Email        string   `json:"email,omitempty"`
+	Logo         string   `json:"logo,omitempty"`
+	FoundingDate string   `json:"founding_date,omitempty"`
+	Employees    string   `json:"employees,omitempty"`
+	Sources      []string `json:"sources,omitempty"` // json-ld, microdata, opengraph
+}
+
+// SocialLink is a company profile on a social network.
+type SocialLink struct {
+	Platform string `json:"platform"` // linkedin, facebook, instagram or youtube
+	URL      string `json:"url"`
+}
+
+// WebsiteSignals are objective facts detected on a customer's website and
+// passed to grading as evidence.
+type WebsiteSignals struct {
+	Ecommerce      []string `json:"ecommerce,omitempty"`    // shop platforms such as shopify or magento
+	Technologies   []string `json:"technologies,omitempty"` // CMS and site builders
+	Languages      []string `json:"languages,omitempty"`    // language codes the site is published in
+	RFQForm        bool     `json:"rfq_form"`
+	CatalogSize    int      `json:"catalog_size,omitempty"` // products seen or announced; 0 when unknown
+	Certifications []string `json:"certifications,omitempty"`
+	Distributors   bool     `json:"distributor_pages"`
+	Evidence       []string `json:"evidence,omitempty"` // where each signal was found
+	DetectedAt     string   `json:"detected_at,omitempty"`
+	Failed         bool     `json:"failed,omitempty"` // the site could not be read at DetectedAt
+}
+
 // ResolveCompanyRequest contains the user query for Step 1.
 type ResolveCompanyRequest struct {
 	Query string `json:"query"`
@@ -54,6 +118,9 @@ type ResolveCompanyResponse struct {
 	Contacts          []Contact           `json:"contacts"`
 	Candidates        []CandidateWebsite  `json:"candidates"`
 	Summary           string              `json:"summary"`
+	Profile           *CompanyProfile     `json:"profile,omitempty"`
+	SocialLinks       []SocialLink        `json:"social_links,omitempty"`
+	WebsiteSignals    *WebsiteSignals     `json:"website_signals,omitempty"`
 	Grade             string              `json:"grade,omitempty"`
 	GradeReason       string              `json:"grade_reason,omitempty"`
 	LastStep          int                 `json:"last_step,omitempty"`
@@ -72,6 +139,8 @@ type CreateCompanyRequest struct {
 	Summary    string          `json:"summary"`
 	Contacts   []Contact       `json:"contacts"`
 	SourceJSON json.RawMessage `json:"source_json"`
+	// WebsiteSignals replaces the stored signals when set.
+	WebsiteSignals *WebsiteSignals `json:"website_signals,omitempty"`
 }
 
 // Customer represents a stored customer record.
@@ -85,8 +154,10 @@ type Customer struct {
 	Summary      string
 	FollowupSent bool
 	SourceJSON   json.RawMessage
-	CreatedAt    string
-	UpdatedAt    string
+	// WebsiteSignals is nil until the website has been analysed.
+	WebsiteSignals *WebsiteSignals
+	CreatedAt      string
+	UpdatedAt      string
 }
 
 // EmailRecord reflects a stored email row.
@@ -230,21 +301,136 @@ type CustomerSummary struct {
 
 // CustomerDetail aggregates all five workflow steps for editing.
 type CustomerDetail struct {
-	ID            int64               `json:"id"`
-	Name          string              `json:"name"`
-	Website       string              `json:"website"`
-	Country       string              `json:"country"`
-	Summary       string              `json:"summary"`
-	Grade         string              `json:"grade"`
-	GradeReason   string              `json:"grade_reason"`
-	FollowupSent  bool                `json:"followup_sent"`
-	Contacts      []Contact           `json:"contacts"`
-	Analysis      *AnalysisResponse   `json:"analysis,omitempty"`
-	EmailDraft    *EmailDraftResponse `json:"email_draft,omitempty"`
-	FollowupID    int64               `json:"followup_id,omitempty"`
-	ScheduledTask *ScheduledTask      `json:"scheduled_task,omitempty"`
-	AutomationJob *AutomationJob      `json:"automation_job,omitempty"`
-	SourceJSON    json.RawMessage     `json:"source_json,omitempty"`
-	CreatedAt     string              `json:"created_at"`
-	UpdatedAt     string              `json:"updated_at"`
+	ID             int64               `json:"id"`
+	Name           string              `json:"name"`
+	Website        string              `json:"website"`
+	Country        string              `json:"country"`
+	Summary        string              `json:"summary"`
+	Grade          string              `json:"grade"`
+	GradeReason    string              `json:"grade_reason"`
+	FollowupSent   bool                `json:"followup_sent"`
+	Contacts       []Contact           `json:"contacts"`
+	Analysis       *AnalysisResponse   `json:"analysis,omitempty"`
+	EmailDraft     *EmailDraftResponse `json:"email_draft,omitempty"`
+	FollowupID     int64               `json:"followup_id,omitempty"`
+	ScheduledTask  *ScheduledTask      `json:"scheduled_task,omitempty"`
+	AutomationJob  *AutomationJob      `json:"automation_job,omitempty"`
+	SourceJSON     json.RawMessage     `json:"source_json,omitempty"`
+	WebsiteSignals *WebsiteSignals     `json:"website_signals,omitempty"`
+	Activities     []CustomerActivity  `json:"activities,omitempty"`
+	CreatedAt      string              `json:"created_at"`
+	UpdatedAt      string              `json:"updated_at"`
+}
+
+// Customer activity kinds.
+const (
+	ActivityWebsiteChange = "website_change"
+)
+
+// CustomerActivity is an entry in a customer's timeline, such as a detected
+// change on their website.
+type CustomerActivity struct {
+	ID         int64  `json:"id"`
+	CustomerID int64  `json:"customer_id"`
+	Kind       string `json:"kind"`
+	Title      string `json:"title"`
+	Detail     string `json:"detail"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// WebsiteCheckResult reports one website monitor check. The first check of a
+// customer only records a baseline snapshot; a partial check reached too few
+// pages to be compared.
+type WebsiteCheckResult struct {
+	CustomerID  int64             `json:"customer_id"`
+	CheckedAt   string            `json:"checked_at"`
+	Pages       int               `json:"pages"`
+	Baseline    bool              `json:"baseline"`
+	Partial     bool              `json:"partial"`
+	Changed     bool              `json:"changed"`
+	Significant bool              `json:"significant"`
+	Activity    *CustomerActivity `json:"activity,omitempty"`
+}
+
+// LLMUsageBucket aggregates LLM calls for one report group (day, step or customer).
+type LLMUsageBucket struct {
+	Key              string `json:"key"`
+	Label            string `json:"label,omitempty"`
+	Calls            int    `json:"calls"`
+	Failures         int    `json:"failures"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	TotalTokens      int    `json:"total_tokens"`
+	AvgLatencyMs     int64  `json:"avg_latency_ms"`
+}
+
+// LLMBudgetStatus reports token consumption against the configured caps (0 means unlimited).
+type LLMBudgetStatus struct {
+	DailyLimit   int  `json:"daily_limit"`
+	DailyUsed    int  `json:"daily_used"`
+	MonthlyLimit int  `json:"monthly_limit"`
+	MonthlyUsed  int  `json:"monthly_used"`
+	Exceeded     bool `json:"exceeded"`
+}
+
+// LLMUsageReport is the payload of the usage report API.
+type LLMUsageReport struct {
+	GroupBy string           `json:"group_by"`
+	From    string           `json:"from"`
+	To      string           `json:"to"`
+	Buckets []LLMUsageBucket `json:"buckets"`
+	Total   LLMUsageBucket   `json:"total"`
+	Budget  LLMBudgetStatus  `json:"budget"`
+}
+
+// PromptTemplate is one version of an editable LLM prompt. The newest version of a
+// name is the active one; BuiltIn marks the compiled-in default.
+type PromptTemplate struct {
+	ID           int64  `json:"id,omitempty"`
+	Name         string `json:"name"`
+	Title        string `json:"title,omitempty"`
+	Version      int    `json:"version"`
+	SystemPrompt string `json:"system_prompt"`
+	UserPrompt   string `json:"user_prompt"`
+	Note         string `json:"note,omitempty"`
+	BuiltIn      bool   `json:"built_in"`
+	CreatedAt    string `json:"created_at,omitempty"`
+}
+
+// PromptTemplateRequest saves a new version of a prompt template.
+type PromptTemplateRequest struct {
+	SystemPrompt string `json:"system_prompt"`
+	UserPrompt   string `json:"user_prompt"`
+	Note         string `json:"note"`
+}
+
+// PromptPreviewRequest renders a template against a real customer. Empty prompts
+// fall back to the active version so unsaved edits can be previewed too.
+type PromptPreviewRequest struct {
+	CustomerID   int64  `json:"customer_id"`
+	SystemPrompt string `json:"system_prompt"`
+	UserPrompt   string `json:"user_prompt"`
+}
+
+// PromptPreview contains the rendered messages sent to the model.
+type PromptPreview struct {
+	Name         string `json:"name"`
+	SystemPrompt string `json:"system_prompt"`
+	UserPrompt   string `json:"user_prompt"`
+}
+
+// CacheNamespaceStats describes the cached entries of one kind (llm or search).
+type CacheNamespaceStats struct {
+	Name    string `json:"name"`
+	Entries int    `json:"entries"`
+	Expired int    `json:"expired"`
+	Bytes   int64  `json:"bytes"`
+}
+
+// CacheStats summarises the on-disk response cache.
+type CacheStats struct {
+	Dir          string                `json:"dir"`
+	Namespaces   []CacheNamespaceStats `json:"namespaces"`
+	TotalEntries int                   `json:"total_entries"`
+	TotalBytes   int64                 `json:"total_bytes"`
 }
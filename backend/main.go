@@ -128,10 +128,27 @@ func run(ctx context.Context) error {
 		loginVersion = 1
 	}
 
-	bundle := services.NewBundle(services.Options{Store: dataStore})
+	bundle := services.NewBundle(services.Options{Store: dataStore, CacheDir: paths.CacheDir, SnapshotDir: paths.SnapshotDir})
+	// Deferred before the runners so browsers shut down after in-flight searches unwind.
+	defer func() {
+		if err := bundle.Close(); err != nil {
+			log.Printf("关闭服务资源失败: %v", err)
+		}
+	}()
 
+	// Work cut short by the previous shutdown or a crash is still marked running.
+	requeued, err := dataStore.RequeueInterruptedJobs(ctx)
+	if err != nil {
+		return err
+	}
+	if requeued.Total() > 0 {
+		log.Printf("已重新排队中断的任务: automation=%d todo=%d scheduled=%d", requeued.Automation, requeued.Todo, requeued.Scheduled)
+	}
+
+	// Runners are stopped before the store closes; Stop waits for in-flight work to unwind.
 	runner := task.NewRunner(dataStore, bundle.Scheduler)
 	runner.Start(ctx)
+	defer runner.Stop()
 
 	// Start Todo runner for persisted input queue
 	todoRunner := task.NewTodoRunner(bundle.Todo)
@@ -146,6 +163,12 @@ func run(ctx context.Context) error {
 		defer automationRunner.Stop()
 	}
 
+	monitorRunner := task.NewMonitorRunner(bundle.Monitor)
+	if monitorRunner != nil {
+		monitorRunner.Start(ctx)
+		defer monitorRunner.Stop()
+	}
+
 	authManager, err := api.NewAuthManager(api.AuthConfig{
 		PasswordHash:    loginHash,
 		PasswordVersion: loginVersion,
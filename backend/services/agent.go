@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+const (
+	defaultAgentMaxTurns  = 4
+	agentToolOutputRunes  = 4000
+	agentToolErrorPreface = "工具执行失败: "
+)
+
+// ToolHandler executes a tool call. args is the JSON object produced by the
+// model; the returned text is handed back to the model as the tool result.
+type ToolHandler func(ctx context.Context, args json.RawMessage) (string, error)
+
+// Tool pairs a definition offered to the model with the handler that runs it.
+type Tool struct {
+	Definition ToolDefinition
+	Handler    ToolHandler
+}
+
+// Agent runs a bounded tool-calling loop on top of LLMClient.Complete. Services
+// register the tools a task may use and call Run with the task's prompt.
+type Agent struct {
+	llm      *LLMClient
+	tools    []ToolDefinition
+	handlers map[string]ToolHandler
+	maxTurns int
+}
+
+// AgentResult is the outcome of Agent.Run.
+type AgentResult struct {
+	Content   string
+	Messages  []ChatMessage // full transcript including tool traffic
+	ToolCalls int
+	Usage     Usage
+}
+
+// NewAgent creates an agent allowing at most maxTurns model turns that may call
+// tools; non-positive values use the default.
+func NewAgent(llm *LLMClient, maxTurns int) *Agent {
+	if maxTurns <= 0 {
+		maxTurns = defaultAgentMaxTurns
+	}
+	return &Agent{llm: llm, handlers: make(map[string]ToolHandler), maxTurns: maxTurns}
+}
+
+// Register makes tools available to the model. Registering a name twice replaces the earlier tool.
+func (a *Agent) Register(tools ...Tool) *Agent {
+	for _, tool := range tools {
+		name := strings.TrimSpace(tool.Definition.Name)
+		if name == "" || tool.Handler == nil {
+			continue
+		}
+		if _, exists := a.handlers[name]; exists {
+			for i := range a.tools {
+				if a.tools[i].Name == name {
+					a.tools[i] = tool.Definition
+				}
+			}
+		} else {
+			a.tools = append(a.tools, tool.Definition)
+		}
+		a.handlers[name] = tool.Handler
+	}
+	return a
+}
+
+// Run sends messages with the registered tools and executes requested calls
+// until the model answers in text. Once maxTurns tool rounds are used up the
+// model is asked for a final answer with tool use disabled.
+func (a *Agent) Run(ctx context.Context, messages []ChatMessage, opts ChatOptions) (*AgentResult, error) {
+	if a == nil || a.llm == nil {
+		return nil, fmt.Errorf("llm 未配置")
+	}
+	result := &AgentResult{Messages: append([]ChatMessage(nil), messages...)}
+	opts.Tools = a.tools
+	for turn := 0; ; turn++ {
+		opts.ToolChoice = ToolChoiceAuto
+		if turn >= a.maxTurns || len(a.tools) == 0 {
+			opts.ToolChoice = ToolChoiceNone
+		}
+		reply, err := a.llm.Complete(ctx, result.Messages, opts)
+		if err != nil {
+			return nil, err
+		}
+		addUsage(&result.Usage, reply.Usage)
+		if len(reply.ToolCalls) == 0 || opts.ToolChoice == ToolChoiceNone {
+			if strings.TrimSpace(reply.Content) == "" {
+				return nil, fmt.Errorf("LLM 未返回任何内容")
+			}
+			result.Content = strings.TrimSpace(reply.Content)
+			result.Messages = append(result.Messages, ChatMessage{Role: "assistant", Content: reply.Content})
+			return result, nil
+		}
+
+		calls := reply.ToolCalls
+		for i := range calls {
+			if calls[i].ID == "" {
+				calls[i].ID = fmt.Sprintf("call_%d_%d", turn, i)
+			}
+		}
+		result.Messages = append(result.Messages, ChatMessage{Role: "assistant", Content: reply.Content, ToolCalls: calls})
+		for _, call := range calls {
+			output := a.invoke(ctx, call, opts.Task)
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			result.ToolCalls++
+			result.Messages = append(result.Messages, ChatMessage{Role: "tool", Content: output, ToolCallID: call.ID, Name: call.Name})
+		}
+	}
+}
+
+// invoke runs one tool call. Failures are reported to the model as text so it
+// can recover, rather than aborting the whole run.
+func (a *Agent) invoke(ctx context.Context, call ToolCall, task string) string {
+	handler, ok := a.handlers[call.Name]
+	if !ok {
+		return fmt.Sprintf("%s未知工具 %s", agentToolErrorPreface, call.Name)
+	}
+	args := json.RawMessage(call.Arguments)
+	if strings.TrimSpace(call.Arguments) == "" {
+		args = json.RawMessage("{}")
+	}
+	output, err := handler(ctx, args)
+	if err != nil {
+		log.Printf("[agent] task=%s tool=%s status=failed error=%v", task, call.Name, err)
+		return agentToolErrorPreface + err.Error()
+	}
+	log.Printf("[agent] task=%s tool=%s status=completed bytes=%d", task, call.Name, len(output))
+	return truncateRunes(strings.TrimSpace(output), agentToolOutputRunes)
+}
+
+func addUsage(total *Usage, usage Usage) {
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	total.TotalTokens += usage.TotalTokens
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+func TestAgentRunsToolLoop(t *testing.T) {
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		requests = append(requests, payload)
+		if len(requests) == 1 {
+			fmt.Fprint(w, `{"choices":[{"message":{"content":"","tool_calls":[
+				{"id":"call_a","type":"function","function":{"name":"lookup","arguments":"{\"name\":\"Acme\"}"}},
+				{"id":"call_b","type":"function","function":{"name":"missing","arguments":"{}"}}]}}],
+				"usage":{"total_tokens":10}}`)
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"content":"Acme makes widgets"}}],"usage":{"total_tokens":5}}`)
+	}))
+	defer server.Close()
+
+	st := setupTestStore(t)
+	defer st.Close()
+	data, _ := json.Marshal(store.Settings{LLMBaseURL: server.URL, LLMAPIKey: "test", LLMModel: "m"})
+	if err := st.SaveSettings(context.Background(), bytes.NewReader(data)); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	var gotName string
+	agent := NewAgent(NewLLMClient(st, server.Client()), 2).Register(Tool{
+		Definition: ToolDefinition{Name: "lookup", Description: "find a company"},
+		Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+			var input struct {
+				Name string `json:"name"`
+			}
+			if err := json.Unmarshal(args, &input); err != nil {
+				return "", err
+			}
+			gotName = input.Name
+			return "Acme: widget maker", nil
+		},
+	})
+
+	result, err := agent.Run(context.Background(), []ChatMessage{{Role: "user", Content: "who is Acme?"}}, ChatOptions{})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if result.Content != "Acme makes widgets" || result.ToolCalls != 2 || result.Usage.TotalTokens != 15 {
+		t.Fatalf("unexpected result %#v", result)
+	}
+	if gotName != "Acme" {
+		t.Fatalf("handler received %q", gotName)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+	tools, _ := requests[0]["tools"].([]any)
+	if len(tools) != 1 || requests[0]["tool_choice"] != ToolChoiceAuto {
+		t.Fatalf("tools not offered: %v", requests[0])
+	}
+
+	messages, _ := requests[1]["messages"].([]any)
+	if len(messages) != 4 {
+		t.Fatalf("expected user, assistant and two tool messages, got %v", messages)
+	}
+	assistant := messages[1].(map[string]any)
+	if calls, _ := assistant["tool_calls"].([]any); len(calls) != 2 {
+		t.Fatalf("assistant tool calls not replayed: %v", assistant)
+	}
+	first := messages[2].(map[string]any)
+	if first["role"] != "tool" || first["tool_call_id"] != "call_a" || first["content"] != "Acme: widget maker" {
+		t.Fatalf("unexpected tool result %v", first)
+	}
+	second := messages[3].(map[string]any)
+	if second["tool_call_id"] != "call_b" || second["content"] != agentToolErrorPreface+"未知工具 missing" {
+		t.Fatalf("unknown tool should be reported to the model, got %v", second)
+	}
+}
+
+func TestAgentForcesAnswerAfterMaxTurns(t *testing.T) {
+	var choices []any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		choices = append(choices, payload["tool_choice"])
+		if payload["tool_choice"] == ToolChoiceNone {
+			fmt.Fprint(w, `{"choices":[{"message":{"content":"final"}}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"tool_calls":[{"id":"c","type":"function","function":{"name":"noop","arguments":""}}]}}]}`)
+	}))
+	defer server.Close()
+
+	st := setupTestStore(t)
+	defer st.Close()
+	data, _ := json.Marshal(store.Settings{LLMBaseURL: server.URL, LLMAPIKey: "test", LLMModel: "m"})
+	if err := st.SaveSettings(context.Background(), bytes.NewReader(data)); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	agent := NewAgent(NewLLMClient(st, server.Client()), 2).Register(Tool{
+		Definition: ToolDefinition{Name: "noop"},
+		Handler:    func(context.Context, json.RawMessage) (string, error) { return "ok", nil },
+	})
+	result, err := agent.Run(context.Background(), []ChatMessage{{Role: "user", Content: "loop"}}, ChatOptions{})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if result.Content != "final" || result.ToolCalls != 2 {
+		t.Fatalf("unexpected result %#v", result)
+	}
+	if len(choices) != 3 || choices[2] != ToolChoiceNone {
+		t.Fatalf("expected two tool turns then a forced answer, got %v", choices)
+	}
+}
+
+func TestAnthropicMessagesEncodeToolTraffic(t *testing.T) {
+	system, messages := anthropicMessages([]ChatMessage{
+		{Role: "system", Content: "be brief"},
+		{Role: "user", Content: "hi"},
+		{Role: "assistant", Content: "checking", ToolCalls: []ToolCall{
+			{ID: "t1", Name: "lookup", Arguments: `{"q":"a"}`},
+			{ID: "t2", Name: "lookup", Arguments: ""},
+		}},
+		{Role: "tool", ToolCallID: "t1", Content: "one"},
+		{Role: "tool", ToolCallID: "t2", Content: "two"},
+	})
+	if len(system) != 1 || len(messages) != 3 {
+		t.Fatalf("unexpected split: %v %v", system, messages)
+	}
+	blocks, _ := messages[1]["content"].([]map[string]any)
+	if len(blocks) != 3 || blocks[0]["type"] != "text" || blocks[2]["type"] != "tool_use" {
+		t.Fatalf("unexpected assistant blocks %v", blocks)
+	}
+	if input, _ := blocks[2]["input"].(json.RawMessage); string(input) != "{}" {
+		t.Fatalf("blank arguments should become an empty object, got %s", input)
+	}
+	results, _ := messages[2]["content"].([]map[string]any)
+	if messages[2]["role"] != "user" || len(results) != 2 || results[1]["tool_use_id"] != "t2" {
+		t.Fatalf("tool results should merge into one user message, got %v", messages[2])
+	}
+}
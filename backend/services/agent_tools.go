@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const agentSearchMaxResults = 8
+
+// SearchTool lets the model run a web search through the configured search provider.
+func SearchTool(search *SearchClient) Tool {
+	return Tool{
+		Definition: ToolDefinition{
+			Name:        "web_search",
+			Description: "Search the web and return result titles, URLs and snippets.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"query": map[string]any{"type": "string", "description": "Search keywords"},
+					"limit": map[string]any{"type": "integer", "description": "Maximum number of results (1-8)"},
+				},
+				"required": []string{"query"},
+			},
+		},
+		Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+			var input struct {
+				Query string `json:"query"`
+				Limit int    `json:"limit"`
+			}
+			if err := json.Unmarshal(args, &input); err != nil {
+				return "", fmt.Errorf("参数解析失败: %w", err)
+			}
+			if strings.TrimSpace(input.Query) == "" {
+				return "", fmt.Errorf("query 不能为空")
+			}
+			if input.Limit <= 0 || input.Limit > agentSearchMaxResults {
+				input.Limit = agentSearchMaxResults
+			}
+			items, err := search.Search(ctx, input.Query, input.Limit)
+			if err != nil {
+				return "", err
+			}
+			if len(items) == 0 {
+				return "没有找到结果。", nil
+			}
+			var b strings.Builder
+			for i, item := range items {
+				fmt.Fprintf(&b, "%d. %s\n   %s\n", i+1, strings.TrimSpace(item.Title), item.URL)
+				if snippet := strings.TrimSpace(item.Snippet); snippet != "" {
+					fmt.Fprintf(&b, "   %s\n", truncateRunes(snippet, 300))
+				}
+			}
+			return b.String(), nil
+		},
+	}
+}
+
+// FetchPageTool lets the model read the condensed text and contact details of a web page.
+func FetchPageTool(fetcher *WebFetcher) Tool {
+	return Tool{
+		Definition: ToolDefinition{
+			Name:        "fetch_page",
+			Description: "Fetch a web page and return its main text plus any emails and phone numbers found.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"url": map[string]any{"type": "string", "description": "Absolute URL or bare domain"},
+				},
+				"required": []string{"url"},
+			},
+		},
+		Handler: func(ctx context.Context, args json.RawMessage) (string, error) {
+			var input struct {
+				URL string `json:"url"`
+			}
+			if err := json.Unmarshal(args, &input); err != nil {
+				return "", fmt.Errorf("参数解析失败: %w", err)
+			}
+			summary, err := fetcher.Fetch(ctx, strings.TrimSpace(input.URL))
+			if err != nil {
+				return "", err
+			}
+			var b strings.Builder
+			fmt.Fprintf(&b, "URL: %s\n", summary.URL)
+			if summary.FetchMode == FetchModeBrowser {
+				b.WriteString("Rendered with headless browser\n")
+			}
+			if len(summary.Emails) > 0 {
+				fmt.Fprintf(&b, "Emails: %s\n", strings.Join(summary.Emails, ", "))
+			}
+			if len(summary.Phones) > 0 {
+				fmt.Fprintf(&b, "Phones: %s\n", strings.Join(summary.Phones, ", "))
+			}
+			b.WriteString(summary.Text)
+			return b.String(), nil
+		},
+	}
+}
@@ -2,7 +2,6 @@ package services
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -10,19 +9,54 @@ import (
 	"github.com/anner/ai-foreign-trade-assistant/backend/store"
 )
 
-// AnalysisServiceImpl implements Step 3 content generation.
+// AnalysisServiceImpl implements Step 3 content generation. It works from the
+// stored customer profile, which the enrichment research agent has already
+// built with web search and page fetches, so it calls no tools itself.
 type AnalysisServiceImpl struct {
-	store *store.Store
-	llm   *LLMClient
+	store   *store.Store
+	llm     *LLMClient
+	prompts *PromptServiceImpl
 }
 
 // NewAnalysisService builds a new analysis service.
-func NewAnalysisService(st *store.Store, llm *LLMClient) *AnalysisServiceImpl {
-	return &AnalysisServiceImpl{store: st, llm: llm}
+func NewAnalysisService(st *store.Store, llm *LLMClient, prompts *PromptServiceImpl) *AnalysisServiceImpl {
+	return &AnalysisServiceImpl{store: st, llm: llm, prompts: prompts}
 }
 
 // Generate produces the product entry-point analysis and persists it.
 func (a *AnalysisServiceImpl) Generate(ctx context.Context, customerID int64) (*domain.AnalysisResponse, error) {
+	messages, err := a.prepareMessages(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	var parsed domain.AnalysisContent
+	if err := a.llm.ChatJSON(ctx, messages, analysisChatOptions.WithCustomer(customerID), analysisSchema, &parsed); err != nil {
+		return nil, fmt.Errorf("解析分析结果失败: %w", err)
+	}
+	return a.persist(ctx, customerID, parsed)
+}
+
+// GenerateStream behaves like Generate but forwards partial model output to onDelta as it arrives.
+func (a *AnalysisServiceImpl) GenerateStream(ctx context.Context, customerID int64, onDelta func(string)) (*domain.AnalysisResponse, error) {
+	messages, err := a.prepareMessages(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	opts := analysisChatOptions.WithCustomer(customerID)
+	content, _, err := a.llm.ChatStream(ctx, messages, opts, onDelta)
+	if err != nil {
+		return nil, err
+	}
+	var parsed domain.AnalysisContent
+	if err := a.llm.DecodeJSON(ctx, messages, opts, content, analysisSchema, &parsed); err != nil {
+		return nil, fmt.Errorf("解析分析结果失败: %w", err)
+	}
+	return a.persist(ctx, customerID, parsed)
+}
+
+var analysisChatOptions = ChatOptions{MaxTokens: 600, Temperature: 0.3, ResponseFormat: "json_object", Task: LLMTaskAnalysis}
+
+func (a *AnalysisServiceImpl) prepareMessages(ctx context.Context, customerID int64) ([]ChatMessage, error) {
 	customer, err := a.store.GetCustomer(ctx, customerID)
 	if err != nil {
 		return nil, err
@@ -35,20 +69,13 @@ func (a *AnalysisServiceImpl) Generate(ctx context.Context, customerID int64) (*
 		return nil, fmt.Errorf("请先在设置页填写“我的产品/服务简介”")
 	}
 
-	prompt := buildAnalysisPrompt(customer, settings.MyProduct)
-	content, _, err := a.llm.Chat(ctx, []ChatMessage{
-		{Role: "system", Content: analysisSystemPrompt},
-		{Role: "user", Content: prompt},
-	}, ChatOptions{MaxTokens: 600, Temperature: 0.3, ResponseFormat: "json_object"})
-	if err != nil {
-		return nil, err
-	}
-
-	var parsed domain.AnalysisContent
-	if err := json.Unmarshal([]byte(content), &parsed); err != nil {
-		return nil, fmt.Errorf("解析分析结果失败: %w", err)
-	}
+	return a.prompts.Messages(ctx, PromptAnalysis, PromptData{
+		Customer: customer,
+		Settings: promptSettingsFrom(settings),
+	})
+}
 
+func (a *AnalysisServiceImpl) persist(ctx context.Context, customerID int64, parsed domain.AnalysisContent) (*domain.AnalysisResponse, error) {
 	analysisID, err := a.store.SaveAnalysis(ctx, customerID, parsed)
 	if err != nil {
 		return nil, err
@@ -56,29 +83,3 @@ func (a *AnalysisServiceImpl) Generate(ctx context.Context, customerID int64) (*
 
 	return &domain.AnalysisResponse{AnalysisID: analysisID, AnalysisContent: parsed}, nil
 }
-
-func buildAnalysisPrompt(customer *domain.Customer, productProfile string) string {
-	var sb strings.Builder
-	sb.WriteString("客户名称: ")
-	sb.WriteString(customer.Name)
-	sb.WriteString("\n官网: ")
-	sb.WriteString(customer.Website)
-	sb.WriteString("\n国家: ")
-	sb.WriteString(customer.Country)
-	sb.WriteString("\n客户摘要: ")
-	sb.WriteString(customer.Summary)
-	sb.WriteString("\n\n我的产品/服务简介:\n")
-	sb.WriteString(productProfile)
-	sb.WriteString(`
-请输出 JSON：{
-  "core_business": "客户主营业务与定位",
-  "pain_points": "客户可能的痛点或待解决问题",
-  "my_entry_points": "结合我方产品的切入建议",
-  "full_report": "对上述内容进行200字左右的综述"
-}
-使用专业中文表达。
-`)
-	return sb.String()
-}
-
-const analysisSystemPrompt = "你是资深产品策略顾问，请结合客户需求与我方产品优势，输出精准的切入分析。"
@@ -18,6 +18,7 @@ type AutomationServiceImpl struct {
 	analyst   AnalysisService
 	email     EmailComposerService
 	scheduler SchedulerService
+	budget    budgetGate
 }
 
 // ErrAutomationJobExists indicates a queued or running automation job already exists.
@@ -25,7 +26,14 @@ var ErrAutomationJobExists = errors.New("automation job already in progress")
 
 // NewAutomationService constructs an automation service instance.
 func NewAutomationService(st *store.Store, grader GradingService, analyst AnalysisService, email EmailComposerService, scheduler SchedulerService) *AutomationServiceImpl {
-	return &AutomationServiceImpl{store: st, grader: grader, analyst: analyst, email: email, scheduler: scheduler}
+	return &AutomationServiceImpl{
+		store:     st,
+		grader:    grader,
+		analyst:   analyst,
+		email:     email,
+		scheduler: scheduler,
+		budget:    budgetGate{scope: "automation"},
+	}
 }
 
 // Enqueue registers a new automation workflow for the given customer.
@@ -40,13 +48,17 @@ func (s *AutomationServiceImpl) Enqueue(ctx context.Context, customerID int64) (
 	job, err := s.store.CreateAutomationJob(ctx, customerID)
 	if err == nil && job != nil {
 		// 轻量“唤醒”，确保队列不会因为 runner 间隔而显得中断
-		go func() { _, _ = s.ProcessNext(context.Background()) }()
+		go func() { _, _ = s.ProcessNext(WithBackgroundPriority(context.Background())) }()
 	}
 	return job, err
 }
 
-// ProcessNext claims and executes the next pending automation job.
+// ProcessNext claims and executes the next pending automation job. Queued jobs
+// stay untouched while the LLM token budget is exhausted.
 func (s *AutomationServiceImpl) ProcessNext(ctx context.Context) (bool, error) {
+	if ok, err := s.budget.allow(ctx, s.store); err != nil || !ok {
+		return false, err
+	}
 	job, err := s.store.ClaimNextAutomationJob(ctx)
 	if err != nil {
 		return false, err
@@ -64,7 +76,7 @@ func (s *AutomationServiceImpl) runJob(ctx context.Context, job *domain.Automati
 	log.Printf("[automation] job=%d customer=%d started", job.ID, job.CustomerID)
 	settings, err := s.store.GetSettings(ctx)
 	if err != nil {
-		_ = s.store.MarkAutomationJobFailed(ctx, job.ID, domain.AutomationStagePending, err.Error())
+		s.markFailed(ctx, job.ID, domain.AutomationStagePending, err)
 		return err
 	}
 
@@ -84,7 +96,7 @@ func (s *AutomationServiceImpl) runJob(ctx context.Context, job *domain.Automati
 
 	suggestion, err := s.grader.Suggest(ctx, job.CustomerID)
 	if err != nil {
-		_ = s.store.MarkAutomationJobFailed(ctx, job.ID, domain.AutomationStageGrading, err.Error())
+		s.markFailed(ctx, job.ID, domain.AutomationStageGrading, err)
 		return err
 	}
 
@@ -95,7 +107,7 @@ func (s *AutomationServiceImpl) runJob(ctx context.Context, job *domain.Automati
 	reason := strings.TrimSpace(suggestion.Reason)
 
 	if err := s.grader.Confirm(ctx, job.CustomerID, grade, reason); err != nil {
-		_ = s.store.MarkAutomationJobFailed(ctx, job.ID, domain.AutomationStageGrading, err.Error())
+		s.markFailed(ctx, job.ID, domain.AutomationStageGrading, err)
 		return err
 	}
 	log.Printf("[automation] job=%d grading grade=%s", job.ID, grade)
@@ -115,7 +127,7 @@ func (s *AutomationServiceImpl) runJob(ctx context.Context, job *domain.Automati
 	}
 	log.Printf("[automation] job=%d stage=%s", job.ID, domain.AutomationStageAnalysis)
 	if _, err := s.analyst.Generate(ctx, job.CustomerID); err != nil {
-		_ = s.store.MarkAutomationJobFailed(ctx, job.ID, domain.AutomationStageAnalysis, err.Error())
+		s.markFailed(ctx, job.ID, domain.AutomationStageAnalysis, err)
 		return err
 	}
 	log.Printf("[automation] job=%d analysis generated", job.ID)
@@ -127,13 +139,13 @@ func (s *AutomationServiceImpl) runJob(ctx context.Context, job *domain.Automati
 	log.Printf("[automation] job=%d stage=%s", job.ID, domain.AutomationStageEmail)
 	emailDraft, err := s.email.DraftInitial(ctx, job.CustomerID)
 	if err != nil {
-		_ = s.store.MarkAutomationJobFailed(ctx, job.ID, domain.AutomationStageEmail, err.Error())
+		s.markFailed(ctx, job.ID, domain.AutomationStageEmail, err)
 		return err
 	}
 	emailID := emailDraft.EmailID
 	if emailID == 0 {
 		err := fmt.Errorf("自动化生成的邮件缺少有效 ID")
-		_ = s.store.MarkAutomationJobFailed(ctx, job.ID, domain.AutomationStageEmail, err.Error())
+		s.markFailed(ctx, job.ID, domain.AutomationStageEmail, err)
 		return err
 	}
 	log.Printf("[automation] job=%d email_draft id=%d", job.ID, emailID)
@@ -150,7 +162,7 @@ func (s *AutomationServiceImpl) runJob(ctx context.Context, job *domain.Automati
 	}
 	if followupID == 0 {
 		if _, err := s.store.SaveInitialFollowup(ctx, job.CustomerID, emailID, "自动化流程创建"); err != nil {
-			_ = s.store.MarkAutomationJobFailed(ctx, job.ID, domain.AutomationStageFollowup, err.Error())
+			s.markFailed(ctx, job.ID, domain.AutomationStageFollowup, err)
 			return err
 		}
 		log.Printf("[automation] job=%d followup created", job.ID)
@@ -183,7 +195,7 @@ func (s *AutomationServiceImpl) runJob(ctx context.Context, job *domain.Automati
 				// Clean up the job record as in other stop/completed paths.
 				return s.store.DeleteAutomationJob(ctx, job.ID)
 			}
-			_ = s.store.MarkAutomationJobFailed(ctx, job.ID, domain.AutomationStageFollowup, err.Error())
+			s.markFailed(ctx, job.ID, domain.AutomationStageFollowup, err)
 			return err
 		}
 		log.Printf("[automation] job=%d followup scheduled delay_days=%d", job.ID, delay)
@@ -196,4 +208,14 @@ func (s *AutomationServiceImpl) runJob(ctx context.Context, job *domain.Automati
 	return s.store.DeleteAutomationJob(ctx, job.ID)
 }
 
+// markFailed records a stage failure. A job interrupted by cancellation is left
+// running so that it is requeued on the next start instead of being failed.
+func (s *AutomationServiceImpl) markFailed(ctx context.Context, jobID int64, stage string, err error) {
+	if ctx.Err() != nil {
+		log.Printf("[automation] job=%d stage=%s interrupted: %v", jobID, stage, err)
+		return
+	}
+	_ = s.store.MarkAutomationJobFailed(ctx, jobID, stage, err.Error())
+}
+
 var _ AutomationService = (*AutomationServiceImpl)(nil)
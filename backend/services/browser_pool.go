@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+const (
+	defaultBrowserPoolSize   = 2
+	defaultBrowserContexts   = 4 // concurrent contexts per browser
+	defaultBrowserMaxUses    = 50
+	browserPoolSweepInterval = 30 * time.Second
+	browserPoolIdleTimeout   = 5 * time.Minute
+)
+
+var errBrowserPoolClosed = errors.New("浏览器池已关闭")
+
+// BrowserPool keeps a few headless Chromium instances alive so searches and
+// page fetches reuse them instead of starting the driver and a browser per
+// query. Every lease gets a fresh browser context, so cookies never leak
+// between queries. A browser is recycled after maxUses leases or as soon as it
+// disconnects, and idle browsers are closed by a background sweep.
+type BrowserPool struct {
+	size       int
+	perBrowser int
+	maxUses    int
+	launch     func() (playwright.Browser, error)
+	slots      chan struct{}
+
+	mu        sync.Mutex
+	browsers  []*pooledBrowser
+	pw        *playwright.Playwright
+	closed    bool
+	stop      chan struct{}
+	sweepOnce sync.Once
+}
+
+type pooledBrowser struct {
+	browser  playwright.Browser
+	active   int
+	uses     int
+	retired  bool
+	lastUsed time.Time
+}
+
+// NewBrowserPool creates a pool of at most size browsers, each recycled after
+// maxUses leases. Non-positive values use the defaults. Nothing is started
+// until the first lease.
+func NewBrowserPool(size, maxUses int) *BrowserPool {
+	if size <= 0 {
+		size = defaultBrowserPoolSize
+	}
+	if maxUses <= 0 {
+		maxUses = defaultBrowserMaxUses
+	}
+	p := &BrowserPool{
+		size:       size,
+		perBrowser: defaultBrowserContexts,
+		maxUses:    maxUses,
+		slots:      make(chan struct{}, size*defaultBrowserContexts),
+		stop:       make(chan struct{}),
+	}
+	p.launch = p.launchChromium
+	return p
+}
+
+// WithPage leases a browser, opens a new context and page, and runs fn. The
+// context is closed when fn returns or ctx is cancelled, which aborts any
+// navigation still in flight. Callers must not keep the page after fn returns.
+func (p *BrowserPool) WithPage(ctx context.Context, opts playwright.BrowserNewContextOptions, fn func(page playwright.Page) error) error {
+	entry, err := p.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	browserCtx, err := entry.browser.NewContext(opts)
+	if err != nil {
+		p.release(entry, err)
+		return fmt.Errorf("创建浏览器上下文失败: %w", err)
+	}
+	// Playwright calls take no context; closing the browser context is the
+	// only way to interrupt them once the caller gives up.
+	stopOnCancel := context.AfterFunc(ctx, func() { _ = browserCtx.Close() })
+	defer func() {
+		stopOnCancel()
+		_ = browserCtx.Close()
+		p.release(entry, err)
+	}()
+
+	page, err := browserCtx.NewPage()
+	if err != nil {
+		return fmt.Errorf("创建页面失败: %w", err)
+	}
+	err = fn(page)
+	return err
+}
+
+// Close shuts down every browser and the Playwright driver. Leases still in
+// flight fail; later calls to WithPage return an error.
+func (p *BrowserPool) Close() error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.stop)
+	browsers := p.browsers
+	p.browsers = nil
+	pw := p.pw
+	p.pw = nil
+	p.mu.Unlock()
+
+	for _, entry := range browsers {
+		_ = entry.browser.Close()
+	}
+	if pw != nil {
+		if err := pw.Stop(); err != nil {
+			return fmt.Errorf("停止 Playwright 失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// acquire waits for a free context slot and returns the least busy healthy
+// browser, launching one when the pool has room.
+func (p *BrowserPool) acquire(ctx context.Context) (*pooledBrowser, error) {
+	if p == nil {
+		return nil, errBrowserPoolClosed
+	}
+	select {
+	case p.slots <- struct{}{}:
+	case <-p.stop:
+		return nil, errBrowserPoolClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		<-p.slots
+		return nil, errBrowserPoolClosed
+	}
+	p.sweepOnce.Do(func() { go p.sweepLoop() })
+
+	var best *pooledBrowser
+	live := 0
+	for _, entry := range append([]*pooledBrowser(nil), p.browsers...) {
+		if entry.retired {
+			continue
+		}
+		if !entry.browser.IsConnected() {
+			p.retireLocked(entry, "disconnected")
+			continue
+		}
+		live++
+		if entry.active < p.perBrowser && (best == nil || entry.active < best.active) {
+			best = entry
+		}
+	}
+	if best == nil || (best.active > 0 && live < p.size) {
+		// Launching while holding the lock serialises start-up, which keeps
+		// a burst of callers from starting more browsers than the pool allows.
+		browser, err := p.launch()
+		if err != nil {
+			if best == nil {
+				<-p.slots
+				return nil, err
+			}
+			log.Printf("[browser] launch failed, reusing running browser: %v", err)
+		} else {
+			best = &pooledBrowser{browser: browser}
+			p.browsers = append(p.browsers, best)
+		}
+	}
+	best.active++
+	best.uses++
+	best.lastUsed = time.Now()
+	if best.uses >= p.maxUses {
+		// No new leases; the browser closes once the current ones finish.
+		best.retired = true
+	}
+	return best, nil
+}
+
+// release returns a lease. Browsers that crashed or reached their use limit
+// are closed once their last lease ends.
+func (p *BrowserPool) release(entry *pooledBrowser, err error) {
+	p.mu.Lock()
+	entry.active--
+	entry.lastUsed = time.Now()
+	if !entry.retired && (errors.Is(err, playwright.ErrTargetClosed) || !entry.browser.IsConnected()) {
+		entry.retired = true
+	}
+	if entry.retired && entry.active == 0 {
+		p.removeLocked(entry)
+	}
+	p.mu.Unlock()
+	<-p.slots
+}
+
+func (p *BrowserPool) retireLocked(entry *pooledBrowser, reason string) {
+	entry.retired = true
+	log.Printf("[browser] retiring browser: %s uses=%d", reason, entry.uses)
+	if entry.active == 0 {
+		p.removeLocked(entry)
+	}
+}
+
+func (p *BrowserPool) removeLocked(entry *pooledBrowser) {
+	for i, candidate := range p.browsers {
+		if candidate == entry {
+			p.browsers = append(p.browsers[:i], p.browsers[i+1:]...)
+			break
+		}
+	}
+	go func() { _ = entry.browser.Close() }()
+}
+
+// sweepLoop is the pool's health check: it drops browsers that disconnected
+// while idle and closes ones unused for browserPoolIdleTimeout.
+func (p *BrowserPool) sweepLoop() {
+	ticker := time.NewTicker(browserPoolSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.sweep(time.Now())
+		}
+	}
+}
+
+func (p *BrowserPool) sweep(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, entry := range append([]*pooledBrowser(nil), p.browsers...) {
+		if entry.active > 0 {
+			continue
+		}
+		switch {
+		case !entry.browser.IsConnected():
+			p.retireLocked(entry, "disconnected")
+		case now.Sub(entry.lastUsed) > browserPoolIdleTimeout:
+			p.retireLocked(entry, "idle")
+		}
+	}
+}
+
+// chromiumLaunchArgs are the anti-detection flags the scrapers rely on. The
+// pool also renders untrusted customer sites, so the same-origin policy stays
+// on: never add --disable-web-security here.
+var chromiumLaunchArgs = []string{
+	"--disable-blink-features=AutomationControlled",
+	"--no-sandbox",
+	"--disable-setuid-sandbox",
+	"--disable-dev-shm-usage",
+	"--disable-features=VizDisplayCompositor",
+}
+
+// launchChromium starts the Playwright driver on first use and launches a
+// headless Chromium with chromiumLaunchArgs.
+func (p *BrowserPool) launchChromium() (playwright.Browser, error) {
+	if p.pw == nil {
+		pw, err := playwright.Run()
+		if err != nil {
+			return nil, fmt.Errorf("failed to start Playwright: %w", err)
+		}
+		p.pw = pw
+	}
+	browser, err := p.pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
+		Headless: playwright.Bool(true),
+		Args:     chromiumLaunchArgs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to launch browser: %w", err)
+	}
+	return browser, nil
+}
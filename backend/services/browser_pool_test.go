@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// fakeBrowser implements just enough of playwright.Browser for pool bookkeeping.
+type fakeBrowser struct {
+	playwright.Browser
+	disconnected atomic.Bool
+	closed       atomic.Int32
+}
+
+func (b *fakeBrowser) IsConnected() bool { return !b.disconnected.Load() }
+
+func (b *fakeBrowser) Close(...playwright.BrowserCloseOptions) error {
+	b.closed.Add(1)
+	return nil
+}
+
+func newFakeBrowserPool(size, maxUses int) (*BrowserPool, *[]*fakeBrowser) {
+	pool := NewBrowserPool(size, maxUses)
+	launched := &[]*fakeBrowser{}
+	pool.launch = func() (playwright.Browser, error) {
+		browser := &fakeBrowser{}
+		*launched = append(*launched, browser)
+		return browser, nil
+	}
+	return pool, launched
+}
+
+func waitClosed(t *testing.T, browser *fakeBrowser) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for browser.closed.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("browser was not closed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBrowserPoolReusesAndRecycles(t *testing.T) {
+	pool, launched := newFakeBrowserPool(1, 2)
+	defer pool.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		entry, err := pool.acquire(ctx)
+		if err != nil {
+			t.Fatalf("acquire: %v", err)
+		}
+		pool.release(entry, nil)
+	}
+	if len(*launched) != 1 {
+		t.Fatalf("expected the browser to be reused, launched %d", len(*launched))
+	}
+	waitClosed(t, (*launched)[0])
+
+	entry, err := pool.acquire(ctx)
+	if err != nil {
+		t.Fatalf("acquire after recycle: %v", err)
+	}
+	pool.release(entry, nil)
+	if len(*launched) != 2 {
+		t.Fatalf("expected a fresh browser after max uses, launched %d", len(*launched))
+	}
+}
+
+func TestBrowserPoolReplacesCrashedBrowser(t *testing.T) {
+	pool, launched := newFakeBrowserPool(1, 10)
+	defer pool.Close()
+	ctx := context.Background()
+
+	entry, err := pool.acquire(ctx)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	pool.release(entry, playwright.ErrTargetClosed)
+	waitClosed(t, (*launched)[0])
+
+	entry, err = pool.acquire(ctx)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	pool.release(entry, nil)
+	(*launched)[1].disconnected.Store(true)
+	entry, err = pool.acquire(ctx)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	pool.release(entry, nil)
+	if len(*launched) != 3 {
+		t.Fatalf("expected each dead browser to be replaced, launched %d", len(*launched))
+	}
+	waitClosed(t, (*launched)[1])
+}
+
+func TestBrowserPoolBoundsConcurrentLeases(t *testing.T) {
+	pool, launched := newFakeBrowserPool(2, 10)
+	defer pool.Close()
+
+	var leases []*pooledBrowser
+	for i := 0; i < 2*defaultBrowserContexts; i++ {
+		entry, err := pool.acquire(context.Background())
+		if err != nil {
+			t.Fatalf("acquire %d: %v", i, err)
+		}
+		leases = append(leases, entry)
+	}
+	if len(*launched) != 2 {
+		t.Fatalf("expected load spread over 2 browsers, launched %d", len(*launched))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected acquire to wait for a free slot, got %v", err)
+	}
+	pool.release(leases[0], nil)
+	entry, err := pool.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+	pool.release(entry, nil)
+}
+
+func TestBrowserPoolSweepAndClose(t *testing.T) {
+	pool, launched := newFakeBrowserPool(2, 10)
+	ctx := context.Background()
+
+	first, _ := pool.acquire(ctx)
+	second, _ := pool.acquire(ctx)
+	pool.release(first, nil)
+	pool.release(second, nil)
+	if len(*launched) != 2 {
+		t.Fatalf("expected 2 browsers, launched %d", len(*launched))
+	}
+
+	first.lastUsed = time.Now().Add(-2 * browserPoolIdleTimeout)
+	pool.sweep(time.Now())
+	waitClosed(t, (*launched)[0])
+	if (*launched)[1].closed.Load() != 0 {
+		t.Fatalf("recently used browser should stay open")
+	}
+
+	if err := pool.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if (*launched)[1].closed.Load() != 1 {
+		t.Fatalf("close should shut down remaining browsers")
+	}
+	if _, err := pool.acquire(ctx); !errors.Is(err, errBrowserPoolClosed) {
+		t.Fatalf("expected closed pool error, got %v", err)
+	}
+}
@@ -25,12 +25,21 @@ type Bundle struct {
 	Scheduler     SchedulerService
 	Automation    AutomationService
 	Todo          TodoService
+	Prompts       PromptService
+	Cache         CacheService
+	Proxy         ProxyService
+	Monitor       WebsiteMonitorService
+	Verifier      EmailVerificationService
+
+	search *SearchClient // owns the browser pool released by Close
 }
 
 // Options describes dependencies shared across services.
 type Options struct {
-	Store      *store.Store
-	HTTPClient *http.Client
+	Store       *store.Store
+	HTTPClient  *http.Client
+	CacheDir    string // response cache root; empty disables caching
+	SnapshotDir string // website monitor snapshots; empty disables monitoring
 }
 
 // LLMService validates credentials and proxies prompt calls.
@@ -48,6 +57,8 @@ type MailService interface {
 type SearchService interface {
 	Search(ctx context.Context, query string, limit int) ([]SearchItem, error)
 	TestSearch(ctx context.Context) error
+	DryRunPlan(ctx context.Context, customerName string, stages []store.SearchPlanStage) (*SearchPlanPreview, error)
+	ProviderHealth() []SearchProviderHealth
 }
 
 // EnrichmentService resolves company data.
@@ -64,11 +75,13 @@ type GradingService interface {
 // AnalysisService builds entry point reports.
 type AnalysisService interface {
 	Generate(ctx context.Context, customerID int64) (*domain.AnalysisResponse, error)
+	GenerateStream(ctx context.Context, customerID int64, onDelta func(string)) (*domain.AnalysisResponse, error)
 }
 
 // EmailComposerService drafts outbound emails.
 type EmailComposerService interface {
 	DraftInitial(ctx context.Context, customerID int64) (*domain.EmailDraftResponse, error)
+	DraftInitialStream(ctx context.Context, customerID int64, onDelta func(string)) (*domain.EmailDraftResponse, error)
 	DraftFollowup(ctx context.Context, customerID int64, contextEmailID int64) (*domain.EmailDraft, error)
 }
 
@@ -84,6 +97,38 @@ type AutomationService interface {
 	ProcessNext(ctx context.Context) (bool, error)
 }
 
+// PromptService manages the editable, versioned prompt templates.
+type PromptService interface {
+	List(ctx context.Context) ([]domain.PromptTemplate, error)
+	Versions(ctx context.Context, name string) ([]domain.PromptTemplate, error)
+	Save(ctx context.Context, name string, req *domain.PromptTemplateRequest) (*domain.PromptTemplate, error)
+	Rollback(ctx context.Context, name string, version int) (*domain.PromptTemplate, error)
+	Reset(ctx context.Context, name string) error
+	Preview(ctx context.Context, name string, req *domain.PromptPreviewRequest) (*domain.PromptPreview, error)
+}
+
+// ProxyService checks the outbound proxy configuration.
+type ProxyService interface {
+	Test(ctx context.Context) ([]ProxyTestResult, error)
+}
+
+// CacheService reports and purges cached LLM and search responses.
+type CacheService interface {
+	Stats(ctx context.Context) (*domain.CacheStats, error)
+	Purge(ctx context.Context, namespace string) (*domain.CacheStats, error)
+}
+
+// WebsiteMonitorService watches tracked customers' websites for changes.
+type WebsiteMonitorService interface {
+	CheckDue(ctx context.Context) (int, error)
+	CheckCustomer(ctx context.Context, customerID int64) (*domain.WebsiteCheckResult, error)
+}
+
+// EmailVerificationService checks whether contact addresses can receive mail.
+type EmailVerificationService interface {
+	VerifyCustomerContacts(ctx context.Context, customerID int64) ([]domain.Contact, error)
+}
+
 // NewStubBundle provides placeholder implementations for early scaffolding.
 func NewStubBundle() *Bundle {
 	return &Bundle{
@@ -96,6 +141,11 @@ func NewStubBundle() *Bundle {
 		EmailComposer: stubEmailComposer{},
 		Scheduler:     stubScheduler{},
 		Automation:    stubAutomation{},
+		Prompts:       stubPrompts{},
+		Cache:         stubCache{},
+		Proxy:         stubProxy{},
+		Monitor:       stubMonitor{},
+		Verifier:      stubVerifier{},
 	}
 }
 
@@ -125,6 +175,14 @@ func (stubSearch) TestSearch(ctx context.Context) error {
 	return ErrNotImplemented
 }
 
+func (stubSearch) ProviderHealth() []SearchProviderHealth {
+	return nil
+}
+
+func (stubSearch) DryRunPlan(ctx context.Context, customerName string, stages []store.SearchPlanStage) (*SearchPlanPreview, error) {
+	return nil, ErrNotImplemented
+}
+
 type stubEnricher struct{}
 
 func (stubEnricher) ResolveCompany(ctx context.Context, req *domain.ResolveCompanyRequest) (*domain.ResolveCompanyResponse, error) {
@@ -147,12 +205,20 @@ func (stubAnalyst) Generate(ctx context.Context, customerID int64) (*domain.Anal
 	return nil, ErrNotImplemented
 }
 
+func (stubAnalyst) GenerateStream(ctx context.Context, customerID int64, onDelta func(string)) (*domain.AnalysisResponse, error) {
+	return nil, ErrNotImplemented
+}
+
 type stubEmailComposer struct{}
 
 func (stubEmailComposer) DraftInitial(ctx context.Context, customerID int64) (*domain.EmailDraftResponse, error) {
 	return nil, ErrNotImplemented
 }
 
+func (stubEmailComposer) DraftInitialStream(ctx context.Context, customerID int64, onDelta func(string)) (*domain.EmailDraftResponse, error) {
+	return nil, ErrNotImplemented
+}
+
 func (stubEmailComposer) DraftFollowup(ctx context.Context, customerID int64, contextEmailID int64) (*domain.EmailDraft, error) {
 	return nil, ErrNotImplemented
 }
@@ -177,6 +243,64 @@ func (stubAutomation) ProcessNext(ctx context.Context) (bool, error) {
 	return false, ErrNotImplemented
 }
 
+type stubPrompts struct{}
+
+func (stubPrompts) List(ctx context.Context) ([]domain.PromptTemplate, error) {
+	return nil, ErrNotImplemented
+}
+
+func (stubPrompts) Versions(ctx context.Context, name string) ([]domain.PromptTemplate, error) {
+	return nil, ErrNotImplemented
+}
+
+func (stubPrompts) Save(ctx context.Context, name string, req *domain.PromptTemplateRequest) (*domain.PromptTemplate, error) {
+	return nil, ErrNotImplemented
+}
+
+func (stubPrompts) Rollback(ctx context.Context, name string, version int) (*domain.PromptTemplate, error) {
+	return nil, ErrNotImplemented
+}
+
+func (stubPrompts) Reset(ctx context.Context, name string) error {
+	return ErrNotImplemented
+}
+
+func (stubPrompts) Preview(ctx context.Context, name string, req *domain.PromptPreviewRequest) (*domain.PromptPreview, error) {
+	return nil, ErrNotImplemented
+}
+
+type stubCache struct{}
+
+func (stubCache) Stats(ctx context.Context) (*domain.CacheStats, error) {
+	return nil, ErrNotImplemented
+}
+
+func (stubCache) Purge(ctx context.Context, namespace string) (*domain.CacheStats, error) {
+	return nil, ErrNotImplemented
+}
+
+type stubProxy struct{}
+
+func (stubProxy) Test(ctx context.Context) ([]ProxyTestResult, error) {
+	return nil, ErrNotImplemented
+}
+
+type stubMonitor struct{}
+
+func (stubMonitor) CheckDue(ctx context.Context) (int, error) {
+	return 0, ErrNotImplemented
+}
+
+func (stubMonitor) CheckCustomer(ctx context.Context, customerID int64) (*domain.WebsiteCheckResult, error) {
+	return nil, ErrNotImplemented
+}
+
+type stubVerifier struct{}
+
+func (stubVerifier) VerifyCustomerContacts(ctx context.Context, customerID int64) ([]domain.Contact, error) {
+	return nil, ErrNotImplemented
+}
+
 // NewBundle wires production implementations backed by the provided store and HTTP client.
 func NewBundle(opts Options) *Bundle {
 	httpClient := opts.HTTPClient
@@ -189,15 +313,38 @@ func NewBundle(opts Options) *Bundle {
 	llmClient := NewLLMClient(opts.Store, httpClient)
 	mailer := NewSMTPMailer(opts.Store)
 	search := NewSearchClient(opts.Store, httpClient)
-	fetcher := NewWebFetcher(httpClient)
-
-	enricher := NewEnrichmentService(llmClient, search, fetcher)
-	grader := NewGradingService(opts.Store, llmClient)
-	analyst := NewAnalysisService(opts.Store, llmClient)
-	emailComposer := NewEmailComposerService(opts.Store, llmClient)
+	guard := newFetchGuard(opts.Store)
+	fetcher := NewWebFetcher(guard.client(httpClient.Timeout))
+	fetcher.guard = guard
+
+	cache := NewResponseCache(opts.CacheDir)
+	llmClient.cache = cache
+	search.cache = cache
+
+	proxies := NewProxyRouter(opts.Store)
+	llmClient.proxies = proxies
+	mailer.proxies = proxies
+	search.proxies = proxies
+	fetcher.proxies = proxies
+	fetcher.browsers = search.browsers
+
+	prompts := NewPromptService(opts.Store)
+	verifier := NewEmailVerifier(opts.Store)
+	verifier.proxies = proxies
+
+	enricher := NewEnrichmentService(opts.Store, llmClient, search, fetcher, prompts)
+	enricher.verifier = verifier
+	grader := NewGradingService(opts.Store, llmClient, prompts)
+	grader.fetcher = fetcher
+	analyst := NewAnalysisService(opts.Store, llmClient, prompts)
+	emailComposer := NewEmailComposerService(opts.Store, llmClient, prompts)
 	scheduler := NewSchedulerService(opts.Store, emailComposer, mailer)
 	automation := NewAutomationService(opts.Store, grader, analyst, emailComposer, scheduler)
 	todo := NewTodoService(opts.Store, enricher, automation)
+	var monitor WebsiteMonitorService = stubMonitor{}
+	if opts.SnapshotDir != "" {
+		monitor = NewWebsiteMonitor(opts.Store, fetcher, llmClient, prompts, opts.SnapshotDir)
+	}
 
 	return &Bundle{
 		LLM:           llmClient,
@@ -210,5 +357,20 @@ func NewBundle(opts Options) *Bundle {
 		Scheduler:     scheduler,
 		Automation:    automation,
 		Todo:          todo,
+		Prompts:       prompts,
+		Cache:         cache,
+		Proxy:         proxies,
+		Monitor:       monitor,
+		Verifier:      verifier,
+		search:        search,
+	}
+}
+
+// Close releases long-lived resources held by the services, such as the pooled
+// Playwright browsers. Call it after the background runners have stopped.
+func (b *Bundle) Close() error {
+	if b == nil || b.search == nil {
+		return nil
 	}
+	return b.search.Close()
 }
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+)
+
+// Cache namespaces under the cache directory.
+const (
+	CacheNamespaceLLM    = "llm"
+	CacheNamespaceSearch = "search"
+)
+
+var cacheNamespaces = []string{CacheNamespaceLLM, CacheNamespaceSearch}
+
+type cacheBypassKey struct{}
+
+// WithCacheBypass makes calls using ctx skip cached entries; fresh results are still written back.
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// ResponseCache is a content-addressed file cache: every entry lives at
+// <dir>/<namespace>/<hh>/<sha256>.json and carries its own expiry.
+type ResponseCache struct {
+	dir string
+}
+
+type cacheEnvelope struct {
+	CreatedAt time.Time       `json:"created_at"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// NewResponseCache returns a cache rooted at dir; an empty dir disables caching.
+func NewResponseCache(dir string) *ResponseCache {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return nil
+	}
+	return &ResponseCache{dir: dir}
+}
+
+// cacheKey hashes the JSON encoding of parts into a hex digest.
+func cacheKey(parts ...any) string {
+	data, err := json.Marshal(parts)
+	if err != nil {
+		data = []byte(fmt.Sprint(parts...))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Get loads a live entry into out. Expired or unreadable entries are removed and reported as misses.
+func (c *ResponseCache) Get(namespace, key string, out any) bool {
+	if c == nil {
+		return false
+	}
+	path := c.entryPath(namespace, key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var env cacheEnvelope
+	if err := json.Unmarshal(data, &env); err != nil || time.Now().After(env.ExpiresAt) {
+		_ = os.Remove(path)
+		return false
+	}
+	if err := json.Unmarshal(env.Value, out); err != nil {
+		_ = os.Remove(path)
+		return false
+	}
+	return true
+}
+
+// Put stores value for ttl. Non-positive TTLs are ignored.
+func (c *ResponseCache) Put(namespace, key string, value any, ttl time.Duration) {
+	if c == nil || ttl <= 0 {
+		return
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	data, err := json.Marshal(cacheEnvelope{CreatedAt: now, ExpiresAt: now.Add(ttl), Value: raw})
+	if err != nil {
+		return
+	}
+	path := c.entryPath(namespace, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		log.Printf("[cache] namespace=%s create dir failed: %v", namespace, err)
+		return
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		log.Printf("[cache] namespace=%s write failed: %v", namespace, err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		log.Printf("[cache] namespace=%s write failed: %v", namespace, err)
+	}
+}
+
+// Delete removes an entry, e.g. a reply the caller could not use.
+func (c *ResponseCache) Delete(namespace, key string) {
+	if c == nil {
+		return
+	}
+	if err := os.Remove(c.entryPath(namespace, key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("[cache] namespace=%s delete failed: %v", namespace, err)
+	}
+}
+
+// Stats reports entry counts and sizes per namespace.
+func (c *ResponseCache) Stats(ctx context.Context) (*domain.CacheStats, error) {
+	if c == nil {
+		return nil, fmt.Errorf("缓存未启用")
+	}
+	stats := &domain.CacheStats{Dir: c.dir, Namespaces: make([]domain.CacheNamespaceStats, 0, len(cacheNamespaces))}
+	now := time.Now()
+	for _, namespace := range cacheNamespaces {
+		item := domain.CacheNamespaceStats{Name: namespace}
+		err := filepath.WalkDir(filepath.Join(c.dir, namespace), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if d.IsDir() || filepath.Ext(path) != ".json" {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			item.Entries++
+			item.Bytes += info.Size()
+			if data, err := os.ReadFile(path); err == nil {
+				var env cacheEnvelope
+				if json.Unmarshal(data, &env) != nil || now.After(env.ExpiresAt) {
+					item.Expired++
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("统计缓存失败: %w", err)
+		}
+		stats.Namespaces = append(stats.Namespaces, item)
+		stats.TotalEntries += item.Entries
+		stats.TotalBytes += item.Bytes
+	}
+	return stats, nil
+}
+
+// Purge deletes one namespace, or every namespace when namespace is empty.
+func (c *ResponseCache) Purge(ctx context.Context, namespace string) (*domain.CacheStats, error) {
+	if c == nil {
+		return nil, fmt.Errorf("缓存未启用")
+	}
+	namespace = strings.TrimSpace(namespace)
+	targets := cacheNamespaces
+	if namespace != "" {
+		if !isCacheNamespace(namespace) {
+			return nil, fmt.Errorf("未知的缓存类型: %s", namespace)
+		}
+		targets = []string{namespace}
+	}
+	for _, target := range targets {
+		if err := os.RemoveAll(filepath.Join(c.dir, target)); err != nil {
+			return nil, fmt.Errorf("清理缓存失败: %w", err)
+		}
+	}
+	log.Printf("[cache] purged namespace=%s", namespace)
+	return c.Stats(ctx)
+}
+
+func (c *ResponseCache) entryPath(namespace, key string) string {
+	return filepath.Join(c.dir, namespace, key[:2], key+".json")
+}
+
+func isCacheNamespace(namespace string) bool {
+	for _, item := range cacheNamespaces {
+		if item == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+func hoursTTL(hours int) time.Duration {
+	if hours <= 0 {
+		return 0
+	}
+	return time.Duration(hours) * time.Hour
+}
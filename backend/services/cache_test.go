@@ -0,0 +1,210 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+func TestResponseCacheLifecycle(t *testing.T) {
+	ctx := context.Background()
+	cache := NewResponseCache(t.TempDir())
+	key := cacheKey("provider", "query", 5)
+	if key != cacheKey("provider", "query", 5) || key == cacheKey("provider", "query", 6) {
+		t.Fatalf("cache keys must be stable and depend on every part")
+	}
+
+	items := []SearchItem{{Title: "Acme", URL: "https://acme.example"}}
+	cache.Put(CacheNamespaceSearch, key, items, time.Hour)
+	var got []SearchItem
+	if !cache.Get(CacheNamespaceSearch, key, &got) || len(got) != 1 || got[0].URL != items[0].URL {
+		t.Fatalf("expected cache hit, got %+v", got)
+	}
+
+	cache.Put(CacheNamespaceLLM, "ab"+key[2:], "old", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	var content string
+	if cache.Get(CacheNamespaceLLM, "ab"+key[2:], &content) {
+		t.Fatalf("expired entry should miss")
+	}
+
+	cache.Put(CacheNamespaceLLM, key, "answer", time.Hour)
+	stats, err := cache.Stats(ctx)
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if stats.TotalEntries != 2 || stats.TotalBytes == 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	stats, err = cache.Purge(ctx, CacheNamespaceLLM)
+	if err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if stats.TotalEntries != 1 || cache.Get(CacheNamespaceLLM, key, &content) {
+		t.Fatalf("llm namespace should be empty after purge: %+v", stats)
+	}
+	if _, err := cache.Purge(ctx, "unknown"); err == nil {
+		t.Fatalf("expected error for unknown namespace")
+	}
+}
+
+func TestChatUsesResponseCache(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprintf(w, `{"choices":[{"message":{"content":"reply %d"}}],"usage":{"total_tokens":10}}`, calls)
+	}))
+	defer server.Close()
+
+	st := setupTestStore(t)
+	defer st.Close()
+	data, _ := json.Marshal(store.Settings{LLMBaseURL: server.URL, LLMAPIKey: "test", LLMModel: "m", LLMCacheTTLHours: 1})
+	if err := st.SaveSettings(context.Background(), bytes.NewReader(data)); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	client := NewLLMClient(st, server.Client())
+	client.cache = NewResponseCache(t.TempDir())
+
+	messages := []ChatMessage{{Role: "user", Content: "hi"}}
+	opts := ChatOptions{Task: LLMTaskGrading, Temperature: 0.2, Cacheable: true}
+	first, _, err := client.Chat(context.Background(), messages, opts)
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	second, usage, err := client.Chat(context.Background(), messages, opts)
+	if err != nil {
+		t.Fatalf("cached chat: %v", err)
+	}
+	if calls != 1 || second != first || usage.TotalTokens != 0 {
+		t.Fatalf("expected cache hit, calls=%d first=%q second=%q", calls, first, second)
+	}
+
+	var streamed string
+	if _, _, err := client.ChatStream(context.Background(), messages, opts, func(text string) { streamed += text }); err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	if calls != 1 || streamed != first {
+		t.Fatalf("stream should replay cached reply, calls=%d streamed=%q", calls, streamed)
+	}
+
+	fresh, _, err := client.Chat(WithCacheBypass(context.Background()), messages, opts)
+	if err != nil {
+		t.Fatalf("bypass chat: %v", err)
+	}
+	if calls != 2 || fresh == first {
+		t.Fatalf("bypass should reach the provider, calls=%d", calls)
+	}
+	if again, _, _ := client.Chat(context.Background(), messages, opts); again != fresh {
+		t.Fatalf("bypassed call should refresh the cache, got %q want %q", again, fresh)
+	}
+
+	if _, _, err := client.Chat(context.Background(), []ChatMessage{{Role: "user", Content: "other"}}, opts); err != nil || calls != 3 {
+		t.Fatalf("different messages must not share an entry, calls=%d err=%v", calls, err)
+	}
+
+	creative := ChatOptions{Task: LLMTaskEmailInitial, Temperature: 0.55}
+	for i := 0; i < 2; i++ {
+		if _, _, err := client.Chat(context.Background(), messages, creative); err != nil {
+			t.Fatalf("creative chat: %v", err)
+		}
+	}
+	if _, err := client.TestConnection(context.Background()); err != nil {
+		t.Fatalf("test connection: %v", err)
+	}
+	if _, err := client.TestConnection(context.Background()); err != nil || calls != 7 {
+		t.Fatalf("uncacheable calls must reach the provider every time, calls=%d err=%v", calls, err)
+	}
+
+	// Another key for the same endpoint and model must not see the old answers.
+	data, _ = json.Marshal(store.Settings{LLMBaseURL: server.URL, LLMAPIKey: "rotated", LLMModel: "m", LLMCacheTTLHours: 1})
+	if err := st.SaveSettings(context.Background(), bytes.NewReader(data)); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if _, _, err := client.Chat(context.Background(), messages, opts); err != nil || calls != 8 {
+		t.Fatalf("changed credentials must miss the cache, calls=%d err=%v", calls, err)
+	}
+}
+
+func TestLLMCacheKeyIncludesEndpointAndCredentials(t *testing.T) {
+	messages := []ChatMessage{{Role: "user", Content: "hi"}}
+	base := &store.Settings{LLMBaseURL: "https://api.example.com/v1", LLMAPIKey: "key-1", LLMModel: "m"}
+	key := llmCacheKey(base, messages, ChatOptions{})
+	same := *base
+	same.LLMBaseURL = "https://api.example.com/v1/"
+	if llmCacheKey(&same, messages, ChatOptions{}) != key {
+		t.Errorf("a trailing slash should not change the key")
+	}
+	for name, change := range map[string]func(*store.Settings){
+		"base URL": func(s *store.Settings) { s.LLMBaseURL = "https://other.example.com/v1" },
+		"API key":  func(s *store.Settings) { s.LLMAPIKey = "key-2" },
+	} {
+		other := *base
+		change(&other)
+		if llmCacheKey(&other, messages, ChatOptions{}) == key {
+			t.Errorf("changing the %s must change the cache key", name)
+		}
+	}
+}
+
+func TestLLMCacheDisabledByDefault(t *testing.T) {
+	st := setupTestStore(t)
+	defer st.Close()
+	settings, err := st.GetSettings(context.Background())
+	if err != nil {
+		t.Fatalf("get settings: %v", err)
+	}
+	if settings.LLMCacheTTLHours != 0 {
+		t.Fatalf("LLM cache TTL defaults to %d hours, want 0", settings.LLMCacheTTLHours)
+	}
+}
+
+func TestChatJSONDoesNotReplayRejectedReplies(t *testing.T) {
+	calls := 0
+	reply := "not json"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		data, _ := json.Marshal(reply)
+		fmt.Fprintf(w, `{"choices":[{"message":{"content":%s}}]}`, data)
+	}))
+	defer server.Close()
+
+	st := setupTestStore(t)
+	defer st.Close()
+	data, _ := json.Marshal(store.Settings{LLMBaseURL: server.URL, LLMAPIKey: "test", LLMModel: "m", LLMCacheTTLHours: 1})
+	if err := st.SaveSettings(context.Background(), bytes.NewReader(data)); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	client := NewLLMClient(st, server.Client())
+	client.cache = NewResponseCache(t.TempDir())
+
+	messages := []ChatMessage{{Role: "user", Content: "grade"}}
+	opts := ChatOptions{Task: LLMTaskGrading, Cacheable: true}
+	var parsed struct {
+		SuggestedGrade string `json:"suggested_grade"`
+	}
+	if err := client.ChatJSON(context.Background(), messages, opts, gradingSchema, &parsed); err == nil {
+		t.Fatalf("expected malformed replies to fail")
+	}
+	if calls != 2 {
+		t.Fatalf("expected the reply and one repair, got %d calls", calls)
+	}
+
+	reply = `{"suggested_grade":"B","confidence_score":0.5}`
+	if err := client.ChatJSON(context.Background(), messages, opts, gradingSchema, &parsed); err != nil {
+		t.Fatalf("retry should reach the provider again: %v", err)
+	}
+	if calls != 3 || parsed.SuggestedGrade != "B" {
+		t.Fatalf("expected a fresh reply, calls=%d parsed=%+v", calls, parsed)
+	}
+	if err := client.ChatJSON(context.Background(), messages, opts, gradingSchema, &parsed); err != nil || calls != 3 {
+		t.Fatalf("valid replies should still be cached, calls=%d err=%v", calls, err)
+	}
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/transform"
+)
+
+// charsetPrescanBytes is how much of the page is searched for a meta charset,
+// a little more than the 1024 bytes browsers use, since many sites put the tag
+// after long inline scripts.
+const charsetPrescanBytes = 4096
+
+var (
+	metaCharsetRegex = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_:.\-]+)`)
+	htmlLangRegex    = regexp.MustCompile(`(?i)<html[^>]*\slang\s*=\s*["']?([a-z]{2,3}(?:[-_][a-z0-9]+)*)`)
+)
+
+// charsetCandidate is a legacy encoding tried when a page declares nothing and
+// is not valid UTF-8.
+type charsetCandidate struct {
+	name     string
+	encoding encoding.Encoding
+}
+
+var charsetCandidates = []charsetCandidate{
+	{"gbk", simplifiedchinese.GB18030},
+	{"big5", traditionalchinese.Big5},
+	{"shift_jis", japanese.ShiftJIS},
+	{"euc-kr", korean.EUCKR},
+	{"windows-1251", charmap.Windows1251},
+}
+
+// commonHanRunes are frequent Chinese characters, in simplified and
+// traditional forms, including business vocabulary. Text decoded with the
+// wrong CJK encoding turns into rare characters, so these make the right
+// decoding stand out.
+const commonHanRunes = "的一是不了在人有我他这這个個们們中来來上大为為和国國地到以说說时時要就出会會可也你对對生能而子那得于着著下自之年过過发發后後作里裡用道行所然家种種事成方多经經么麼去法学學如都同现現当當没沒动動面起看定天分还還进進好小部其些主样樣理心本前开開但因只从從想实實日公司产產品服务務电電话話址系联聯"
+
+// commonHangulRunes are frequent Korean syllables. CP949 accepts most double
+// byte sequences, so other encodings read as Korean give rare syllables.
+const commonHangulRunes = "이다는의에가을를하고한로서있기지사도수리대자으국적인해게어아정시니나라일부요주전상보들것거여회업제품문연락습니합및소개우희저산용생년성장객고객센터서비스영업"
+
+// decodeHTML converts a page body to UTF-8 and reports the charset used. The
+// encoding comes from, in order: a byte order mark, the Content-Type header,
+// a meta tag, UTF-8 validity, and finally a guess among the common legacy
+// encodings, hinted by the html lang attribute. A declared UTF-8 is trusted
+// unless much of the body fails to validate, since mislabelled GBK pages are
+// common. A character cut off by the read limit is dropped first.
+func decodeHTML(body []byte, contentType string) ([]byte, string) {
+	if enc, name, ok := charsetFromBOM(body); ok {
+		return decodeWith(enc, body[len(bomFor(name)):]), name
+	}
+	body = trimPartialRune(body)
+	declared := ""
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		declared = params["charset"]
+	}
+	if declared == "" {
+		head := body
+		if len(head) > charsetPrescanBytes {
+			head = head[:charsetPrescanBytes]
+		}
+		if m := metaCharsetRegex.FindSubmatch(head); m != nil {
+			declared = string(m[1])
+		}
+	}
+	if declared != "" {
+		if enc, err := htmlindex.Get(declared); err == nil {
+			name, _ := htmlindex.Name(enc)
+			if name != "utf-8" {
+				return decodeWith(enc, body), name
+			}
+			if mostlyUTF8(body) {
+				return body, "utf-8"
+			}
+		}
+	}
+	if utf8.Valid(body) {
+		return body, "utf-8"
+	}
+	candidate := guessCharset(body)
+	return decodeWith(candidate.encoding, body), candidate.name
+}
+
+// trimPartialRune drops an incomplete UTF-8 sequence at the end of body, as
+// left behind when a page is cut at the read limit.
+func trimPartialRune(body []byte) []byte {
+	for i := len(body) - 1; i >= 0 && i >= len(body)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(body[i]) {
+			if body[i] >= utf8.RuneSelf && !utf8.FullRune(body[i:]) {
+				return body[:i]
+			}
+			break
+		}
+	}
+	return body
+}
+
+// mostlyUTF8 reports whether body reads as UTF-8 apart from a few stray bytes:
+// invalid sequences must stay under a tenth of the multi-byte characters.
+// Legacy CJK text almost never forms valid multi-byte sequences.
+func mostlyUTF8(body []byte) bool {
+	invalid, multi := 0, 0
+	for len(body) > 0 {
+		r, size := utf8.DecodeRune(body)
+		switch {
+		case r == utf8.RuneError && size == 1:
+			invalid++
+		case size > 1:
+			multi++
+		}
+		body = body[size:]
+	}
+	return invalid*10 <= multi
+}
+
+// charsetReader adapts decodeHTML's encodings for encoding/xml, which only
+// understands UTF-8 on its own.
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		return nil, fmt.Errorf("不支持的字符集: %s", label)
+	}
+	return transform.NewReader(input, enc.NewDecoder()), nil
+}
+
+func charsetFromBOM(body []byte) (encoding.Encoding, string, bool) {
+	for _, name := range []string{"utf-8", "utf-16le", "utf-16be"} {
+		if bytes.HasPrefix(body, bomFor(name)) {
+			enc, _ := htmlindex.Get(name)
+			return enc, name, true
+		}
+	}
+	return nil, "", false
+}
+
+func bomFor(name string) []byte {
+	switch name {
+	case "utf-8":
+		return []byte{0xEF, 0xBB, 0xBF}
+	case "utf-16le":
+		return []byte{0xFF, 0xFE}
+	case "utf-16be":
+		return []byte{0xFE, 0xFF}
+	}
+	return nil
+}
+
+func decodeWith(enc encoding.Encoding, body []byte) []byte {
+	decoded, _, err := transform.Bytes(enc.NewDecoder(), body)
+	if err != nil {
+		return body
+	}
+	return decoded
+}
+
+// guessCharset picks the candidate whose decoding reads most like real text.
+// The html lang attribute, when present, breaks close calls.
+func guessCharset(body []byte) charsetCandidate {
+	hint := ""
+	if m := htmlLangRegex.FindSubmatch(body); m != nil {
+		hint = charsetForLang(string(m[1]))
+	}
+	best, bestScore := charsetCandidates[0], -1<<31
+	for _, candidate := range charsetCandidates {
+		score := charsetScore(decodeWith(candidate.encoding, body))
+		if candidate.name == hint {
+			score += score/4 + 10
+		}
+		if score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+	return best
+}
+
+// charsetScore rewards characters typical of correctly decoded text and
+// penalises replacement characters, half-width katakana and stray symbols,
+// which wrong decodings produce.
+func charsetScore(text []byte) int {
+	score := 0
+	var prev rune
+	for _, r := range string(text) {
+		switch {
+		case r < utf8.RuneSelf:
+		case r == utf8.RuneError:
+			score -= 8
+		case strings.ContainsRune(commonHanRunes, r):
+			score += 3
+		case strings.ContainsRune(commonHangulRunes, r):
+			score += 3
+		case unicode.Is(unicode.Hangul, r):
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana) && (r < 0xFF61 || r > 0xFF9F):
+			score += 2
+		case r >= 0xFF61 && r <= 0xFF9F:
+			score -= 3
+		case unicode.Is(unicode.Cyrillic, r):
+			// Real words are mostly lower case; CJK bytes read as
+			// Windows-1251 flip case at random.
+			if unicode.IsLower(r) && unicode.Is(unicode.Cyrillic, prev) && unicode.IsLower(prev) {
+				score += 2
+			} else if unicode.IsUpper(r) && unicode.Is(unicode.Cyrillic, prev) {
+				score -= 1
+			}
+		case unicode.Is(unicode.Han, r):
+		case unicode.IsPunct(r) || unicode.IsSpace(r):
+		default:
+			score -= 2
+		}
+		prev = r
+	}
+	return score
+}
+
+func charsetForLang(lang string) string {
+	lang = strings.ToLower(strings.ReplaceAll(lang, "_", "-"))
+	switch {
+	case lang == "zh-tw" || lang == "zh-hk" || lang == "zh-mo" || strings.HasPrefix(lang, "zh-hant"):
+		return "big5"
+	case strings.HasPrefix(lang, "zh"):
+		return "gbk"
+	case strings.HasPrefix(lang, "ja"):
+		return "shift_jis"
+	case strings.HasPrefix(lang, "ko"):
+		return "euc-kr"
+	}
+	switch strings.SplitN(lang, "-", 2)[0] {
+	case "ru", "uk", "be", "bg", "sr", "mk":
+		return "windows-1251"
+	}
+	return ""
+}
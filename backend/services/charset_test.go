@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var charsetFixtures = []struct {
+	file        string
+	contentType string
+	charset     string
+	want        string
+}{
+	{"gbk_meta.html", "text/html", "gbk", "深圳市华达机械有限公司"},
+	{"big5_header.html", "text/html; charset=BIG5", "big5", "台灣精密機械股份有限公司"},
+	{"shift_jis_lang.html", "", "shift_jis", "株式会社サクラ工業"},
+	{"euc_kr.html", "", "euc-kr", "한국정밀산업 주식회사"},
+	{"windows1251.html", "text/html", "windows-1251", "ООО Уральские Насосы"},
+	{"gbk_mislabelled.html", "", "gbk", "宁波海天贸易有限公司"},
+	{"utf8_truncated.html", "", "utf-8", "杭州宏达阀门有限公司"},
+}
+
+func readCharsetFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join("testdata", "charset", name))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return raw
+}
+
+func TestDecodeHTMLFixtures(t *testing.T) {
+	for _, tc := range charsetFixtures {
+		decoded, charset := decodeHTML(readCharsetFixture(t, tc.file), tc.contentType)
+		if charset != tc.charset {
+			t.Errorf("%s: charset = %q, want %q", tc.file, charset, tc.charset)
+		}
+		if !strings.Contains(string(decoded), tc.want) {
+			t.Errorf("%s: decoded text missing %q", tc.file, tc.want)
+		}
+	}
+}
+
+// Without any declaration the encoding must be guessed from the bytes alone.
+func TestDecodeHTMLGuessesUndeclaredCharsets(t *testing.T) {
+	declarations := regexp.MustCompile(`(?i)<meta[^>]*>|\slang="[^"]*"`)
+	for _, tc := range charsetFixtures {
+		raw := declarations.ReplaceAll(readCharsetFixture(t, tc.file), nil)
+		decoded, charset := decodeHTML(raw, "text/html")
+		if charset != tc.charset || !strings.Contains(string(decoded), tc.want) {
+			t.Errorf("%s: guessed %q, want %q", tc.file, charset, tc.charset)
+		}
+	}
+}
+
+func TestDecodeHTMLKeepsUTF8(t *testing.T) {
+	page := []byte(`<html><body><p>Ünïcödé — 中文 — Кириллица</p></body></html>`)
+	decoded, charset := decodeHTML(page, "text/html; charset=utf-8")
+	if charset != "utf-8" || string(decoded) != string(page) {
+		t.Errorf("utf-8 page changed: %q %q", charset, decoded)
+	}
+	bom := append([]byte{0xEF, 0xBB, 0xBF}, page...)
+	if decoded, charset := decodeHTML(bom, "text/html; charset=windows-1252"); charset != "utf-8" || string(decoded) != string(page) {
+		t.Errorf("BOM should win over the header: %q %q", charset, decoded)
+	}
+}
+
+func TestFetchDecodesLegacyCharset(t *testing.T) {
+	page := readCharsetFixture(t, "big5_header.html")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=big5")
+		w.Write(page)
+	}))
+	defer server.Close()
+
+	summary, err := NewWebFetcher(server.Client()).Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if !strings.Contains(summary.Text, "專業製造精密零件") {
+		t.Errorf("summary text = %q", summary.Text)
+	}
+}
+
+// Pages are read up to 1 MB, which usually cuts a UTF-8 page inside a character.
+func TestFetchKeepsUTF8CutAtReadLimit(t *testing.T) {
+	head := `<html><head><title>杭州宏达阀门有限公司</title></head><body><h1>杭州宏达阀门有限公司</h1><p>Since 1998 `
+	if (1<<20-len(head))%3 == 0 {
+		t.Fatalf("the read limit should fall inside a character")
+	}
+	page := []byte(head)
+	for len(page) <= 1<<20 {
+		page = append(page, "专业生产工业阀门欢迎来电咨询"...)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+	}))
+	defer server.Close()
+
+	summary, err := NewWebFetcher(server.Client()).Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if !strings.Contains(summary.Text, "杭州宏达阀门有限公司") {
+		t.Errorf("summary text = %.200q", summary.Text)
+	}
+}
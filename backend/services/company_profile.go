@@ -0,0 +1,447 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+)
+
+// Profile sources recorded in CompanyProfile.Sources.
+const (
+	profileSourceJSONLD    = "json-ld"
+	profileSourceMicrodata = "microdata"
+	profileSourceOpenGraph = "opengraph"
+)
+
+// organizationTypes are the schema.org types describing a company. Subtypes of
+// LocalBusiness not listed here are caught by the "Business" suffix check.
+var organizationTypes = map[string]struct{}{
+	"organization": {}, "corporation": {}, "localbusiness": {}, "onlinebusiness": {},
+	"onlinestore": {}, "store": {}, "professionalservice": {}, "ngo": {},
+	"wholesalestore": {}, "manufacturer": {},
+}
+
+func isOrganizationType(types []string) bool {
+	for _, t := range types {
+		t = strings.ToLower(strings.TrimSpace(t))
+		t = t[strings.LastIndex(t, "/")+1:] // "https://schema.org/Organization"
+		if _, ok := organizationTypes[t]; ok || strings.HasSuffix(t, "business") || strings.HasSuffix(t, "organization") {
+			return true
+		}
+	}
+	return false
+}
+
+// extractCompanyProfile reads the company's self-published structured data.
+// JSON-LD wins over microdata, which wins over OpenGraph. Returns nil when the
+// page has none.
+func extractCompanyProfile(doc *goquery.Document) *domain.CompanyProfile {
+	profile := &domain.CompanyProfile{}
+	doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, sel *goquery.Selection) {
+		var data any
+		if err := json.Unmarshal([]byte(strings.TrimSpace(sel.Text())), &data); err != nil {
+			return
+		}
+		walkJSONLD(data, func(node map[string]any) {
+			mergeCompanyProfile(profile, profileFromJSONLD(node))
+		})
+	})
+	doc.Find("[itemscope][itemtype]").Each(func(_ int, sel *goquery.Selection) {
+		itemType, _ := sel.Attr("itemtype")
+		if _, nested := sel.Attr("itemprop"); nested || !isOrganizationType(strings.Fields(itemType)) {
+			return
+		}
+		mergeCompanyProfile(profile, profileFromMicrodata(sel))
+	})
+	mergeCompanyProfile(profile, profileFromOpenGraph(doc))
+	if len(profile.Sources) == 0 {
+		return nil
+	}
+	return profile
+}
+
+// jsonLDOtherOrganizations are properties naming organizations other than the
+// site owner, which walkJSONLD does not descend into.
+var jsonLDOtherOrganizations = map[string]struct{}{
+	"parentOrganization": {}, "subOrganization": {}, "brand": {}, "memberOf": {},
+	"member": {}, "funder": {}, "sponsor": {}, "seller": {}, "manufacturer": {},
+	"review": {}, "itemReviewed": {}, "alumniOf": {}, "worksFor": {},
+}
+
+// walkJSONLD calls fn for every organization object, including ones nested in
+// @graph arrays or properties such as publisher.
+func walkJSONLD(data any, fn func(map[string]any)) {
+	switch v := data.(type) {
+	case []any:
+		for _, item := range v {
+			walkJSONLD(item, fn)
+		}
+	case map[string]any:
+		if isOrganizationType(jsonLDStrings(v["@type"])) {
+			fn(v)
+		}
+		for key, child := range v {
+			if _, other := jsonLDOtherOrganizations[key]; other || key == "@type" || key == "@context" {
+				continue
+			}
+			walkJSONLD(child, fn)
+		}
+	}
+}
+
+func profileFromJSONLD(node map[string]any) *domain.CompanyProfile {
+	profile := &domain.CompanyProfile{
+		Name:         jsonLDText(node["name"]),
+		LegalName:    jsonLDText(node["legalName"]),
+		Description:  jsonLDText(node["description"]),
+		Phone:        jsonLDText(node["telephone"]),
+		Email:        cleanMailto(jsonLDText(node["email"])),
+		Logo:         jsonLDText(node["logo"]),
+		FoundingDate: jsonLDText(node["foundingDate"]),
+		Employees:    jsonLDQuantity(node["numberOfEmployees"]),
+	}
+	profile.Address, profile.Country = jsonLDAddress(node["address"])
+	if location, ok := node["location"].(map[string]any); ok && profile.Address == "" {
+		profile.Address, profile.Country = jsonLDAddress(location["address"])
+	}
+	for _, point := range jsonLDObjects(node["contactPoint"]) {
+		profile.Phone = firstNonEmpty(profile.Phone, jsonLDText(point["telephone"]))
+		profile.Email = firstNonEmpty(profile.Email, cleanMailto(jsonLDText(point["email"])))
+	}
+	profile.Sources = []string{profileSourceJSONLD}
+	return profile
+}
+
+// jsonLDText reads a plain value, the first element of an array, or the url,
+// name or @value of a nested object.
+func jsonLDText(value any) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return fmt.Sprint(v)
+	case []any:
+		for _, item := range v {
+			if text := jsonLDText(item); text != "" {
+				return text
+			}
+		}
+	case map[string]any:
+		for _, key := range []string{"url", "contentUrl", "name", "@value"} {
+			if text := jsonLDText(v[key]); text != "" {
+				return text
+			}
+		}
+	}
+	return ""
+}
+
+func jsonLDStrings(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var out []string
+		for _, item := range v {
+			out = append(out, jsonLDStrings(item)...)
+		}
+		return out
+	}
+	return nil
+}
+
+func jsonLDObjects(value any) []map[string]any {
+	switch v := value.(type) {
+	case map[string]any:
+		return []map[string]any{v}
+	case []any:
+		var out []map[string]any
+		for _, item := range v {
+			out = append(out, jsonLDObjects(item)...)
+		}
+		return out
+	}
+	return nil
+}
+
+// jsonLDQuantity formats numberOfEmployees, a number or a QuantitativeValue.
+func jsonLDQuantity(value any) string {
+	if obj, ok := value.(map[string]any); ok {
+		if text := jsonLDText(obj["value"]); text != "" {
+			return text
+		}
+		low, high := jsonLDText(obj["minValue"]), jsonLDText(obj["maxValue"])
+		if low != "" && high != "" {
+			return low + "-" + high
+		}
+		return firstNonEmpty(low, high)
+	}
+	return jsonLDText(value)
+}
+
+// jsonLDAddress formats a PostalAddress (or plain string) and returns its country.
+func jsonLDAddress(value any) (string, string) {
+	for _, obj := range jsonLDObjects(value) {
+		country := jsonLDText(obj["addressCountry"])
+		address := joinNonEmpty(", ",
+			jsonLDText(obj["streetAddress"]),
+			jsonLDText(obj["addressLocality"]),
+			jsonLDText(obj["addressRegion"]),
+			jsonLDText(obj["postalCode"]),
+			country,
+		)
+		if address != "" {
+			return address, country
+		}
+	}
+	return jsonLDText(value), ""
+}
+
+// profileFromMicrodata reads the properties that belong to this itemscope,
+// skipping those of nested items other than the address.
+func profileFromMicrodata(scope *goquery.Selection) *domain.CompanyProfile {
+	props := microdataProps(scope)
+	profile := &domain.CompanyProfile{
+		Name:         props["name"],
+		LegalName:    props["legalName"],
+		Description:  props["description"],
+		Phone:        props["telephone"],
+		Email:        cleanMailto(props["email"]),
+		Logo:         props["logo"],
+		FoundingDate: props["foundingDate"],
+		Employees:    props["numberOfEmployees"],
+		Sources:      []string{profileSourceMicrodata},
+	}
+	scope.Find(`[itemprop~="address"][itemscope]`).EachWithBreak(func(_ int, sel *goquery.Selection) bool {
+		if !ownedByScope(sel, scope) {
+			return true
+		}
+		address := microdataProps(sel)
+		profile.Country = address["addressCountry"]
+		profile.Address = joinNonEmpty(", ", address["streetAddress"], address["addressLocality"],
+			address["addressRegion"], address["postalCode"], address["addressCountry"])
+		return false
+	})
+	if profile.Address == "" && props["address"] != "" {
+		profile.Address = props["address"]
+	}
+	return profile
+}
+
+// microdataProps collects the first value of each itemprop owned by scope.
+func microdataProps(scope *goquery.Selection) map[string]string {
+	props := map[string]string{}
+	scope.Find("[itemprop]").Each(func(_ int, sel *goquery.Selection) {
+		if !ownedByScope(sel, scope) {
+			return
+		}
+		value := microdataValue(sel)
+		if value == "" {
+			return
+		}
+		names, _ := sel.Attr("itemprop")
+		for _, name := range strings.Fields(names) {
+			if _, ok := props[name]; !ok {
+				props[name] = value
+			}
+		}
+	})
+	return props
+}
+
+// ownedByScope reports whether sel's nearest enclosing itemscope is scope.
+func ownedByScope(sel, scope *goquery.Selection) bool {
+	parent := sel.ParentsFiltered("[itemscope]").First()
+	return parent.Length() > 0 && parent.Get(0) == scope.Get(0)
+}
+
+func microdataValue(sel *goquery.Selection) string {
+	for _, attr := range []string{"content", "href", "src", "datetime", "value"} {
+		if value, ok := sel.Attr(attr); ok && strings.TrimSpace(value) != "" {
+			return strings.TrimPrefix(strings.TrimSpace(value), "tel:")
+		}
+	}
+	if _, nested := sel.Attr("itemscope"); nested {
+		return ""
+	}
+	return strings.TrimSpace(compactWhitespace(sel.Text()))
+}
+
+func profileFromOpenGraph(doc *goquery.Document) *domain.CompanyProfile {
+	og := func(property string) string {
+		value, _ := doc.Find(fmt.Sprintf(`meta[property="og:%s"]`, property)).First().Attr("content")
+		return strings.TrimSpace(value)
+	}
+	profile := &domain.CompanyProfile{
+		Name:        og("site_name"),
+		Description: og("description"),
+		Phone:       og("phone_number"),
+		Email:       cleanMailto(og("email")),
+		Country:     og("country-name"),
+		Address:     joinNonEmpty(", ", og("street-address"), og("locality"), og("region"), og("postal-code"), og("country-name")),
+	}
+	if joinNonEmpty("", profile.Name, profile.Description, profile.Phone, profile.Email, profile.Address) == "" {
+		return nil
+	}
+	profile.Sources = []string{profileSourceOpenGraph}
+	return profile
+}
+
+// mergeCompanyProfile fills empty fields of dst from src.
+func mergeCompanyProfile(dst, src *domain.CompanyProfile) {
+	if dst == nil || src == nil {
+		return
+	}
+	dst.Name = firstNonEmpty(dst.Name, src.Name)
+	dst.LegalName = firstNonEmpty(dst.LegalName, src.LegalName)
+	dst.Description = firstNonEmpty(dst.Description, src.Description)
+	dst.Address = firstNonEmpty(dst.Address, src.Address)
+	dst.Country = firstNonEmpty(dst.Country, src.Country)
+	dst.Phone = firstNonEmpty(dst.Phone, src.Phone)
+	dst.Email = firstNonEmpty(dst.Email, src.Email)
+	dst.Logo = firstNonEmpty(dst.Logo, src.Logo)
+	dst.FoundingDate = firstNonEmpty(dst.FoundingDate, src.FoundingDate)
+	dst.Employees = firstNonEmpty(dst.Employees, src.Employees)
+	dst.Sources = mergeUnique(dst.Sources, src.Sources)
+}
+
+// socialPlatforms maps hosts to platform names.
+var socialPlatforms = map[string]string{
+	"linkedin.com":  "linkedin",
+	"facebook.com":  "facebook",
+	"fb.com":        "facebook",
+	"instagram.com": "instagram",
+	"youtube.com":   "youtube",
+}
+
+// extractSocialLinks collects one profile link per platform, preferring the
+// sameAs links of the structured data over links found in the page.
+func extractSocialLinks(doc *goquery.Document) []domain.SocialLink {
+	var links []domain.SocialLink
+	add := func(raw string) {
+		link, ok := normalizeSocialLink(raw)
+		if !ok {
+			return
+		}
+		for _, existing := range links {
+			if existing.Platform == link.Platform {
+				return
+			}
+		}
+		links = append(links, link)
+	}
+	doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, sel *goquery.Selection) {
+		var data any
+		if err := json.Unmarshal([]byte(strings.TrimSpace(sel.Text())), &data); err != nil {
+			return
+		}
+		walkJSONLD(data, func(node map[string]any) {
+			for _, raw := range jsonLDStrings(node["sameAs"]) {
+				add(raw)
+			}
+		})
+	})
+	doc.Find(`[itemprop~="sameAs"]`).Each(func(_ int, sel *goquery.Selection) {
+		add(microdataValue(sel))
+	})
+	doc.Find("a[href]").Each(func(_ int, sel *goquery.Selection) {
+		href, _ := sel.Attr("href")
+		add(href)
+	})
+	return links
+}
+
+// normalizeSocialLink accepts company or person profiles and channels, and
+// rejects share buttons, posts and videos.
+func normalizeSocialLink(raw string) (domain.SocialLink, bool) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return domain.SocialLink{}, false
+	}
+	host := strings.ToLower(parsed.Hostname())
+	for _, prefix := range []string{"www.", "m.", "mobile.", "business."} {
+		host = strings.TrimPrefix(host, prefix)
+	}
+	if strings.HasSuffix(host, ".linkedin.com") {
+		host = "linkedin.com" // country subdomains such as de.linkedin.com
+	}
+	platform, ok := socialPlatforms[host]
+	if !ok {
+		return domain.SocialLink{}, false
+	}
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	first := strings.ToLower(segments[0])
+	if first == "" {
+		return domain.SocialLink{}, false
+	}
+	switch platform {
+	case "linkedin":
+		if (first != "company" && first != "in" && first != "school" && first != "showcase") || len(segments) < 2 {
+			return domain.SocialLink{}, false
+		}
+		segments = segments[:2]
+	case "facebook":
+		switch first {
+		case "sharer", "sharer.php", "share.php", "dialog", "plugins", "tr", "login", "events", "groups", "watch", "photo.php", "story.php":
+			return domain.SocialLink{}, false
+		}
+		if first == "pages" || first == "profile.php" {
+			if first == "profile.php" && parsed.Query().Get("id") != "" {
+				return domain.SocialLink{Platform: platform, URL: "https://www.facebook.com/profile.php?id=" + parsed.Query().Get("id")}, true
+			}
+		} else {
+			segments = segments[:1]
+		}
+	case "instagram":
+		switch first {
+		case "p", "reel", "reels", "explore", "stories", "accounts":
+			return domain.SocialLink{}, false
+		}
+		segments = segments[:1]
+	case "youtube":
+		switch {
+		case strings.HasPrefix(first, "@"):
+			segments = segments[:1]
+		case first == "channel" || first == "c" || first == "user":
+			if len(segments) < 2 {
+				return domain.SocialLink{}, false
+			}
+			segments = segments[:2]
+		default:
+			return domain.SocialLink{}, false
+		}
+	}
+	return domain.SocialLink{Platform: platform, URL: "https://www." + host + "/" + strings.Join(segments, "/")}, true
+}
+
+// mergeSocialLinks adds links for platforms not yet present.
+func mergeSocialLinks(dst, src []domain.SocialLink) []domain.SocialLink {
+	for _, link := range src {
+		present := false
+		for _, existing := range dst {
+			if existing.Platform == link.Platform {
+				present = true
+				break
+			}
+		}
+		if !present {
+			dst = append(dst, link)
+		}
+	}
+	return dst
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	var kept []string
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" && !containsString(kept, part) {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, sep)
+}
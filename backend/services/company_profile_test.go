@@ -0,0 +1,129 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+)
+
+func parseTestDocument(t *testing.T, html string) *goquery.Document {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	return doc
+}
+
+func TestExtractCompanyProfileFromJSONLD(t *testing.T) {
+	doc := parseTestDocument(t, `<html><head>
+<meta property="og:site_name" content="Acme Site">
+<meta property="og:description" content="OpenGraph description">
+<script type="application/ld+json">{
+  "@context": "https://schema.org",
+  "@graph": [
+    {"@type": "WebSite", "name": "Acme", "publisher": {"@id": "#org"}},
+    {
+      "@type": ["Organization", "Corporation"],
+      "@id": "#org",
+      "name": "Acme Valves",
+      "legalName": "Acme Valves GmbH",
+      "logo": {"@type": "ImageObject", "url": "https://acme-valves.de/logo.png"},
+      "foundingDate": "1962",
+      "numberOfEmployees": {"@type": "QuantitativeValue", "minValue": 200, "maxValue": 500},
+      "address": {"@type": "PostalAddress", "streetAddress": "Industriestr. 5", "addressLocality": "Stuttgart", "postalCode": "70565", "addressCountry": {"@type": "Country", "name": "DE"}},
+      "contactPoint": [{"@type": "ContactPoint", "telephone": "+49 711 123456", "email": "mailto:Sales@acme-valves.de"}],
+      "parentOrganization": {"@type": "Organization", "name": "Acme Holding", "telephone": "+1 000 000 0000"},
+      "sameAs": ["https://de.linkedin.com/company/acme-valves/about/", "https://www.facebook.com/AcmeValves?ref=page", "https://twitter.com/acme"]
+    }
+  ]
+}</script>
+<script type="application/ld+json">{ invalid json </script>
+</head><body>
+<a href="https://www.facebook.com/sharer/sharer.php?u=x">Share</a>
+<a href="https://www.instagram.com/p/Cx123/">Post</a>
+<a href="https://www.instagram.com/acmevalves/">Instagram</a>
+<a href="https://www.youtube.com/watch?v=abc">Video</a>
+<a href="https://youtube.com/@AcmeValves/videos">YouTube</a>
+<a href="https://www.linkedin.com/shareArticle?url=x">Share</a>
+</body></html>`)
+
+	profile := extractCompanyProfile(doc)
+	want := &domain.CompanyProfile{
+		Name:         "Acme Valves",
+		LegalName:    "Acme Valves GmbH",
+		Description:  "OpenGraph description",
+		Address:      "Industriestr. 5, Stuttgart, 70565, DE",
+		Country:      "DE",
+		Phone:        "+49 711 123456",
+		Email:        "sales@acme-valves.de",
+		Logo:         "https://acme-valves.de/logo.png",
+		FoundingDate: "1962",
+		Employees:    "200-500",
+		Sources:      []string{profileSourceJSONLD, profileSourceOpenGraph},
+	}
+	if !reflect.DeepEqual(profile, want) {
+		t.Errorf("profile = %+v\nwant      %+v", profile, want)
+	}
+
+	links := extractSocialLinks(doc)
+	wantLinks := []domain.SocialLink{
+		{Platform: "linkedin", URL: "https://www.linkedin.com/company/acme-valves"},
+		{Platform: "facebook", URL: "https://www.facebook.com/AcmeValves"},
+		{Platform: "instagram", URL: "https://www.instagram.com/acmevalves"},
+		{Platform: "youtube", URL: "https://www.youtube.com/@AcmeValves"},
+	}
+	if !reflect.DeepEqual(links, wantLinks) {
+		t.Errorf("social links = %+v\nwant %+v", links, wantLinks)
+	}
+}
+
+func TestExtractCompanyProfileFromMicrodata(t *testing.T) {
+	doc := parseTestDocument(t, `<html><body>
+<div itemscope itemtype="https://schema.org/LocalBusiness">
+  <span itemprop="name">Nordic Pumps AB</span>
+  <div itemprop="address" itemscope itemtype="https://schema.org/PostalAddress">
+    <span itemprop="streetAddress">Hamngatan 1</span>
+    <span itemprop="addressLocality">Göteborg</span>
+    <meta itemprop="addressCountry" content="SE">
+  </div>
+  <div itemprop="employee" itemscope itemtype="https://schema.org/Person">
+    <span itemprop="name">Erik Lund</span>
+    <span itemprop="telephone">+46 31 999 999</span>
+  </div>
+  <a itemprop="telephone" href="tel:+46311234567">+46 31 123 45 67</a>
+  <a itemprop="sameAs" href="https://www.linkedin.com/company/nordic-pumps">LinkedIn</a>
+</div>
+</body></html>`)
+
+	profile := extractCompanyProfile(doc)
+	if profile == nil {
+		t.Fatal("expected a profile")
+	}
+	if profile.Name != "Nordic Pumps AB" || profile.Country != "SE" || profile.Address != "Hamngatan 1, Göteborg, SE" {
+		t.Errorf("profile = %+v", profile)
+	}
+	if profile.Phone != "+46311234567" {
+		t.Errorf("phone should come from the business, not the employee: %q", profile.Phone)
+	}
+	links := extractSocialLinks(doc)
+	if len(links) != 1 || links[0].URL != "https://www.linkedin.com/company/nordic-pumps" {
+		t.Errorf("social links = %+v", links)
+	}
+
+	summary := summarizeDocument(doc, "https://nordicpumps.se", 4000)
+	if !containsString(summary.Phones, "+46311234567") || summary.Profile == nil {
+		t.Errorf("summary should carry the profile phone: %+v", summary)
+	}
+}
+
+func TestExtractCompanyProfileNone(t *testing.T) {
+	doc := parseTestDocument(t, `<html><head><meta property="og:image" content="https://x.test/a.png"></head><body><p>Hello</p></body></html>`)
+	if profile := extractCompanyProfile(doc); profile != nil {
+		t.Errorf("profile = %+v, want nil", profile)
+	}
+}
@@ -0,0 +1,519 @@
+package services
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+)
+
+// Crawl limits. The resolved page always counts as the first page.
+const (
+	defaultCrawlMaxPages = 6
+	defaultCrawlMaxDepth = 2
+	crawlRootTextRunes   = 4000
+	crawlPageTextRunes   = 1500
+	crawlMaxTextRunes    = 9000
+	crawlMaxSitemapURLs  = 500
+	crawlMaxSitemaps     = 3
+	// crawlFetchesPerPage bounds fetch attempts at this many per wanted page,
+	// so a site full of broken links does not drain the whole queue.
+	crawlFetchesPerPage = 2
+)
+
+// robotsAgent is the product token matched against robots.txt user-agent lines.
+const robotsAgent = "ai-trade-assistant"
+
+// CrawlOptions bounds a same-domain crawl. Zero values use the defaults.
+type CrawlOptions struct {
+	MaxPages int // total pages fetched, including the start page
+	MaxDepth int // link hops from the start page
+
+	// keywords replaces crawlKeywords for picking the pages to visit.
+	keywords []crawlKeywordGroup
+	// onPage is called with every fetched page, the start page first.
+	onPage func(pageURL string, doc *goquery.Document)
+	// onLink is called once with every page queued from links or sitemaps,
+	// whether or not it is fetched later.
+	onLink func(pageURL string)
+}
+
+func (o CrawlOptions) withDefaults() CrawlOptions {
+	if o.MaxPages <= 0 {
+		o.MaxPages = defaultCrawlMaxPages
+	}
+	if o.MaxDepth <= 0 {
+		o.MaxDepth = defaultCrawlMaxDepth
+	}
+	if o.keywords == nil {
+		o.keywords = crawlKeywords
+	}
+	return o
+}
+
+// crawlKeywordGroup scores links whose path or text contains one of words.
+type crawlKeywordGroup struct {
+	words []string
+	score int
+}
+
+// crawlKeywords rates how likely a page is to hold contacts or company details,
+// matched against the URL path and the link text.
+var crawlKeywords = []crawlKeywordGroup{
+	{[]string{"contact", "kontakt", "contacto", "contatti", "impressum", "imprint", "联系", "聯繫", "お問い合わせ"}, 5},
+	{[]string{"about", "company", "profile", "who-we-are", "ueber-uns", "uber-uns", "quienes-somos", "关于", "關於", "简介", "会社概要"}, 4},
+	{[]string{"team", "management", "leadership", "people", "staff", "团队", "團隊"}, 3},
+	{[]string{"product", "solution", "service", "catalog", "产品", "產品", "服务", "服務"}, 2},
+	{[]string{"certificat", "quality", "qualitaet", "distributor", "dealer", "where-to-buy", "认证", "資質", "资质", "经销"}, 1},
+}
+
+var crawlSkipExtensions = map[string]struct{}{
+	".pdf": {}, ".jpg": {}, ".jpeg": {}, ".png": {}, ".gif": {}, ".svg": {}, ".webp": {},
+	".zip": {}, ".rar": {}, ".doc": {}, ".docx": {}, ".xls": {}, ".xlsx": {}, ".ppt": {}, ".pptx": {},
+	".mp4": {}, ".mp3": {}, ".css": {}, ".js": {}, ".xml": {}, ".gz": {},
+}
+
+func crawlLinkScore(pageURL *url.URL, anchor string, keywords []crawlKeywordGroup) int {
+	target := strings.ToLower(pageURL.Path + " " + anchor)
+	score := 0
+	for _, group := range keywords {
+		for _, word := range group.words {
+			if strings.Contains(target, word) {
+				score += group.score
+				break
+			}
+		}
+	}
+	return score
+}
+
+type crawlTarget struct {
+	url   *url.URL
+	depth int
+	score int
+	order int
+}
+
+// crawler holds the state of one Crawl call.
+type crawler struct {
+	fetcher *WebFetcher
+	opts    CrawlOptions
+	host    string
+	robots  *robotsRules
+	queue   []crawlTarget
+	seen    map[string]struct{}
+	order   int
+}
+
+// Crawl fetches the start page plus a bounded number of high-value pages on the
+// same site (contact, about, team, products, certificates, dealers), found
+// through page links and sitemap.xml, and merges them into one summary.
+// robots.txt is honoured for every page except the start page, which the
+// caller asked for explicitly.
+func (w *WebFetcher) Crawl(ctx context.Context, rawURL string, opts CrawlOptions) (*WebPageSummary, error) {
+	if rawURL == "" {
+		return nil, fmt.Errorf("empty url")
+	}
+	startURL, err := normalizeURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	doc, finalURL, mode, err := w.fetchPage(ctx, startURL)
+	if err != nil {
+		return nil, err
+	}
+	summary := summarizeDocument(doc, startURL, crawlRootTextRunes)
+	summary.Pages = []string{startURL}
+	summary.FetchMode = mode
+
+	opts = opts.withDefaults()
+	if opts.onPage != nil {
+		opts.onPage(startURL, doc)
+	}
+	if opts.MaxPages <= 1 {
+		return summary, nil
+	}
+	base, err := url.Parse(finalURL)
+	if err != nil {
+		return summary, nil
+	}
+	c := &crawler{
+		fetcher: w,
+		opts:    opts,
+		host:    crawlHostKey(base.Host),
+		seen:    map[string]struct{}{},
+	}
+	c.markSeen(base)
+	if start, err := url.Parse(startURL); err == nil {
+		c.markSeen(start)
+	}
+	c.robots = w.fetchRobots(ctx, base)
+	// Queue the start page links even when robots.txt stops the crawl, so
+	// onLink still learns what the site links to.
+	c.enqueueLinks(doc, base, 1)
+	if c.robots.disallowAll {
+		return summary, nil
+	}
+	c.enqueueSitemap(ctx, base)
+
+	texts := []string{summary.Text}
+	fetches, maxFetches := 0, crawlFetchesPerPage*(opts.MaxPages-1)
+	for len(summary.Pages) < opts.MaxPages && len(c.queue) > 0 && fetches < maxFetches && ctx.Err() == nil {
+		target := c.next()
+		if !c.robots.allowed(target.url) {
+			continue
+		}
+		fetches++
+		pageDoc, pageURL, _, err := w.fetchPage(ctx, target.url.String())
+		if err != nil {
+			log.Printf("[crawl] 抓取页面失败 %s: %v", target.url, err)
+			continue
+		}
+		if parsed, err := url.Parse(pageURL); err == nil && crawlHostKey(parsed.Host) != c.host {
+			continue
+		}
+		page := summarizeDocument(pageDoc, pageURL, crawlPageTextRunes)
+		summary.Pages = append(summary.Pages, pageURL)
+		if opts.onPage != nil {
+			opts.onPage(pageURL, pageDoc)
+		}
+		if strings.TrimSpace(page.Text) != "" {
+			texts = append(texts, fmt.Sprintf("[页面 %s]\n%s", target.url.EscapedPath(), page.Text))
+		}
+		summary.Emails = mergeUnique(summary.Emails, page.Emails)
+		summary.Phones = mergeUnique(summary.Phones, page.Phones)
+		summary.SocialLinks = mergeSocialLinks(summary.SocialLinks, page.SocialLinks)
+		mergeWebsiteSignals(summary.Signals, page.Signals)
+		if page.Profile != nil {
+			if summary.Profile == nil {
+				summary.Profile = &domain.CompanyProfile{}
+			}
+			mergeCompanyProfile(summary.Profile, page.Profile)
+		}
+		if target.depth < opts.MaxDepth {
+			if parsed, err := url.Parse(pageURL); err == nil {
+				c.enqueueLinks(pageDoc, parsed, target.depth+1)
+			}
+		}
+	}
+	summary.Text = truncateRunes(strings.Join(texts, "\n===\n"), crawlMaxTextRunes)
+	return summary, nil
+}
+
+// next pops the best queued page: highest score, then shallowest, then first found.
+func (c *crawler) next() crawlTarget {
+	sort.SliceStable(c.queue, func(i, j int) bool {
+		a, b := c.queue[i], c.queue[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.depth != b.depth {
+			return a.depth < b.depth
+		}
+		return a.order < b.order
+	})
+	target := c.queue[0]
+	c.queue = c.queue[1:]
+	return target
+}
+
+// enqueue adds a same-site page that looks like it holds company details.
+func (c *crawler) enqueue(target *url.URL, anchor string, depth int) {
+	if target == nil || (target.Scheme != "http" && target.Scheme != "https") {
+		return
+	}
+	if crawlHostKey(target.Host) != c.host {
+		return
+	}
+	if _, skip := crawlSkipExtensions[strings.ToLower(path.Ext(target.Path))]; skip {
+		return
+	}
+	score := crawlLinkScore(target, anchor, c.opts.keywords)
+	if score == 0 {
+		return
+	}
+	target.Fragment = ""
+	if !c.markSeen(target) {
+		return
+	}
+	c.order++
+	c.queue = append(c.queue, crawlTarget{url: target, depth: depth, score: score, order: c.order})
+	if c.opts.onLink != nil {
+		c.opts.onLink(target.String())
+	}
+}
+
+// markSeen records a page and reports whether it was new.
+func (c *crawler) markSeen(u *url.URL) bool {
+	key := crawlHostKey(u.Host) + strings.TrimSuffix(u.EscapedPath(), "/")
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+	}
+	if _, ok := c.seen[key]; ok {
+		return false
+	}
+	c.seen[key] = struct{}{}
+	return true
+}
+
+func (c *crawler) enqueueLinks(doc *goquery.Document, base *url.URL, depth int) {
+	doc.Find("a[href]").Each(func(_ int, sel *goquery.Selection) {
+		href, _ := sel.Attr("href")
+		href = strings.TrimSpace(href)
+		if href == "" || strings.HasPrefix(href, "#") {
+			return
+		}
+		ref, err := url.Parse(href)
+		if err != nil {
+			return
+		}
+		anchor := compactWhitespace(sel.Text())
+		if title, ok := sel.Attr("title"); ok {
+			anchor += " " + title
+		}
+		c.enqueue(base.ResolveReference(ref), anchor, depth)
+	})
+}
+
+// enqueueSitemap reads the sitemaps listed in robots.txt, or /sitemap.xml, and
+// queues the high-value pages they list. Sitemap indexes are followed until
+// crawlMaxSitemaps files have been read.
+func (c *crawler) enqueueSitemap(ctx context.Context, base *url.URL) {
+	sitemaps := c.robots.sitemaps
+	if len(sitemaps) == 0 {
+		sitemaps = []string{base.Scheme + "://" + base.Host + "/sitemap.xml"}
+	}
+	fetched, listed := 0, 0
+	for i := 0; i < len(sitemaps) && fetched < crawlMaxSitemaps && ctx.Err() == nil; i++ {
+		loc, err := base.Parse(strings.TrimSpace(sitemaps[i]))
+		if err != nil || crawlHostKey(loc.Host) != c.host || strings.HasSuffix(strings.ToLower(loc.Path), ".gz") {
+			continue
+		}
+		fetched++
+		set, err := c.fetcher.fetchSitemap(ctx, loc.String())
+		if err != nil {
+			log.Printf("[crawl] 读取 sitemap 失败 %s: %v", loc, err)
+			continue
+		}
+		for _, child := range set.Sitemaps {
+			sitemaps = append(sitemaps, child.Loc)
+		}
+		for _, entry := range set.URLs {
+			if listed >= crawlMaxSitemapURLs {
+				break
+			}
+			listed++
+			if pageURL, err := url.Parse(strings.TrimSpace(entry.Loc)); err == nil {
+				c.enqueue(pageURL, "", 1)
+			}
+		}
+	}
+}
+
+// sitemapDocument covers both <urlset> and <sitemapindex> documents.
+type sitemapDocument struct {
+	URLs []struct {
+		Loc string `xml:"loc"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+func (w *WebFetcher) fetchSitemap(ctx context.Context, sitemapURL string) (*sitemapDocument, error) {
+	resp, err := w.get(ctx, sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("状态码 %d", resp.StatusCode)
+	}
+	var doc sitemapDocument
+	decoder := xml.NewDecoder(io.LimitReader(resp.Body, 2<<20))
+	decoder.CharsetReader = charsetReader
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("解析 sitemap 失败: %w", err)
+	}
+	return &doc, nil
+}
+
+// fetchRobots loads robots.txt for the site. A missing file allows everything;
+// an unreachable or failing server disallows everything, as RFC 9309 advises.
+func (w *WebFetcher) fetchRobots(ctx context.Context, base *url.URL) *robotsRules {
+	resp, err := w.get(ctx, base.Scheme+"://"+base.Host+"/robots.txt")
+	if err != nil {
+		return &robotsRules{disallowAll: true}
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500:
+		return &robotsRules{disallowAll: true}
+	case resp.StatusCode >= 400:
+		return &robotsRules{}
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 512<<10))
+	if err != nil {
+		return &robotsRules{disallowAll: true}
+	}
+	return parseRobots(string(body), robotsAgent)
+}
+
+// robotsRules is the robots.txt group that applies to this crawler.
+type robotsRules struct {
+	rules       []robotsRule
+	sitemaps    []string
+	disallowAll bool
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// parseRobots picks the group naming agent, falling back to "*", and collects
+// every Sitemap line.
+func parseRobots(body, agent string) *robotsRules {
+	type group struct {
+		agents []string
+		rules  []robotsRule
+	}
+	var (
+		groups   []*group
+		current  *group
+		sitemaps []string
+		inAgents bool
+	)
+	for _, line := range strings.Split(body, "\n") {
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				current = &group{}
+				groups = append(groups, current)
+				inAgents = true
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inAgents = false
+			if current == nil || value == "" {
+				continue
+			}
+			current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: value})
+		case "sitemap":
+			sitemaps = append(sitemaps, value)
+		default:
+			inAgents = false
+		}
+	}
+
+	agent = strings.ToLower(agent)
+	var specific, wildcard []robotsRule
+	matchedSpecific := false
+	for _, g := range groups {
+		for _, name := range g.agents {
+			switch {
+			case name == "*":
+				wildcard = append(wildcard, g.rules...)
+			case name != "" && strings.Contains(agent, name):
+				specific = append(specific, g.rules...)
+				matchedSpecific = true
+			}
+		}
+	}
+	rules := wildcard
+	if matchedSpecific {
+		rules = specific
+	}
+	return &robotsRules{rules: rules, sitemaps: sitemaps}
+}
+
+// allowed applies the longest matching rule; Allow wins ties.
+func (r *robotsRules) allowed(u *url.URL) bool {
+	if r == nil {
+		return true
+	}
+	if r.disallowAll {
+		return false
+	}
+	target := u.EscapedPath()
+	if target == "" {
+		target = "/"
+	}
+	if u.RawQuery != "" {
+		target += "?" + u.RawQuery
+	}
+	best, allow := -1, true
+	for _, rule := range r.rules {
+		if !robotsPatternMatches(rule.pattern, target) {
+			continue
+		}
+		if len(rule.pattern) > best || (len(rule.pattern) == best && rule.allow) {
+			best, allow = len(rule.pattern), rule.allow
+		}
+	}
+	return allow
+}
+
+// robotsPatternMatches supports the "*" wildcard and the "$" end anchor.
+func robotsPatternMatches(pattern, target string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(target, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+	for _, part := range parts[1:] {
+		idx := strings.Index(target[pos:], part)
+		if idx < 0 {
+			return false
+		}
+		pos += idx + len(part)
+	}
+	if !anchored {
+		return true
+	}
+	if len(parts) > 1 && parts[len(parts)-1] == "" {
+		return true
+	}
+	if len(parts) > 1 {
+		return strings.HasSuffix(target, parts[len(parts)-1])
+	}
+	return pos == len(target)
+}
+
+func crawlHostKey(host string) string {
+	host = strings.ToLower(host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimPrefix(host, "www.")
+}
+
+func mergeUnique(items, extra []string) []string {
+	for _, item := range extra {
+		if !containsString(items, item) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCrawlMergesHighValuePages(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		visited []string
+	)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		visited = append(visited, r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprintf(w, "User-agent: *\nDisallow: /team\n\nSitemap: %s/sitemap.xml\n", server.URL)
+		case "/sitemap.xml":
+			fmt.Fprintf(w, `<?xml version="1.0"?><urlset><url><loc>%[1]s/contact-us</loc></url><url><loc>%[1]s/blog/post-1</loc></url></urlset>`, server.URL)
+		case "/":
+			fmt.Fprint(w, `<html><body><nav>
+				<a href="/about">About Us</a>
+				<a href="/team">Our Team</a>
+				<a href="/brochure.pdf">Product brochure</a>
+				<a href="https://other.example/contact">Partner</a>
+				<a href="/news">News</a>
+			</nav><p>Acme makes industrial valves. Reach us at info@acme.test.</p></body></html>`)
+		case "/about":
+			fmt.Fprint(w, `<html><body><h1>About Acme</h1><p>Family owned valve manufacturer since 1962 with plants in Ohio and Texas serving refineries.</p>
+				<a href="/products">Products</a></body></html>`)
+		case "/contact-us":
+			fmt.Fprint(w, `<html><body><a href="mailto:sales@acme.test">Sales</a><a href="tel:+1 555 010 2000">Call</a></body></html>`)
+		case "/products":
+			fmt.Fprint(w, `<html><body><p>Ball valves, gate valves and custom actuators for oil and gas customers worldwide.</p></body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := NewWebFetcher(server.Client())
+	summary, err := fetcher.Crawl(context.Background(), server.URL, CrawlOptions{MaxPages: 4})
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+
+	if len(summary.Pages) != 4 {
+		t.Fatalf("pages = %v, want start page plus 3", summary.Pages)
+	}
+	for _, want := range []string{"/contact-us", "/about", "/products"} {
+		if !containsString(summary.Pages, server.URL+want) {
+			t.Errorf("pages %v missing %s", summary.Pages, want)
+		}
+	}
+	if summary.Pages[1] != server.URL+"/contact-us" {
+		t.Errorf("contact page should be crawled first, got %v", summary.Pages)
+	}
+	for _, want := range []string{"info@acme.test", "sales@acme.test"} {
+		if !containsString(summary.Emails, want) {
+			t.Errorf("emails %v missing %s", summary.Emails, want)
+		}
+	}
+	if !containsString(summary.Phones, "+15550102000") {
+		t.Errorf("phones = %v", summary.Phones)
+	}
+	if !strings.Contains(summary.Text, "Family owned valve manufacturer") || !strings.Contains(summary.Text, "[页面 /about]") {
+		t.Errorf("text missing about section: %q", summary.Text)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, path := range visited {
+		switch path {
+		case "/team", "/brochure.pdf", "/news", "/blog/post-1":
+			t.Errorf("crawler should not request %s", path)
+		}
+	}
+}
+
+func TestCrawlLimitsFailedFetches(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		fetched int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt", "/sitemap.xml":
+			http.NotFound(w, r)
+		case "/":
+			var links strings.Builder
+			for i := 0; i < 30; i++ {
+				fmt.Fprintf(&links, `<a href="/products/%d">Product %d</a>`, i, i)
+			}
+			fmt.Fprintf(w, `<html><body>%s</body></html>`, links.String())
+		default:
+			mu.Lock()
+			fetched++
+			mu.Unlock()
+			http.Error(w, "broken", http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	summary, err := NewWebFetcher(server.Client()).Crawl(context.Background(), server.URL, CrawlOptions{MaxPages: 4})
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+	if len(summary.Pages) != 1 {
+		t.Fatalf("pages = %v", summary.Pages)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if fetched != 6 {
+		t.Fatalf("fetched %d broken pages, want 6", fetched)
+	}
+}
+
+func TestCrawlStopsWhenRobotsUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/":
+			fmt.Fprint(w, `<html><body><a href="/contact">Contact</a> <p>Write to office@acme.test</p></body></html>`)
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	summary, err := NewWebFetcher(server.Client()).Crawl(context.Background(), server.URL, CrawlOptions{})
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+	if len(summary.Pages) != 1 || !containsString(summary.Emails, "office@acme.test") {
+		t.Fatalf("summary = %+v", summary)
+	}
+}
+
+func TestRobotsRules(t *testing.T) {
+	body := `
+# comment
+User-agent: *
+Disallow: /private
+Allow: /private/contact
+
+User-agent: Googlebot
+User-agent: AI-Trade-Assistant
+Disallow: /*.php$
+Disallow: /internal/
+
+Sitemap: https://acme.test/sitemap_index.xml
+`
+	rules := parseRobots(body, robotsAgent)
+	if len(rules.sitemaps) != 1 || rules.sitemaps[0] != "https://acme.test/sitemap_index.xml" {
+		t.Fatalf("sitemaps = %v", rules.sitemaps)
+	}
+	cases := map[string]bool{
+		"/":                true,
+		"/private/page":    true, // our own group replaces the * group
+		"/internal/staff":  false,
+		"/contact.php":     false,
+		"/contact.php?x=1": true,
+	}
+	for raw, want := range cases {
+		u, _ := url.Parse("https://acme.test" + raw)
+		if got := rules.allowed(u); got != want {
+			t.Errorf("allowed(%s) = %v, want %v", raw, got, want)
+		}
+	}
+
+	generic := parseRobots(body, "otherbot")
+	for raw, want := range map[string]bool{"/private/team": false, "/private/contact": true, "/internal/": true} {
+		u, _ := url.Parse("https://acme.test" + raw)
+		if got := generic.allowed(u); got != want {
+			t.Errorf("generic allowed(%s) = %v, want %v", raw, got, want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+// Score adjustments applied by the domain rules during website resolution.
+const (
+	allowedDomainBonus    = 5.0
+	allowedCandidateBonus = 0.3
+	blockedCandidateScore = 0.1
+)
+
+// domainRules is the enabled block/allow list used to rank website candidates.
+type domainRules struct {
+	rules []store.DomainRule
+}
+
+// domainRuleMatch reports which rule applied to a domain.
+type domainRuleMatch struct {
+	Kind    string
+	Pattern string
+}
+
+func (m domainRuleMatch) blocked() bool { return m.Kind == store.DomainRuleBlock }
+func (m domainRuleMatch) allowed() bool { return m.Kind == store.DomainRuleAllow }
+
+// reasonSuffix describes the match for candidate reasons.
+func (m domainRuleMatch) reasonSuffix() string {
+	switch m.Kind {
+	case store.DomainRuleBlock:
+		return fmt.Sprintf("（已屏蔽：匹配规则 %s）", m.Pattern)
+	case store.DomainRuleAllow:
+		return fmt.Sprintf("（优先域名：匹配规则 %s）", m.Pattern)
+	}
+	return ""
+}
+
+func newDomainRules(rules []store.DomainRule) *domainRules {
+	enabled := make([]store.DomainRule, 0, len(rules))
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		pattern, err := store.NormalizeDomainPattern(rule.Pattern)
+		if err != nil {
+			continue
+		}
+		rule.Pattern = pattern
+		enabled = append(enabled, rule)
+	}
+	return &domainRules{rules: enabled}
+}
+
+// defaultDomainRules is used when the configured rules cannot be loaded.
+var defaultDomainRules = newDomainRules(store.DefaultDomainRules())
+
+// loadDomainRules reads the enabled rules from the store, falling back to the
+// built-in block list so resolution keeps working without a database.
+func loadDomainRules(ctx context.Context, st *store.Store) *domainRules {
+	if st == nil {
+		return defaultDomainRules
+	}
+	rules, err := st.ListDomainRules(ctx)
+	if err != nil {
+		log.Printf("[enrichment] 读取域名规则失败，使用内置规则: %v", err)
+		return defaultDomainRules
+	}
+	return newDomainRules(rules)
+}
+
+// match returns the most specific rule matching the domain. Longer patterns
+// win, and allow beats block on a tie, so "shop.alibaba.com" can be preferred
+// while the rest of alibaba.com stays blocked.
+func (r *domainRules) match(domain string) (domainRuleMatch, bool) {
+	if r == nil {
+		return domainRuleMatch{}, false
+	}
+	host := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "www.")
+	if host == "" {
+		return domainRuleMatch{}, false
+	}
+	var (
+		best  domainRuleMatch
+		found bool
+	)
+	for _, rule := range r.rules {
+		if !domainPatternMatches(host, rule.Pattern) {
+			continue
+		}
+		better := !found ||
+			len(rule.Pattern) > len(best.Pattern) ||
+			(len(rule.Pattern) == len(best.Pattern) && rule.Kind == store.DomainRuleAllow)
+		if better {
+			best = domainRuleMatch{Kind: rule.Kind, Pattern: rule.Pattern}
+			found = true
+		}
+	}
+	return best, found
+}
+
+// isBlocked reports whether the domain matches a block rule.
+func (r *domainRules) isBlocked(domain string) bool {
+	m, ok := r.match(domain)
+	return ok && m.blocked()
+}
+
+func domainPatternMatches(host, pattern string) bool {
+	if pattern == "" {
+		return false
+	}
+	if strings.Contains(pattern, ".") {
+		return host == pattern || strings.HasSuffix(host, "."+pattern)
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+func TestDomainRulesMatch(t *testing.T) {
+	rules := newDomainRules([]store.DomainRule{
+		{Pattern: "alibaba.com", Kind: store.DomainRuleBlock, Enabled: true},
+		{Pattern: "acme.alibaba.com", Kind: store.DomainRuleAllow, Enabled: true},
+		{Pattern: "kompass", Kind: store.DomainRuleBlock, Enabled: true},
+		{Pattern: "europages", Kind: store.DomainRuleBlock, Enabled: false},
+	})
+	cases := []struct {
+		domain  string
+		kind    string
+		pattern string
+	}{
+		{"alibaba.com", store.DomainRuleBlock, "alibaba.com"},
+		{"www.m.alibaba.com", store.DomainRuleBlock, "alibaba.com"},
+		{"acme.alibaba.com", store.DomainRuleAllow, "acme.alibaba.com"},
+		{"fr.kompass.com", store.DomainRuleBlock, "kompass"},
+		{"kompass.de", store.DomainRuleBlock, "kompass"},
+		{"notalibaba.com", "", ""},
+		{"kompasstech.com", "", ""},
+		{"europages.co.uk", "", ""},
+	}
+	for _, tc := range cases {
+		match, ok := rules.match(tc.domain)
+		if tc.kind == "" {
+			if ok {
+				t.Fatalf("%s: expected no match, got %#v", tc.domain, match)
+			}
+			continue
+		}
+		if !ok || match.Kind != tc.kind || match.Pattern != tc.pattern {
+			t.Fatalf("%s: expected %s/%s, got %#v", tc.domain, tc.kind, tc.pattern, match)
+		}
+	}
+}
+
+func TestWebsiteResolutionAppliesDomainRules(t *testing.T) {
+	items := []SearchItem{
+		{Title: "Acme Tools - Alibaba.com", URL: "https://www.alibaba.com/showroom/acme-tools.html", Snippet: "Acme Tools supplier"},
+		{Title: "Acme Tools | Kompass", URL: "https://fr.kompass.com/c/acme-tools", Snippet: "Acme Tools company profile"},
+		{Title: "Acme Tools GmbH", URL: "https://acme-tools.de", Snippet: "Acme Tools official site"},
+		{Title: "Acme distributor", URL: "https://tools-partner.com", Snippet: "Authorised Acme Tools partner"},
+	}
+
+	if got := choosePrimaryWebsite(items, "Acme Tools", defaultDomainRules); !strings.Contains(got, "acme-tools.de") {
+		t.Fatalf("expected marketplaces to be skipped, got %s", got)
+	}
+
+	rules := newDomainRules(append(store.DefaultDomainRules(),
+		store.DomainRule{Pattern: "tools-partner.com", Kind: store.DomainRuleAllow, Enabled: true},
+	))
+	chosen := choosePrimaryWebsite(items, "Acme Tools", rules)
+	if !strings.Contains(chosen, "tools-partner.com") {
+		t.Fatalf("expected the preferred domain to win, got %s", chosen)
+	}
+	blocked := scoreDomainForTokens("alibaba.com", buildQueryTokens("Acme Tools"), items[0], 0, "Acme Tools", rules)
+	if blocked >= 0 {
+		t.Fatalf("expected blocked domain to score below zero, got %.2f", blocked)
+	}
+
+	candidates := buildCandidateList(items, chosen, rules)
+	if len(candidates) != 4 || !strings.Contains(candidates[0].URL, "tools-partner.com") {
+		t.Fatalf("expected preferred domain first, got %#v", candidates)
+	}
+	if !strings.Contains(candidates[0].Reason, "优先域名：匹配规则 tools-partner.com") {
+		t.Fatalf("expected allow rule in reason, got %q", candidates[0].Reason)
+	}
+	reasons := map[string]string{}
+	for _, cand := range candidates {
+		reasons[normalizeDomain(cand.URL)] = cand.Reason
+	}
+	if !strings.Contains(reasons["alibaba.com"], "已屏蔽：匹配规则 alibaba.com") {
+		t.Fatalf("expected block rule in alibaba reason, got %q", reasons["alibaba.com"])
+	}
+	if !strings.Contains(reasons["fr.kompass.com"], "已屏蔽：匹配规则 kompass") {
+		t.Fatalf("expected block rule in kompass reason, got %q", reasons["fr.kompass.com"])
+	}
+	for _, cand := range candidates[2:] {
+		if !strings.Contains(cand.Reason, "已屏蔽") {
+			t.Fatalf("expected blocked candidates to rank last, got %#v", candidates)
+		}
+	}
+}
+
+func TestFinalizeWebsiteSkipsBlockedLLMChoice(t *testing.T) {
+	primary := "https://acme-tools.de"
+	if got := finalizeWebsite("https://www.alibaba.com/showroom/acme-tools.html", primary, "Acme Tools", false, defaultDomainRules); got != primary {
+		t.Fatalf("blocked LLM website accepted: %s", got)
+	}
+	if got := finalizeWebsite("https://acme-tools.com", primary, "Acme Tools", false, defaultDomainRules); got != "https://acme-tools.com" {
+		t.Fatalf("LLM website = %s, want it kept", got)
+	}
+}
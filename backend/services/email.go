@@ -2,7 +2,6 @@ package services
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -12,48 +11,81 @@ import (
 
 // EmailComposerServiceImpl handles email drafting logic.
 type EmailComposerServiceImpl struct {
-	store *store.Store
-	llm   *LLMClient
+	store   *store.Store
+	llm     *LLMClient
+	prompts *PromptServiceImpl
 }
 
 // NewEmailComposerService constructs the email composer.
-func NewEmailComposerService(st *store.Store, llm *LLMClient) *EmailComposerServiceImpl {
-	return &EmailComposerServiceImpl{store: st, llm: llm}
+func NewEmailComposerService(st *store.Store, llm *LLMClient, prompts *PromptServiceImpl) *EmailComposerServiceImpl {
+	return &EmailComposerServiceImpl{store: st, llm: llm, prompts: prompts}
 }
 
 // DraftInitial generates and persists the first outreach email.
 func (e *EmailComposerServiceImpl) DraftInitial(ctx context.Context, customerID int64) (*domain.EmailDraftResponse, error) {
-	customer, err := e.store.GetCustomer(ctx, customerID)
+	customer, messages, err := e.prepareInitial(ctx, customerID)
 	if err != nil {
 		return nil, err
 	}
-	analysis, err := e.store.GetLatestAnalysis(ctx, customerID)
+	var parsed domain.EmailDraft
+	if err := e.llm.ChatJSON(ctx, messages, initialEmailChatOptions.WithCustomer(customerID), emailDraftSchema, &parsed); err != nil {
+		return nil, fmt.Errorf("解析邮件草稿失败: %w", err)
+	}
+	return e.persistInitial(ctx, customer, parsed)
+}
+
+// DraftInitialStream behaves like DraftInitial but forwards partial model output to onDelta as it arrives.
+func (e *EmailComposerServiceImpl) DraftInitialStream(ctx context.Context, customerID int64, onDelta func(string)) (*domain.EmailDraftResponse, error) {
+	customer, messages, err := e.prepareInitial(ctx, customerID)
 	if err != nil {
 		return nil, err
 	}
-	contacts, err := e.store.ListContacts(ctx, customerID)
+	opts := initialEmailChatOptions.WithCustomer(customerID)
+	content, _, err := e.llm.ChatStream(ctx, messages, opts, onDelta)
 	if err != nil {
 		return nil, err
 	}
-	settings, err := e.store.GetSettings(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("读取配置失败: %w", err)
+	var parsed domain.EmailDraft
+	if err := e.llm.DecodeJSON(ctx, messages, opts, content, emailDraftSchema, &parsed); err != nil {
+		return nil, fmt.Errorf("解析邮件草稿失败: %w", err)
 	}
+	return e.persistInitial(ctx, customer, parsed)
+}
+
+var initialEmailChatOptions = ChatOptions{MaxTokens: 550, Temperature: 0.55, ResponseFormat: "json_object", Task: LLMTaskEmailInitial}
 
-	prompt := buildInitialEmailPrompt(customer, analysis, contacts, settings)
-	content, _, err := e.llm.Chat(ctx, []ChatMessage{
-		{Role: "system", Content: emailSystemPrompt},
-		{Role: "user", Content: prompt},
-	}, ChatOptions{MaxTokens: 550, Temperature: 0.55, ResponseFormat: "json_object"})
+func (e *EmailComposerServiceImpl) prepareInitial(ctx context.Context, customerID int64) (*domain.Customer, []ChatMessage, error) {
+	customer, err := e.store.GetCustomer(ctx, customerID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	analysis, err := e.store.GetLatestAnalysis(ctx, customerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	contacts, err := e.store.ListContacts(ctx, customerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	settings, err := e.store.GetSettings(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取配置失败: %w", err)
 	}
 
-	var parsed domain.EmailDraft
-	if err := json.Unmarshal([]byte(content), &parsed); err != nil {
-		return nil, fmt.Errorf("解析邮件草稿失败: %w", err)
+	messages, err := e.prompts.Messages(ctx, PromptEmailInitial, PromptData{
+		Customer:   customer,
+		Contacts:   contacts,
+		KeyContact: keyContactName(contacts),
+		Analysis:   &analysis.AnalysisContent,
+		Settings:   promptSettingsFrom(settings),
+	})
+	if err != nil {
+		return nil, nil, err
 	}
+	return customer, messages, nil
+}
 
+func (e *EmailComposerServiceImpl) persistInitial(ctx context.Context, customer *domain.Customer, parsed domain.EmailDraft) (*domain.EmailDraftResponse, error) {
 	subject := strings.TrimSpace(parsed.Subject)
 	if subject == "" {
 		subject = fmt.Sprintf("合作机会：关于 %s 的解决方案", customer.Name)
@@ -61,7 +93,7 @@ func (e *EmailComposerServiceImpl) DraftInitial(ctx context.Context, customerID
 	parsed.Subject = subject
 	parsed.Body = strings.TrimSpace(parsed.Body)
 
-	emailID, err := e.store.InsertEmailDraft(ctx, customerID, "initial", parsed, "draft")
+	emailID, err := e.store.InsertEmailDraft(ctx, customer.ID, "initial", parsed, "draft")
 	if err != nil {
 		return nil, err
 	}
@@ -83,88 +115,19 @@ func (e *EmailComposerServiceImpl) DraftFollowup(ctx context.Context, customerID
 		return nil, fmt.Errorf("读取配置失败: %w", err)
 	}
 
-	prompt := buildFollowupPrompt(contextEmail, settings)
-	content, _, err := e.llm.Chat(ctx, []ChatMessage{
-		{Role: "system", Content: followupSystemPrompt},
-		{Role: "user", Content: prompt},
-	}, ChatOptions{MaxTokens: 320, Temperature: 0.6, ResponseFormat: "json_object"})
+	messages, err := e.prompts.Messages(ctx, PromptEmailFollowup, PromptData{
+		ContextEmail: contextEmail,
+		Settings:     promptSettingsFrom(settings),
+	})
 	if err != nil {
 		return nil, err
 	}
-
 	var parsed domain.EmailDraft
-	if err := json.Unmarshal([]byte(content), &parsed); err != nil {
+	opts := ChatOptions{MaxTokens: 320, Temperature: 0.6, ResponseFormat: "json_object", Task: LLMTaskEmailFollowup, CustomerID: customerID}
+	if err := e.llm.ChatJSON(ctx, messages, opts, emailDraftSchema, &parsed); err != nil {
 		return nil, fmt.Errorf("解析跟进邮件失败: %w", err)
 	}
 	parsed.Subject = strings.TrimSpace(parsed.Subject)
 	parsed.Body = strings.TrimSpace(parsed.Body)
 	return &parsed, nil
 }
-
-func buildInitialEmailPrompt(customer *domain.Customer, analysis *domain.AnalysisResponse, contacts []domain.Contact, settings *store.Settings) string {
-	var contactLine string
-	if len(contacts) > 0 {
-		key := contacts[0]
-		if key.Name != "" {
-			contactLine = key.Name
-		} else if key.Email != "" {
-			contactLine = key.Email
-		} else {
-			contactLine = "there"
-		}
-	} else {
-		contactLine = "there"
-	}
-
-	var sb strings.Builder
-	sb.WriteString("客户名称: ")
-	sb.WriteString(customer.Name)
-	sb.WriteString("\n官网: ")
-	sb.WriteString(customer.Website)
-	sb.WriteString("\n客户摘要: ")
-	sb.WriteString(customer.Summary)
-	sb.WriteString("\n\n切入点分析:\n")
-	sb.WriteString("核心业务: " + analysis.CoreBusiness + "\n")
-	sb.WriteString("痛点: " + analysis.PainPoints + "\n")
-	sb.WriteString("我方切入点: " + analysis.MyEntryPoints + "\n")
-	sb.WriteString("\n我的公司名: " + strings.TrimSpace(settings.MyCompanyName) + "\n")
-	sb.WriteString("产品简介: " + strings.TrimSpace(settings.MyProduct) + "\n")
-	sb.WriteString("目标联系人: " + contactLine + "\n")
-	sb.WriteString("请参考以下三段式英文邮件的结构与语气（仅示例，不可直接复制原文）：\n")
-	sb.WriteString("Dear Shirley,\nThank you for your feedback.\nFor your information, we have received quotation for gold plated > 3microns with this target price in average.\nLet me know if your price can be more competitive.\nI would be happy to consider a partnership then.\nThank you very much for your continuous support,\nKind regards,\n")
-	sb.WriteString(`
-请使用简洁商务英文，输出 JSON：{
-  "subject": "邮件标题",
-  "body": "150-220词正文，需包含客户痛点与我方解决方案，并以柔性 CTA 收尾"
-}
-如果缺少联系人姓名，请以 "Hi there" 开头。
-`)
-	return sb.String()
-}
-
-func buildFollowupPrompt(contextEmail *domain.EmailRecord, settings *store.Settings) string {
-	var sb strings.Builder
-	sb.WriteString("此前已发送的首封邮件正文如下：\n")
-	sb.WriteString(contextEmail.Body)
-	sb.WriteString(`
-请基于上封邮件，撰写一封不超过120词的英文跟进邮件，保持友好语气，并提供一个新的价值点（例如更高性能指标、成功案例或资源下载链接占位）。输出 JSON：{
-  "subject": "标题",
-  "body": "正文"
-}
-请继续参考以下三段式示例的语气与节奏（不可原封不动复制内容）：
-Dear Shirley,
-Thank you for your feedback.
-For your information, we have received quotation for gold plated > 3microns with this target price in average.
-Let me know if your price can be more competitive.
-I would be happy to consider a partnership then.
-Thank you very much for your continuous support,
-Kind regards,
-`)
-	if strings.TrimSpace(settings.MyCompanyName) != "" {
-		sb.WriteString("发件公司: " + strings.TrimSpace(settings.MyCompanyName) + "\n")
-	}
-	return sb.String()
-}
-
-const emailSystemPrompt = "你是专业的 B2B 外贸业务开发邮件写手，请输出高质量英文邮件。"
-const followupSystemPrompt = "你是专业的客户成功经理，请基于已有上下文撰写精炼的英文跟进。"
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"encoding/hex"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Text forms such as "sales [at] acme [dot] com", "sales(at)acme.com" or
+// "sales AT acme DOT com". Bracketed markers match in any case; bare words
+// only in upper case, so ordinary prose ("meet us at the fair") is ignored.
+// A bare AT is only trusted around a plausible address (see bareAtAddress),
+// because upper-case headings read the same way.
+var (
+	obfuscatedAtPattern  = `(?:\s*(?:\[\s*(?i:at)\s*\]|\(\s*(?i:at)\s*\)|\{\s*(?i:at)\s*\}|<\s*(?i:at)\s*>)\s*|\s+AT\s+|\s*\[@\]\s*|\s*\(@\)\s*)`
+	obfuscatedDotPattern = `(?:\s*(?:\[\s*(?i:dot)\s*\]|\(\s*(?i:dot)\s*\)|\{\s*(?i:dot)\s*\}|<\s*(?i:dot)\s*>)\s*|\s+DOT\s+|\s*\[\.\]\s*|\.)`
+	obfuscatedEmailRegex = regexp.MustCompile(
+		`([A-Za-z0-9._%+-]+)(` + obfuscatedAtPattern + `)` +
+			`([A-Za-z0-9-]+(?:` + obfuscatedDotPattern + `[A-Za-z0-9-]+)+)`)
+	obfuscatedDotRegex = regexp.MustCompile(obfuscatedDotPattern)
+)
+
+var (
+	jsConcatRegex     = regexp.MustCompile(`(['"])\s*\+\s*(['"])`)
+	jsCharCodeRegex   = regexp.MustCompile(`String\.fromCharCode\(\s*([0-9,\s]+)\)`)
+	jsUnicodeRegex    = regexp.MustCompile(`\\u([0-9a-fA-F]{4})|\\x([0-9a-fA-F]{2})`)
+	cfEmailPathRegex  = regexp.MustCompile(`/cdn-cgi/l/email-protection#([0-9a-fA-F]+)`)
+	nonEmailFileTLDs  = map[string]struct{}{"png": {}, "jpg": {}, "jpeg": {}, "gif": {}, "svg": {}, "webp": {}, "css": {}, "js": {}, "ico": {}, "pdf": {}, "htm": {}, "html": {}, "php": {}}
+	emailAttributeKey = []string{"value", "content", "action", "data-email", "data-mail", "data-mailto", "data-address", "data-recipient", "data-to"}
+)
+
+// deobfuscateEmails finds addresses hidden from plain regex scanning:
+// Cloudflare email protection, "[at]"/"[dot]" text, HTML entities and
+// escapes inside scripts, string concatenation in JavaScript, and addresses
+// kept in form fields or data attributes.
+func deobfuscateEmails(doc *goquery.Document) []string {
+	var found []string
+	add := func(candidates ...string) {
+		for _, candidate := range candidates {
+			email := cleanEmail(candidate)
+			if email != "" && !containsString(found, email) {
+				found = append(found, email)
+			}
+		}
+	}
+
+	// Cloudflare replaces addresses with a hex blob in data-cfemail or in an
+	// /cdn-cgi/l/email-protection#... link.
+	doc.Find("[data-cfemail]").Each(func(_ int, sel *goquery.Selection) {
+		encoded, _ := sel.Attr("data-cfemail")
+		add(decodeCloudflareEmail(encoded))
+	})
+	doc.Find("a[href*='email-protection#']").Each(func(_ int, sel *goquery.Selection) {
+		href, _ := sel.Attr("href")
+		if m := cfEmailPathRegex.FindStringSubmatch(href); m != nil {
+			add(decodeCloudflareEmail(m[1]))
+		}
+	})
+
+	// Form fields and data attributes: hidden recipients, mailto actions and
+	// data-user/data-domain pairs assembled by scripts.
+	doc.Find("*").Each(func(_ int, sel *goquery.Selection) {
+		for _, key := range emailAttributeKey {
+			if value, ok := sel.Attr(key); ok && value != "" {
+				add(emailRegex.FindAllString(decodeEscapes(value), -1)...)
+			}
+		}
+		user, hasUser := sel.Attr("data-user")
+		domain, hasDomain := sel.Attr("data-domain")
+		if hasUser && hasDomain {
+			add(strings.TrimSpace(user) + "@" + strings.TrimSpace(domain))
+		}
+	})
+
+	// Scripts: unescape, join concatenated literals and decode fromCharCode.
+	doc.Find("script").Each(func(_ int, sel *goquery.Selection) {
+		add(emailRegex.FindAllString(decodeScriptText(sel.Text()), -1)...)
+	})
+
+	// Visible text using [at]/[dot] style replacements.
+	body := doc.Find("body").Clone()
+	body.Find("script, style").Remove()
+	add(findObfuscatedTextEmails(decodeEscapes(body.Text()))...)
+
+	return found
+}
+
+// decodeCloudflareEmail reverses Cloudflare's scheme: the first byte is an XOR
+// key applied to every following byte.
+func decodeCloudflareEmail(encoded string) string {
+	data, err := hex.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(data) < 2 {
+		return ""
+	}
+	key := data[0]
+	out := make([]byte, len(data)-1)
+	for i, b := range data[1:] {
+		out[i] = b ^ key
+	}
+	return string(out)
+}
+
+// findObfuscatedTextEmails rewrites "[at]"/"[dot]" forms into addresses.
+func findObfuscatedTextEmails(text string) []string {
+	var emails []string
+	for _, m := range obfuscatedEmailRegex.FindAllStringSubmatch(text, -1) {
+		domain := obfuscatedDotRegex.ReplaceAllString(m[3], ".")
+		if strings.TrimSpace(m[2]) == "AT" && !bareAtAddress(m[1], domain) {
+			continue
+		}
+		emails = append(emails, m[1]+"@"+domain)
+	}
+	return emails
+}
+
+// bareAtAddress reports whether the words around a bare AT look like a local
+// part and a domain rather than prose: the local part has a letter, the top
+// level label is alphabetic, and the address is not all upper case, as in
+// "MEET US AT BOOTH 5 OR VISIT EXAMPLE DOT COM".
+func bareAtAddress(local, domain string) bool {
+	if strings.IndexFunc(local, unicode.IsLetter) < 0 {
+		return false
+	}
+	tld := domain[strings.LastIndex(domain, ".")+1:]
+	if len(tld) < 2 || strings.IndexFunc(tld, func(r rune) bool { return !unicode.IsLetter(r) }) >= 0 {
+		return false
+	}
+	return strings.IndexFunc(local+domain, unicode.IsLower) >= 0
+}
+
+// decodeScriptText turns common JavaScript obfuscation back into plain text.
+func decodeScriptText(script string) string {
+	script = jsCharCodeRegex.ReplaceAllStringFunc(script, func(match string) string {
+		codes := jsCharCodeRegex.FindStringSubmatch(match)[1]
+		var b strings.Builder
+		b.WriteByte('"')
+		for _, part := range strings.Split(codes, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || n <= 0 || n > 0x10FFFF {
+				return match
+			}
+			b.WriteRune(rune(n))
+		}
+		b.WriteByte('"')
+		return b.String()
+	})
+	script = decodeEscapes(script)
+	return jsConcatRegex.ReplaceAllString(script, "")
+}
+
+// decodeEscapes resolves HTML entities, JavaScript \uXXXX/\xXX escapes and
+// percent-encoded @ signs.
+func decodeEscapes(text string) string {
+	text = jsUnicodeRegex.ReplaceAllStringFunc(text, func(match string) string {
+		n, err := strconv.ParseUint(match[2:], 16, 32)
+		if err != nil {
+			return match
+		}
+		return string(rune(n))
+	})
+	text = html.UnescapeString(text)
+	return strings.ReplaceAll(strings.ReplaceAll(text, "%40", "@"), "%2E", ".")
+}
+
+// cleanMailto extracts the address part of a mailto: link.
+func cleanMailto(href string) string {
+	address := strings.TrimSpace(href)
+	if len(address) >= 7 && strings.EqualFold(address[:7], "mailto:") {
+		address = address[7:]
+	}
+	if idx := strings.IndexAny(address, "?#"); idx >= 0 {
+		address = address[:idx]
+	}
+	if decoded, err := url.PathUnescape(address); err == nil {
+		address = decoded
+	}
+	if idx := strings.Index(address, ","); idx >= 0 {
+		address = address[:idx]
+	}
+	return cleanEmail(address)
+}
+
+// cleanEmail trims and lowercases an address, rejecting strings that only look
+// like one, such as retina image names ("logo@2x.png").
+func cleanEmail(candidate string) string {
+	email := strings.ToLower(strings.Trim(strings.TrimSpace(candidate), ".,;:<>\"'()[]"))
+	if !emailRegex.MatchString(email) || emailRegex.FindString(email) != email {
+		return ""
+	}
+	tld := email[strings.LastIndex(email, ".")+1:]
+	if _, ok := nonEmailFileTLDs[tld]; ok {
+		return ""
+	}
+	return email
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestSummarizeDocumentDecodesObfuscatedEmails(t *testing.T) {
+	cases := map[string][]string{
+		"cloudflare.html": {"sales@acme-valves.com", "export@acme-valves.com"},
+		"at_dot.html": {
+			"info@acme-valves.com",
+			"j.smith@acme-valves.co.uk",
+			"purchasing@acme-valves.de",
+			"logistics@acme-valves.com",
+		},
+		"entities.html": {"ceo@acme-valves.com", "hr@acme-valves.com", "quality@acme-valves.com"},
+		"javascript.html": {
+			"m.keller@acme-valves.com",
+			"support@acme-valves.com",
+			"tech@acme-valves.com",
+			"service@acme-valves.com",
+		},
+		"form_metadata.html": {
+			"orders@acme-valves.com",
+			"rfq@acme-valves.com",
+			"anna.berg@acme-valves.se",
+			"p.novak@acme-valves.cz",
+			"hello@acme-valves.com",
+		},
+		"prose.html": {},
+	}
+	for name, want := range cases {
+		t.Run(name, func(t *testing.T) {
+			raw, err := os.ReadFile(filepath.Join("testdata", "obfuscation", name))
+			if err != nil {
+				t.Fatalf("read fixture: %v", err)
+			}
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(string(raw)))
+			if err != nil {
+				t.Fatalf("parse fixture: %v", err)
+			}
+			got := summarizeDocument(doc, "https://acme-valves.com", 4000).Emails
+			sort.Strings(got)
+			sort.Strings(want)
+			if strings.Join(got, ",") != strings.Join(want, ",") {
+				t.Errorf("emails = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCleanMailto(t *testing.T) {
+	cases := map[string]string{
+		"mailto:Sales@Acme.com":                "sales@acme.com",
+		"MAILTO:info@acme.com?subject=Hi":      "info@acme.com",
+		"mailto:a%40acme.com":                  "a@acme.com",
+		"mailto:one@acme.com,two@acme.com":     "one@acme.com",
+		"mailto:":                              "",
+		"mailto:logo@2x.png":                   "",
+		"/cdn-cgi/l/email-protection#a1b2c3d4": "",
+	}
+	for href, want := range cases {
+		if got := cleanMailto(href); got != want {
+			t.Errorf("cleanMailto(%q) = %q, want %q", href, got, want)
+		}
+	}
+}
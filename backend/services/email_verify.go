@@ -0,0 +1,426 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/idna"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+// Email verification limits.
+const (
+	emailVerifyDNSTimeout    = 8 * time.Second
+	emailVerifySMTPTimeout   = 20 * time.Second
+	emailVerifyBatchTimeout  = 45 * time.Second
+	emailVerifyInlineTimeout = 10 * time.Second
+	emailVerifyMaxMXHosts    = 2
+	emailVerifyConcurrency   = 4
+)
+
+// disposableEmailDomains are throwaway mailbox providers. Subdomains match too.
+var disposableEmailDomains = map[string]struct{}{
+	"10minutemail.com": {}, "20minutemail.com": {}, "33mail.com": {}, "burnermail.io": {},
+	"discard.email": {}, "dispostable.com": {}, "emailondeck.com": {}, "fakeinbox.com": {},
+	"getairmail.com": {}, "getnada.com": {}, "guerrillamail.com": {}, "guerrillamail.net": {},
+	"guerrillamailblock.com": {}, "mailcatch.com": {}, "maildrop.cc": {}, "mailinator.com": {},
+	"mailnesia.com": {}, "mintemail.com": {}, "moakt.com": {}, "mohmal.com": {},
+	"mytemp.email": {}, "sharklasers.com": {}, "spamgourmet.com": {}, "temp-mail.org": {},
+	"tempail.com": {}, "tempmail.dev": {}, "tempmailo.com": {}, "tempr.email": {},
+	"throwawaymail.com": {}, "trashmail.com": {}, "trashmail.de": {}, "yopmail.com": {},
+	"yopmail.fr": {},
+}
+
+// roleEmailLocalParts are shared mailboxes that rarely reach a decision maker.
+var roleEmailLocalParts = map[string]struct{}{
+	"info": {}, "sales": {}, "contact": {}, "contacts": {}, "admin": {}, "office": {},
+	"support": {}, "service": {}, "hello": {}, "enquiry": {}, "enquiries": {}, "inquiry": {},
+	"inquiries": {}, "marketing": {}, "export": {}, "import": {}, "purchasing": {},
+	"procurement": {}, "purchase": {}, "hr": {}, "jobs": {}, "careers": {}, "webmaster": {},
+	"postmaster": {}, "hostmaster": {}, "abuse": {}, "noreply": {}, "no-reply": {}, "mail": {},
+	"team": {}, "help": {}, "billing": {}, "accounts": {}, "order": {}, "orders": {},
+	"press": {}, "media": {}, "kontakt": {}, "vertrieb": {}, "einkauf": {}, "ventas": {},
+	"compras": {}, "commercial": {}, "general": {},
+}
+
+// EmailVerifierImpl checks whether contact addresses can receive mail: syntax,
+// disposable domains and role mailboxes, MX records through the configured
+// resolver and, when enabled, an SMTP RCPT probe that never sends a message.
+type EmailVerifierImpl struct {
+	store   *store.Store
+	proxies *ProxyRouter
+	// smtpPort is the port probed on the mail servers; tests point it at a fake server.
+	smtpPort string
+}
+
+// NewEmailVerifier constructs the email verification service.
+func NewEmailVerifier(st *store.Store) *EmailVerifierImpl {
+	return &EmailVerifierImpl{store: st, smtpPort: "25"}
+}
+
+// Verify checks one address and stores the result.
+func (v *EmailVerifierImpl) Verify(ctx context.Context, email string) (*domain.EmailVerification, error) {
+	settings, err := v.store.GetSettings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置失败: %w", err)
+	}
+	result := v.verify(ctx, settings, email)
+	if err := v.store.SaveEmailVerification(ctx, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// VerifyCustomerContacts checks every contact address of a customer within
+// emailVerifyBatchTimeout and returns the contacts with their new status.
+func (v *EmailVerifierImpl) VerifyCustomerContacts(ctx context.Context, customerID int64) ([]domain.Contact, error) {
+	contacts, err := v.store.ListContacts(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := v.store.GetSettings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置失败: %w", err)
+	}
+	v.verifyContacts(ctx, settings, contacts, emailVerifyBatchTimeout)
+	return contacts, nil
+}
+
+// annotateContacts runs the cheap checks (syntax, disposable domains, MX
+// records) on freshly resolved contacts, so resolving a company does not wait
+// for SMTP probes; those run from VerifyCustomerContacts. Failures only leave
+// contacts unverified.
+func (v *EmailVerifierImpl) annotateContacts(ctx context.Context, contacts []domain.Contact) {
+	if len(contacts) == 0 {
+		return
+	}
+	settings, err := v.store.GetSettings(ctx)
+	if err != nil {
+		log.Printf("[verify] 读取配置失败: %v", err)
+		return
+	}
+	quick := *settings
+	quick.EmailVerifySMTPProbe = false
+	v.verifyContacts(ctx, &quick, contacts, emailVerifyInlineTimeout)
+}
+
+// verifyContacts checks the distinct addresses of contacts, a few at a time,
+// within timeout, stores the results and copies them onto the contacts.
+// Addresses not reached before the deadline stay unverified.
+func (v *EmailVerifierImpl) verifyContacts(ctx context.Context, settings *store.Settings, contacts []domain.Contact, timeout time.Duration) {
+	results := make(map[string]*domain.EmailVerification)
+	var emails []string
+	for i := range contacts {
+		email := strings.ToLower(strings.TrimSpace(contacts[i].Email))
+		if _, seen := results[email]; email == "" || seen {
+			continue
+		}
+		results[email] = nil
+		emails = append(emails, email)
+	}
+
+	batchCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	verified := make([]*domain.EmailVerification, len(emails))
+	sem := make(chan struct{}, emailVerifyConcurrency)
+	var wg sync.WaitGroup
+	for i, email := range emails {
+		wg.Add(1)
+		go func(i int, email string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if batchCtx.Err() == nil {
+				verified[i] = v.verify(batchCtx, settings, email)
+			}
+		}(i, email)
+	}
+	wg.Wait()
+
+	for i, email := range emails {
+		if verified[i] == nil {
+			continue
+		}
+		results[email] = verified[i]
+		if err := v.store.SaveEmailVerification(ctx, verified[i]); err != nil {
+			log.Printf("[verify] 保存验证结果失败 email=%s: %v", email, err)
+		}
+	}
+	for i := range contacts {
+		if result := results[strings.ToLower(strings.TrimSpace(contacts[i].Email))]; result != nil {
+			applyEmailVerification(&contacts[i], result)
+		}
+	}
+}
+
+// applyEmailVerification copies a verification result onto a contact.
+func applyEmailVerification(contact *domain.Contact, result *domain.EmailVerification) {
+	contact.EmailStatus = result.Status
+	contact.EmailStatusReason = result.Reason
+	contact.EmailRole = result.Role
+	contact.EmailCheckedAt = result.CheckedAt
+}
+
+// verify runs the checks in order of cost and stops at the first conclusive one.
+func (v *EmailVerifierImpl) verify(ctx context.Context, settings *store.Settings, email string) *domain.EmailVerification {
+	result := &domain.EmailVerification{Email: strings.ToLower(strings.TrimSpace(email)), CheckedAt: store.Now()}
+	local, host, err := splitEmailAddress(result.Email)
+	if err != nil {
+		result.Status, result.Reason = domain.EmailStatusInvalid, err.Error()
+		return result
+	}
+	result.Role = isRoleEmail(local)
+	if isDisposableDomain(host) {
+		result.Disposable = true
+		result.Status, result.Reason = domain.EmailStatusInvalid, "一次性临时邮箱"
+		return result
+	}
+
+	resolver := v.resolver(settings)
+	hosts, status, reason := lookupMailHosts(ctx, resolver, host)
+	if status != "" {
+		result.Status, result.Reason = status, reason
+		return result
+	}
+	result.MXHost = hosts[0]
+	result.Status, result.Reason = domain.EmailStatusDomainOK, "域名可以接收邮件，未确认邮箱是否存在"
+	if settings.EmailVerifySMTPProbe {
+		v.probe(ctx, settings, resolver, hosts, result)
+	}
+	return result
+}
+
+// resolver returns the configured DNS server, or the system resolver.
+func (v *EmailVerifierImpl) resolver(settings *store.Settings) *net.Resolver {
+	server := strings.TrimSpace(settings.EmailVerifyDNSServer)
+	if server == "" {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			dialer := net.Dialer{Timeout: emailVerifyDNSTimeout}
+			return dialer.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// splitEmailAddress validates a bare address and returns its local part and
+// its domain in ASCII form.
+func splitEmailAddress(email string) (string, string, error) {
+	if email == "" {
+		return "", "", fmt.Errorf("邮箱地址为空")
+	}
+	parsed, err := mail.ParseAddress(email)
+	if err != nil || parsed.Address != email || parsed.Name != "" {
+		return "", "", fmt.Errorf("邮箱格式不正确")
+	}
+	at := strings.LastIndex(email, "@")
+	local, host := email[:at], email[at+1:]
+	if len(local) > 64 || len(email) > 254 {
+		return "", "", fmt.Errorf("邮箱地址过长")
+	}
+	asciiHost, err := idna.Lookup.ToASCII(host)
+	if err != nil || !strings.Contains(asciiHost, ".") || net.ParseIP(strings.Trim(asciiHost, "[]")) != nil {
+		return "", "", fmt.Errorf("邮箱域名不正确")
+	}
+	return local, asciiHost, nil
+}
+
+func isDisposableDomain(host string) bool {
+	for host != "" {
+		if _, ok := disposableEmailDomains[host]; ok {
+			return true
+		}
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			break
+		}
+		host = parent
+	}
+	return false
+}
+
+func isRoleEmail(local string) bool {
+	local = strings.ToLower(local)
+	if base, _, found := strings.Cut(local, "+"); found {
+		local = base
+	}
+	_, ok := roleEmailLocalParts[local]
+	return ok
+}
+
+// lookupMailHosts returns the mail servers of a domain, best first. A domain
+// without MX records falls back to its address records (RFC 5321 5.1). A
+// non-empty status means the lookup settled the verification.
+func lookupMailHosts(ctx context.Context, resolver *net.Resolver, host string) ([]string, string, string) {
+	lookupCtx, cancel := context.WithTimeout(ctx, emailVerifyDNSTimeout)
+	defer cancel()
+	records, err := resolver.LookupMX(lookupCtx, host)
+	if err != nil && !isDNSNotFound(err) {
+		return nil, domain.EmailStatusUnknown, fmt.Sprintf("查询 MX 记录失败: %v", err)
+	}
+	var hosts []string
+	for _, record := range records {
+		name := strings.TrimSuffix(record.Host, ".")
+		if name == "" {
+			// Null MX (RFC 7505): the domain accepts no mail.
+			return nil, domain.EmailStatusInvalid, "域名声明不接收邮件"
+		}
+		hosts = append(hosts, name)
+	}
+	if len(hosts) > 0 {
+		return hosts, "", ""
+	}
+	addrs, err := resolver.LookupIPAddr(lookupCtx, host)
+	if err != nil && !isDNSNotFound(err) {
+		return nil, domain.EmailStatusUnknown, fmt.Sprintf("查询域名地址失败: %v", err)
+	}
+	if len(addrs) == 0 {
+		return nil, domain.EmailStatusInvalid, "域名不存在或没有邮件服务器"
+	}
+	return []string{host}, "", ""
+}
+
+func isDNSNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// probe asks the mail servers whether they accept the mailbox, without
+// sending anything, and checks for catch-all servers with a random address.
+// Connection failures keep the DNS result, as many networks block port 25.
+func (v *EmailVerifierImpl) probe(ctx context.Context, settings *store.Settings, resolver *net.Resolver, hosts []string, result *domain.EmailVerification) {
+	var cfg *proxyConfig
+	if v.proxies != nil {
+		var err error
+		if cfg, err = resolveProxy(settings, store.ProxyChannelSMTP); err != nil {
+			result.Reason += fmt.Sprintf("；代理配置无效，跳过 SMTP 验证: %v", err)
+			return
+		}
+	}
+	allow := splitHostList(settings.FetchAllowedHosts)
+	var lastErr error
+	for i, host := range hosts {
+		if i >= emailVerifyMaxMXHosts || ctx.Err() != nil {
+			break
+		}
+		conn, err := v.dialMailHost(ctx, cfg, resolver, allow, host)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		catchAll, rcptErr, err := smtpProbe(ctx, conn, host, settings, result.Email)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		result.SMTPChecked = true
+		result.MXHost = host
+		var protoErr *textproto.Error
+		switch {
+		case rcptErr == nil && catchAll:
+			result.Status, result.Reason = domain.EmailStatusRisky, "邮件服务器接收任意地址（catch-all），无法确认邮箱是否存在"
+		case rcptErr == nil:
+			result.Status, result.Reason = domain.EmailStatusValid, "邮件服务器确认邮箱存在"
+		case errors.As(rcptErr, &protoErr) && protoErr.Code >= 500:
+			result.Status, result.Reason = domain.EmailStatusInvalid, fmt.Sprintf("邮件服务器拒绝该邮箱: %d %s", protoErr.Code, protoErr.Msg)
+		default:
+			result.Reason += fmt.Sprintf("；邮件服务器暂未确认邮箱: %v", rcptErr)
+		}
+		return
+	}
+	if lastErr != nil {
+		result.Reason += fmt.Sprintf("；SMTP 验证未完成: %v", lastErr)
+	}
+}
+
+// dialMailHost connects to the first address of host that is not internal,
+// unless it is on the admin allowlist.
+func (v *EmailVerifierImpl) dialMailHost(ctx context.Context, cfg *proxyConfig, resolver *net.Resolver, allow []string, host string) (net.Conn, error) {
+	lookupCtx, cancel := context.WithTimeout(ctx, emailVerifyDNSTimeout)
+	addrs, err := resolver.LookupIPAddr(lookupCtx, host)
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("解析邮件服务器 %s 失败: %w", host, err)
+	}
+	var lastErr error = fmt.Errorf("邮件服务器 %s 没有可用地址", host)
+	for _, addr := range addrs {
+		if blockedIP(addr.IP) && !matchHostList(allow, host) && !matchHostList(allow, addr.IP.String()) {
+			lastErr = fmt.Errorf("%w: %s (%s)", ErrBlockedAddress, host, addr.IP)
+			continue
+		}
+		conn, err := cfg.dialContext(ctx, net.JoinHostPort(addr.IP.String(), v.smtpPort))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// smtpProbe runs HELO, MAIL FROM and RCPT TO for the address and, if it is
+// accepted, for a random address on the same domain, then quits without
+// sending data. rcptErr is the server's answer to the address; err reports a
+// session that failed before it.
+func smtpProbe(ctx context.Context, conn net.Conn, host string, settings *store.Settings, email string) (catchAll bool, rcptErr, err error) {
+	deadline := time.Now().Add(emailVerifySMTPTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return false, nil, err
+	}
+	defer client.Close()
+
+	from := probeSender(settings)
+	helo := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		helo = from[at+1:]
+	}
+	if err := client.Hello(helo); err != nil {
+		return false, nil, err
+	}
+	if err := client.Mail(from); err != nil {
+		return false, nil, err
+	}
+	if rcptErr = client.Rcpt(email); rcptErr != nil {
+		_ = client.Quit()
+		return false, rcptErr, nil
+	}
+	random := make([]byte, 8)
+	_, _ = rand.Read(random)
+	probeAddr := "verify-" + hex.EncodeToString(random) + email[strings.LastIndex(email, "@"):]
+	catchAll = client.Rcpt(probeAddr) == nil
+	_ = client.Reset()
+	_ = client.Quit()
+	return catchAll, nil, nil
+}
+
+// probeSender picks the envelope sender for RCPT probes: the configured SMTP
+// account, then the admin address.
+func probeSender(settings *store.Settings) string {
+	for _, candidate := range []string{settings.SMTPUsername, settings.AdminEmail} {
+		candidate = strings.TrimSpace(candidate)
+		if strings.Contains(candidate, "@") {
+			return candidate
+		}
+	}
+	return "postmaster@localhost"
+}
@@ -0,0 +1,362 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+// dnsStub answers MX and A queries over UDP from fixed tables. Unknown names
+// get NXDOMAIN.
+type dnsStub struct {
+	conn net.PacketConn
+	mx   map[string][]string // domain -> mail hosts, "." for a null MX
+	a    map[string]string   // host -> IPv4 address
+}
+
+func startDNSStub(t *testing.T, mx map[string][]string, a map[string]string) *dnsStub {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen dns: %v", err)
+	}
+	stub := &dnsStub{conn: conn, mx: mx, a: a}
+	t.Cleanup(func() { conn.Close() })
+	go stub.serve()
+	return stub
+}
+
+func (s *dnsStub) addr() string { return s.conn.LocalAddr().String() }
+
+func (s *dnsStub) serve() {
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		var parser dnsmessage.Parser
+		header, err := parser.Start(buf[:n])
+		if err != nil {
+			continue
+		}
+		question, err := parser.Question()
+		if err != nil {
+			continue
+		}
+		if reply, err := s.answer(header, question); err == nil {
+			s.conn.WriteTo(reply, peer)
+		}
+	}
+}
+
+func (s *dnsStub) answer(header dnsmessage.Header, q dnsmessage.Question) ([]byte, error) {
+	name := strings.TrimSuffix(strings.ToLower(q.Name.String()), ".")
+	mxHosts, hasMX := s.mx[name]
+	ip, hasA := s.a[name]
+	rcode := dnsmessage.RCodeSuccess
+	if !hasMX && !hasA {
+		rcode = dnsmessage.RCodeNameError
+	}
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: header.ID, Response: true, Authoritative: true, RCode: rcode})
+	builder.EnableCompression()
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := builder.Question(q); err != nil {
+		return nil, err
+	}
+	if err := builder.StartAnswers(); err != nil {
+		return nil, err
+	}
+	rr := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 60}
+	switch {
+	case q.Type == dnsmessage.TypeMX && hasMX:
+		for i, host := range mxHosts {
+			if err := builder.MXResource(rr, dnsmessage.MXResource{Pref: uint16(10 * (i + 1)), MX: dnsmessage.MustNewName(strings.TrimSuffix(host, ".") + ".")}); err != nil {
+				return nil, err
+			}
+		}
+	case q.Type == dnsmessage.TypeA && hasA:
+		var addr [4]byte
+		copy(addr[:], net.ParseIP(ip).To4())
+		if err := builder.AResource(rr, dnsmessage.AResource{A: addr}); err != nil {
+			return nil, err
+		}
+	}
+	return builder.Finish()
+}
+
+// fakeSMTP accepts RCPT for the listed addresses and for any address on the
+// catch-all domains, and fails the test if a message is ever sent.
+type fakeSMTP struct {
+	t         *testing.T
+	listener  net.Listener
+	mailboxes map[string]bool
+	catchAll  map[string]bool
+
+	mu    sync.Mutex
+	rcpts []string
+}
+
+func startFakeSMTP(t *testing.T, mailboxes []string, catchAll ...string) *fakeSMTP {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen smtp: %v", err)
+	}
+	server := &fakeSMTP{t: t, listener: listener, mailboxes: map[string]bool{}, catchAll: map[string]bool{}}
+	for _, box := range mailboxes {
+		server.mailboxes[box] = true
+	}
+	for _, domainName := range catchAll {
+		server.catchAll[domainName] = true
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.handle(conn)
+		}
+	}()
+	return server
+}
+
+func (s *fakeSMTP) port() string {
+	return fmt.Sprint(s.listener.Addr().(*net.TCPAddr).Port)
+}
+
+func (s *fakeSMTP) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	fmt.Fprint(conn, "220 mx.fake ESMTP\r\n")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimSpace(line)
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO":
+			fmt.Fprint(conn, "250 mx.fake\r\n")
+		case "MAIL", "RSET", "NOOP":
+			fmt.Fprint(conn, "250 OK\r\n")
+		case "RCPT":
+			addr := strings.ToLower(strings.Trim(strings.TrimPrefix(line[len("RCPT TO:"):], " "), "<>"))
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, addr)
+			s.mu.Unlock()
+			if s.mailboxes[addr] || s.catchAll[addr[strings.LastIndex(addr, "@")+1:]] {
+				fmt.Fprint(conn, "250 Accepted\r\n")
+			} else {
+				fmt.Fprint(conn, "550 5.1.1 No such user\r\n")
+			}
+		case "QUIT":
+			fmt.Fprint(conn, "221 Bye\r\n")
+			return
+		default:
+			s.t.Errorf("unexpected SMTP command %q", line)
+			fmt.Fprint(conn, "502 Not implemented\r\n")
+		}
+	}
+}
+
+func newVerifierStore(t *testing.T, settingsJSON string) *store.Store {
+	t.Helper()
+	st, err := store.Open(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	if err := st.InitSchema(context.Background()); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+	if err := st.SaveSettings(context.Background(), strings.NewReader(settingsJSON)); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+	return st
+}
+
+func TestEmailVerifierChecks(t *testing.T) {
+	dns := startDNSStub(t,
+		map[string][]string{
+			"acme.test":     {"mx.acme.test"},
+			"catchall.test": {"mx.catchall.test"},
+			"nomail.test":   {"."},
+		},
+		map[string]string{
+			"mx.acme.test":     "127.0.0.1",
+			"mx.catchall.test": "127.0.0.1",
+			"hostonly.test":    "127.0.0.1",
+		},
+	)
+	smtpServer := startFakeSMTP(t, []string{"john@acme.test", "info@acme.test", "bob@hostonly.test"}, "catchall.test")
+	st := newVerifierStore(t, fmt.Sprintf(`{"email_verify_dns_server": %q, "email_verify_smtp_probe": true, "fetch_allowed_hosts": "127.0.0.1", "smtp_username": "me@seller.test"}`, dns.addr()))
+	verifier := NewEmailVerifier(st)
+	verifier.smtpPort = smtpServer.port()
+
+	cases := []struct {
+		email  string
+		status string
+		role   bool
+		reason string
+	}{
+		{"John@Acme.test", domain.EmailStatusValid, false, "确认邮箱存在"},
+		{"info@acme.test", domain.EmailStatusValid, true, ""},
+		{"ghost@acme.test", domain.EmailStatusInvalid, false, "550"},
+		{"anyone@catchall.test", domain.EmailStatusRisky, false, "catch-all"},
+		{"bob@hostonly.test", domain.EmailStatusValid, false, ""},
+		{"sales@nomail.test", domain.EmailStatusInvalid, true, "不接收邮件"},
+		{"x@missing.test", domain.EmailStatusInvalid, false, "不存在"},
+		{"john@@acme.test", domain.EmailStatusInvalid, false, "格式"},
+		{"John Smith <john@acme.test>", domain.EmailStatusInvalid, false, "格式"},
+		{"buyer@mailinator.com", domain.EmailStatusInvalid, false, "一次性"},
+		{"buyer@eu.yopmail.com", domain.EmailStatusInvalid, false, "一次性"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.email, func(t *testing.T) {
+			result, err := verifier.Verify(context.Background(), tc.email)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if result.Status != tc.status || result.Role != tc.role || !strings.Contains(result.Reason, tc.reason) {
+				t.Fatalf("result = %+v, want status %s role %v reason containing %q", result, tc.status, tc.role, tc.reason)
+			}
+			if result.CheckedAt == "" {
+				t.Errorf("missing timestamp")
+			}
+			stored, err := st.GetEmailVerification(context.Background(), tc.email)
+			if err != nil || stored == nil || stored.Status != tc.status {
+				t.Errorf("stored = %+v, %v", stored, err)
+			}
+		})
+	}
+
+	smtpServer.mu.Lock()
+	defer smtpServer.mu.Unlock()
+	for _, rcpt := range smtpServer.rcpts {
+		if strings.HasSuffix(rcpt, "mailinator.com") || strings.HasSuffix(rcpt, "missing.test") {
+			t.Errorf("probed %s although DNS or the domain list already decided", rcpt)
+		}
+	}
+}
+
+func TestEmailVerifierWithoutProbe(t *testing.T) {
+	dns := startDNSStub(t, map[string][]string{"acme.test": {"mx.acme.test"}}, map[string]string{"mx.acme.test": "127.0.0.1"})
+	smtpServer := startFakeSMTP(t, nil)
+
+	// Probing disabled: a resolvable domain is all that can be said.
+	st := newVerifierStore(t, fmt.Sprintf(`{"email_verify_dns_server": %q}`, dns.addr()))
+	verifier := NewEmailVerifier(st)
+	verifier.smtpPort = smtpServer.port()
+	result, err := verifier.Verify(context.Background(), "ghost@acme.test")
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if result.Status != domain.EmailStatusDomainOK || result.SMTPChecked || result.MXHost != "mx.acme.test" {
+		t.Fatalf("result = %+v", result)
+	}
+
+	// Probing enabled, but the mail server resolves to a loopback address that
+	// is not allowlisted.
+	st = newVerifierStore(t, fmt.Sprintf(`{"email_verify_dns_server": %q, "email_verify_smtp_probe": true}`, dns.addr()))
+	verifier = NewEmailVerifier(st)
+	verifier.smtpPort = smtpServer.port()
+	result, err = verifier.Verify(context.Background(), "ghost@acme.test")
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if result.Status != domain.EmailStatusDomainOK || result.SMTPChecked || !strings.Contains(result.Reason, ErrBlockedAddress.Error()) {
+		t.Fatalf("result = %+v", result)
+	}
+	if len(smtpServer.rcpts) != 0 {
+		t.Fatalf("internal mail server was probed: %v", smtpServer.rcpts)
+	}
+}
+
+// Resolving a company only runs the DNS checks; probes wait for the
+// on-demand verification.
+func TestAnnotateContactsSkipsSMTPProbe(t *testing.T) {
+	dns := startDNSStub(t, map[string][]string{"acme.test": {"mx.acme.test"}}, map[string]string{"mx.acme.test": "127.0.0.1"})
+	smtpServer := startFakeSMTP(t, []string{"john@acme.test"})
+	st := newVerifierStore(t, fmt.Sprintf(`{"email_verify_dns_server": %q, "email_verify_smtp_probe": true, "fetch_allowed_hosts": "127.0.0.1"}`, dns.addr()))
+	verifier := NewEmailVerifier(st)
+	verifier.smtpPort = smtpServer.port()
+
+	contacts := []domain.Contact{{Name: "John", Email: "john@acme.test"}, {Name: "Temp", Email: "x@mailinator.com"}}
+	verifier.annotateContacts(context.Background(), contacts)
+	if contacts[0].EmailStatus != domain.EmailStatusDomainOK || contacts[1].EmailStatus != domain.EmailStatusInvalid {
+		t.Fatalf("contacts = %+v", contacts)
+	}
+	if len(smtpServer.rcpts) != 0 {
+		t.Fatalf("mail server probed while resolving: %v", smtpServer.rcpts)
+	}
+}
+
+func TestVerifyCustomerContacts(t *testing.T) {
+	dns := startDNSStub(t, map[string][]string{"acme.test": {"mx.acme.test"}}, map[string]string{"mx.acme.test": "127.0.0.1"})
+	st := newVerifierStore(t, fmt.Sprintf(`{"email_verify_dns_server": %q}`, dns.addr()))
+	ctx := context.Background()
+	customerID, err := st.CreateCustomer(ctx, &domain.CreateCompanyRequest{
+		Name:    "Acme",
+		Website: "https://acme.test",
+		Contacts: []domain.Contact{
+			{Name: "John", Email: "john@acme.test", IsKey: true},
+			{Name: "Sales", Email: "sales@acme.test"},
+			{Name: "Ghost", Email: "ghost@unknown-domain.test"},
+			{Name: "No email"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("create customer: %v", err)
+	}
+
+	contacts, err := NewEmailVerifier(st).VerifyCustomerContacts(ctx, customerID)
+	if err != nil {
+		t.Fatalf("VerifyCustomerContacts: %v", err)
+	}
+	want := map[string]string{
+		"john@acme.test":            domain.EmailStatusDomainOK,
+		"sales@acme.test":           domain.EmailStatusDomainOK,
+		"ghost@unknown-domain.test": domain.EmailStatusInvalid,
+		"":                          "",
+	}
+	for _, contact := range contacts {
+		if contact.EmailStatus != want[contact.Email] {
+			t.Errorf("%q status = %q, want %q", contact.Email, contact.EmailStatus, want[contact.Email])
+		}
+	}
+
+	// The status survives edits that rewrite the contact rows.
+	if err := st.UpdateCustomer(ctx, customerID, &domain.CreateCompanyRequest{
+		Name:     "Acme",
+		Website:  "https://acme.test",
+		Contacts: []domain.Contact{{Name: "John Doe", Email: "John@acme.test", IsKey: true}},
+	}); err != nil {
+		t.Fatalf("update customer: %v", err)
+	}
+	detail, err := st.GetCustomerDetail(ctx, customerID)
+	if err != nil {
+		t.Fatalf("get detail: %v", err)
+	}
+	if len(detail.Contacts) != 1 || detail.Contacts[0].EmailStatus != domain.EmailStatusDomainOK || detail.Contacts[0].EmailCheckedAt == "" {
+		t.Fatalf("detail contacts = %+v", detail.Contacts)
+	}
+	if listed, _ := st.ListContacts(ctx, customerID); listed[0].EmailRole {
+		t.Errorf("personal address flagged as role mailbox")
+	}
+}
@@ -2,7 +2,6 @@ package services
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"math"
@@ -16,42 +15,22 @@ import (
 	"github.com/mozillazg/go-pinyin"
 
 	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
 )
 
 // EnrichmentServiceImpl implements Step 1 resolution.
 type EnrichmentServiceImpl struct {
-	llm     *LLMClient
-	search  *SearchClient
-	fetcher *WebFetcher
-}
-
-var aggregatorDomainSuffixes = []string{
-	"linkedin.com",
-	"linkedin.cn",
-	"facebook.com",
-	"twitter.com",
-	"instagram.com",
-	"youtube.com",
-	"crunchbase.com",
-	"bloomberg.com",
-	"reuters.com",
-	"glassdoor.com",
-	"indeed.com",
-	"fang.com",
-	"58.com",
-	"ganji.com",
-	"zhihu.com",
-	"baidu.com",
-	"baike.com",
-	"wiki",
-	"wikipedia.org",
-	"google.com",
-	"bing.com",
+	store    *store.Store
+	llm      *LLMClient
+	search   *SearchClient
+	fetcher  *WebFetcher
+	prompts  *PromptServiceImpl
+	verifier *EmailVerifierImpl // checks contact addresses; nil leaves them unverified
 }
 
 // NewEnrichmentService creates a new enrichment service instance.
-func NewEnrichmentService(llm *LLMClient, search *SearchClient, fetcher *WebFetcher) *EnrichmentServiceImpl {
-	return &EnrichmentServiceImpl{llm: llm, search: search, fetcher: fetcher}
+func NewEnrichmentService(st *store.Store, llm *LLMClient, search *SearchClient, fetcher *WebFetcher, prompts *PromptServiceImpl) *EnrichmentServiceImpl {
+	return &EnrichmentServiceImpl{store: st, llm: llm, search: search, fetcher: fetcher, prompts: prompts}
 }
 
 // ResolveCompany aggregates search + website info and asks LLM for structured insights.
@@ -62,6 +41,7 @@ func (s *EnrichmentServiceImpl) ResolveCompany(ctx context.Context, req *domain.
 
 	query := strings.TrimSpace(req.Query)
 	looksURL := looksLikeURL(query)
+	rules := loadDomainRules(ctx, s.store)
 
 	llmReady := s.llm != nil
 	if llmReady {
@@ -86,7 +66,7 @@ func (s *EnrichmentServiceImpl) ResolveCompany(ctx context.Context, req *domain.
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		searchPlan, searchItems, primaryURL, pageSummary, searchErr = s.collectSearchArtifacts(ctx, query, looksURL)
+		searchPlan, searchItems, primaryURL, pageSummary, searchErr = s.collectSearchArtifacts(ctx, query, looksURL, rules)
 	}()
 
 	if llmReady {
@@ -129,73 +109,73 @@ func (s *EnrichmentServiceImpl) ResolveCompany(ctx context.Context, req *domain.
 	websiteCandidate := ""
 
 	if llmReady {
-		prompt := buildEnrichmentPrompt(query, searchPlan, searchItems, pageSummary, knowledge)
-		content, _, err := s.llm.Chat(ctx, []ChatMessage{
-			{Role: "system", Content: enrichmentSystemPrompt},
-			{Role: "user", Content: prompt},
-		}, ChatOptions{MaxTokens: 900, Temperature: 0.2, ResponseFormat: "json_object"})
+		var parsed struct {
+			Website           string  `json:"website"`
+			WebsiteConfidence float64 `json:"website_confidence"`
+			Country           string  `json:"country"`
+			Contacts          []struct {
+				Name   string `json:"name"`
+				Title  string `json:"title"`
+				Email  string `json:"email"`
+				Phone  string `json:"phone"`
+				Source string `json:"source"`
+				IsKey  bool   `json:"is_key_decision_maker"`
+			} `json:"contacts"`
+			Summary    string `json:"summary"`
+			Candidates []struct {
+				URL    string `json:"url"`
+				Title  string `json:"title"`
+				Rank   int    `json:"rank"`
+				Reason string `json:"reason"`
+			} `json:"candidates"`
+		}
+		messages, err := s.prompts.Messages(ctx, PromptEnrichment, PromptData{
+			Query:     query,
+			Materials: buildEnrichmentMaterials(query, searchPlan, searchItems, pageSummary, knowledge),
+		})
+		if err == nil {
+			err = s.llm.ChatJSON(ctx, messages, ChatOptions{MaxTokens: 900, Temperature: 0.2, ResponseFormat: "json_object", Task: LLMTaskEnrichment, Cacheable: true}, enrichmentSchema, &parsed)
+		}
 		if err != nil {
 			log.Printf("[enrichment] 解析 LLM 工作流失败，启用降级模式: %v", err)
 			llmReady = false
 		} else {
-			var parsed struct {
-				Website           string  `json:"website"`
-				WebsiteConfidence float64 `json:"website_confidence"`
-				Country           string  `json:"country"`
-				Contacts          []struct {
-					Name   string `json:"name"`
-					Title  string `json:"title"`
-					Email  string `json:"email"`
-					Phone  string `json:"phone"`
-					Source string `json:"source"`
-					IsKey  bool   `json:"is_key_decision_maker"`
-				} `json:"contacts"`
-				Summary    string `json:"summary"`
-				Candidates []struct {
-					URL    string `json:"url"`
-					Title  string `json:"title"`
-					Rank   int    `json:"rank"`
-					Reason string `json:"reason"`
-				} `json:"candidates"`
-			}
-			if err := json.Unmarshal([]byte(content), &parsed); err != nil {
-				log.Printf("[enrichment] 解析结构化结果失败，启用降级模式: %v", err)
-				llmReady = false
-			} else {
-				llmUsed = true
-				websiteCandidate = strings.TrimSpace(parsed.Website)
-				summary = strings.TrimSpace(parsed.Summary)
-				country = strings.TrimSpace(parsed.Country)
-				for _, c := range parsed.Contacts {
-					rawContacts = append(rawContacts, domain.Contact{
-						Name:               strings.TrimSpace(c.Name),
-						Title:              strings.TrimSpace(c.Title),
-						Email:              strings.TrimSpace(c.Email),
-						Phone:              strings.TrimSpace(c.Phone),
-						Source:             strings.TrimSpace(c.Source),
-						IsKey:              c.IsKey,
-						IsKeyDecisionMaker: c.IsKey,
-					})
-				}
-				parsedConfidence = parsed.WebsiteConfidence
-				llmCandidates = parsed.Candidates
+			llmUsed = true
+			websiteCandidate = strings.TrimSpace(parsed.Website)
+			summary = strings.TrimSpace(parsed.Summary)
+			country = strings.TrimSpace(parsed.Country)
+			for _, c := range parsed.Contacts {
+				rawContacts = append(rawContacts, domain.Contact{
+					Name:               strings.TrimSpace(c.Name),
+					Title:              strings.TrimSpace(c.Title),
+					Email:              strings.TrimSpace(c.Email),
+					Phone:              strings.TrimSpace(c.Phone),
+					Source:             strings.TrimSpace(c.Source),
+					IsKey:              c.IsKey,
+					IsKeyDecisionMaker: c.IsKey,
+				})
 			}
+			parsedConfidence = parsed.WebsiteConfidence
+			llmCandidates = parsed.Candidates
 		}
 	}
 
-	website := finalizeWebsite(websiteCandidate, primaryURL, query, looksURL)
+	website := finalizeWebsite(websiteCandidate, primaryURL, query, looksURL, rules)
 	if website == "" {
 		website = baseWebsite
 	}
 
 	contacts := sanitizeContacts(rawContacts)
+	if s.verifier != nil {
+		s.verifier.annotateContacts(ctx, contacts)
+	}
 	websiteConfidence := computeWebsiteConfidence(website, primaryURL, searchItems, pageSummary)
 	if parsedConfidence > 0 {
 		websiteConfidence = math.Max(websiteConfidence, parsedConfidence)
 	}
 	websiteConfidence = math.Min(1, math.Max(0, websiteConfidence))
 
-	candidates := buildCandidateList(searchItems, website)
+	candidates := buildCandidateList(searchItems, website, rules)
 	if len(llmCandidates) > 0 {
 		candidates = mergeCandidateDetails(candidates, llmCandidates)
 	}
@@ -204,6 +184,25 @@ func (s *EnrichmentServiceImpl) ResolveCompany(ctx context.Context, req *domain.
 		summary = buildFallbackSummary(pageSummary, knowledge)
 	}
 
+	var (
+		profile     *domain.CompanyProfile
+		socialLinks []domain.SocialLink
+		signals     *domain.WebsiteSignals
+	)
+	if pageSummary != nil {
+		profile = pageSummary.Profile
+		socialLinks = pageSummary.SocialLinks
+		signals = pageSummary.Signals
+	}
+	if profile != nil {
+		if name == "" {
+			name = firstNonEmpty(profile.LegalName, profile.Name)
+		}
+		if country == "" {
+			country = profile.Country
+		}
+	}
+
 	log.Printf("[enrichment] result query=%s name=%s website=%s confidence=%.2f contacts=%d llm_used=%v", query, name, website, websiteConfidence, len(contacts), llmUsed)
 
 	return &domain.ResolveCompanyResponse{
@@ -214,10 +213,13 @@ func (s *EnrichmentServiceImpl) ResolveCompany(ctx context.Context, req *domain.
 		Contacts:          contacts,
 		Candidates:        candidates,
 		Summary:           summary,
+		Profile:           profile,
+		SocialLinks:       socialLinks,
+		WebsiteSignals:    signals,
 	}, nil
 }
 
-func (s *EnrichmentServiceImpl) collectSearchArtifacts(ctx context.Context, query string, looksURL bool) (*SearchPlanResult, []SearchItem, string, *WebPageSummary, error) {
+func (s *EnrichmentServiceImpl) collectSearchArtifacts(ctx context.Context, query string, looksURL bool, rules *domainRules) (*SearchPlanResult, []SearchItem, string, *WebPageSummary, error) {
 	if looksURL {
 		normalized := normalizeMaybe(query)
 		items := []SearchItem{{
@@ -225,7 +227,7 @@ func (s *EnrichmentServiceImpl) collectSearchArtifacts(ctx context.Context, quer
 			URL:     normalized,
 			Snippet: "用户直接提供的候选官网",
 		}}
-		primary := choosePrimaryWebsite(items, query)
+		primary := choosePrimaryWebsite(items, query, rules)
 		if primary == "" {
 			primary = normalized
 		}
@@ -242,7 +244,7 @@ func (s *EnrichmentServiceImpl) collectSearchArtifacts(ctx context.Context, quer
 	if len(items) == 0 {
 		return plan, nil, "", nil, fmt.Errorf("未从搜索中获取有效结果，请尝试手动输入官网")
 	}
-	primary := choosePrimaryWebsite(items, query)
+	primary := choosePrimaryWebsite(items, query, rules)
 	summary := s.fetchWebSummary(ctx, primary)
 	log.Printf("[enrichment] query=%s aggregated_results=%d", query, len(items))
 	return plan, items, primary, summary, nil
@@ -252,12 +254,13 @@ func (s *EnrichmentServiceImpl) fetchWebSummary(ctx context.Context, url string)
 	if strings.TrimSpace(url) == "" {
 		return &WebPageSummary{}
 	}
-	ctxFetch, cancel := context.WithTimeout(ctx, 20*time.Second)
+	ctxFetch, cancel := context.WithTimeout(ctx, 45*time.Second)
 	defer cancel()
-	summary, err := s.fetcher.Fetch(ctxFetch, url)
+	summary, err := s.fetcher.Crawl(ctxFetch, url, CrawlOptions{})
 	if err != nil {
 		return &WebPageSummary{URL: normalizeMaybe(url)}
 	}
+	log.Printf("[enrichment] website=%s fetch_mode=%s pages=%d", summary.URL, summary.FetchMode, len(summary.Pages))
 	return summary
 }
 
@@ -265,18 +268,49 @@ func (s *EnrichmentServiceImpl) gatherLLMContext(ctx context.Context, query stri
 	if s.llm == nil {
 		return "", fmt.Errorf("llm 未配置")
 	}
-	messages := []ChatMessage{
-		{Role: "system", Content: researchSystemPrompt},
-		{Role: "user", Content: fmt.Sprintf("请提供关于公司「%s」的公开信息，包括主要业务、所在国家、官网（如知道）以及其他有参考价值的事实。", strings.TrimSpace(query))},
+	messages, err := s.prompts.Messages(ctx, PromptResearch, PromptData{Query: query})
+	if err != nil {
+		return "", err
+	}
+	opts := ChatOptions{MaxTokens: 600, Temperature: 0.3, Task: LLMTaskResearch, Cacheable: true}
+	if agent := s.researchAgent(); agent != nil {
+		result, err := agent.Run(ctx, messages, opts)
+		if err == nil {
+			log.Printf("[enrichment] research query=%q tool_calls=%d", query, result.ToolCalls)
+			return result.Content, nil
+		}
+		if ctx.Err() != nil {
+			return "", err
+		}
+		// Models or gateways without tool support reject the request; answer from prior knowledge instead.
+		log.Printf("[enrichment] research agent failed, falling back to plain chat: %v", err)
 	}
-	content, _, err := s.llm.Chat(ctx, messages, ChatOptions{MaxTokens: 600, Temperature: 0.3})
+	content, _, err := s.llm.Chat(ctx, messages, opts)
 	if err != nil {
 		return "", err
 	}
 	return strings.TrimSpace(content), nil
 }
 
-func buildEnrichmentPrompt(query string, plan *SearchPlanResult, fallbackItems []SearchItem, page *WebPageSummary, knowledge string) string {
+// researchAgent lets the background research step search and read pages itself
+// instead of relying on the model's prior knowledge alone.
+func (s *EnrichmentServiceImpl) researchAgent() *Agent {
+	var tools []Tool
+	if s.search != nil {
+		tools = append(tools, SearchTool(s.search))
+	}
+	if s.fetcher != nil {
+		tools = append(tools, FetchPageTool(s.fetcher))
+	}
+	if len(tools) == 0 {
+		return nil
+	}
+	return NewAgent(s.llm, 3).Register(tools...)
+}
+
+// buildEnrichmentMaterials formats the search, website and background material
+// that the enrichment prompt template embeds as {{.Materials}}.
+func buildEnrichmentMaterials(query string, plan *SearchPlanResult, fallbackItems []SearchItem, page *WebPageSummary, knowledge string) string {
 	var b strings.Builder
 	b.WriteString("My Goal: To build a profile for a potential B2B customer.\n")
 	b.WriteString("Initial Query: ")
@@ -300,6 +334,13 @@ func buildEnrichmentPrompt(query string, plan *SearchPlanResult, fallbackItems [
 		} else {
 			b.WriteString("(抓取失败)")
 		}
+		if page.FetchMode == FetchModeBrowser {
+			b.WriteString(" (rendered with headless browser)")
+		}
+		if len(page.Pages) > 1 {
+			b.WriteString("\nAlso Crawled: ")
+			b.WriteString(strings.Join(page.Pages[1:], ", "))
+		}
 		b.WriteString("\nKey Text Sections:\n")
 		if strings.TrimSpace(page.Text) != "" {
 			b.WriteString(page.Text)
@@ -308,58 +349,65 @@ func buildEnrichmentPrompt(query string, plan *SearchPlanResult, fallbackItems [
 		}
 		b.WriteString("\nDiscovered Emails: ")
 		b.WriteString(formatEmailsList(page.Emails))
+		if len(page.Phones) > 0 {
+			b.WriteString("\nDiscovered Phones: ")
+			b.WriteString(strings.Join(page.Phones, ", "))
+		}
 		b.WriteString("\n")
 	} else {
 		b.WriteString("Crawled URL: (未抓取)\nKey Text Sections:\n(无)\nDiscovered Emails: 无\n")
 	}
 
+	if page != nil && (page.Profile != nil || len(page.SocialLinks) > 0) {
+		b.WriteString("\n### Structured Company Data (high trust, published by the website itself):\n")
+		b.WriteString(formatCompanyProfile(page.Profile, page.SocialLinks))
+	}
+
 	if strings.TrimSpace(knowledge) != "" {
 		b.WriteString("\n### LLM Background Insights:\n")
 		b.WriteString(strings.TrimSpace(knowledge))
 		b.WriteString("\n")
 	}
 
-	b.WriteString(`
-### Instructions:
-Based on all the material provided, please perform the following steps:
-1.  **Identify the most credible official website.**
-2.  **Determine the company's country of operation.**
-3.  **Extract key contact persons, especially those in leadership or procurement roles.**
-4.  **Write a concise summary focusing on their business model and target market.**
-5.  **Output a clean JSON object.**
-
-### JSON Output Format:
-{
-  "website": "The normalized official URL (https://...)",
-  "website_confidence": 0.95,
-  "country": "...",
-  "contacts": [
-    {
-      "name": "...",
-      "title": "...",
-      "email": "...",
-      "is_key_decision_maker": true,
-      "source": "e.g., Website 'About Us' page, Search Snippet 1"
-    }
-  ],
-  "summary": "A 100-150 word summary in Chinese, focusing on their core business, scale, and primary customer base.",
-  "candidates": [
-    {
-      "url": "...",
-      "title": "...",
-      "rank": 2,
-      "reason": "e.g., Appears to be a regional distributor site, not corporate HQ."
-    }
-  ]
-}
-`)
+	return b.String()
+}
 
+// formatCompanyProfile lists the non-empty structured data fields.
+func formatCompanyProfile(profile *domain.CompanyProfile, links []domain.SocialLink) string {
+	var b strings.Builder
+	if profile != nil {
+		fields := []struct{ label, value string }{
+			{"Name", profile.Name},
+			{"Legal Name", profile.LegalName},
+			{"Address", profile.Address},
+			{"Country", profile.Country},
+			{"Phone", profile.Phone},
+			{"Email", profile.Email},
+			{"Founded", profile.FoundingDate},
+			{"Employees", profile.Employees},
+			{"Description", truncateRunes(profile.Description, 400)},
+		}
+		for _, field := range fields {
+			if strings.TrimSpace(field.value) != "" {
+				fmt.Fprintf(&b, "- %s: %s\n", field.label, field.value)
+			}
+		}
+		if len(profile.Sources) > 0 {
+			fmt.Fprintf(&b, "- Sources: %s\n", strings.Join(profile.Sources, ", "))
+		}
+	}
+	for _, link := range links {
+		fmt.Fprintf(&b, "- Social (%s): %s\n", link.Platform, link.URL)
+	}
 	return b.String()
 }
 
 func formatSearchSection(stage SearchStage, result *SearchTaskResult) string {
 	var b strings.Builder
 	label := stageLabel(stage)
+	if result != nil && strings.TrimSpace(result.Label) != "" {
+		label = result.Label
+	}
 	if result == nil || len(result.Items) == 0 {
 		b.WriteString(fmt.Sprintf("- %s：无结果 (query: %s)\n", label, strings.TrimSpace(resultQuery(result))))
 		return b.String()
@@ -413,8 +461,10 @@ func buildFallbackSummary(page *WebPageSummary, knowledge string) string {
 	return "暂无概述，可在后续步骤中手动补充。"
 }
 
-func finalizeWebsite(candidate, primary, query string, looksURL bool) string {
-	if isPlausibleWebsite(candidate) {
+// finalizeWebsite picks the customer website: the LLM's choice unless the
+// domain rules block it, then the primary search result, then the query.
+func finalizeWebsite(candidate, primary, query string, looksURL bool, rules *domainRules) string {
+	if isPlausibleWebsite(candidate) && !rules.isBlocked(normalizeDomain(candidate)) {
 		return normalizeMaybe(candidate)
 	}
 	if isPlausibleWebsite(primary) {
@@ -513,7 +563,7 @@ func computeWebsiteConfidence(website, primaryURL string, items []SearchItem, pa
 	return score
 }
 
-func buildCandidateList(items []SearchItem, chosenWebsite string) []domain.CandidateWebsite {
+func buildCandidateList(items []SearchItem, chosenWebsite string, rules *domainRules) []domain.CandidateWebsite {
 	chosenDomain := normalizeDomain(chosenWebsite)
 	type candidate struct {
 		domain string
@@ -553,6 +603,15 @@ func buildCandidateList(items []SearchItem, chosenWebsite string) []domain.Candi
 		} else if chosenDomain != "" && strings.Contains(strings.ToLower(reason), chosenDomain) {
 			score += 0.2
 		}
+		if match, ok := rules.match(domain); ok {
+			switch {
+			case match.allowed():
+				score += allowedCandidateBonus
+			case match.blocked() && domain != chosenDomain:
+				score = blockedCandidateScore
+			}
+			reason = reasonWithSuffix(reason, match.reasonSuffix())
+		}
 		candidates = append(candidates, candidate{
 			domain: domain,
 			url:    normURL,
@@ -775,7 +834,7 @@ func contactsFromPage(page *WebPageSummary, website string) []domain.Contact {
 	return contacts
 }
 
-func choosePrimaryWebsite(items []SearchItem, query string) string {
+func choosePrimaryWebsite(items []SearchItem, query string, rules *domainRules) string {
 	if len(items) == 0 {
 		return ""
 	}
@@ -791,10 +850,10 @@ func choosePrimaryWebsite(items []SearchItem, query string) string {
 		if domain == "" {
 			continue
 		}
-		if isAggregatorDomain(domain) {
+		if rules.isBlocked(domain) {
 			continue
 		}
-		score := scoreDomainForTokens(domain, tokens, item, idx, query)
+		score := scoreDomainForTokens(domain, tokens, item, idx, query, rules)
 		if score > bestScore {
 			bestScore = score
 			bestURL = url
@@ -809,7 +868,7 @@ func choosePrimaryWebsite(items []SearchItem, query string) string {
 			continue
 		}
 		domain := normalizeDomain(url)
-		if domain == "" || isAggregatorDomain(domain) {
+		if domain == "" || rules.isBlocked(domain) {
 			continue
 		}
 		return url
@@ -817,28 +876,18 @@ func choosePrimaryWebsite(items []SearchItem, query string) string {
 	return strings.TrimSpace(normalizeMaybe(items[0].URL))
 }
 
-func isAggregatorDomain(domain string) bool {
+func scoreDomainForTokens(domain string, tokens []string, item SearchItem, index int, query string, rules *domainRules) float64 {
 	if domain == "" {
-		return false
-	}
-	lower := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "www.")
-	for _, suffix := range aggregatorDomainSuffixes {
-		s := strings.ToLower(strings.TrimSpace(suffix))
-		if s == "" {
-			continue
-		}
-		if strings.HasSuffix(lower, s) || (len(s) > 4 && strings.Contains(lower, s)) {
-			return true
-		}
+		return -1
 	}
-	return false
-}
-
-func scoreDomainForTokens(domain string, tokens []string, item SearchItem, index int, query string) float64 {
-	if domain == "" {
+	match, matched := rules.match(domain)
+	if matched && match.blocked() {
 		return -1
 	}
 	score := 1.0
+	if matched && match.allowed() {
+		score += allowedDomainBonus
+	}
 	if index >= 0 {
 		score += 2.0 / float64(index+1)
 	}
@@ -951,7 +1000,3 @@ func normalizeMaybe(raw string) string {
 	}
 	return normalized
 }
-
-const researchSystemPrompt = "You are a seasoned market analyst. Provide factual, concise public information about companies. Prefer verifiable knowledge and mention when uncertain. 使用中文输出"
-
-const enrichmentSystemPrompt = "You are a senior B2B market intelligence analyst. Your mission is to synthesize web search results, prior knowledge, and website content into a structured company profile for a sales team. Prioritize accuracy and identify key decision-makers. 使用中文输出"
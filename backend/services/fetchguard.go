@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+// ErrBlockedAddress is returned when a server-side fetch would reach a
+// loopback, private, link-local or otherwise internal address.
+var ErrBlockedAddress = errors.New("目标地址属于内网或保留地址，已拒绝访问")
+
+// fetchMaxRedirects caps the redirects followed for one fetch.
+const fetchMaxRedirects = 5
+
+// blockedNetworks are ranges not covered by the net.IP predicates used in
+// blockedIP: "this network", carrier-grade NAT (where several clouds put their
+// metadata services), IETF protocol assignments, benchmarking, reserved space
+// and NAT64, which can embed any IPv4 address.
+var blockedNetworks = func() []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range []string{
+		"0.0.0.0/8",
+		"100.64.0.0/10",
+		"192.0.0.0/24",
+		"198.18.0.0/15",
+		"240.0.0.0/4",
+		"64:ff9b::/96",
+	} {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}()
+
+// blockedIP reports whether ip must not be fetched from the server. Cloud
+// metadata endpoints (169.254.169.254, fd00:ec2::254) fall in the link-local
+// and private ranges.
+func blockedIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchGuard keeps server-side fetches of user supplied URLs away from the
+// host and its network. For direct HTTP fetches names are resolved once and
+// the connection is made to the checked address, so DNS rebinding cannot swap
+// in an internal one, and every redirect is checked again. Browser renders get
+// the same through a guardProxy. Proxied fetches only get checkURL: the proxy
+// resolves the name again, so a host that rebinds in between can still reach
+// an internal address.
+// Hosts on the admin allowlist (Settings.FetchAllowedHosts) are exempt, for
+// testing against intranet sites.
+type fetchGuard struct {
+	store  *store.Store
+	dialer *net.Dialer
+	lookup func(ctx context.Context, host string) ([]net.IP, error)
+	// allowed overrides the allowlist read from the settings.
+	allowed []string
+}
+
+func newFetchGuard(st *store.Store) *fetchGuard {
+	return &fetchGuard{
+		store:  st,
+		dialer: &net.Dialer{Timeout: 15 * time.Second, KeepAlive: 30 * time.Second},
+		lookup: func(ctx context.Context, host string) ([]net.IP, error) {
+			return net.DefaultResolver.LookupIP(ctx, "ip", host)
+		},
+	}
+}
+
+// client returns an HTTP client whose connections and redirects go through
+// the guard.
+func (g *fetchGuard) client(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = g.dialContext
+	return &http.Client{
+		Timeout:       timeout,
+		Transport:     transport,
+		CheckRedirect: g.checkRedirect,
+	}
+}
+
+// allowlist returns the admin allowlist entries.
+func (g *fetchGuard) allowlist(ctx context.Context) []string {
+	if g.allowed != nil || g.store == nil {
+		return g.allowed
+	}
+	settings, err := g.store.GetSettings(ctx)
+	if err != nil {
+		log.Printf("[fetch] 读取访问白名单失败: %v", err)
+		return nil
+	}
+	return splitHostList(settings.FetchAllowedHosts)
+}
+
+// checkURL rejects unsupported schemes and hosts resolving to internal
+// addresses. Requests sent through a proxy never reach dialContext, so this
+// is their only check.
+func (g *fetchGuard) checkURL(ctx context.Context, target *url.URL) error {
+	if target == nil {
+		return fmt.Errorf("网址为空")
+	}
+	if err := checkFetchScheme(target); err != nil {
+		return err
+	}
+	_, err := g.resolve(ctx, target.Hostname())
+	return err
+}
+
+// resolve returns the addresses of host, failing if any of them is internal
+// and neither the host nor the address is allowlisted.
+func (g *fetchGuard) resolve(ctx context.Context, host string) ([]net.IP, error) {
+	host = normalizeHostEntry(host)
+	if host == "" {
+		return nil, fmt.Errorf("网址缺少主机名")
+	}
+	allow := g.allowlist(ctx)
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		resolved, err := g.lookup(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("解析域名失败: %w", err)
+		}
+		if len(resolved) == 0 {
+			return nil, fmt.Errorf("域名 %s 没有可用地址", host)
+		}
+		ips = resolved
+	}
+	if matchHostList(allow, host) {
+		return ips, nil
+	}
+	for _, ip := range ips {
+		if blockedIP(ip) && !matchHostList(allow, ip.String()) {
+			log.Printf("[fetch] 拒绝访问内网地址 host=%s ip=%s", host, ip)
+			return nil, fmt.Errorf("%w: %s (%s)", ErrBlockedAddress, host, ip)
+		}
+	}
+	return ips, nil
+}
+
+// dialContext connects to the first reachable checked address of addr.
+func (g *fetchGuard) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := g.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var lastErr error
+	for _, ip := range ips {
+		conn, err := g.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// checkRedirect limits redirects and checks each new target.
+func (g *fetchGuard) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= fetchMaxRedirects {
+		return fmt.Errorf("重定向次数超过 %d 次", fetchMaxRedirects)
+	}
+	return g.checkURL(req.Context(), req.URL)
+}
+
+// checkFetchScheme allows only plain web URLs.
+func checkFetchScheme(target *url.URL) error {
+	switch strings.ToLower(target.Scheme) {
+	case "http", "https":
+		return nil
+	default:
+		return fmt.Errorf("不支持的网址协议: %q", target.Scheme)
+	}
+}
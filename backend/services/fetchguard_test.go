@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+// newTestGuard returns a guard with a stubbed resolver and a fixed allowlist.
+func newTestGuard(hosts map[string]string, allowed ...string) *fetchGuard {
+	guard := newFetchGuard(nil)
+	guard.allowed = append([]string{}, allowed...)
+	guard.lookup = func(ctx context.Context, host string) ([]net.IP, error) {
+		if addr, ok := hosts[host]; ok {
+			return []net.IP{net.ParseIP(addr)}, nil
+		}
+		return nil, fmt.Errorf("no such host %s", host)
+	}
+	return guard
+}
+
+func newGuardedFetcher(guard *fetchGuard) *WebFetcher {
+	fetcher := NewWebFetcher(guard.client(5 * time.Second))
+	fetcher.guard = guard
+	return fetcher
+}
+
+func TestBlockedIP(t *testing.T) {
+	cases := map[string]bool{
+		"127.0.0.1":        true,
+		"10.20.30.40":      true,
+		"172.16.0.1":       true,
+		"192.168.1.10":     true,
+		"169.254.169.254":  true,
+		"100.100.100.200":  true,
+		"0.0.0.0":          true,
+		"255.255.255.255":  true,
+		"224.0.0.1":        true,
+		"::1":              true,
+		"fd00:ec2::254":    true,
+		"fe80::1":          true,
+		"::ffff:127.0.0.1": true,
+		"64:ff9b::a00:1":   true,
+		"93.184.216.34":    false,
+		"8.8.8.8":          false,
+		"2606:4700::1111":  false,
+	}
+	for addr, want := range cases {
+		if got := blockedIP(net.ParseIP(addr)); got != want {
+			t.Errorf("blockedIP(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestFetchGuardBlocksInternalTargets(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		io.WriteString(w, "<html><body><p>internal admin page</p></body></html>")
+	}))
+	defer server.Close()
+	port := server.Listener.Addr().(*net.TCPAddr).Port
+
+	guard := newTestGuard(map[string]string{
+		"intranet.example": "10.1.2.3",
+		"metadata.example": "169.254.169.254",
+		"sneaky.example":   "127.0.0.1",
+	})
+	fetcher := newGuardedFetcher(guard)
+	for _, target := range []string{
+		server.URL,
+		fmt.Sprintf("http://localhost:%d/", port),
+		fmt.Sprintf("http://sneaky.example:%d/", port),
+		"http://intranet.example/",
+		"http://metadata.example/latest/meta-data/",
+		"http://[::1]/",
+		"http://2130706433/",
+	} {
+		if _, err := fetcher.Fetch(context.Background(), target); err == nil {
+			t.Errorf("%s: expected fetch to be refused", target)
+		}
+	}
+	if _, err := fetcher.Fetch(context.Background(), "http://metadata.example/"); !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("expected ErrBlockedAddress, got %v", err)
+	}
+	if n := hits.Load(); n != 0 {
+		t.Fatalf("internal server was contacted %d times", n)
+	}
+
+	guard.allowed = []string{"127.0.0.1"}
+	summary, err := fetcher.Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("allowlisted fetch: %v", err)
+	}
+	if !strings.Contains(summary.Text, "internal admin page") {
+		t.Errorf("summary text = %q", summary.Text)
+	}
+}
+
+func TestFetchGuardChecksRedirects(t *testing.T) {
+	var loops atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/metadata":
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+		case "/rebind":
+			http.Redirect(w, r, "http://rebind.example/", http.StatusFound)
+		case "/file":
+			http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+		case "/gopher":
+			http.Redirect(w, r, "gopher://127.0.0.1:6379/_INFO", http.StatusFound)
+		default:
+			loops.Add(1)
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	// The test server itself is allowlisted, its redirect targets are not.
+	guard := newTestGuard(map[string]string{"rebind.example": "192.168.0.1"}, "127.0.0.1")
+	fetcher := newGuardedFetcher(guard)
+	for _, path := range []string{"/metadata", "/rebind"} {
+		if _, err := fetcher.Fetch(context.Background(), server.URL+path); !errors.Is(err, ErrBlockedAddress) {
+			t.Errorf("%s: expected ErrBlockedAddress, got %v", path, err)
+		}
+	}
+	for _, path := range []string{"/file", "/gopher"} {
+		if _, err := fetcher.Fetch(context.Background(), server.URL+path); err == nil || !strings.Contains(err.Error(), "不支持的网址协议") {
+			t.Errorf("%s: expected scheme to be refused, got %v", path, err)
+		}
+	}
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/loop"); err == nil || !strings.Contains(err.Error(), "重定向次数超过") {
+		t.Errorf("expected redirect limit, got %v", err)
+	}
+	if n := loops.Load(); n != fetchMaxRedirects {
+		t.Errorf("followed %d redirects, want %d", n, fetchMaxRedirects)
+	}
+}
+
+// A name that resolves publicly when checked but privately when dialled must
+// not reach the private address.
+func TestFetchGuardResistsDNSRebinding(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("rebound request reached %s", r.URL)
+	}))
+	defer server.Close()
+	port := server.Listener.Addr().(*net.TCPAddr).Port
+
+	var lookups atomic.Int32
+	guard := newFetchGuard(nil)
+	guard.allowed = []string{}
+	guard.lookup = func(ctx context.Context, host string) ([]net.IP, error) {
+		if lookups.Add(1) == 1 {
+			return []net.IP{net.ParseIP("93.184.216.34")}, nil
+		}
+		return []net.IP{net.ParseIP("127.0.0.1")}, nil
+	}
+	_, err := newGuardedFetcher(guard).Fetch(context.Background(), fmt.Sprintf("http://rebind.example:%d/", port))
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("expected ErrBlockedAddress, got %v", err)
+	}
+}
+
+// The admin's proxy may live on the LAN; only the targets are checked.
+func TestFetchGuardAllowsLocalProxy(t *testing.T) {
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "<html><body><p>fetched via proxy "+r.URL.Host+"</p></body></html>")
+	}))
+	defer proxyServer.Close()
+
+	guard := newTestGuard(map[string]string{
+		"public.example":   "93.184.216.34",
+		"intranet.example": "10.0.0.8",
+	})
+	router := NewProxyRouter(nil)
+	settings := &store.Settings{ProxyURL: proxyServer.URL}
+	client := router.httpClient(settings, store.ProxyChannelFetch, guard.client(5*time.Second))
+
+	resp, err := client.Get("http://public.example/")
+	if err != nil {
+		t.Fatalf("proxied fetch: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "fetched via proxy public.example") {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	fetcher := newGuardedFetcher(guard)
+	if _, err := fetcher.Fetch(context.Background(), "http://intranet.example/"); !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("expected proxied internal target to be refused, got %v", err)
+	}
+}
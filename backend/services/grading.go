@@ -2,23 +2,34 @@ package services
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"log"
 	"strings"
+	"time"
 
 	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
 	"github.com/anner/ai-foreign-trade-assistant/backend/store"
 )
 
+// gradingSignalsTimeout bounds the website visit made when a customer has no
+// stored website signals yet.
+const gradingSignalsTimeout = 40 * time.Second
+
+// gradingSignalsRetryAfter is how long a failed signal detection is kept before
+// grading visits the website again.
+const gradingSignalsRetryAfter = 24 * time.Hour
+
 // GradingServiceImpl handles Step 2 AI grading.
 type GradingServiceImpl struct {
-	store *store.Store
-	llm   *LLMClient
+	store   *store.Store
+	llm     *LLMClient
+	prompts *PromptServiceImpl
+	fetcher *WebFetcher // detects website signals for older customers; nil skips them
 }
 
 // NewGradingService constructs the grading service.
-func NewGradingService(st *store.Store, llm *LLMClient) *GradingServiceImpl {
-	return &GradingServiceImpl{store: st, llm: llm}
+func NewGradingService(st *store.Store, llm *LLMClient, prompts *PromptServiceImpl) *GradingServiceImpl {
+	return &GradingServiceImpl{store: st, llm: llm, prompts: prompts}
 }
 
 // Suggest provides AI grade recommendation.
@@ -28,25 +39,23 @@ func (g *GradingServiceImpl) Suggest(ctx context.Context, customerID int64) (*do
 		return nil, err
 	}
 
+	if signalsDue(customer.WebsiteSignals, time.Now()) {
+		customer.WebsiteSignals = g.detectSignals(ctx, customer)
+	}
+
 	settings, err := g.store.GetSettings(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("读取配置失败: %w", err)
 	}
-    guideline := strings.TrimSpace(settings.RatingGuideline)
-    if guideline == "" {
-        // Use the built-in comprehensive default when no guideline is configured.
-        guideline = defaultRatingGuideline
-    }
-
-	prompt := buildGradingPrompt(customer, guideline)
-	content, _, err := g.llm.Chat(ctx, []ChatMessage{
-		{Role: "system", Content: gradingSystemPrompt},
-		{Role: "user", Content: prompt},
-	}, ChatOptions{MaxTokens: 320, Temperature: 0.2, ResponseFormat: "json_object"})
+	messages, err := g.prompts.Messages(ctx, PromptGrading, PromptData{
+		Customer:       customer,
+		Settings:       promptSettingsFrom(settings),
+		Guideline:      ratingGuideline(settings),
+		WebsiteSignals: formatWebsiteSignals(customer.WebsiteSignals),
+	})
 	if err != nil {
 		return nil, err
 	}
-
 	var parsed struct {
 		SuggestedGrade string  `json:"suggested_grade"`
 		Confidence     float64 `json:"confidence_score"`
@@ -55,13 +64,11 @@ func (g *GradingServiceImpl) Suggest(ctx context.Context, customerID int64) (*do
 			Negative []string `json:"negative_signals"`
 		} `json:"reasoning"`
 	}
-	if err := json.Unmarshal([]byte(content), &parsed); err != nil {
+	opts := ChatOptions{MaxTokens: 320, Temperature: 0.2, ResponseFormat: "json_object", Task: LLMTaskGrading, CustomerID: customerID, Cacheable: true}
+	if err := g.llm.ChatJSON(ctx, messages, opts, gradingSchema, &parsed); err != nil {
 		return nil, fmt.Errorf("解析评分结果失败: %w", err)
 	}
-	grade := strings.ToUpper(strings.TrimSpace(parsed.SuggestedGrade))
-	if grade != "A" && grade != "B" && grade != "C" {
-		grade = "C"
-	}
+	grade := parsed.SuggestedGrade
 	positive := sanitizeSignals(parsed.Reasoning.Positive)
 	negative := sanitizeSignals(parsed.Reasoning.Negative)
 	reason := buildReasonSummary(positive, negative)
@@ -74,6 +81,46 @@ func (g *GradingServiceImpl) Suggest(ctx context.Context, customerID int64) (*do
 	}, nil
 }
 
+// signalsDue reports whether grading should detect website signals: none are
+// stored, or the last detection failed more than gradingSignalsRetryAfter ago.
+func signalsDue(signals *domain.WebsiteSignals, now time.Time) bool {
+	if signals == nil {
+		return true
+	}
+	if !signals.Failed {
+		return false
+	}
+	detectedAt, err := time.Parse(time.RFC3339, signals.DetectedAt)
+	return err != nil || now.Sub(detectedAt) >= gradingSignalsRetryAfter
+}
+
+// detectSignals crawls the website of a customer saved before signals were
+// collected and stores the result. Failures only cost the grade its evidence;
+// they are stored as a failed detection so the next grades do not wait for
+// the same crawl again.
+func (g *GradingServiceImpl) detectSignals(ctx context.Context, customer *domain.Customer) *domain.WebsiteSignals {
+	website := strings.TrimSpace(customer.Website)
+	if g.fetcher == nil || website == "" {
+		return customer.WebsiteSignals
+	}
+	crawlCtx, cancel := context.WithTimeout(ctx, gradingSignalsTimeout)
+	defer cancel()
+	signals := &domain.WebsiteSignals{Failed: true, DetectedAt: store.Now()}
+	summary, err := g.fetcher.Crawl(crawlCtx, website, CrawlOptions{})
+	if err != nil || summary.Signals == nil {
+		log.Printf("[grading] 检测网站信号失败 customer=%d website=%s: %v", customer.ID, website, err)
+		if ctx.Err() != nil {
+			return customer.WebsiteSignals
+		}
+	} else {
+		signals = summary.Signals
+	}
+	if err := g.store.UpdateCustomerWebsiteSignals(ctx, customer.ID, signals); err != nil {
+		log.Printf("[grading] 保存网站信号失败 customer=%d: %v", customer.ID, err)
+	}
+	return signals
+}
+
 // Confirm persists the user's final grade decision.
 func (g *GradingServiceImpl) Confirm(ctx context.Context, customerID int64, grade, reason string) error {
 	grade = strings.ToUpper(strings.TrimSpace(grade))
@@ -86,58 +133,11 @@ func (g *GradingServiceImpl) Confirm(ctx context.Context, customerID int64, grad
 	return nil
 }
 
-var sb strings.Builder
-
-func buildGradingPrompt(customer *domain.Customer, guideline string) string {
-	var sb strings.Builder
-	sb.WriteString("### Customer Profile:\n")
-	sb.WriteString("- Name: ")
-	sb.WriteString(strings.TrimSpace(customer.Name))
-	sb.WriteString("\n- Website: ")
-	sb.WriteString(strings.TrimSpace(customer.Website))
-	sb.WriteString("\n- Country: ")
-	sb.WriteString(strings.TrimSpace(customer.Country))
-	sb.WriteString("\n- Summary: ")
-	sb.WriteString(strings.TrimSpace(customer.Summary))
-
-	sb.WriteString("\n\n### Rating Guideline:\n")
-	sb.WriteString(strings.TrimSpace(guideline))
-
-	sb.WriteString(`
-
-### Rating Examples (for calibration):
-- **Example of an 'A' Grade Customer**: A large manufacturer in a target industry with over 200 employees and clear demand for our type of product.
-- **Example of a 'C' Grade Customer**: A small trading company with a generic website, unclear business focus, and located in a high-risk region.
-
-### Instructions:
-Analyze the customer profile against the guideline and examples. Output a JSON object with your suggested grade and a structured reasoning.
-
-### JSON Output Format:
-{
-  "suggested_grade": "A|B|C",
-  "confidence_score": 0.9,
-  "reasoning": {
-    "positive_signals": [
-      "e.g., Company operates in a key target industry.",
-      "e.g., Website showcases high-value products relevant to our offerings."
-    ],
-    "negative_signals": [
-      "e.g., Company size appears to be small.",
-      "e.g., No key contact information was found."
-    ]
-  }
-}
-`)
-	return sb.String()
-}
-
 const defaultRatingGuideline = `请按照以下标准对潜在客户进行评级：
 A级：核心目标客户，具有明确采购需求，规模较大（员工数>200 或营收显著），所在行业与我方产品高度契合，决策链清晰且风险低。
 B级：潜在合作伙伴，业务方向相关但短期内需求不明朗，体量或采购能力有限，或信息尚不充分，需要持续跟进验证。
 C级：暂不跟进的客户，业务需求与我方产品匹配度低，规模较小或风险较高，无法短期产生合作机会。`
 
-const gradingSystemPrompt = "You are a B2B sales strategist specializing in customer segmentation. Your task is to provide a precise customer rating (A, B, or C) based on the user's guideline. You must justify your rating by listing clear positive and negative signals.\n使用中文输出"
-
 func sanitizeSignals(items []string) []string {
 	clean := make([]string, 0, len(items))
 	for _, item := range items {
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// guardProxyHopHeaders are the hop-by-hop headers dropped when forwarding.
+var guardProxyHopHeaders = []string{
+	"Connection", "Proxy-Connection", "Keep-Alive", "Proxy-Authenticate",
+	"Proxy-Authorization", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// guardProxy is a loopback HTTP proxy that guarded browser renders go
+// through. Chromium follows redirects and loads subresources on its own, out
+// of reach of page routes, but every connection it opens goes through here and
+// is dialled by the guard. Without an upstream proxy the guard resolves each
+// host once and connects to the checked address, so neither a redirect nor DNS
+// rebinding reaches an internal address. With an upstream proxy the target is
+// checked and the tunnel is opened through that proxy, which resolves the name
+// again.
+type guardProxy struct {
+	guard     *fetchGuard
+	upstream  *proxyConfig // nil connects directly
+	listener  net.Listener
+	server    *http.Server
+	transport *http.Transport
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{} // hijacked tunnels, closed with the proxy
+}
+
+// startGuardProxy listens on a random loopback port until Close.
+func startGuardProxy(guard *fetchGuard, upstream *proxyConfig) (*guardProxy, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("启动浏览器代理失败: %w", err)
+	}
+	p := &guardProxy{guard: guard, upstream: upstream, listener: listener, conns: map[net.Conn]struct{}{}}
+	p.transport = &http.Transport{
+		DialContext:         p.dial,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConnsPerHost: 4,
+	}
+	p.server = &http.Server{Handler: p, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := p.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[fetch] 浏览器代理退出: %v", err)
+		}
+	}()
+	return p, nil
+}
+
+// url is the proxy address for Chromium.
+func (p *guardProxy) url() string {
+	return "http://" + p.listener.Addr().String()
+}
+
+// Close stops the proxy and drops its open connections.
+func (p *guardProxy) Close() error {
+	err := p.server.Close()
+	p.transport.CloseIdleConnections()
+	p.mu.Lock()
+	for conn := range p.conns {
+		conn.Close()
+	}
+	p.mu.Unlock()
+	return err
+}
+
+// dial opens a connection to addr after the guard has approved it.
+func (p *guardProxy) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	if p.upstream == nil || p.upstream.bypassed(addr) {
+		return p.guard.dialContext(ctx, network, addr)
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.guard.resolve(ctx, host); err != nil {
+		return nil, err
+	}
+	return p.upstream.dialContext(ctx, addr)
+}
+
+func (p *guardProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+	if r.URL.Host == "" || !strings.EqualFold(r.URL.Scheme, "http") {
+		http.Error(w, "proxy request expected", http.StatusBadRequest)
+		return
+	}
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	for _, header := range guardProxyHopHeaders {
+		out.Header.Del(header)
+	}
+	// RoundTrip does not follow redirects; Chromium sends the next hop here too.
+	resp, err := p.transport.RoundTrip(out)
+	if err != nil {
+		guardProxyError(w, err)
+		return
+	}
+	defer resp.Body.Close()
+	for _, header := range guardProxyHopHeaders {
+		resp.Header.Del(header)
+	}
+	for key, values := range resp.Header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
+
+// tunnel answers a CONNECT request with a spliced connection to the target.
+func (p *guardProxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	target, err := p.dial(r.Context(), "tcp", r.Host)
+	if err != nil {
+		guardProxyError(w, err)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		target.Close()
+		http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	client, buffered, err := hijacker.Hijack()
+	if err != nil {
+		target.Close()
+		return
+	}
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		client.Close()
+		target.Close()
+		return
+	}
+	p.track(client, target)
+	done := make(chan struct{}, 2)
+	go func() {
+		// Bytes the client sent after the CONNECT line are already buffered.
+		_, _ = io.Copy(target, buffered)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(client, target)
+		done <- struct{}{}
+	}()
+	<-done
+	client.Close()
+	target.Close()
+	p.untrack(client, target)
+}
+
+func (p *guardProxy) track(conns ...net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, conn := range conns {
+		p.conns[conn] = struct{}{}
+	}
+}
+
+func (p *guardProxy) untrack(conns ...net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, conn := range conns {
+		delete(p.conns, conn)
+	}
+}
+
+// guardProxyError answers 403 for addresses the guard rejects and 502 for
+// targets that cannot be reached.
+func guardProxyError(w http.ResponseWriter, err error) {
+	status := http.StatusBadGateway
+	if errors.Is(err, ErrBlockedAddress) {
+		status = http.StatusForbidden
+	}
+	http.Error(w, err.Error(), status)
+}
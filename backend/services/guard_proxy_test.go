@@ -0,0 +1,76 @@
+package services
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGuardProxyBlocksRedirectToInternalAddress(t *testing.T) {
+	var internalHits atomic.Int32
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		internalHits.Add(1)
+		w.Write([]byte("instance credentials"))
+	}))
+	defer internal.Close()
+	_, internalPort, _ := net.SplitHostPort(internal.Listener.Addr().String())
+
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "http://metadata.internal:"+internalPort+"/latest/meta-data/", http.StatusFound)
+		default:
+			w.Write([]byte("<html><body>Acme</body></html>"))
+		}
+	}))
+	defer public.Close()
+	_, publicPort, _ := net.SplitHostPort(public.Listener.Addr().String())
+
+	// Both test servers listen on loopback; only the public name is allowlisted.
+	guard := newTestGuard(map[string]string{
+		"public.example":    "127.0.0.1",
+		"metadata.internal": "127.0.0.1",
+	}, "public.example")
+	proxy, err := startGuardProxy(guard, nil)
+	if err != nil {
+		t.Fatalf("start proxy: %v", err)
+	}
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.url())
+	// Chromium follows redirects itself, sending each hop to the proxy.
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)},
+	}
+
+	resp, err := client.Get("http://public.example:" + publicPort + "/")
+	if err != nil {
+		t.Fatalf("public page: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("public page status = %d", resp.StatusCode)
+	}
+
+	resp, err = client.Get("http://public.example:" + publicPort + "/redirect")
+	if err != nil {
+		t.Fatalf("redirect: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("redirect to internal address status = %d, want 403", resp.StatusCode)
+	}
+
+	// HTTPS requests and WebSockets arrive as CONNECT tunnels.
+	if resp, err := client.Get("https://metadata.internal:" + internalPort + "/"); err == nil {
+		resp.Body.Close()
+		t.Errorf("tunnel to internal address opened")
+	}
+	if hits := internalHits.Load(); hits != 0 {
+		t.Fatalf("internal server reached %d times", hits)
+	}
+}
@@ -1,13 +1,15 @@
 package services
 
 import (
-	"bytes"
+	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
-	"net/url"
 	"strings"
 	"time"
 
@@ -18,10 +20,15 @@ const (
 	defaultLLMTimeout = 5 * time.Minute
 )
 
-// ChatMessage models a single message in a chat completion.
+// ChatMessage models a single message in a chat completion. Assistant messages
+// may carry the tool calls the model requested; role "tool" messages answer one
+// of them via ToolCallID.
 type ChatMessage struct {
-	Role    string
-	Content string
+	Role       string
+	Content    string
+	ToolCalls  []ToolCall `json:",omitempty"`
+	ToolCallID string     `json:",omitempty"`
+	Name       string     `json:",omitempty"` // tool name on role "tool" messages
 }
 
 // ChatOptions controls the chat completion parameters.
@@ -29,12 +36,26 @@ type ChatOptions struct {
 	MaxTokens      int
 	Temperature    float32
 	ResponseFormat string // e.g. "json_object"
+	Task           string // routes the call to the per-task model, e.g. LLMTaskGrading
+	CustomerID     int64  // attributes token usage to a customer in the ledger
+	Tools          []ToolDefinition
+	ToolChoice     string // "auto" (default), "none" or "required"; only used with Tools
+	Cacheable      bool   // the reply depends only on the input and may be served from the response cache
 }
 
-// LLMClient calls OpenAI-compatible chat completion endpoints.
+// WithCustomer returns a copy of the options attributed to the given customer.
+func (o ChatOptions) WithCustomer(customerID int64) ChatOptions {
+	o.CustomerID = customerID
+	return o
+}
+
+// LLMClient calls chat completion endpoints through the configured provider adapter.
 type LLMClient struct {
 	store      *store.Store
 	httpClient *http.Client
+	limiter    *llmLimiter
+	cache      *ResponseCache
+	proxies    *ProxyRouter
 }
 
 // NewLLMClient constructs a new LLMClient.
@@ -45,12 +66,14 @@ func NewLLMClient(st *store.Store, client *http.Client) *LLMClient {
 	return &LLMClient{
 		store:      st,
 		httpClient: client,
+		limiter:    sharedLLMLimiter,
 	}
 }
 
 // TestConnection sends a lightweight chat completion request to validate credentials.
 func (c *LLMClient) TestConnection(ctx context.Context) (map[string]string, error) {
-	if _, err := c.ensureConfigured(ctx); err != nil {
+	settings, err := c.ensureConfigured(ctx)
+	if err != nil {
 		return nil, err
 	}
 
@@ -61,6 +84,7 @@ func (c *LLMClient) TestConnection(ctx context.Context) (map[string]string, erro
 
 	return map[string]string{
 		"message":           "LLM 测试成功",
+		"provider":          settings.LLMProvider,
 		"echo":              content,
 		"prompt_tokens":     fmt.Sprintf("%d", usage.PromptTokens),
 		"completion_tokens": fmt.Sprintf("%d", usage.CompletionTokens),
@@ -77,6 +101,9 @@ func stringifyError(payload map[string]any) string {
 			return msg
 		}
 	}
+	if msg, ok := payload["error"].(string); ok && msg != "" {
+		return msg
+	}
 	if msg, ok := payload["message"].(string); ok {
 		return msg
 	}
@@ -88,18 +115,25 @@ func (c *LLMClient) ensureConfigured(ctx context.Context) (*store.Settings, erro
 	if c == nil || c.store == nil {
 		return nil, fmt.Errorf("llm client not initialized")
 	}
-	if ctx == nil || ctx.Err() != nil {
+	if ctx == nil {
 		ctx = context.Background()
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	settings, err := c.store.GetSettings(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("读取配置失败: %w", err)
 	}
+	provider := providerFor(settings.LLMProvider)
+	if strings.TrimSpace(settings.LLMBaseURL) == "" {
+		settings.LLMBaseURL = provider.defaultBaseURL()
+	}
 	var missing []string
 	if strings.TrimSpace(settings.LLMBaseURL) == "" {
 		missing = append(missing, "Base URL")
 	}
-	if strings.TrimSpace(settings.LLMAPIKey) == "" {
+	if provider.requiresAPIKey() && strings.TrimSpace(settings.LLMAPIKey) == "" {
 		missing = append(missing, "API Key")
 	}
 	if strings.TrimSpace(settings.LLMModel) == "" {
@@ -118,103 +152,316 @@ func (c *LLMClient) EnsureConfigured(ctx context.Context) error {
 }
 
 // Chat executes a chat completion request and returns the assistant message content.
+// The model is chosen by opts.Task; 5xx responses and timeouts fall through to the
+// configured fallback models in order.
 func (c *LLMClient) Chat(ctx context.Context, messages []ChatMessage, opts ChatOptions) (string, *Usage, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	settings, err := c.ensureConfigured(ctx)
 	if err != nil {
 		return "", nil, err
 	}
-	log.Printf("[llm] model=%s messages=%d status=started", settings.LLMModel, len(messages))
-
-	if len(messages) == 0 {
-		return "", nil, fmt.Errorf("至少需要一条对话消息")
+	provider := providerFor(settings.LLMProvider)
+	cacheKey := llmCacheKey(settings, messages, opts)
+	if cached, ok := c.cachedCompletion(ctx, settings, cacheKey, opts); ok {
+		return cached, &Usage{}, nil
 	}
 
-	reqMessages := make([]map[string]string, 0, len(messages))
-	for _, msg := range messages {
-		role := strings.TrimSpace(msg.Role)
-		if role == "" {
-			role = "user"
+	var (
+		content string
+		usage   *Usage
+	)
+	err = c.withFallback(ctx, settings, opts.Task, shouldFallback, func(attempt *store.Settings) error {
+		started := time.Now()
+		result, err := c.chatOnce(ctx, provider, attempt, messages, opts)
+		content, usage = "", nil
+		if result != nil {
+			content, usage = result.Content, &result.Usage
 		}
-		reqMessages = append(reqMessages, map[string]string{
-			"role":    role,
-			"content": msg.Content,
-		})
+		if err == nil && content == "" {
+			err = fmt.Errorf("LLM 未返回任何内容")
+		}
+		c.recordUsage(attempt, opts, usage, started, err)
+		return err
+	})
+	if err == nil && c.cacheable(settings, opts) {
+		c.cache.Put(CacheNamespaceLLM, cacheKey, content, hoursTTL(settings.LLMCacheTTLHours))
 	}
+	return content, usage, err
+}
 
-	maxTokens := opts.MaxTokens
-	if maxTokens <= 0 {
-		maxTokens = 1024
+func (c *LLMClient) chatOnce(ctx context.Context, provider llmProvider, settings *store.Settings, messages []ChatMessage, opts ChatOptions) (*ChatReply, error) {
+	release, err := c.acquire(ctx, settings)
+	if err != nil {
+		return nil, err
 	}
-	temperature := opts.Temperature
-	if temperature < 0 {
-		temperature = 0.6
+	defer release()
+	log.Printf("[llm] provider=%s model=%s task=%s messages=%d tools=%d status=started", settings.LLMProvider, settings.LLMModel, opts.Task, len(messages), len(opts.Tools))
+
+	execCtx, cancel := context.WithTimeout(ctx, defaultLLMTimeout)
+	defer cancel()
+
+	resp, err := c.doChatRequest(execCtx, provider, settings, messages, opts, false)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	payload := map[string]any{
-		"model":       settings.LLMModel,
-		"messages":    reqMessages,
-		"max_tokens":  maxTokens,
-		"temperature": temperature,
-		"stream":      false,
+	result, err := provider.decodeResponse(resp.Body)
+	if err != nil {
+		return nil, err
 	}
-	if opts.ResponseFormat != "" {
-		payload["response_format"] = map[string]string{"type": opts.ResponseFormat}
+
+	result.Content = strings.TrimSpace(result.Content)
+	if result.Content == "" && len(result.ToolCalls) == 0 {
+		log.Printf("[llm] provider=%s model=%s status=empty-response", settings.LLMProvider, settings.LLMModel)
+		return &result, nil
 	}
+	log.Printf("[llm] provider=%s model=%s status=completed tool_calls=%d total_tokens=%d", settings.LLMProvider, settings.LLMModel, len(result.ToolCalls), result.Usage.TotalTokens)
+	return &result, nil
+}
 
-	body, err := json.Marshal(payload)
+// ChatStream executes a streaming chat completion. onDelta receives every content
+// fragment as it arrives; the full assistant message is returned once the stream ends.
+// Fallback models are only tried while nothing has been streamed yet.
+func (c *LLMClient) ChatStream(ctx context.Context, messages []ChatMessage, opts ChatOptions, onDelta func(string)) (string, *Usage, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if len(opts.Tools) > 0 {
+		return "", nil, fmt.Errorf("流式对话不支持工具调用")
+	}
+	settings, err := c.ensureConfigured(ctx)
 	if err != nil {
-		return "", nil, fmt.Errorf("编码 LLM 请求失败: %w", err)
+		return "", nil, err
+	}
+	provider := providerFor(settings.LLMProvider)
+	cacheKey := llmCacheKey(settings, messages, opts)
+	if cached, ok := c.cachedCompletion(ctx, settings, cacheKey, opts); ok {
+		if onDelta != nil {
+			onDelta(cached)
+		}
+		return cached, &Usage{}, nil
 	}
 
-	endpoint, err := url.JoinPath(strings.TrimRight(settings.LLMBaseURL, "/"), "chat/completions")
+	var (
+		content string
+		usage   *Usage
+		emitted bool
+	)
+	retry := func(err error) bool {
+		return !emitted && shouldFallback(err)
+	}
+	err = c.withFallback(ctx, settings, opts.Task, retry, func(attempt *store.Settings) error {
+		started := time.Now()
+		var err error
+		content, usage, err = c.chatStreamOnce(ctx, provider, attempt, messages, opts, func(text string) {
+			emitted = true
+			if onDelta != nil {
+				onDelta(text)
+			}
+		})
+		c.recordUsage(attempt, opts, usage, started, err)
+		return err
+	})
+	if err == nil && c.cacheable(settings, opts) {
+		c.cache.Put(CacheNamespaceLLM, cacheKey, content, hoursTTL(settings.LLMCacheTTLHours))
+	}
+	return content, usage, err
+}
+
+// llmCacheKey addresses a completion by endpoint, credentials, provider, routed
+// model, messages and the options that influence the output, so switching to
+// another deployment or key never serves the previous one's answers.
+func llmCacheKey(settings *store.Settings, messages []ChatMessage, opts ChatOptions) string {
+	credentials := sha256.Sum256([]byte(settings.LLMAPIKey))
+	return cacheKey(
+		strings.TrimRight(strings.TrimSpace(settings.LLMBaseURL), "/"),
+		hex.EncodeToString(credentials[:8]),
+		settings.LLMProvider,
+		modelChain(settings, opts.Task)[0],
+		messages, opts.MaxTokens, opts.Temperature, opts.ResponseFormat,
+	)
+}
+
+// cacheable reports whether a call may read or write the response cache: only
+// calls marked Cacheable without tools, and only while a TTL is configured.
+func (c *LLMClient) cacheable(settings *store.Settings, opts ChatOptions) bool {
+	return c.cache != nil && settings.LLMCacheTTLHours > 0 && opts.Cacheable && len(opts.Tools) == 0
+}
+
+// cachedCompletion returns a cached reply unless the call is not cacheable or
+// the cache is bypassed for ctx.
+func (c *LLMClient) cachedCompletion(ctx context.Context, settings *store.Settings, key string, opts ChatOptions) (string, bool) {
+	if !c.cacheable(settings, opts) || cacheBypassed(ctx) {
+		return "", false
+	}
+	var content string
+	if !c.cache.Get(CacheNamespaceLLM, key, &content) {
+		return "", false
+	}
+	log.Printf("[llm] task=%s status=cache-hit", opts.Task)
+	return content, true
+}
+
+// forgetCompletion drops the cached reply for messages, so a reply the caller
+// rejected is requested again instead of replayed for the whole TTL.
+func (c *LLMClient) forgetCompletion(ctx context.Context, messages []ChatMessage, opts ChatOptions) {
+	if c == nil || c.cache == nil || c.store == nil {
+		return
+	}
+	settings, err := c.store.GetSettings(context.WithoutCancel(ctx))
+	if err != nil || !c.cacheable(settings, opts) {
+		return
+	}
+	c.cache.Delete(CacheNamespaceLLM, llmCacheKey(settings, messages, opts))
+}
+
+func (c *LLMClient) chatStreamOnce(ctx context.Context, provider llmProvider, settings *store.Settings, messages []ChatMessage, opts ChatOptions, onDelta func(string)) (string, *Usage, error) {
+	release, err := c.acquire(ctx, settings)
 	if err != nil {
-		return "", nil, fmt.Errorf("拼接 LLM 地址失败: %w", err)
+		return "", nil, err
 	}
+	defer release()
+	log.Printf("[llm] provider=%s model=%s task=%s messages=%d status=started stream=true", settings.LLMProvider, settings.LLMModel, opts.Task, len(messages))
 
-	execCtx, cancel := context.WithTimeout(context.Background(), defaultLLMTimeout)
+	execCtx, cancel := context.WithTimeout(ctx, defaultLLMTimeout)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(execCtx, http.MethodPost, endpoint, bytes.NewReader(body))
+	resp, err := c.doChatRequest(execCtx, provider, settings, messages, opts, true)
 	if err != nil {
-		return "", nil, fmt.Errorf("创建 LLM 请求失败: %w", err)
+		return "", nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", settings.LLMAPIKey))
+	defer resp.Body.Close()
 
-	resp, err := c.httpClient.Do(req)
+	var content strings.Builder
+	usage, err := provider.decodeStream(resp.Body, func(text string) {
+		content.WriteString(text)
+		onDelta(text)
+	})
 	if err != nil {
-		log.Printf("[llm] model=%s status=failed error=%v", settings.LLMModel, err)
-		return "", nil, fmt.Errorf("无法连接到 LLM 服务: %w", err)
+		log.Printf("[llm] provider=%s model=%s status=failed stream=true error=%v", settings.LLMProvider, settings.LLMModel, err)
+		return "", nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode >= 400 {
-		var apiErr map[string]any
-		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
-		return "", nil, fmt.Errorf("LLM 接口返回错误(%d): %s", resp.StatusCode, stringifyError(apiErr))
+	result := strings.TrimSpace(content.String())
+	if result == "" {
+		log.Printf("[llm] provider=%s model=%s status=empty-response stream=true", settings.LLMProvider, settings.LLMModel)
+		return "", &usage, fmt.Errorf("LLM 未返回任何内容")
+	}
+	log.Printf("[llm] provider=%s model=%s status=completed stream=true total_tokens=%d", settings.LLMProvider, settings.LLMModel, usage.TotalTokens)
+	return result, &usage, nil
+}
+
+// withFallback runs attempt against each model in the task's chain until one
+// succeeds or retry rejects the error. 429/503 responses are first retried on the
+// same model with exponential backoff, honoring Retry-After.
+func (c *LLMClient) withFallback(ctx context.Context, settings *store.Settings, task string, retry func(error) bool, attempt func(*store.Settings) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	models := modelChain(settings, task)
+	var err error
+	for i, model := range models {
+		target := withModel(settings, model)
+		for n := 0; ; n++ {
+			err = attempt(target)
+			if err == nil {
+				return nil
+			}
+			// The caller went away (closed tab, shutdown): neither retry nor fall back.
+			if ctx.Err() != nil {
+				return err
+			}
+			// Rate limits surface as HTTP status before any content is streamed, so
+			// retrying the same model is always safe.
+			if n >= llmMaxRetries || !shouldRetrySameModel(err, i == len(models)-1) {
+				break
+			}
+			delay := retryDelay(err, n)
+			log.Printf("[llm] task=%s model=%s status=retry attempt=%d wait=%s error=%v", task, model, n+1, delay, err)
+			if sleepErr := sleepContext(ctx, delay); sleepErr != nil {
+				return err
+			}
+		}
+		if i == len(models)-1 || !retry(err) {
+			break
+		}
+		log.Printf("[llm] task=%s model=%s status=fallback next=%s error=%v", task, model, models[i+1], err)
+	}
+	return err
+}
+
+// acquire waits for a slot in the process-wide limiter.
+func (c *LLMClient) acquire(ctx context.Context, settings *store.Settings) (func(), error) {
+	if c.limiter == nil {
+		return func() {}, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
 	}
+	return c.limiter.acquire(ctx, settings.LLMMaxConcurrency, settings.LLMRequestsPerMinute, isBackgroundPriority(ctx))
+}
 
-	var parsed struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-		Usage Usage `json:"usage"`
+// doChatRequest sends the provider specific request and returns the successful HTTP response.
+func (c *LLMClient) doChatRequest(ctx context.Context, provider llmProvider, settings *store.Settings, messages []ChatMessage, opts ChatOptions, stream bool) (*http.Response, error) {
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("至少需要一条对话消息")
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
-		return "", nil, fmt.Errorf("解析 LLM 响应失败: %w", err)
+	req, err := provider.newRequest(ctx, settings, messages, opts, stream)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(parsed.Choices) == 0 {
-		log.Printf("[llm] model=%s status=empty-response", settings.LLMModel)
-		return "", &parsed.Usage, fmt.Errorf("LLM 未返回任何内容")
+	resp, err := c.proxies.httpClient(settings, store.ProxyChannelLLM, c.httpClient).Do(req)
+	if err != nil {
+		log.Printf("[llm] provider=%s model=%s status=failed error=%v", settings.LLMProvider, settings.LLMModel, err)
+		return nil, fmt.Errorf("无法连接到 LLM 服务: %w", err)
 	}
 
-	content := strings.TrimSpace(parsed.Choices[0].Message.Content)
-	log.Printf("[llm] model=%s status=completed total_tokens=%d", settings.LLMModel, parsed.Usage.TotalTokens)
-	return content, &parsed.Usage, nil
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		var apiErr map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		return nil, &llmHTTPError{
+			StatusCode: resp.StatusCode,
+			Message:    stringifyError(apiErr),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	return resp, nil
+}
+
+// readSSE consumes a server-sent event stream and hands every data payload to fn
+// until the stream closes or the terminal [DONE] marker arrives.
+func readSSE(r io.Reader, fn func(data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ":") {
+			continue
+		}
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "" {
+			continue
+		}
+		if data == "[DONE]" {
+			return nil
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("读取 LLM 流式响应失败: %w", err)
+	}
+	return nil
 }
 
 // Usage represents token usage stats from LLM responses.
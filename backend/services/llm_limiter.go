@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLLMMaxConcurrency = 4
+	llmMaxRetries            = 3
+	llmMaxRetryDelay         = time.Minute
+)
+
+// llmRetryBaseDelay is the first backoff step for 429/503 retries; tests shorten it.
+var llmRetryBaseDelay = time.Second
+
+type llmPriorityKey struct{}
+
+// WithBackgroundPriority marks LLM calls made with ctx as background work. Callers
+// without the mark (HTTP handlers) are treated as interactive and served first.
+func WithBackgroundPriority(ctx context.Context) context.Context {
+	return context.WithValue(ctx, llmPriorityKey{}, true)
+}
+
+func isBackgroundPriority(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	background, _ := ctx.Value(llmPriorityKey{}).(bool)
+	return background
+}
+
+// llmLimiter bounds concurrent LLM requests and the request rate for the whole
+// process. Background callers wait while any interactive caller is queued.
+type llmLimiter struct {
+	mu                 sync.Mutex
+	inFlight           int
+	waitingInteractive int
+	recent             []time.Time
+	wake               chan struct{}
+}
+
+// sharedLLMLimiter is used by every LLMClient so separate services share one budget.
+var sharedLLMLimiter = newLLMLimiter()
+
+func newLLMLimiter() *llmLimiter {
+	return &llmLimiter{wake: make(chan struct{})}
+}
+
+// acquire blocks until a slot is free under maxInFlight and perMinute (0 means
+// no rate cap) and returns the function releasing it.
+func (l *llmLimiter) acquire(ctx context.Context, maxInFlight, perMinute int, background bool) (func(), error) {
+	if maxInFlight <= 0 {
+		maxInFlight = defaultLLMMaxConcurrency
+	}
+	l.mu.Lock()
+	if !background {
+		l.waitingInteractive++
+	}
+	for {
+		now := time.Now()
+		l.pruneLocked(now)
+		var wait time.Duration
+		ready := l.inFlight < maxInFlight && (!background || l.waitingInteractive == 0)
+		if ready && perMinute > 0 && len(l.recent) >= perMinute {
+			ready = false
+			wait = l.recent[len(l.recent)-perMinute].Add(time.Minute).Sub(now)
+		}
+		if ready {
+			if !background {
+				l.waitingInteractive--
+			}
+			l.inFlight++
+			if perMinute > 0 {
+				l.recent = append(l.recent, now)
+			}
+			l.mu.Unlock()
+			var once sync.Once
+			return func() { once.Do(l.release) }, nil
+		}
+
+		wake := l.wake
+		l.mu.Unlock()
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+		select {
+		case <-wake:
+		case <-timeout:
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			l.mu.Lock()
+			if !background {
+				l.waitingInteractive--
+			}
+			l.broadcastLocked()
+			l.mu.Unlock()
+			return nil, ctx.Err()
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		l.mu.Lock()
+	}
+}
+
+func (l *llmLimiter) release() {
+	l.mu.Lock()
+	l.inFlight--
+	l.broadcastLocked()
+	l.mu.Unlock()
+}
+
+func (l *llmLimiter) pruneLocked(now time.Time) {
+	cutoff := now.Add(-time.Minute)
+	idx := 0
+	for idx < len(l.recent) && !l.recent[idx].After(cutoff) {
+		idx++
+	}
+	if idx > 0 {
+		l.recent = append(l.recent[:0], l.recent[idx:]...)
+	}
+}
+
+func (l *llmLimiter) broadcastLocked() {
+	close(l.wake)
+	l.wake = make(chan struct{})
+}
+
+// shouldRetrySameModel reports whether a throttled request should be retried on
+// the same model after a backoff. 429 always qualifies; 503 only once no fallback
+// model is left, since another model is likely to answer straight away.
+func shouldRetrySameModel(err error, lastModel bool) bool {
+	var httpErr *llmHTTPError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+	switch httpErr.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusServiceUnavailable:
+		return lastModel
+	default:
+		return false
+	}
+}
+
+// retryDelay returns the wait before the given retry (0-based): the server's
+// Retry-After when present, exponential backoff otherwise.
+func retryDelay(err error, retry int) time.Duration {
+	var httpErr *llmHTTPError
+	if errors.As(err, &httpErr) && httpErr.RetryAfter > 0 {
+		if httpErr.RetryAfter > llmMaxRetryDelay {
+			return llmMaxRetryDelay
+		}
+		return httpErr.RetryAfter
+	}
+	delay := llmRetryBaseDelay << retry
+	if delay > llmMaxRetryDelay {
+		delay = llmMaxRetryDelay
+	}
+	return delay
+}
+
+// parseRetryAfter accepts both forms of the Retry-After header: delta seconds and an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+func TestChatRetriesRateLimitHonoringRetryAfter(t *testing.T) {
+	defer func(base time.Duration) { llmRetryBaseDelay = base }(llmRetryBaseDelay)
+	llmRetryBaseDelay = time.Millisecond
+
+	var calls []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, time.Now())
+		switch len(calls) {
+		case 1:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":{"message":"slow down"}}`)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"error":{"message":"overloaded"}}`)
+		default:
+			fmt.Fprint(w, `{"choices":[{"message":{"content":"ok"}}]}`)
+		}
+	}))
+	defer server.Close()
+
+	st := setupTestStore(t)
+	defer st.Close()
+	data, _ := json.Marshal(store.Settings{LLMBaseURL: server.URL, LLMAPIKey: "test", LLMModel: "m"})
+	if err := st.SaveSettings(context.Background(), bytes.NewReader(data)); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	client := NewLLMClient(st, server.Client())
+
+	content, _, err := client.Chat(context.Background(), []ChatMessage{{Role: "user", Content: "hi"}}, ChatOptions{})
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if content != "ok" || len(calls) != 3 {
+		t.Fatalf("expected success on third attempt, got %q after %d calls", content, len(calls))
+	}
+	if gap := calls[1].Sub(calls[0]); gap < time.Second {
+		t.Fatalf("Retry-After was not honored, retried after %s", gap)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("7"); got != 7*time.Second {
+		t.Fatalf("seconds form: %s", got)
+	}
+	date := time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(date); got < 25*time.Second || got > 31*time.Second {
+		t.Fatalf("date form: %s", got)
+	}
+	if got := parseRetryAfter("soon"); got != 0 {
+		t.Fatalf("invalid value should be ignored, got %s", got)
+	}
+}
+
+func TestLLMLimiterPrefersInteractive(t *testing.T) {
+	limiter := newLLMLimiter()
+	release, err := limiter.acquire(context.Background(), 1, 0, false)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	order := make(chan string, 2)
+	waiter := func(name string, background bool) {
+		done, err := limiter.acquire(context.Background(), 1, 0, background)
+		if err != nil {
+			order <- "error"
+			return
+		}
+		order <- name
+		done()
+	}
+	go waiter("background", true)
+	time.Sleep(20 * time.Millisecond)
+	go waiter("interactive", false)
+	waitFor(t, func() bool {
+		limiter.mu.Lock()
+		defer limiter.mu.Unlock()
+		return limiter.waitingInteractive == 1
+	})
+
+	release()
+	if first := <-order; first != "interactive" {
+		t.Fatalf("interactive caller should be served first, got %s", first)
+	}
+	if second := <-order; second != "background" {
+		t.Fatalf("background caller should follow, got %s", second)
+	}
+}
+
+func TestLLMLimiterRequestsPerMinute(t *testing.T) {
+	limiter := newLLMLimiter()
+	for i := 0; i < 2; i++ {
+		release, err := limiter.acquire(context.Background(), 5, 2, true)
+		if err != nil {
+			t.Fatalf("acquire %d: %v", i, err)
+		}
+		release()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.acquire(ctx, 5, 2, true); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("third request within a minute should wait, got %v", err)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
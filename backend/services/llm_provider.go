@@ -0,0 +1,598 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+const (
+	defaultAnthropicBaseURL = "https://api.anthropic.com/v1"
+	defaultAnthropicVersion = "2023-06-01"
+	defaultOllamaBaseURL    = "http://localhost:11434"
+	defaultAzureAPIVersion  = "2024-10-21"
+)
+
+// llmProvider adapts the generic chat request to a vendor specific wire protocol.
+type llmProvider interface {
+	// defaultBaseURL is used when the settings leave the base URL empty.
+	defaultBaseURL() string
+	// requiresAPIKey reports whether requests must carry credentials.
+	requiresAPIKey() bool
+	newRequest(ctx context.Context, settings *store.Settings, messages []ChatMessage, opts ChatOptions, stream bool) (*http.Request, error)
+	decodeResponse(r io.Reader) (ChatReply, error)
+	decodeStream(r io.Reader, onDelta func(string)) (Usage, error)
+}
+
+// providerFor returns the protocol adapter for the configured provider name.
+func providerFor(name string) llmProvider {
+	switch store.NormalizeLLMProvider(name) {
+	case store.LLMProviderAnthropic:
+		return anthropicProvider{}
+	case store.LLMProviderOllama:
+		return ollamaProvider{}
+	case store.LLMProviderAzure:
+		return azureProvider{}
+	default:
+		return openAIProvider{}
+	}
+}
+
+func chatLimits(opts ChatOptions) (int, float32) {
+	maxTokens := opts.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = 1024
+	}
+	temperature := opts.Temperature
+	if temperature < 0 {
+		temperature = 0.6
+	}
+	return maxTokens, temperature
+}
+
+func chatRole(role string) string {
+	role = strings.TrimSpace(role)
+	if role == "" {
+		return "user"
+	}
+	return role
+}
+
+func newJSONRequest(ctx context.Context, endpoint string, payload any, stream bool) (*http.Request, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("编码 LLM 请求失败: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("创建 LLM 请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	return req, nil
+}
+
+func joinLLMEndpoint(base string, elem ...string) (string, error) {
+	endpoint, err := url.JoinPath(strings.TrimRight(strings.TrimSpace(base), "/"), elem...)
+	if err != nil {
+		return "", fmt.Errorf("拼接 LLM 地址失败: %w", err)
+	}
+	return endpoint, nil
+}
+
+// openAIProvider speaks the OpenAI-compatible chat completions protocol.
+type openAIProvider struct{}
+
+func (openAIProvider) defaultBaseURL() string { return "" }
+
+func (openAIProvider) requiresAPIKey() bool { return true }
+
+func (p openAIProvider) newRequest(ctx context.Context, settings *store.Settings, messages []ChatMessage, opts ChatOptions, stream bool) (*http.Request, error) {
+	endpoint, err := joinLLMEndpoint(settings.LLMBaseURL, "chat/completions")
+	if err != nil {
+		return nil, err
+	}
+	req, err := newJSONRequest(ctx, endpoint, openAIPayload(settings.LLMModel, messages, opts, stream, true), stream)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", settings.LLMAPIKey))
+	return req, nil
+}
+
+func (openAIProvider) decodeResponse(r io.Reader) (ChatReply, error) {
+	var parsed struct {
+		Choices []struct {
+			Message struct {
+				Content   string `json:"content"`
+				ToolCalls []struct {
+					ID       string `json:"id"`
+					Function struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage Usage `json:"usage"`
+	}
+	if err := json.NewDecoder(r).Decode(&parsed); err != nil {
+		return ChatReply{}, fmt.Errorf("解析 LLM 响应失败: %w", err)
+	}
+	reply := ChatReply{Usage: parsed.Usage}
+	if len(parsed.Choices) == 0 {
+		return reply, nil
+	}
+	message := parsed.Choices[0].Message
+	reply.Content = message.Content
+	for _, call := range message.ToolCalls {
+		reply.ToolCalls = append(reply.ToolCalls, ToolCall{ID: call.ID, Name: call.Function.Name, Arguments: call.Function.Arguments})
+	}
+	return reply, nil
+}
+
+func (openAIProvider) decodeStream(r io.Reader, onDelta func(string)) (Usage, error) {
+	var usage Usage
+	err := readSSE(r, func(data string) error {
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Usage *Usage `json:"usage"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("解析 LLM 流式响应失败: %w", err)
+		}
+		if chunk.Usage != nil {
+			usage = *chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				onDelta(choice.Delta.Content)
+			}
+		}
+		return nil
+	})
+	return usage, err
+}
+
+func openAIPayload(model string, messages []ChatMessage, opts ChatOptions, stream, includeUsage bool) map[string]any {
+	reqMessages := make([]map[string]any, 0, len(messages))
+	for _, msg := range messages {
+		item := map[string]any{
+			"role":    chatRole(msg.Role),
+			"content": msg.Content,
+		}
+		if len(msg.ToolCalls) > 0 {
+			calls := make([]map[string]any, 0, len(msg.ToolCalls))
+			for _, call := range msg.ToolCalls {
+				calls = append(calls, map[string]any{
+					"id":   call.ID,
+					"type": "function",
+					"function": map[string]string{
+						"name":      call.Name,
+						"arguments": string(toolArguments(call)),
+					},
+				})
+			}
+			item["tool_calls"] = calls
+			if msg.Content == "" {
+				item["content"] = nil
+			}
+		}
+		if msg.ToolCallID != "" {
+			item["tool_call_id"] = msg.ToolCallID
+		}
+		reqMessages = append(reqMessages, item)
+	}
+	maxTokens, temperature := chatLimits(opts)
+	payload := map[string]any{
+		"model":       model,
+		"messages":    reqMessages,
+		"max_tokens":  maxTokens,
+		"temperature": temperature,
+		"stream":      stream,
+	}
+	if stream && includeUsage {
+		payload["stream_options"] = map[string]bool{"include_usage": true}
+	}
+	if opts.ResponseFormat != "" {
+		payload["response_format"] = map[string]string{"type": opts.ResponseFormat}
+	}
+	if len(opts.Tools) > 0 {
+		payload["tools"] = openAITools(opts.Tools)
+		if opts.ToolChoice != "" {
+			payload["tool_choice"] = opts.ToolChoice
+		}
+	}
+	return payload
+}
+
+// openAITools encodes tool definitions in the OpenAI function format, which
+// Ollama accepts as well.
+func openAITools(tools []ToolDefinition) []map[string]any {
+	encoded := make([]map[string]any, 0, len(tools))
+	for _, tool := range tools {
+		encoded = append(encoded, map[string]any{
+			"type": "function",
+			"function": map[string]any{
+				"name":        tool.Name,
+				"description": tool.Description,
+				"parameters":  toolParameters(tool),
+			},
+		})
+	}
+	return encoded
+}
+
+// azureProvider targets Azure OpenAI deployments, which share the OpenAI
+// response shape but use deployment paths, api-version and the api-key header.
+type azureProvider struct {
+	openAIProvider
+}
+
+func (p azureProvider) newRequest(ctx context.Context, settings *store.Settings, messages []ChatMessage, opts ChatOptions, stream bool) (*http.Request, error) {
+	base := strings.TrimRight(strings.TrimSpace(settings.LLMBaseURL), "/")
+	var (
+		endpoint string
+		err      error
+	)
+	if strings.Contains(base, "/openai/deployments/") {
+		endpoint, err = joinLLMEndpoint(base, "chat/completions")
+	} else {
+		endpoint, err = joinLLMEndpoint(base, "openai/deployments", settings.LLMModel, "chat/completions")
+	}
+	if err != nil {
+		return nil, err
+	}
+	apiVersion := strings.TrimSpace(settings.LLMAPIVersion)
+	if apiVersion == "" {
+		apiVersion = defaultAzureAPIVersion
+	}
+	endpoint += "?api-version=" + url.QueryEscape(apiVersion)
+
+	req, err := newJSONRequest(ctx, endpoint, openAIPayload(settings.LLMModel, messages, opts, stream, false), stream)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("api-key", settings.LLMAPIKey)
+	return req, nil
+}
+
+// anthropicProvider speaks the Anthropic Messages API.
+type anthropicProvider struct{}
+
+func (anthropicProvider) defaultBaseURL() string { return defaultAnthropicBaseURL }
+
+func (anthropicProvider) requiresAPIKey() bool { return true }
+
+func (anthropicProvider) newRequest(ctx context.Context, settings *store.Settings, messages []ChatMessage, opts ChatOptions, stream bool) (*http.Request, error) {
+	endpoint, err := joinLLMEndpoint(settings.LLMBaseURL, "messages")
+	if err != nil {
+		return nil, err
+	}
+
+	system, reqMessages := anthropicMessages(messages)
+	if len(reqMessages) == 0 {
+		return nil, fmt.Errorf("至少需要一条非 system 的对话消息")
+	}
+
+	maxTokens, temperature := chatLimits(opts)
+	payload := map[string]any{
+		"model":       settings.LLMModel,
+		"messages":    reqMessages,
+		"max_tokens":  maxTokens,
+		"temperature": temperature,
+		"stream":      stream,
+	}
+	if len(system) > 0 {
+		payload["system"] = strings.Join(system, "\n\n")
+	}
+	if len(opts.Tools) > 0 {
+		tools := make([]map[string]any, 0, len(opts.Tools))
+		for _, tool := range opts.Tools {
+			tools = append(tools, map[string]any{
+				"name":         tool.Name,
+				"description":  tool.Description,
+				"input_schema": toolParameters(tool),
+			})
+		}
+		payload["tools"] = tools
+		switch opts.ToolChoice {
+		case ToolChoiceRequired:
+			payload["tool_choice"] = map[string]string{"type": "any"}
+		case ToolChoiceNone, ToolChoiceAuto:
+			payload["tool_choice"] = map[string]string{"type": opts.ToolChoice}
+		}
+	}
+
+	req, err := newJSONRequest(ctx, endpoint, payload, stream)
+	if err != nil {
+		return nil, err
+	}
+	version := strings.TrimSpace(settings.LLMAPIVersion)
+	if version == "" {
+		version = defaultAnthropicVersion
+	}
+	req.Header.Set("x-api-key", settings.LLMAPIKey)
+	req.Header.Set("anthropic-version", version)
+	return req, nil
+}
+
+// anthropicMessages splits out system prompts and converts tool traffic into
+// content blocks: assistant tool calls become tool_use blocks and consecutive
+// tool results are merged into a single user message of tool_result blocks.
+func anthropicMessages(messages []ChatMessage) ([]string, []map[string]any) {
+	var system []string
+	reqMessages := make([]map[string]any, 0, len(messages))
+	for _, msg := range messages {
+		role := chatRole(msg.Role)
+		switch {
+		case role == "system":
+			system = append(system, msg.Content)
+		case role == "tool":
+			block := map[string]any{
+				"type":        "tool_result",
+				"tool_use_id": msg.ToolCallID,
+				"content":     msg.Content,
+			}
+			if n := len(reqMessages); n > 0 && reqMessages[n-1]["role"] == "user" {
+				if blocks, ok := reqMessages[n-1]["content"].([]map[string]any); ok {
+					reqMessages[n-1]["content"] = append(blocks, block)
+					continue
+				}
+			}
+			reqMessages = append(reqMessages, map[string]any{
+				"role":    "user",
+				"content": []map[string]any{block},
+			})
+		case len(msg.ToolCalls) > 0:
+			blocks := make([]map[string]any, 0, len(msg.ToolCalls)+1)
+			if msg.Content != "" {
+				blocks = append(blocks, map[string]any{"type": "text", "text": msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				blocks = append(blocks, map[string]any{
+					"type":  "tool_use",
+					"id":    call.ID,
+					"name":  call.Name,
+					"input": toolArguments(call),
+				})
+			}
+			reqMessages = append(reqMessages, map[string]any{"role": role, "content": blocks})
+		default:
+			reqMessages = append(reqMessages, map[string]any{
+				"role":    role,
+				"content": msg.Content,
+			})
+		}
+	}
+	return system, reqMessages
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+func (u anthropicUsage) toUsage() Usage {
+	return Usage{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      u.InputTokens + u.OutputTokens,
+	}
+}
+
+func (anthropicProvider) decodeResponse(r io.Reader) (ChatReply, error) {
+	var parsed struct {
+		Content []struct {
+			Type  string          `json:"type"`
+			Text  string          `json:"text"`
+			ID    string          `json:"id"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
+		} `json:"content"`
+		Usage anthropicUsage `json:"usage"`
+	}
+	if err := json.NewDecoder(r).Decode(&parsed); err != nil {
+		return ChatReply{}, fmt.Errorf("解析 LLM 响应失败: %w", err)
+	}
+	reply := ChatReply{Usage: parsed.Usage.toUsage()}
+	var content strings.Builder
+	for _, block := range parsed.Content {
+		switch block.Type {
+		case "text":
+			content.WriteString(block.Text)
+		case "tool_use":
+			reply.ToolCalls = append(reply.ToolCalls, ToolCall{ID: block.ID, Name: block.Name, Arguments: string(block.Input)})
+		}
+	}
+	reply.Content = content.String()
+	return reply, nil
+}
+
+func (anthropicProvider) decodeStream(r io.Reader, onDelta func(string)) (Usage, error) {
+	var usage anthropicUsage
+	err := readSSE(r, func(data string) error {
+		var event struct {
+			Type    string `json:"type"`
+			Message struct {
+				Usage anthropicUsage `json:"usage"`
+			} `json:"message"`
+			Delta struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"delta"`
+			Usage *anthropicUsage `json:"usage"`
+			Error map[string]any  `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("解析 LLM 流式响应失败: %w", err)
+		}
+		switch event.Type {
+		case "message_start":
+			usage.InputTokens = event.Message.Usage.InputTokens
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+				onDelta(event.Delta.Text)
+			}
+		case "message_delta":
+			if event.Usage != nil {
+				usage.OutputTokens = event.Usage.OutputTokens
+			}
+		case "error":
+			return fmt.Errorf("LLM 流式响应返回错误: %s", stringifyError(map[string]any{"error": event.Error}))
+		}
+		return nil
+	})
+	return usage.toUsage(), err
+}
+
+// ollamaProvider speaks the native Ollama /api/chat protocol.
+type ollamaProvider struct{}
+
+func (ollamaProvider) defaultBaseURL() string { return defaultOllamaBaseURL }
+
+func (ollamaProvider) requiresAPIKey() bool { return false }
+
+func (ollamaProvider) newRequest(ctx context.Context, settings *store.Settings, messages []ChatMessage, opts ChatOptions, stream bool) (*http.Request, error) {
+	// 兼容填写了 OpenAI 兼容地址（/v1）的情况，原生接口挂在根路径下。
+	base := strings.TrimSuffix(strings.TrimRight(strings.TrimSpace(settings.LLMBaseURL), "/"), "/v1")
+	endpoint, err := joinLLMEndpoint(base, "api/chat")
+	if err != nil {
+		return nil, err
+	}
+
+	reqMessages := make([]map[string]any, 0, len(messages))
+	for _, msg := range messages {
+		item := map[string]any{
+			"role":    chatRole(msg.Role),
+			"content": msg.Content,
+		}
+		if len(msg.ToolCalls) > 0 {
+			calls := make([]map[string]any, 0, len(msg.ToolCalls))
+			for _, call := range msg.ToolCalls {
+				calls = append(calls, map[string]any{
+					"function": map[string]any{"name": call.Name, "arguments": toolArguments(call)},
+				})
+			}
+			item["tool_calls"] = calls
+		}
+		if msg.Name != "" {
+			item["tool_name"] = msg.Name
+		}
+		reqMessages = append(reqMessages, item)
+	}
+	maxTokens, temperature := chatLimits(opts)
+	payload := map[string]any{
+		"model":    settings.LLMModel,
+		"messages": reqMessages,
+		"stream":   stream,
+		"options": map[string]any{
+			"temperature": temperature,
+			"num_predict": maxTokens,
+		},
+	}
+	if opts.ResponseFormat == "json_object" {
+		payload["format"] = "json"
+	}
+	// Ollama has no tool_choice; "none" is honored by not offering the tools.
+	if len(opts.Tools) > 0 && opts.ToolChoice != ToolChoiceNone {
+		payload["tools"] = openAITools(opts.Tools)
+	}
+
+	req, err := newJSONRequest(ctx, endpoint, payload, false)
+	if err != nil {
+		return nil, err
+	}
+	if key := strings.TrimSpace(settings.LLMAPIKey); key != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", key))
+	}
+	return req, nil
+}
+
+type ollamaChunk struct {
+	Message struct {
+		Content   string `json:"content"`
+		ToolCalls []struct {
+			Function struct {
+				Name      string          `json:"name"`
+				Arguments json.RawMessage `json:"arguments"`
+			} `json:"function"`
+		} `json:"tool_calls"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	Error           string `json:"error"`
+}
+
+func (c ollamaChunk) usage() Usage {
+	return Usage{
+		PromptTokens:     c.PromptEvalCount,
+		CompletionTokens: c.EvalCount,
+		TotalTokens:      c.PromptEvalCount + c.EvalCount,
+	}
+}
+
+func (ollamaProvider) decodeResponse(r io.Reader) (ChatReply, error) {
+	var parsed ollamaChunk
+	if err := json.NewDecoder(r).Decode(&parsed); err != nil {
+		return ChatReply{}, fmt.Errorf("解析 LLM 响应失败: %w", err)
+	}
+	if parsed.Error != "" {
+		return ChatReply{}, fmt.Errorf("LLM 接口返回错误: %s", parsed.Error)
+	}
+	reply := ChatReply{Content: parsed.Message.Content, Usage: parsed.usage()}
+	// Ollama does not issue call IDs; synthesize stable ones for the transcript.
+	for i, call := range parsed.Message.ToolCalls {
+		reply.ToolCalls = append(reply.ToolCalls, ToolCall{
+			ID:        fmt.Sprintf("call_%d", i),
+			Name:      call.Function.Name,
+			Arguments: string(call.Function.Arguments),
+		})
+	}
+	return reply, nil
+}
+
+// decodeStream reads Ollama's newline-delimited JSON stream.
+func (ollamaProvider) decodeStream(r io.Reader, onDelta func(string)) (Usage, error) {
+	var usage Usage
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var chunk ollamaChunk
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			return usage, fmt.Errorf("解析 LLM 流式响应失败: %w", err)
+		}
+		if chunk.Error != "" {
+			return usage, fmt.Errorf("LLM 流式响应返回错误: %s", chunk.Error)
+		}
+		if chunk.Message.Content != "" {
+			onDelta(chunk.Message.Content)
+		}
+		if chunk.Done {
+			return chunk.usage(), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return usage, fmt.Errorf("读取 LLM 流式响应失败: %w", err)
+	}
+	return usage, nil
+}
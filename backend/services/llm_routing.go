@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+// LLM task names used to route each workflow step to its configured model.
+const (
+	LLMTaskGrading       = "grading"
+	LLMTaskEnrichment    = "enrichment"
+	LLMTaskResearch      = "research"
+	LLMTaskAnalysis      = "analysis"
+	LLMTaskEmailInitial  = "email_initial"
+	LLMTaskEmailFollowup = "email_followup"
+	LLMTaskMonitor       = "monitor"
+)
+
+// llmHTTPError records a non-2xx response from the LLM endpoint.
+type llmHTTPError struct {
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration // parsed Retry-After header, zero when absent
+}
+
+func (e *llmHTTPError) Error() string {
+	return fmt.Sprintf("LLM 接口返回错误(%d): %s", e.StatusCode, e.Message)
+}
+
+// modelChain returns the ordered list of models to try for a task: the task
+// specific model (or the default model) followed by the configured fallbacks.
+func modelChain(settings *store.Settings, task string) []string {
+	primary := strings.TrimSpace(settings.LLMModel)
+	if task != "" {
+		if model := strings.TrimSpace(settings.LLMTaskModels[task]); model != "" {
+			primary = model
+		}
+	}
+	chain := []string{primary}
+	seen := map[string]struct{}{primary: {}}
+	for _, model := range settings.LLMFallbackModels {
+		model = strings.TrimSpace(model)
+		if model == "" {
+			continue
+		}
+		if _, ok := seen[model]; ok {
+			continue
+		}
+		seen[model] = struct{}{}
+		chain = append(chain, model)
+	}
+	return chain
+}
+
+// shouldFallback reports whether an error warrants retrying on the next model:
+// upstream 5xx responses and timeouts qualify, client errors do not.
+func shouldFallback(err error) bool {
+	if err == nil {
+		return false
+	}
+	var httpErr *llmHTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// withModel returns a shallow copy of settings targeting the given model.
+func withModel(settings *store.Settings, model string) *store.Settings {
+	clone := *settings
+	clone.LLMModel = model
+	return &clone
+}
@@ -4,7 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/anner/ai-foreign-trade-assistant/backend/store"
 )
@@ -32,3 +39,241 @@ func TestEnsureConfigured(t *testing.T) {
 		t.Fatalf("ensure configured unexpectedly failed: %v", err)
 	}
 }
+
+func TestChatStreamCollectsDeltas(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		if payload["stream"] != true {
+			t.Errorf("expected stream=true, got %v", payload["stream"])
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": keep-alive\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"{\\\"subject\\\":\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"\\\"Hi\\\"}\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":5,\"completion_tokens\":3,\"total_tokens\":8}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	st := setupTestStore(t)
+	defer st.Close()
+	data, _ := json.Marshal(store.Settings{LLMBaseURL: server.URL, LLMAPIKey: "test", LLMModel: "gpt"})
+	if err := st.SaveSettings(context.Background(), bytes.NewReader(data)); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	var deltas []string
+	client := NewLLMClient(st, server.Client())
+	content, usage, err := client.ChatStream(context.Background(), []ChatMessage{{Role: "user", Content: "hi"}}, ChatOptions{}, func(text string) {
+		deltas = append(deltas, text)
+	})
+	if err != nil {
+		t.Fatalf("chat stream: %v", err)
+	}
+	if content != `{"subject":"Hi"}` {
+		t.Fatalf("unexpected content %q", content)
+	}
+	if len(deltas) != 2 {
+		t.Fatalf("expected 2 deltas, got %d", len(deltas))
+	}
+	if usage == nil || usage.TotalTokens != 8 {
+		t.Fatalf("unexpected usage %#v", usage)
+	}
+}
+
+func TestConnectionAcrossProviders(t *testing.T) {
+	cases := []struct {
+		name     string
+		provider string
+		model    string
+		check    func(t *testing.T, r *http.Request, payload map[string]any)
+		response string
+	}{
+		{
+			name:     "anthropic",
+			provider: store.LLMProviderAnthropic,
+			model:    "claude-test",
+			check: func(t *testing.T, r *http.Request, payload map[string]any) {
+				if r.URL.Path != "/v1/messages" {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				if r.Header.Get("x-api-key") != "secret" || r.Header.Get("anthropic-version") == "" {
+					t.Errorf("missing anthropic headers: %v", r.Header)
+				}
+			},
+			response: `{"content":[{"type":"text","text":"pong"}],"usage":{"input_tokens":4,"output_tokens":2}}`,
+		},
+		{
+			name:     "ollama",
+			provider: store.LLMProviderOllama,
+			model:    "llama3",
+			check: func(t *testing.T, r *http.Request, payload map[string]any) {
+				if r.URL.Path != "/api/chat" {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				if payload["stream"] != false {
+					t.Errorf("expected stream=false, got %v", payload["stream"])
+				}
+			},
+			response: `{"message":{"role":"assistant","content":"pong"},"done":true,"prompt_eval_count":4,"eval_count":2}`,
+		},
+		{
+			name:     "azure",
+			provider: store.LLMProviderAzure,
+			model:    "gpt4o-deploy",
+			check: func(t *testing.T, r *http.Request, payload map[string]any) {
+				if r.URL.Path != "/v1/openai/deployments/gpt4o-deploy/chat/completions" {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				if r.URL.Query().Get("api-version") == "" {
+					t.Errorf("missing api-version query")
+				}
+				if r.Header.Get("api-key") != "secret" || r.Header.Get("Authorization") != "" {
+					t.Errorf("unexpected auth headers: %v", r.Header)
+				}
+			},
+			response: `{"choices":[{"message":{"content":"pong"}}],"usage":{"prompt_tokens":4,"completion_tokens":2,"total_tokens":6}}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var payload map[string]any
+				_ = json.NewDecoder(r.Body).Decode(&payload)
+				tc.check(t, r, payload)
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, tc.response)
+			}))
+			defer server.Close()
+
+			st := setupTestStore(t)
+			defer st.Close()
+			data, _ := json.Marshal(store.Settings{
+				LLMProvider: tc.provider,
+				LLMBaseURL:  server.URL + "/v1",
+				LLMAPIKey:   "secret",
+				LLMModel:    tc.model,
+			})
+			if err := st.SaveSettings(context.Background(), bytes.NewReader(data)); err != nil {
+				t.Fatalf("save: %v", err)
+			}
+
+			result, err := NewLLMClient(st, server.Client()).TestConnection(context.Background())
+			if err != nil {
+				t.Fatalf("test connection: %v", err)
+			}
+			if result["echo"] != "pong" || result["total_tokens"] != "6" || result["provider"] != tc.provider {
+				t.Fatalf("unexpected result %#v", result)
+			}
+		})
+	}
+}
+
+func TestChatRoutesTaskModelAndFallsBack(t *testing.T) {
+	var models []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		model, _ := payload["model"].(string)
+		models = append(models, model)
+		switch model {
+		case "fast-model":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"error":{"message":"overloaded"}}`)
+		case "bad-request":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"message":"invalid"}}`)
+		default:
+			fmt.Fprintf(w, `{"choices":[{"message":{"content":"from %s"}}]}`, model)
+		}
+	}))
+	defer server.Close()
+
+	st := setupTestStore(t)
+	defer st.Close()
+	data, _ := json.Marshal(store.Settings{
+		LLMBaseURL:        server.URL,
+		LLMAPIKey:         "test",
+		LLMModel:          "default-model",
+		LLMTaskModels:     map[string]string{LLMTaskGrading: "fast-model", LLMTaskAnalysis: "bad-request"},
+		LLMFallbackModels: []string{"backup-model"},
+	})
+	if err := st.SaveSettings(context.Background(), bytes.NewReader(data)); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	client := NewLLMClient(st, server.Client())
+	messages := []ChatMessage{{Role: "user", Content: "hi"}}
+
+	content, _, err := client.Chat(context.Background(), messages, ChatOptions{Task: LLMTaskGrading})
+	if err != nil {
+		t.Fatalf("chat: %v", err)
+	}
+	if content != "from backup-model" {
+		t.Fatalf("expected fallback content, got %q", content)
+	}
+	if len(models) != 2 || models[0] != "fast-model" || models[1] != "backup-model" {
+		t.Fatalf("unexpected model sequence %v", models)
+	}
+
+	models = nil
+	if _, _, err := client.Chat(context.Background(), messages, ChatOptions{Task: LLMTaskAnalysis}); err == nil {
+		t.Fatalf("expected client error to surface without fallback")
+	}
+	if len(models) != 1 {
+		t.Fatalf("4xx should not fall back, tried %v", models)
+	}
+
+	models = nil
+	content, _, err = client.Chat(context.Background(), messages, ChatOptions{})
+	if err != nil || content != "from default-model" {
+		t.Fatalf("default routing failed: %q %v", content, err)
+	}
+}
+
+func TestChatCancellationAbortsRequestWithoutFallback(t *testing.T) {
+	started := make(chan struct{}, 4)
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		_, _ = io.Copy(io.Discard, r.Body)
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	st := setupTestStore(t)
+	defer st.Close()
+	data, _ := json.Marshal(store.Settings{
+		LLMBaseURL:        server.URL,
+		LLMAPIKey:         "test",
+		LLMModel:          "primary",
+		LLMFallbackModels: []string{"backup"},
+	})
+	if err := st.SaveSettings(context.Background(), bytes.NewReader(data)); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	client := NewLLMClient(st, server.Client())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	begin := time.Now()
+	_, _, err := client.Chat(ctx, []ChatMessage{{Role: "user", Content: "hi"}}, ChatOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(begin); elapsed > 5*time.Second {
+		t.Fatalf("cancellation took %s", elapsed)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("cancelled call should not fall back, got %d requests", n)
+	}
+
+	if _, _, err := client.Chat(ctx, []ChatMessage{{Role: "user", Content: "hi"}}, ChatOptions{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected done ctx to be rejected up front, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+// Tool choice values for ChatOptions.ToolChoice.
+const (
+	ToolChoiceAuto     = "auto"
+	ToolChoiceNone     = "none"
+	ToolChoiceRequired = "required"
+)
+
+// ToolDefinition describes a function the model may call. Parameters is a JSON
+// Schema object describing the arguments; nil means the tool takes none.
+type ToolDefinition struct {
+	Name        string
+	Description string
+	Parameters  map[string]any
+}
+
+// ToolCall is one function invocation requested by the model. Arguments holds
+// the raw JSON object produced by the model.
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string
+}
+
+// ChatReply is a single non-streaming completion: the assistant text, the tool
+// calls it requested and the token usage of the turn.
+type ChatReply struct {
+	Content   string
+	ToolCalls []ToolCall
+	Usage     Usage
+}
+
+// Complete runs one chat turn with opts.Tools offered to the model. Unlike Chat,
+// a reply without text is valid as long as it requests tools, and replies are
+// never cached because tool results change between runs.
+func (c *LLMClient) Complete(ctx context.Context, messages []ChatMessage, opts ChatOptions) (*ChatReply, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	settings, err := c.ensureConfigured(ctx)
+	if err != nil {
+		return nil, err
+	}
+	provider := providerFor(settings.LLMProvider)
+
+	var reply *ChatReply
+	err = c.withFallback(ctx, settings, opts.Task, shouldFallback, func(attempt *store.Settings) error {
+		started := time.Now()
+		result, err := c.chatOnce(ctx, provider, attempt, messages, opts)
+		var usage *Usage
+		if result != nil {
+			usage = &result.Usage
+		}
+		if err == nil && result.Content == "" && len(result.ToolCalls) == 0 {
+			err = fmt.Errorf("LLM 未返回任何内容")
+		}
+		c.recordUsage(attempt, opts, usage, started, err)
+		reply = result
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// toolParameters returns the JSON Schema of a tool, defaulting to an empty object.
+func toolParameters(def ToolDefinition) map[string]any {
+	if def.Parameters != nil {
+		return def.Parameters
+	}
+	return map[string]any{"type": "object", "properties": map[string]any{}}
+}
+
+// toolArguments returns the call arguments as raw JSON, substituting an empty
+// object for blank or malformed input so providers always receive an object.
+func toolArguments(call ToolCall) json.RawMessage {
+	if json.Valid([]byte(call.Arguments)) {
+		return json.RawMessage(call.Arguments)
+	}
+	return json.RawMessage("{}")
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+const usageRecordTimeout = 5 * time.Second
+
+// recordUsage appends one call attempt to the token ledger. Failures are logged
+// rather than returned so that bookkeeping never breaks the caller.
+func (c *LLMClient) recordUsage(settings *store.Settings, opts ChatOptions, usage *Usage, started time.Time, callErr error) {
+	if c == nil || c.store == nil || settings == nil {
+		return
+	}
+	entry := store.LLMUsageEntry{
+		Task:       opts.Task,
+		Provider:   settings.LLMProvider,
+		Model:      settings.LLMModel,
+		CustomerID: opts.CustomerID,
+		LatencyMs:  time.Since(started).Milliseconds(),
+	}
+	if usage != nil {
+		entry.PromptTokens = usage.PromptTokens
+		entry.CompletionTokens = usage.CompletionTokens
+		entry.TotalTokens = usage.TotalTokens
+	}
+	if callErr != nil {
+		entry.Error = callErr.Error()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), usageRecordTimeout)
+	defer cancel()
+	if err := c.store.InsertLLMUsage(ctx, entry); err != nil {
+		log.Printf("[llm] record usage failed: %v", err)
+	}
+}
+
+// budgetGate lets background workers pause while the LLM token budget is
+// exhausted, logging only when the paused state changes.
+type budgetGate struct {
+	scope  string
+	mu     sync.Mutex
+	paused bool
+}
+
+// allow reports whether background work may proceed under the current budget.
+func (g *budgetGate) allow(ctx context.Context, st *store.Store) (bool, error) {
+	err := st.CheckLLMBudget(ctx)
+	exceeded := errors.Is(err, store.ErrLLMBudgetExceeded)
+	if err != nil && !exceeded {
+		return false, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if exceeded && !g.paused {
+		log.Printf("[%s] 已达到 LLM 预算上限，暂停处理: %v", g.scope, err)
+	} else if !exceeded && g.paused {
+		log.Printf("[%s] LLM 预算已恢复，继续处理队列", g.scope)
+	}
+	g.paused = exceeded
+	return !exceeded, nil
+}
@@ -6,8 +6,12 @@ import (
 	"fmt"
 	"html/template"
 	"log"
+	"net"
+	"net/mail"
+	"net/smtp"
 	"net/url"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,7 +24,8 @@ const mailTimeout = 20 * time.Second
 
 // SMTPMailer delivers emails using SMTP settings stored in SQLite.
 type SMTPMailer struct {
-	store *store.Store
+	store   *store.Store
+	proxies *ProxyRouter
 }
 
 // NewSMTPMailer creates a new SMTPMailer.
@@ -65,12 +70,9 @@ Thank you for your continued trust and cooperation.`
 		msg.SetBody("text/plain", plainBody)
 	}
 
-	dialer := gomail.NewDialer(inputs.host, inputs.port, inputs.username, inputs.password)
-	applyDialerSecurity(dialer, inputs)
-
 	done := make(chan error, 1)
 	go func() {
-		done <- dialer.DialAndSend(msg)
+		done <- m.deliver(ctx, settings, inputs, msg)
 	}()
 
 	select {
@@ -125,12 +127,9 @@ func (m *SMTPMailer) Send(ctx context.Context, to []string, subject, body string
 		msg.SetBody("text/plain", plainBody)
 	}
 
-	dialer := gomail.NewDialer(inputs.host, inputs.port, inputs.username, inputs.password)
-	applyDialerSecurity(dialer, inputs)
-
 	done := make(chan error, 1)
 	go func() {
-		done <- dialer.DialAndSend(msg)
+		done <- m.deliver(ctx, settings, inputs, msg)
 	}()
 
 	select {
@@ -146,6 +145,100 @@ func (m *SMTPMailer) Send(ctx context.Context, to []string, subject, body string
 	return messageID, nil
 }
 
+// deliver sends msg with gomail, or through a proxy tunnel when the SMTP
+// channel is configured to use one.
+func (m *SMTPMailer) deliver(ctx context.Context, settings *store.Settings, inputs *smtpInputs, msg *gomail.Message) error {
+	var cfg *proxyConfig
+	if m.proxies != nil {
+		var err error
+		if cfg, err = resolveProxy(settings, store.ProxyChannelSMTP); err != nil {
+			return fmt.Errorf("代理配置无效: %w", err)
+		}
+	}
+	addr := net.JoinHostPort(inputs.host, strconv.Itoa(inputs.port))
+	if cfg == nil || cfg.bypassed(addr) {
+		dialer := gomail.NewDialer(inputs.host, inputs.port, inputs.username, inputs.password)
+		applyDialerSecurity(dialer, inputs)
+		return dialer.DialAndSend(msg)
+	}
+	conn, err := cfg.dialContext(ctx, addr)
+	if err != nil {
+		return fmt.Errorf("通过代理连接 SMTP 服务器失败: %w", err)
+	}
+	return sendSMTPOverConn(ctx, conn, inputs, msg)
+}
+
+// sendSMTPOverConn runs the SMTP dialogue over an established connection,
+// mirroring gomail: implicit TLS for "ssl", STARTTLS when offered otherwise.
+func sendSMTPOverConn(ctx context.Context, conn net.Conn, inputs *smtpInputs, msg *gomail.Message) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if inputs.security == "ssl" {
+		tlsConn := tls.Client(conn, tlsConfigForHost(inputs.host))
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return fmt.Errorf("SMTP TLS 握手失败: %w", err)
+		}
+		conn = tlsConn
+	}
+	client, err := smtp.NewClient(conn, inputs.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if err := client.Hello("localhost"); err != nil {
+		return err
+	}
+	if inputs.security != "ssl" {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfigForHost(inputs.host)); err != nil {
+				return err
+			}
+		}
+	}
+	if ok, _ := client.Extension("AUTH"); ok && inputs.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", inputs.username, inputs.password, inputs.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(envelopeAddress(msg.GetHeader("From"))); err != nil {
+		return err
+	}
+	for _, field := range []string{"To", "Cc", "Bcc"} {
+		for _, rcpt := range msg.GetHeader(field) {
+			if err := client.Rcpt(envelopeAddress([]string{rcpt})); err != nil {
+				return err
+			}
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := msg.WriteTo(w); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func envelopeAddress(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	if addr, err := mail.ParseAddress(values[0]); err == nil {
+		return addr.Address
+	}
+	return strings.TrimSpace(values[0])
+}
+
 func (m *SMTPMailer) resolveSettings(ctx context.Context, overrides *store.Settings) (*store.Settings, error) {
 	if m == nil || m.store == nil {
 		if overrides == nil {
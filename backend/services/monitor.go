@@ -0,0 +1,568 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+// Website monitor limits.
+const (
+	monitorCrawlTimeout     = 90 * time.Second
+	monitorSummaryTimeout   = 60 * time.Second
+	monitorMaxPages         = 8
+	monitorBatchSize        = 5
+	monitorKeepSnapshots    = 10
+	monitorMinChangedLines  = 5
+	monitorMaxPageLines     = 400
+	monitorLineRunes        = 300
+	monitorDigestLines      = 40
+	monitorSnapshotFileTime = "20060102T150405.000000000Z"
+)
+
+// monitorKeywords steer the monitor crawl to the pages where product lines,
+// markets, hiring and news show up first.
+var monitorKeywords = []crawlKeywordGroup{
+	{[]string{"product", "solution", "catalog", "range", "portfolio", "produkte", "productos", "产品", "產品"}, 5},
+	{[]string{"news", "press", "media", "blog", "event", "aktuell", "noticias", "新闻", "新聞", "动态"}, 4},
+	{[]string{"career", "job", "vacanc", "join-us", "work-with-us", "karriere", "stellen", "empleo", "招聘", "加入我们"}, 4},
+	{[]string{"market", "industr", "application", "location", "office", "global", "worldwide", "市场", "市場", "应用"}, 3},
+	{[]string{"distributor", "dealer", "partner", "where-to-buy", "haendler", "经销", "代理"}, 2},
+	{[]string{"about", "company", "关于"}, 1},
+}
+
+// monitorHighlightWords mark added text that usually means business news.
+var monitorHighlightWords = []string{
+	"new product", "launch", "introducing", "now available", "new range", "new series",
+	"career", "job", "vacanc", "hiring", "we are looking for", "join our team",
+	"new market", "new office", "subsidiary", "expansion", "expand", "distributor",
+	"acquisition", "acquired", "trade fair", "exhibition",
+	"neuheit", "stellenangebot", "karriere",
+	"新品", "新产品", "发布", "招聘", "新市场", "展会", "分公司", "收购",
+}
+
+// monitorVolatileRegex matches text that changes without meaning anything:
+// copyright years, cookie banners, visitor counters and update stamps.
+var monitorVolatileRegex = regexp.MustCompile(`(?i)(©|&copy;|\(c\)\s*\d{4}|copyright|all rights reserved|cookie|last updated|updated on|visitors?:|版权所有)`)
+
+// monitorNoLetterRegex matches lines made only of digits, dates and punctuation.
+var monitorNoLetterRegex = regexp.MustCompile(`^[^\pL]*$`)
+
+// monitorBlockSelector lists the elements read as snapshot lines.
+const monitorBlockSelector = "h1, h2, h3, h4, h5, h6, p, li, dt, dd, th, td, figcaption, blockquote"
+
+// WebsiteMonitorImpl re-crawls the key pages of tracked customers, keeps text
+// snapshots under the data directory and records significant changes as
+// customer activities.
+type WebsiteMonitorImpl struct {
+	store   *store.Store
+	fetcher *WebFetcher
+	llm     *LLMClient
+	prompts *PromptServiceImpl
+	dir     string
+	budget  budgetGate
+
+	mu    sync.Mutex
+	locks map[int64]*sync.Mutex // per customer, so snapshots of a customer never interleave
+}
+
+// NewWebsiteMonitor constructs the website monitor. Snapshots are stored in dir.
+func NewWebsiteMonitor(st *store.Store, fetcher *WebFetcher, llm *LLMClient, prompts *PromptServiceImpl, dir string) *WebsiteMonitorImpl {
+	return &WebsiteMonitorImpl{store: st, fetcher: fetcher, llm: llm, prompts: prompts, dir: dir, budget: budgetGate{scope: "monitor"}}
+}
+
+// CheckDue checks the customers whose grade is monitored and whose last check
+// is older than the configured interval. It returns the number of customers
+// checked; nothing happens while monitoring is disabled.
+func (m *WebsiteMonitorImpl) CheckDue(ctx context.Context) (int, error) {
+	settings, err := m.store.GetSettings(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("读取配置失败: %w", err)
+	}
+	if !settings.MonitorEnabled {
+		return 0, nil
+	}
+	cutoff := time.Now().UTC().Add(-time.Duration(settings.MonitorIntervalHours) * time.Hour).Format(time.RFC3339)
+	targets, err := m.store.ListMonitorDueCustomers(ctx, strings.Split(settings.MonitorGrades, ","), cutoff, monitorBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	checked := 0
+	for _, target := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+		customer := &domain.Customer{ID: target.CustomerID, Name: target.Name, Website: target.Website, Grade: target.Grade}
+		if _, err := m.check(ctx, customer, settings); err != nil {
+			log.Printf("[monitor] 检查客户官网失败 customer=%d website=%s: %v", target.CustomerID, target.Website, err)
+		}
+		checked++
+	}
+	return checked, nil
+}
+
+// CheckCustomer checks one customer's website right away, whatever its grade.
+func (m *WebsiteMonitorImpl) CheckCustomer(ctx context.Context, customerID int64) (*domain.WebsiteCheckResult, error) {
+	customer, err := m.store.GetCustomer(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(customer.Website) == "" {
+		return nil, fmt.Errorf("客户没有官网地址")
+	}
+	settings, err := m.store.GetSettings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置失败: %w", err)
+	}
+	return m.check(ctx, customer, settings)
+}
+
+func (m *WebsiteMonitorImpl) check(ctx context.Context, customer *domain.Customer, settings *store.Settings) (*domain.WebsiteCheckResult, error) {
+	lock := m.customerLock(customer.ID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	snapshot, err := m.capture(ctx, customer)
+	if err != nil {
+		if recordErr := m.store.RecordMonitorCheck(ctx, customer.ID, false, err.Error()); recordErr != nil {
+			log.Printf("[monitor] 记录检查结果失败 customer=%d: %v", customer.ID, recordErr)
+		}
+		return nil, err
+	}
+	latest, previous, err := m.latestSnapshots(customer.ID)
+	if err != nil {
+		log.Printf("[monitor] 读取上次快照失败 customer=%d: %v", customer.ID, err)
+	}
+	// A crawl that reached far fewer pages than the last complete one most
+	// likely ran into failing fetches, so it is stored but not compared. A
+	// second short crawl in a row is taken as the site really shrinking.
+	if previous != nil && crawlFellShort(previous, snapshot) && (latest == nil || !latest.Partial) {
+		snapshot.Partial = true
+		log.Printf("[monitor] 本次抓取页面过少，跳过对比 customer=%d pages=%d previous=%d", customer.ID, len(snapshot.Pages), len(previous.Pages))
+	}
+	if err := m.saveSnapshot(snapshot); err != nil {
+		return nil, err
+	}
+
+	result := &domain.WebsiteCheckResult{
+		CustomerID: customer.ID,
+		CheckedAt:  snapshot.TakenAt,
+		Pages:      len(snapshot.Pages),
+		Baseline:   previous == nil,
+		Partial:    snapshot.Partial,
+	}
+	if previous != nil && !snapshot.Partial {
+		change := diffSnapshots(previous, snapshot)
+		result.Changed = !change.empty()
+		result.Significant = change.significant()
+		if result.Significant {
+			activity := m.changeActivity(ctx, customer, change, settings)
+			if _, err := m.store.CreateCustomerActivity(ctx, activity); err != nil {
+				return nil, err
+			}
+			result.Activity = activity
+			log.Printf("[monitor] 客户官网有重要变化 customer=%d new_pages=%d changed_lines=%d", customer.ID, len(change.NewPages), change.changedLines())
+		}
+	}
+	if err := m.store.RecordMonitorCheck(ctx, customer.ID, result.Significant, ""); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// customerLock returns the lock held while one customer is checked. Checks of
+// different customers run side by side.
+func (m *WebsiteMonitorImpl) customerLock(customerID int64) *sync.Mutex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.locks == nil {
+		m.locks = make(map[int64]*sync.Mutex)
+	}
+	lock, ok := m.locks[customerID]
+	if !ok {
+		lock = &sync.Mutex{}
+		m.locks[customerID] = lock
+	}
+	return lock
+}
+
+// capture crawls the customer's key pages and reduces each to its text lines.
+func (m *WebsiteMonitorImpl) capture(ctx context.Context, customer *domain.Customer) (*websiteSnapshot, error) {
+	if m.fetcher == nil {
+		return nil, fmt.Errorf("网页抓取服务未初始化")
+	}
+	snapshot := &websiteSnapshot{CustomerID: customer.ID, Website: customer.Website}
+	crawlCtx, cancel := context.WithTimeout(ctx, monitorCrawlTimeout)
+	defer cancel()
+	_, err := m.fetcher.Crawl(crawlCtx, customer.Website, CrawlOptions{
+		MaxPages: monitorMaxPages,
+		keywords: monitorKeywords,
+		onPage: func(pageURL string, doc *goquery.Document) {
+			snapshot.Pages = append(snapshot.Pages, snapshotPage{
+				URL:   pageURL,
+				Title: compactWhitespace(doc.Find("title").First().Text()),
+				Lines: snapshotLines(doc),
+			})
+		},
+		onLink: func(pageURL string) {
+			snapshot.Links = append(snapshot.Links, pageURL)
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("抓取客户官网失败: %w", err)
+	}
+	snapshot.TakenAt = time.Now().UTC().Format(time.RFC3339)
+	return snapshot, nil
+}
+
+func (m *WebsiteMonitorImpl) changeActivity(ctx context.Context, customer *domain.Customer, change *websiteChange, settings *store.Settings) *domain.CustomerActivity {
+	var parts []string
+	if n := len(change.NewPages); n > 0 {
+		parts = append(parts, fmt.Sprintf("新增 %d 个页面", n))
+	}
+	if n := change.changedLines(); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d 处内容变化", n))
+	}
+	digest := change.digest()
+	detail := digest
+	if settings.MonitorLLMSummary {
+		if summary, err := m.summarize(ctx, customer, digest); err != nil {
+			log.Printf("[monitor] 生成变化摘要失败 customer=%d: %v", customer.ID, err)
+		} else if summary != "" {
+			detail = summary + "\n\n---\n" + digest
+		}
+	}
+	return &domain.CustomerActivity{
+		CustomerID: customer.ID,
+		Kind:       domain.ActivityWebsiteChange,
+		Title:      "官网更新：" + strings.Join(parts, "，"),
+		Detail:     detail,
+	}
+}
+
+// summarize asks the LLM what the changes mean for sales. It returns an empty
+// summary while the LLM token budget is exhausted.
+func (m *WebsiteMonitorImpl) summarize(ctx context.Context, customer *domain.Customer, digest string) (string, error) {
+	if m.llm == nil || m.prompts == nil {
+		return "", nil
+	}
+	if ok, err := m.budget.allow(ctx, m.store); err != nil || !ok {
+		return "", err
+	}
+	messages, err := m.prompts.Messages(ctx, PromptMonitor, PromptData{Customer: customer, Changes: digest})
+	if err != nil {
+		return "", err
+	}
+	summaryCtx, cancel := context.WithTimeout(ctx, monitorSummaryTimeout)
+	defer cancel()
+	opts := ChatOptions{MaxTokens: 500, Temperature: 0.3, Task: LLMTaskMonitor, CustomerID: customer.ID}
+	content, _, err := m.llm.Chat(summaryCtx, messages, opts)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(content), nil
+}
+
+// websiteSnapshot is the stored text of one monitor check. Links lists every
+// page the crawl found through links and sitemaps, fetched or not; Partial
+// marks a crawl that fell short and was not compared.
+type websiteSnapshot struct {
+	CustomerID int64          `json:"customer_id"`
+	Website    string         `json:"website"`
+	TakenAt    string         `json:"taken_at"`
+	Pages      []snapshotPage `json:"pages"`
+	Links      []string       `json:"links,omitempty"`
+	Partial    bool           `json:"partial,omitempty"`
+}
+
+type snapshotPage struct {
+	URL   string   `json:"url"`
+	Title string   `json:"title,omitempty"`
+	Lines []string `json:"lines"`
+}
+
+func (m *WebsiteMonitorImpl) customerDir(customerID int64) (string, error) {
+	if strings.TrimSpace(m.dir) == "" {
+		return "", fmt.Errorf("未配置网站快照目录")
+	}
+	return filepath.Join(m.dir, fmt.Sprintf("%d", customerID)), nil
+}
+
+// snapshotFiles lists a customer's snapshot files, oldest first.
+func (m *WebsiteMonitorImpl) snapshotFiles(customerID int64) ([]string, error) {
+	dir, err := m.customerDir(customerID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// latestSnapshots returns the newest stored snapshot and the newest one that
+// is not partial. Either is nil if there is none.
+func (m *WebsiteMonitorImpl) latestSnapshots(customerID int64) (latest, complete *websiteSnapshot, err error) {
+	files, err := m.snapshotFiles(customerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := len(files) - 1; i >= 0; i-- {
+		raw, err := os.ReadFile(files[i])
+		if err != nil {
+			return latest, nil, err
+		}
+		var snapshot websiteSnapshot
+		if err := json.Unmarshal(raw, &snapshot); err != nil {
+			return latest, nil, fmt.Errorf("解析网站快照失败: %w", err)
+		}
+		if latest == nil {
+			latest = &snapshot
+		}
+		if !snapshot.Partial {
+			return latest, &snapshot, nil
+		}
+	}
+	return latest, nil, nil
+}
+
+// saveSnapshot writes the snapshot and prunes all but the newest
+// monitorKeepSnapshots files.
+func (m *WebsiteMonitorImpl) saveSnapshot(snapshot *websiteSnapshot) error {
+	dir, err := m.customerDir(snapshot.CustomerID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("创建快照目录失败: %w", err)
+	}
+	raw, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化网站快照失败: %w", err)
+	}
+	name := time.Now().UTC().Format(monitorSnapshotFileTime) + ".json"
+	if err := os.WriteFile(filepath.Join(dir, name), raw, 0o644); err != nil {
+		return fmt.Errorf("保存网站快照失败: %w", err)
+	}
+	files, err := m.snapshotFiles(snapshot.CustomerID)
+	if err != nil {
+		return nil
+	}
+	for len(files) > monitorKeepSnapshots {
+		if err := os.Remove(files[0]); err != nil {
+			log.Printf("[monitor] 删除旧快照失败 %s: %v", files[0], err)
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+// snapshotLines returns the distinct text blocks of a page in document order,
+// skipping containers whose text is already covered by a nested block and
+// lines that change on every visit.
+func snapshotLines(doc *goquery.Document) []string {
+	seen := make(map[string]struct{})
+	var lines []string
+	doc.Find("body").Find(monitorBlockSelector).EachWithBreak(func(_ int, sel *goquery.Selection) bool {
+		if sel.Find(monitorBlockSelector).Length() > 0 {
+			return true
+		}
+		line := truncateRunes(compactWhitespace(sel.Text()), monitorLineRunes)
+		if utf8.RuneCountInString(line) < 3 || monitorVolatileRegex.MatchString(line) || monitorNoLetterRegex.MatchString(line) {
+			return true
+		}
+		if _, ok := seen[line]; ok {
+			return true
+		}
+		seen[line] = struct{}{}
+		lines = append(lines, line)
+		return len(lines) < monitorMaxPageLines
+	})
+	return lines
+}
+
+// websiteChange is the difference between two snapshots. Pages missing from
+// the newer snapshot are ignored, as a crawl may skip a page for many reasons.
+// For the same reason a page only counts as new when the older crawl did not
+// find a link to it either.
+type websiteChange struct {
+	NewPages   []snapshotPage
+	Pages      []pageChange
+	Highlights []string
+}
+
+// pageChange lists the lines added to and removed from a page, ignoring order.
+type pageChange struct {
+	URL     string
+	Added   []string
+	Removed []string
+}
+
+func diffSnapshots(previous, current *websiteSnapshot) *websiteChange {
+	before := make(map[string]snapshotPage, len(previous.Pages))
+	for _, page := range previous.Pages {
+		before[snapshotPageKey(page.URL)] = page
+	}
+	linked := make(map[string]struct{}, len(previous.Links))
+	for _, link := range previous.Links {
+		linked[snapshotPageKey(link)] = struct{}{}
+	}
+	change := &websiteChange{}
+	for _, page := range current.Pages {
+		key := snapshotPageKey(page.URL)
+		old, ok := before[key]
+		if !ok {
+			if _, known := linked[key]; known {
+				// Linked before but not fetched; there is nothing to compare.
+				continue
+			}
+			change.NewPages = append(change.NewPages, page)
+			change.highlight(page.Title)
+			for _, line := range page.Lines {
+				change.highlight(line)
+			}
+			continue
+		}
+		added, removed := diffLines(old.Lines, page.Lines)
+		if len(added) == 0 && len(removed) == 0 {
+			continue
+		}
+		for _, line := range added {
+			change.highlight(line)
+		}
+		change.Pages = append(change.Pages, pageChange{URL: page.URL, Added: added, Removed: removed})
+	}
+	return change
+}
+
+// crawlFellShort reports whether current reached fewer than half the pages of
+// previous.
+func crawlFellShort(previous, current *websiteSnapshot) bool {
+	return len(current.Pages)*2 < len(previous.Pages)
+}
+
+// diffLines returns the lines only in current and the lines only in previous.
+func diffLines(previous, current []string) (added, removed []string) {
+	old := make(map[string]struct{}, len(previous))
+	for _, line := range previous {
+		old[line] = struct{}{}
+	}
+	now := make(map[string]struct{}, len(current))
+	for _, line := range current {
+		now[line] = struct{}{}
+		if _, ok := old[line]; !ok {
+			added = append(added, line)
+		}
+	}
+	for _, line := range previous {
+		if _, ok := now[line]; !ok {
+			removed = append(removed, line)
+		}
+	}
+	return added, removed
+}
+
+func (c *websiteChange) highlight(line string) {
+	lower := strings.ToLower(line)
+	for _, word := range monitorHighlightWords {
+		if strings.Contains(lower, word) {
+			if !containsString(c.Highlights, line) {
+				c.Highlights = append(c.Highlights, line)
+			}
+			return
+		}
+	}
+}
+
+func (c *websiteChange) changedLines() int {
+	total := 0
+	for _, page := range c.Pages {
+		total += len(page.Added) + len(page.Removed)
+	}
+	return total
+}
+
+func (c *websiteChange) empty() bool {
+	return len(c.NewPages) == 0 && len(c.Pages) == 0
+}
+
+// significant reports whether the change is worth an activity entry: a new
+// key page, text that reads like business news, or a larger rewrite.
+func (c *websiteChange) significant() bool {
+	return len(c.NewPages) > 0 || len(c.Highlights) > 0 || c.changedLines() >= monitorMinChangedLines
+}
+
+// digest renders the change as plain text, capped at monitorDigestLines lines.
+func (c *websiteChange) digest() string {
+	var lines []string
+	if len(c.Highlights) > 0 {
+		lines = append(lines, "值得关注：")
+		for _, line := range c.Highlights {
+			lines = append(lines, "* "+line)
+		}
+	}
+	if len(c.NewPages) > 0 {
+		lines = append(lines, "新页面：")
+		for _, page := range c.NewPages {
+			entry := "- " + page.URL
+			if page.Title != "" {
+				entry += "（" + page.Title + "）"
+			}
+			lines = append(lines, entry)
+		}
+	}
+	for _, page := range c.Pages {
+		lines = append(lines, fmt.Sprintf("页面 %s 的变化：", page.URL))
+		for _, line := range page.Added {
+			lines = append(lines, "+ "+line)
+		}
+		for _, line := range page.Removed {
+			lines = append(lines, "- "+line)
+		}
+	}
+	if len(lines) > monitorDigestLines {
+		rest := len(lines) - monitorDigestLines
+		lines = append(lines[:monitorDigestLines], fmt.Sprintf("……另有 %d 行未列出", rest))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// snapshotPageKey identifies a page across snapshots regardless of scheme,
+// "www." and trailing slashes.
+func snapshotPageKey(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	key := crawlHostKey(parsed.Host) + strings.TrimSuffix(parsed.EscapedPath(), "/")
+	if parsed.RawQuery != "" {
+		key += "?" + parsed.RawQuery
+	}
+	return key
+}
@@ -0,0 +1,328 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+func TestSnapshotLines(t *testing.T) {
+	doc := parseTestDocument(t, `<html><body>
+		<nav><ul><li><a href="/products">Products</a></li><li><a href="/news">News</a></li></ul></nav>
+		<h1>Industrial valves</h1>
+		<ul><li><p>Ball valves DN15 to DN600</p></li></ul>
+		<p>Industrial valves</p>
+		<p>Visitors: 120,331</p>
+		<p>2024-05-01</p>
+		<footer><p>© 2024 Acme GmbH. All rights reserved.</p><p>We use cookies to improve your experience.</p></footer>
+	</body></html>`)
+	want := []string{"Products", "News", "Industrial valves", "Ball valves DN15 to DN600"}
+	if got := snapshotLines(doc); !reflect.DeepEqual(got, want) {
+		t.Fatalf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	previous := &websiteSnapshot{Pages: []snapshotPage{
+		{URL: "https://acme.example/", Lines: []string{"Industrial valves", "Ball valves"}},
+		{URL: "https://acme.example/about", Lines: []string{"Founded 1962"}},
+	}}
+	current := &websiteSnapshot{Pages: []snapshotPage{
+		{URL: "https://www.acme.example/", Lines: []string{"Ball valves", "Industrial valves", "Launch of our new hydrogen valve series"}},
+		{URL: "https://acme.example/careers/", Title: "Careers", Lines: []string{"Sales engineer, Mexico"}},
+	}}
+	change := diffSnapshots(previous, current)
+	if len(change.NewPages) != 1 || change.NewPages[0].URL != "https://acme.example/careers/" {
+		t.Fatalf("new pages = %+v", change.NewPages)
+	}
+	if len(change.Pages) != 1 || !reflect.DeepEqual(change.Pages[0].Added, []string{"Launch of our new hydrogen valve series"}) || len(change.Pages[0].Removed) != 0 {
+		t.Fatalf("page changes = %+v", change.Pages)
+	}
+	if !reflect.DeepEqual(change.Highlights, []string{"Launch of our new hydrogen valve series", "Careers"}) {
+		t.Errorf("highlights = %q", change.Highlights)
+	}
+	if !change.significant() {
+		t.Errorf("change should be significant")
+	}
+	digest := change.digest()
+	for _, want := range []string{"值得关注：", "- https://acme.example/careers/（Careers）", "+ Launch of our new hydrogen valve series"} {
+		if !strings.Contains(digest, want) {
+			t.Errorf("digest missing %q:\n%s", want, digest)
+		}
+	}
+
+	reordered := &websiteSnapshot{Pages: []snapshotPage{{URL: "https://acme.example", Lines: []string{"Ball valves", "Industrial valves"}}}}
+	if change := diffSnapshots(previous, reordered); !change.empty() {
+		t.Errorf("reordering and a missing page should not count as change: %+v", change)
+	}
+	linked := &websiteSnapshot{Pages: previous.Pages, Links: []string{"https://acme.example/careers"}}
+	if change := diffSnapshots(linked, current); len(change.NewPages) != 0 {
+		t.Errorf("a page linked from the previous crawl should not count as new: %+v", change.NewPages)
+	}
+	minor := &websiteSnapshot{Pages: []snapshotPage{{URL: "https://acme.example/", Lines: []string{"Industrial valves", "Ball valves", "Butterfly valves"}}}}
+	if change := diffSnapshots(previous, minor); change.empty() || change.significant() {
+		t.Errorf("a single added line should be a minor change: %+v", change)
+	}
+}
+
+func TestWebsiteMonitorRecordsSignificantChanges(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		version = 1
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		v := version
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/robots.txt", "/sitemap.xml":
+			http.NotFound(w, r)
+		case "/":
+			nav := `<li><a href="/products">Products</a></li>`
+			if v >= 3 {
+				nav += `<li><a href="/careers">Careers</a></li>`
+			}
+			fmt.Fprintf(w, `<html><body><ul>%s</ul><h1>Acme valves</h1><p>Valves for refineries.</p><footer><p>© %d Acme</p></footer></body></html>`, nav, 2023+v)
+		case "/products":
+			fmt.Fprint(w, `<html><body><ul><li>Ball valves</li><li>Gate valves</li></ul></body></html>`)
+		case "/careers":
+			fmt.Fprint(w, `<html><head><title>Careers at Acme</title></head><body><p>We are hiring a sales engineer for our new office in Mexico.</p></body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	st, err := store.Open(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer st.Close()
+	if err := st.InitSchema(ctx); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+	if err := st.SaveSettings(ctx, strings.NewReader(`{"monitor_enabled": true, "monitor_grades": "a"}`)); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+	customerID, err := st.CreateCustomer(ctx, &domain.CreateCompanyRequest{Name: "Acme", Website: server.URL})
+	if err != nil {
+		t.Fatalf("create customer: %v", err)
+	}
+	if err := st.UpdateCustomerGrade(ctx, customerID, "A", "key account"); err != nil {
+		t.Fatalf("grade customer: %v", err)
+	}
+
+	dir := t.TempDir()
+	monitor := NewWebsiteMonitor(st, NewWebFetcher(server.Client()), nil, nil, dir)
+	checked, err := monitor.CheckDue(ctx)
+	if err != nil || checked != 1 {
+		t.Fatalf("CheckDue = %d, %v; want the A customer checked", checked, err)
+	}
+	if checked, _ := monitor.CheckDue(ctx); checked != 0 {
+		t.Fatalf("customer checked again before the interval passed")
+	}
+
+	// Only the copyright year changes.
+	mu.Lock()
+	version = 2
+	mu.Unlock()
+	result, err := monitor.CheckCustomer(ctx, customerID)
+	if err != nil {
+		t.Fatalf("CheckCustomer: %v", err)
+	}
+	if result.Baseline || result.Changed || result.Activity != nil {
+		t.Fatalf("volatile change reported: %+v", result)
+	}
+
+	mu.Lock()
+	version = 3
+	mu.Unlock()
+	result, err = monitor.CheckCustomer(ctx, customerID)
+	if err != nil {
+		t.Fatalf("CheckCustomer: %v", err)
+	}
+	if !result.Significant || result.Activity == nil {
+		t.Fatalf("new careers page not reported: %+v", result)
+	}
+	activities, err := st.ListCustomerActivities(ctx, customerID, 10)
+	if err != nil {
+		t.Fatalf("list activities: %v", err)
+	}
+	if len(activities) != 1 || activities[0].Kind != domain.ActivityWebsiteChange {
+		t.Fatalf("activities = %+v", activities)
+	}
+	for _, want := range []string{server.URL + "/careers", "We are hiring a sales engineer", "+ Careers"} {
+		if !strings.Contains(activities[0].Detail, want) {
+			t.Errorf("activity detail missing %q:\n%s", want, activities[0].Detail)
+		}
+	}
+
+	files, err := os.ReadDir(filepath.Join(dir, fmt.Sprint(customerID)))
+	if err != nil || len(files) != 3 {
+		t.Fatalf("snapshots = %d, %v; want 3", len(files), err)
+	}
+}
+
+func TestWebsiteMonitorIgnoresFailedFetches(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		failCareers = true
+		failRobots  = false
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		careersDown, robotsDown := failCareers, failRobots
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/robots.txt":
+			if robotsDown {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			http.NotFound(w, r)
+		case "/":
+			fmt.Fprint(w, `<html><body><ul><li><a href="/products">Products</a></li><li><a href="/news">News</a></li><li><a href="/careers">Careers</a></li></ul><h1>Acme valves</h1></body></html>`)
+		case "/products":
+			fmt.Fprint(w, `<html><body><ul><li>Ball valves</li><li>Gate valves</li></ul></body></html>`)
+		case "/news":
+			fmt.Fprint(w, `<html><body><p>Acme at the Hannover fair.</p></body></html>`)
+		case "/careers":
+			if careersDown {
+				http.Error(w, "boom", http.StatusInternalServerError)
+				return
+			}
+			fmt.Fprint(w, `<html><head><title>Careers at Acme</title></head><body><p>We are hiring a sales engineer.</p></body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	st := setupTestStore(t)
+	defer st.Close()
+	customerID, err := st.CreateCustomer(ctx, &domain.CreateCompanyRequest{Name: "Acme", Website: server.URL})
+	if err != nil {
+		t.Fatalf("create customer: %v", err)
+	}
+	monitor := NewWebsiteMonitor(st, NewWebFetcher(server.Client()), nil, nil, t.TempDir())
+	check := func(step string) *domain.WebsiteCheckResult {
+		t.Helper()
+		result, err := monitor.CheckCustomer(ctx, customerID)
+		if err != nil {
+			t.Fatalf("%s: CheckCustomer: %v", step, err)
+		}
+		return result
+	}
+
+	// The careers page fails in the baseline crawl, so it only shows up later.
+	if result := check("baseline"); !result.Baseline || result.Pages != 3 {
+		t.Fatalf("baseline = %+v, want 3 pages", result)
+	}
+	mu.Lock()
+	failCareers = false
+	mu.Unlock()
+	if result := check("recovered"); result.Pages != 4 || result.Changed || result.Activity != nil {
+		t.Fatalf("page that failed to fetch reported as new: %+v", result)
+	}
+
+	// robots.txt fails and the crawl stops at the start page.
+	mu.Lock()
+	failRobots = true
+	mu.Unlock()
+	if result := check("robots down"); !result.Partial || result.Changed || result.Activity != nil {
+		t.Fatalf("short crawl compared: %+v", result)
+	}
+	mu.Lock()
+	failRobots = false
+	mu.Unlock()
+	if result := check("robots back"); result.Partial || result.Changed || result.Activity != nil {
+		t.Fatalf("pages reported after a short crawl: %+v", result)
+	}
+
+	activities, err := st.ListCustomerActivities(ctx, customerID, 10)
+	if err != nil {
+		t.Fatalf("list activities: %v", err)
+	}
+	if len(activities) != 0 {
+		t.Fatalf("activities = %+v, want none", activities)
+	}
+}
+
+func TestWebsiteMonitorLocksPerCustomer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><h1>Acme valves</h1></body></html>`)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	st := setupTestStore(t)
+	defer st.Close()
+	customerID, err := st.CreateCustomer(ctx, &domain.CreateCompanyRequest{Name: "Acme", Website: server.URL})
+	if err != nil {
+		t.Fatalf("create customer: %v", err)
+	}
+	monitor := NewWebsiteMonitor(st, NewWebFetcher(server.Client()), nil, nil, t.TempDir())
+
+	// A long check of another customer must not hold this one up.
+	busy := monitor.customerLock(customerID + 1)
+	busy.Lock()
+	defer busy.Unlock()
+	done := make(chan error, 1)
+	go func() {
+		_, err := monitor.CheckCustomer(ctx, customerID)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("CheckCustomer: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("check waited for another customer")
+	}
+}
+
+func TestMonitorSummaryRespectsBudget(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, `{"choices":[{"message":{"content":"Hiring in Mexico."}}]}`)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	st := setupTestStore(t)
+	defer st.Close()
+	settings := fmt.Sprintf(`{"llm_base_url": %q, "llm_api_key": "test", "llm_model": "gpt", "llm_daily_token_budget": 100}`, server.URL)
+	if err := st.SaveSettings(ctx, strings.NewReader(settings)); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+	monitor := NewWebsiteMonitor(st, nil, NewLLMClient(st, server.Client()), NewPromptService(st), t.TempDir())
+	customer := &domain.Customer{ID: 1, Name: "Acme", Website: "https://acme.example"}
+
+	if summary, err := monitor.summarize(ctx, customer, "+ Careers"); err != nil || summary != "Hiring in Mexico." {
+		t.Fatalf("summary = %q, %v", summary, err)
+	}
+	if err := st.InsertLLMUsage(ctx, store.LLMUsageEntry{Task: LLMTaskMonitor, TotalTokens: 150}); err != nil {
+		t.Fatalf("insert usage: %v", err)
+	}
+	if summary, err := monitor.summarize(ctx, customer, "+ Jobs"); err != nil || summary != "" {
+		t.Fatalf("summary over budget = %q, %v", summary, err)
+	}
+	if calls != 1 {
+		t.Fatalf("LLM called %d times, want 1", calls)
+	}
+}
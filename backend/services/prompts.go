@@ -0,0 +1,495 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+// Prompt template names.
+const (
+	PromptGrading       = "grading"
+	PromptAnalysis      = "analysis"
+	PromptEmailInitial  = "email_initial"
+	PromptEmailFollowup = "email_followup"
+	PromptEnrichment    = "enrichment"
+	PromptResearch      = "research"
+	PromptMonitor       = "monitor"
+)
+
+// PromptData is the data made available to prompt templates.
+type PromptData struct {
+	Customer       *domain.Customer
+	Contacts       []domain.Contact
+	KeyContact     string
+	Analysis       *domain.AnalysisContent
+	ContextEmail   *domain.EmailRecord
+	Settings       PromptSettings
+	Guideline      string
+	Query          string
+	Materials      string
+	WebsiteSignals string
+	Changes        string
+}
+
+// PromptSettings exposes the non-sensitive settings to templates.
+type PromptSettings struct {
+	MyCompanyName   string
+	MyProduct       string
+	RatingGuideline string
+}
+
+func promptSettingsFrom(settings *store.Settings) PromptSettings {
+	if settings == nil {
+		return PromptSettings{}
+	}
+	return PromptSettings{
+		MyCompanyName:   strings.TrimSpace(settings.MyCompanyName),
+		MyProduct:       strings.TrimSpace(settings.MyProduct),
+		RatingGuideline: strings.TrimSpace(settings.RatingGuideline),
+	}
+}
+
+// PromptServiceImpl renders prompts from the versioned template store, falling
+// back to the built-in defaults when no version has been saved.
+type PromptServiceImpl struct {
+	store *store.Store
+}
+
+// NewPromptService constructs the prompt template service.
+func NewPromptService(st *store.Store) *PromptServiceImpl {
+	return &PromptServiceImpl{store: st}
+}
+
+var promptFuncs = template.FuncMap{
+	"trim": strings.TrimSpace,
+	"join": strings.Join,
+}
+
+// List returns the active version of every known prompt.
+func (p *PromptServiceImpl) List(ctx context.Context) ([]domain.PromptTemplate, error) {
+	items := make([]domain.PromptTemplate, 0, len(promptOrder))
+	for _, name := range promptOrder {
+		tpl, err := p.active(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *tpl)
+	}
+	return items, nil
+}
+
+// Versions lists stored versions newest first, followed by the built-in default as version 0.
+func (p *PromptServiceImpl) Versions(ctx context.Context, name string) ([]domain.PromptTemplate, error) {
+	builtin, err := builtinPrompt(name)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := p.store.ListPromptTemplateVersions(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	for i := range versions {
+		versions[i].Title = builtin.Title
+	}
+	return append(versions, *builtin), nil
+}
+
+// Save validates and stores a new version of the prompt.
+func (p *PromptServiceImpl) Save(ctx context.Context, name string, req *domain.PromptTemplateRequest) (*domain.PromptTemplate, error) {
+	builtin, err := builtinPrompt(name)
+	if err != nil {
+		return nil, err
+	}
+	if req == nil || strings.TrimSpace(req.UserPrompt) == "" {
+		return nil, fmt.Errorf("用户提示词不能为空")
+	}
+	if _, _, err := renderPromptPair(name, req.SystemPrompt, req.UserPrompt, PromptData{}); err != nil {
+		return nil, err
+	}
+	tpl, err := p.store.CreatePromptTemplateVersion(ctx, name, req.SystemPrompt, req.UserPrompt, req.Note)
+	if err != nil {
+		return nil, err
+	}
+	tpl.Title = builtin.Title
+	return tpl, nil
+}
+
+// Rollback re-activates an earlier version by copying it as the newest one;
+// version 0 restores the built-in default.
+func (p *PromptServiceImpl) Rollback(ctx context.Context, name string, version int) (*domain.PromptTemplate, error) {
+	var source *domain.PromptTemplate
+	var err error
+	if version == 0 {
+		source, err = builtinPrompt(name)
+	} else {
+		if _, err = builtinPrompt(name); err != nil {
+			return nil, err
+		}
+		source, err = p.store.GetPromptTemplateVersion(ctx, name, version)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p.Save(ctx, name, &domain.PromptTemplateRequest{
+		SystemPrompt: source.SystemPrompt,
+		UserPrompt:   source.UserPrompt,
+		Note:         fmt.Sprintf("回滚至版本 %d", version),
+	})
+}
+
+// Reset drops every stored version so the built-in default applies again.
+func (p *PromptServiceImpl) Reset(ctx context.Context, name string) error {
+	if _, err := builtinPrompt(name); err != nil {
+		return err
+	}
+	return p.store.DeletePromptTemplates(ctx, name)
+}
+
+// Preview renders a prompt against a real customer. Unsaved template text in the
+// request takes precedence over the active version.
+func (p *PromptServiceImpl) Preview(ctx context.Context, name string, req *domain.PromptPreviewRequest) (*domain.PromptPreview, error) {
+	if req == nil || req.CustomerID <= 0 {
+		return nil, fmt.Errorf("请选择用于预览的客户")
+	}
+	tpl, err := p.active(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	systemText, userText := tpl.SystemPrompt, tpl.UserPrompt
+	if strings.TrimSpace(req.SystemPrompt) != "" {
+		systemText = req.SystemPrompt
+	}
+	if strings.TrimSpace(req.UserPrompt) != "" {
+		userText = req.UserPrompt
+	}
+
+	data, err := p.customerData(ctx, req.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+	system, user, err := renderPromptPair(name, systemText, userText, data)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.PromptPreview{Name: name, SystemPrompt: system, UserPrompt: user}, nil
+}
+
+// Messages renders the active template into the system and user chat messages.
+func (p *PromptServiceImpl) Messages(ctx context.Context, name string, data PromptData) ([]ChatMessage, error) {
+	tpl, err := p.active(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	system, user, err := renderPromptPair(name, tpl.SystemPrompt, tpl.UserPrompt, data)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]ChatMessage, 0, 2)
+	if strings.TrimSpace(system) != "" {
+		messages = append(messages, ChatMessage{Role: "system", Content: system})
+	}
+	return append(messages, ChatMessage{Role: "user", Content: user}), nil
+}
+
+func (p *PromptServiceImpl) active(ctx context.Context, name string) (*domain.PromptTemplate, error) {
+	builtin, err := builtinPrompt(name)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil || p.store == nil {
+		return builtin, nil
+	}
+	stored, err := p.store.GetActivePromptTemplate(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return builtin, nil
+	}
+	stored.Title = builtin.Title
+	return stored, nil
+}
+
+// customerData assembles template data for previews from what is persisted for a customer.
+func (p *PromptServiceImpl) customerData(ctx context.Context, customerID int64) (PromptData, error) {
+	customer, err := p.store.GetCustomer(ctx, customerID)
+	if err != nil {
+		return PromptData{}, err
+	}
+	contacts, err := p.store.ListContacts(ctx, customerID)
+	if err != nil {
+		return PromptData{}, err
+	}
+	settings, err := p.store.GetSettings(ctx)
+	if err != nil {
+		return PromptData{}, fmt.Errorf("读取配置失败: %w", err)
+	}
+	data := PromptData{
+		Customer:       customer,
+		Contacts:       contacts,
+		KeyContact:     keyContactName(contacts),
+		Settings:       promptSettingsFrom(settings),
+		Guideline:      ratingGuideline(settings),
+		Query:          customer.Name,
+		Materials:      "(预览：实际调用时此处为搜索结果、官网抓取内容与背景信息)",
+		WebsiteSignals: formatWebsiteSignals(customer.WebsiteSignals),
+	}
+	if analysis, err := p.store.GetLatestAnalysis(ctx, customerID); err == nil {
+		data.Analysis = &analysis.AnalysisContent
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return PromptData{}, err
+	}
+	if draft, err := p.store.GetLatestEmailDraft(ctx, customerID, "initial"); err == nil {
+		data.ContextEmail = &domain.EmailRecord{ID: draft.EmailID, CustomerID: customerID, Type: "initial", Subject: draft.Subject, Body: draft.Body}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return PromptData{}, err
+	}
+	return data, nil
+}
+
+func renderPromptPair(name, systemText, userText string, data PromptData) (string, string, error) {
+	if data.Customer == nil {
+		data.Customer = &domain.Customer{}
+	}
+	if data.Analysis == nil {
+		data.Analysis = &domain.AnalysisContent{}
+	}
+	if data.ContextEmail == nil {
+		data.ContextEmail = &domain.EmailRecord{}
+	}
+	system, err := renderPrompt(name+".system", systemText, data)
+	if err != nil {
+		return "", "", err
+	}
+	user, err := renderPrompt(name+".user", userText, data)
+	if err != nil {
+		return "", "", err
+	}
+	return system, user, nil
+}
+
+func renderPrompt(name, text string, data PromptData) (string, error) {
+	tpl, err := template.New(name).Funcs(promptFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("解析提示词模板 %s 失败: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("渲染提示词模板 %s 失败: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+func builtinPrompt(name string) (*domain.PromptTemplate, error) {
+	def, ok := defaultPrompts[strings.TrimSpace(name)]
+	if !ok {
+		return nil, fmt.Errorf("未知的提示词模板: %s", name)
+	}
+	tpl := def
+	tpl.Name = name
+	tpl.BuiltIn = true
+	return &tpl, nil
+}
+
+// keyContactName picks the salutation target for outreach emails.
+func keyContactName(contacts []domain.Contact) string {
+	if len(contacts) > 0 {
+		key := contacts[0]
+		if key.Name != "" {
+			return key.Name
+		}
+		if key.Email != "" {
+			return key.Email
+		}
+	}
+	return "there"
+}
+
+// ratingGuideline returns the configured guideline or the built-in default.
+func ratingGuideline(settings *store.Settings) string {
+	if settings != nil {
+		if guideline := strings.TrimSpace(settings.RatingGuideline); guideline != "" {
+			return guideline
+		}
+	}
+	return defaultRatingGuideline
+}
+
+var promptOrder = []string{PromptResearch, PromptEnrichment, PromptGrading, PromptAnalysis, PromptEmailInitial, PromptEmailFollowup, PromptMonitor}
+
+var defaultPrompts = map[string]domain.PromptTemplate{
+	PromptResearch: {
+		Title:        "背景检索",
+		SystemPrompt: "You are a seasoned market analyst. Provide factual, concise public information about companies. Prefer verifiable knowledge and mention when uncertain. 使用中文输出",
+		UserPrompt:   "请提供关于公司「{{trim .Query}}」的公开信息，包括主要业务、所在国家、官网（如知道）以及其他有参考价值的事实。",
+	},
+	PromptEnrichment: {
+		Title:        "客户信息解析",
+		SystemPrompt: "You are a senior B2B market intelligence analyst. Your mission is to synthesize web search results, prior knowledge, and website content into a structured company profile for a sales team. Prioritize accuracy and identify key decision-makers. 使用中文输出",
+		UserPrompt: `{{.Materials}}
+### Instructions:
+Based on all the material provided, please perform the following steps:
+1.  **Identify the most credible official website.**
+2.  **Determine the company's country of operation.**
+3.  **Extract key contact persons, especially those in leadership or procurement roles.**
+4.  **Write a concise summary focusing on their business model and target market.**
+5.  **Output a clean JSON object.**
+
+Structured company data published by the website itself is high-trust evidence; prefer it over search snippets when they conflict.
+
+### JSON Output Format:
+{
+  "website": "The normalized official URL (https://...)",
+  "website_confidence": 0.95,
+  "country": "...",
+  "contacts": [
+    {
+      "name": "...",
+      "title": "...",
+      "email": "...",
+      "is_key_decision_maker": true,
+      "source": "e.g., Website 'About Us' page, Search Snippet 1"
+    }
+  ],
+  "summary": "A 100-150 word summary in Chinese, focusing on their core business, scale, and primary customer base.",
+  "candidates": [
+    {
+      "url": "...",
+      "title": "...",
+      "rank": 2,
+      "reason": "e.g., Appears to be a regional distributor site, not corporate HQ."
+    }
+  ]
+}
+`,
+	},
+	PromptGrading: {
+		Title:        "客户评级",
+		SystemPrompt: "You are a B2B sales strategist specializing in customer segmentation. Your task is to provide a precise customer rating (A, B, or C) based on the user's guideline. You must justify your rating by listing clear positive and negative signals.\n使用中文输出",
+		UserPrompt: `### Customer Profile:
+- Name: {{trim .Customer.Name}}
+- Website: {{trim .Customer.Website}}
+- Country: {{trim .Customer.Country}}
+- Summary: {{trim .Customer.Summary}}
+{{- if .WebsiteSignals}}
+
+### Website Signals (detected automatically on the customer's website):
+{{.WebsiteSignals}}
+{{- end}}
+
+### Rating Guideline:
+{{trim .Guideline}}
+
+### Rating Examples (for calibration):
+- **Example of an 'A' Grade Customer**: A large manufacturer in a target industry with over 200 employees and clear demand for our type of product.
+- **Example of a 'C' Grade Customer**: A small trading company with a generic website, unclear business focus, and located in a high-risk region.
+
+### Instructions:
+Analyze the customer profile against the guideline and examples. Treat the website signals as objective evidence: an RFQ form, a large catalog, several languages, certifications and a distributor network point to an established B2B business, while a consumer shop platform with none of these points to a small retailer. Cite the signals you rely on. Output a JSON object with your suggested grade and a structured reasoning.
+
+### JSON Output Format:
+{
+  "suggested_grade": "A|B|C",
+  "confidence_score": 0.9,
+  "reasoning": {
+    "positive_signals": [
+      "e.g., Company operates in a key target industry.",
+      "e.g., Website showcases high-value products relevant to our offerings."
+    ],
+    "negative_signals": [
+      "e.g., Company size appears to be small.",
+      "e.g., No key contact information was found."
+    ]
+  }
+}
+`,
+	},
+	PromptAnalysis: {
+		Title:        "切入点分析",
+		SystemPrompt: "你是资深产品策略顾问，请结合客户需求与我方产品优势，输出精准的切入分析。",
+		UserPrompt: `客户名称: {{.Customer.Name}}
+官网: {{.Customer.Website}}
+国家: {{.Customer.Country}}
+客户摘要: {{.Customer.Summary}}
+
+我的产品/服务简介:
+{{.Settings.MyProduct}}
+请输出 JSON：{
+  "core_business": "客户主营业务与定位",
+  "pain_points": "客户可能的痛点或待解决问题",
+  "my_entry_points": "结合我方产品的切入建议",
+  "full_report": "对上述内容进行200字左右的综述"
+}
+使用专业中文表达。
+`,
+	},
+	PromptEmailInitial: {
+		Title:        "开发信",
+		SystemPrompt: "你是专业的 B2B 外贸业务开发邮件写手，请输出高质量英文邮件。",
+		UserPrompt: `客户名称: {{.Customer.Name}}
+官网: {{.Customer.Website}}
+客户摘要: {{.Customer.Summary}}
+
+切入点分析:
+核心业务: {{.Analysis.CoreBusiness}}
+痛点: {{.Analysis.PainPoints}}
+我方切入点: {{.Analysis.MyEntryPoints}}
+
+我的公司名: {{.Settings.MyCompanyName}}
+产品简介: {{.Settings.MyProduct}}
+目标联系人: {{.KeyContact}}
+请参考以下三段式英文邮件的结构（方括号内为占位说明，需替换为真实内容）：
+Dear [Contact Name],
+[First paragraph: why you are writing, tied to one fact about their business.]
+[Second paragraph: how our product addresses their need, with one concrete detail.]
+[Third paragraph: a low-pressure next step, such as a short call or samples.]
+Kind regards,
+
+请使用简洁商务英文，输出 JSON：{
+  "subject": "邮件标题",
+  "body": "150-220词正文，需包含客户痛点与我方解决方案，并以柔性 CTA 收尾"
+}
+如果缺少联系人姓名，请以 "Hi there" 开头。
+`,
+	},
+	PromptEmailFollowup: {
+		Title:        "跟进邮件",
+		SystemPrompt: "你是专业的客户成功经理，请基于已有上下文撰写精炼的英文跟进。",
+		UserPrompt: `此前已发送的首封邮件正文如下：
+{{.ContextEmail.Body}}
+请基于上封邮件，撰写一封不超过120词的英文跟进邮件，保持友好语气，并提供一个新的价值点（例如更高性能指标、成功案例或资源下载链接占位）。输出 JSON：{
+  "subject": "标题",
+  "body": "正文"
+}
+请继续参考以下三段式结构（方括号内为占位说明，需替换为真实内容）：
+Dear [Contact Name],
+[First paragraph: a brief reference to the previous email.]
+[Second paragraph: one new value point.]
+[Third paragraph: a simple question that is easy to answer.]
+Kind regards,
+{{with .Settings.MyCompanyName}}发件公司: {{.}}
+{{end}}`,
+	},
+	PromptMonitor: {
+		Title:        "官网变化摘要",
+		SystemPrompt: "You are a B2B account manager watching a key customer's website. Summarize what changed and why it may matter for sales. 使用中文输出",
+		UserPrompt: `### Customer:
+- Name: {{trim .Customer.Name}}
+- Website: {{trim .Customer.Website}}
+
+### Website changes since the last check:
+{{.Changes}}
+
+### Instructions:
+Summarize the changes in at most 5 short bullet points. Focus on new products or product lines, new markets, offices or distributors, hiring, and company news. Ignore cosmetic or navigation changes. If nothing business-relevant changed, say so in one sentence. End with one line suggesting whether to contact the customer now and why.
+`,
+	},
+}
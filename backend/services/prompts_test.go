@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+)
+
+func TestPromptTemplatesVersionRollbackAndPreview(t *testing.T) {
+	st := setupTestStore(t)
+	defer st.Close()
+	ctx := context.Background()
+	prompts := NewPromptService(st)
+
+	customerID, err := st.CreateCustomer(ctx, &domain.CreateCompanyRequest{
+		Name:     "Acme Trading",
+		Website:  "https://acme.example",
+		Country:  "Germany",
+		Summary:  "Industrial fasteners distributor",
+		Contacts: []domain.Contact{{Name: "Jane Doe", Email: "jane@acme.example", IsKey: true}},
+	})
+	if err != nil {
+		t.Fatalf("create customer: %v", err)
+	}
+
+	builtin, err := prompts.Messages(ctx, PromptAnalysis, PromptData{Customer: &domain.Customer{Name: "Acme Trading"}})
+	if err != nil {
+		t.Fatalf("render built-in: %v", err)
+	}
+	if len(builtin) != 2 || !strings.Contains(builtin[1].Content, "Acme Trading") {
+		t.Fatalf("unexpected built-in messages: %+v", builtin)
+	}
+
+	if _, err := prompts.Save(ctx, PromptAnalysis, &domain.PromptTemplateRequest{UserPrompt: "{{.Customer.Name"}); err == nil {
+		t.Fatalf("expected invalid template to be rejected")
+	}
+	v1, err := prompts.Save(ctx, PromptAnalysis, &domain.PromptTemplateRequest{
+		SystemPrompt: "system v1",
+		UserPrompt:   "客户：{{.Customer.Name}}（{{.Customer.Country}}）联系人：{{.KeyContact}}",
+	})
+	if err != nil {
+		t.Fatalf("save v1: %v", err)
+	}
+	if _, err := prompts.Save(ctx, PromptAnalysis, &domain.PromptTemplateRequest{UserPrompt: "v2 {{.Customer.Name}}"}); err != nil {
+		t.Fatalf("save v2: %v", err)
+	}
+
+	preview, err := prompts.Preview(ctx, PromptAnalysis, &domain.PromptPreviewRequest{CustomerID: customerID})
+	if err != nil {
+		t.Fatalf("preview: %v", err)
+	}
+	if preview.UserPrompt != "v2 Acme Trading" {
+		t.Fatalf("preview should render the active version, got %q", preview.UserPrompt)
+	}
+
+	rolled, err := prompts.Rollback(ctx, PromptAnalysis, v1.Version)
+	if err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if rolled.Version != 3 {
+		t.Fatalf("rollback should create version 3, got %d", rolled.Version)
+	}
+	preview, err = prompts.Preview(ctx, PromptAnalysis, &domain.PromptPreviewRequest{CustomerID: customerID})
+	if err != nil {
+		t.Fatalf("preview after rollback: %v", err)
+	}
+	if preview.SystemPrompt != "system v1" || preview.UserPrompt != "客户：Acme Trading（Germany）联系人：Jane Doe" {
+		t.Fatalf("unexpected preview after rollback: %+v", preview)
+	}
+
+	versions, err := prompts.Versions(ctx, PromptAnalysis)
+	if err != nil {
+		t.Fatalf("versions: %v", err)
+	}
+	if len(versions) != 4 || versions[0].Version != 3 || !versions[3].BuiltIn {
+		t.Fatalf("unexpected versions: %+v", versions)
+	}
+
+	if err := prompts.Reset(ctx, PromptAnalysis); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	list, err := prompts.List(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	for _, tpl := range list {
+		if tpl.Name == PromptAnalysis && !tpl.BuiltIn {
+			t.Fatalf("reset should restore the built-in template, got %+v", tpl)
+		}
+	}
+}
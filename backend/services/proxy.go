@@ -0,0 +1,491 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+	"golang.org/x/net/proxy"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+const proxyDialTimeout = 15 * time.Second
+
+// proxyConfig is the proxy resolved for one channel.
+type proxyConfig struct {
+	server *url.URL // proxy address including credentials
+	bypass []string // NO_PROXY style entries: hosts, domain suffixes, IPs and CIDRs
+}
+
+// resolveProxy returns the proxy a channel should use, or nil for a direct
+// connection. The channel override wins over the global proxy.
+func resolveProxy(settings *store.Settings, channel string) (*proxyConfig, error) {
+	if settings == nil {
+		return nil, nil
+	}
+	server := settings.ProxyURL
+	switch value := strings.TrimSpace(settings.ProxyChannels[channel]); strings.ToLower(value) {
+	case "", store.ProxyModeGlobal:
+	case store.ProxyModeDirect:
+		return nil, nil
+	default:
+		server = value
+	}
+	normalized, err := store.NormalizeProxyURL(server)
+	if err != nil {
+		return nil, err
+	}
+	if normalized == "" {
+		return nil, nil
+	}
+	parsed, err := url.Parse(normalized)
+	if err != nil {
+		return nil, fmt.Errorf("代理地址无效: %w", err)
+	}
+	if username := strings.TrimSpace(settings.ProxyUsername); username != "" {
+		parsed.User = url.UserPassword(username, settings.ProxyPassword)
+	}
+	return &proxyConfig{server: parsed, bypass: splitHostList(settings.ProxyBypass)}, nil
+}
+
+// splitHostList splits a comma, semicolon, space or newline separated host
+// list into lower-case entries.
+func splitHostList(raw string) []string {
+	var entries []string
+	for _, entry := range strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n' || r == ' '
+	}) {
+		entries = append(entries, strings.ToLower(strings.TrimSpace(entry)))
+	}
+	return entries
+}
+
+// key identifies the configuration for client caching.
+func (p *proxyConfig) key() string {
+	return p.server.String() + "|" + strings.Join(p.bypass, ",")
+}
+
+// proxyFor is the http.Transport proxy function: nil for bypassed hosts.
+// Go's transport resolves names on the proxy for socks5 too, so socks5h is
+// passed on as socks5.
+func (p *proxyConfig) proxyFor(target *url.URL) (*url.URL, error) {
+	if p.bypassed(target.Host) {
+		return nil, nil
+	}
+	server := *p.server
+	if server.Scheme == "socks5h" {
+		server.Scheme = "socks5"
+	}
+	return &server, nil
+}
+
+// display returns the proxy address without credentials.
+func (p *proxyConfig) display() string {
+	return p.server.Scheme + "://" + p.server.Host
+}
+
+// bypassed reports whether connections to addr skip the proxy. Loopback
+// addresses always do, as do hosts matching the bypass list.
+func (p *proxyConfig) bypassed(addr string) bool {
+	host := normalizeHostEntry(addr)
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return true
+	}
+	return matchHostList(p.bypass, host)
+}
+
+// normalizeHostEntry strips the port, IPv6 brackets and trailing dot from addr.
+func normalizeHostEntry(addr string) string {
+	host := addr
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(strings.Trim(host, "[]"), "."))
+}
+
+// matchHostList reports whether host matches one of the NO_PROXY style
+// entries: exact hosts, their subdomains ("example.com", ".example.com" and
+// "*.example.com" alike), IPs and CIDR ranges; "*" matches all.
+func matchHostList(entries []string, host string) bool {
+	host = normalizeHostEntry(host)
+	ip := net.ParseIP(host)
+	for _, entry := range entries {
+		switch {
+		case entry == "*":
+			return true
+		case strings.Contains(entry, "/"):
+			if _, network, err := net.ParseCIDR(entry); err == nil && ip != nil && network.Contains(ip) {
+				return true
+			}
+		default:
+			domain := strings.TrimPrefix(strings.TrimPrefix(entry, "*"), ".")
+			if h, _, err := net.SplitHostPort(domain); err == nil {
+				domain = h
+			}
+			if domain != "" && (host == domain || strings.HasSuffix(host, "."+domain)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// playwright converts the proxy for browser contexts. Chromium cannot
+// authenticate against SOCKS5 proxies, so credentials only apply to HTTP ones.
+func (p *proxyConfig) playwright() *playwright.Proxy {
+	if p == nil {
+		return nil
+	}
+	scheme := p.server.Scheme
+	if scheme == "socks5h" {
+		scheme = "socks5"
+	}
+	out := &playwright.Proxy{Server: scheme + "://" + p.server.Host}
+	if len(p.bypass) > 0 {
+		out.Bypass = playwright.String(strings.Join(p.bypass, ","))
+	}
+	if p.server.User != nil {
+		password, _ := p.server.User.Password()
+		out.Username = playwright.String(p.server.User.Username())
+		out.Password = playwright.String(password)
+	}
+	return out
+}
+
+// dialContext opens a TCP connection to addr through the proxy, or directly
+// when addr is on the bypass list. HTTP proxies are asked for a CONNECT tunnel.
+func (p *proxyConfig) dialContext(ctx context.Context, addr string) (net.Conn, error) {
+	direct := &net.Dialer{Timeout: proxyDialTimeout}
+	if p == nil || p.bypassed(addr) {
+		return direct.DialContext(ctx, "tcp", addr)
+	}
+	switch p.server.Scheme {
+	case "socks5", "socks5h":
+		var auth *proxy.Auth
+		if p.server.User != nil {
+			password, _ := p.server.User.Password()
+			auth = &proxy.Auth{User: p.server.User.Username(), Password: password}
+		}
+		dialer, err := proxy.SOCKS5("tcp", p.server.Host, auth, direct)
+		if err != nil {
+			return nil, fmt.Errorf("创建 SOCKS5 代理失败: %w", err)
+		}
+		return dialer.(proxy.ContextDialer).DialContext(ctx, "tcp", addr)
+	default:
+		return p.dialConnect(ctx, direct, addr)
+	}
+}
+
+func (p *proxyConfig) dialConnect(ctx context.Context, direct *net.Dialer, addr string) (net.Conn, error) {
+	conn, err := direct.DialContext(ctx, "tcp", p.server.Host)
+	if err != nil {
+		return nil, fmt.Errorf("连接代理服务器失败: %w", err)
+	}
+	if p.server.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: p.server.Hostname(), MinVersion: tls.VersionTLS12})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("代理服务器 TLS 握手失败: %w", err)
+		}
+		conn = tlsConn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if p.server.User != nil {
+		password, _ := p.server.User.Password()
+		token := base64.StdEncoding.EncodeToString([]byte(p.server.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+token)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("发送 CONNECT 请求失败: %w", err)
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("读取代理响应失败: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("代理拒绝建立隧道: %s", resp.Status)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	// The server may speak first (SMTP greeting), so keep what was buffered.
+	return &bufferedConn{Conn: conn, reader: reader}, nil
+}
+
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// errorTransport fails every request; used when the proxy settings are invalid
+// so traffic never silently bypasses the configured proxy.
+type errorTransport struct{ err error }
+
+func (t errorTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, t.err
+}
+
+// ProxyRouter hands out HTTP clients and dialers that follow the proxy
+// settings. Settings are read on every call, so changes apply without a
+// restart; each channel keeps one proxied client, rebuilt when its base client
+// or proxy changes.
+type ProxyRouter struct {
+	store   *store.Store
+	mu      sync.Mutex
+	clients map[string]proxiedClient
+}
+
+// proxiedClient is a channel's cached client and what it was built from.
+type proxiedClient struct {
+	base   *http.Client
+	proxy  string
+	client *http.Client
+}
+
+// NewProxyRouter creates a router reading proxy settings from the store.
+func NewProxyRouter(st *store.Store) *ProxyRouter {
+	return &ProxyRouter{store: st, clients: make(map[string]proxiedClient)}
+}
+
+// httpClient returns base routed through the channel's proxy. A nil router or
+// a direct channel returns base unchanged.
+func (r *ProxyRouter) httpClient(settings *store.Settings, channel string, base *http.Client) *http.Client {
+	if base == nil {
+		base = &http.Client{Timeout: 30 * time.Second}
+	}
+	if r == nil {
+		return base
+	}
+	cfg, err := resolveProxy(settings, channel)
+	if err != nil {
+		log.Printf("[proxy] channel=%s status=invalid error=%v", channel, err)
+		clone := *base
+		clone.Transport = errorTransport{err: fmt.Errorf("代理配置无效: %w", err)}
+		return &clone
+	}
+	if cfg == nil {
+		return base
+	}
+
+	key := cfg.key()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cached, ok := r.clients[channel]
+	if ok && cached.base == base && cached.proxy == key {
+		return cached.client
+	}
+	if ok {
+		cached.client.CloseIdleConnections()
+	}
+	client := proxyClient(cfg, base)
+	r.clients[channel] = proxiedClient{base: base, proxy: key, client: client}
+	return client
+}
+
+// proxyClient clones base with its transport routed through cfg.
+func proxyClient(cfg *proxyConfig, base *http.Client) *http.Client {
+	transport, ok := base.Transport.(*http.Transport)
+	if !ok || transport == nil {
+		transport = http.DefaultTransport.(*http.Transport)
+	}
+	transport = transport.Clone()
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		return cfg.proxyFor(req.URL)
+	}
+	if guarded := transport.DialContext; guarded != nil {
+		// The base dialer may refuse private addresses (see fetchGuard); the
+		// proxy itself is configured by the admin and often runs on the LAN.
+		direct := &net.Dialer{Timeout: proxyDialTimeout, KeepAlive: 30 * time.Second}
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if addr == cfg.server.Host {
+				return direct.DialContext(ctx, network, addr)
+			}
+			return guarded(ctx, network, addr)
+		}
+	}
+	clone := *base
+	clone.Transport = transport
+	return &clone
+}
+
+// channelClient loads the settings and returns the client for channel. When
+// the settings cannot be read the base client is used.
+func (r *ProxyRouter) channelClient(ctx context.Context, channel string, base *http.Client) *http.Client {
+	if r == nil || r.store == nil {
+		return r.httpClient(nil, channel, base)
+	}
+	settings, err := r.store.GetSettings(ctx)
+	if err != nil {
+		log.Printf("[proxy] 读取代理配置失败: %v", err)
+		return r.httpClient(nil, channel, base)
+	}
+	return r.httpClient(settings, channel, base)
+}
+
+// channelBrowserProxy loads the settings and returns the Playwright proxy for
+// channel, or nil for a direct connection.
+func (r *ProxyRouter) channelBrowserProxy(ctx context.Context, channel string) (*playwright.Proxy, error) {
+	cfg, err := r.channelProxy(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.playwright(), nil
+}
+
+// channelProxy loads the settings and returns the proxy for channel, or nil
+// for a direct connection or unreadable settings.
+func (r *ProxyRouter) channelProxy(ctx context.Context, channel string) (*proxyConfig, error) {
+	if r == nil || r.store == nil {
+		return nil, nil
+	}
+	settings, err := r.store.GetSettings(ctx)
+	if err != nil {
+		log.Printf("[proxy] 读取代理配置失败: %v", err)
+		return nil, nil
+	}
+	cfg, err := resolveProxy(settings, channel)
+	if err != nil {
+		return nil, fmt.Errorf("代理配置无效: %w", err)
+	}
+	return cfg, nil
+}
+
+// ProxyTestResult reports the connectivity check of one channel.
+type ProxyTestResult struct {
+	Channel   string `json:"channel"`
+	Proxy     string `json:"proxy"` // proxy address without credentials, or "direct"
+	Target    string `json:"target"`
+	OK        bool   `json:"ok"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Test checks every channel with the saved settings: HTTP channels request a
+// well-known page and SMTP opens a TCP connection to the mail server.
+func (r *ProxyRouter) Test(ctx context.Context) ([]ProxyTestResult, error) {
+	if r == nil || r.store == nil {
+		return nil, fmt.Errorf("proxy router not initialized")
+	}
+	settings, err := r.store.GetSettings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("读取代理配置失败: %w", err)
+	}
+	results := make([]ProxyTestResult, len(store.ProxyChannels))
+	var wg sync.WaitGroup
+	for i, channel := range store.ProxyChannels {
+		wg.Add(1)
+		go func(i int, channel string) {
+			defer wg.Done()
+			results[i] = r.testChannel(ctx, settings, channel)
+		}(i, channel)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+func (r *ProxyRouter) testChannel(ctx context.Context, settings *store.Settings, channel string) ProxyTestResult {
+	result := ProxyTestResult{Channel: channel, Proxy: store.ProxyModeDirect}
+	cfg, err := resolveProxy(settings, channel)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if cfg != nil {
+		result.Proxy = cfg.display()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, proxyDialTimeout)
+	defer cancel()
+	start := time.Now()
+	if channel == store.ProxyChannelSMTP {
+		host := strings.TrimSpace(settings.SMTPHost)
+		if host == "" {
+			result.Error = "未配置 SMTP 主机"
+			return result
+		}
+		port := settings.SMTPPort
+		if port <= 0 {
+			port = defaultSMTPPort(strings.ToLower(strings.TrimSpace(settings.SMTPSecurity)))
+		}
+		result.Target = net.JoinHostPort(host, fmt.Sprint(port))
+		conn, err := cfg.dialContext(ctx, result.Target)
+		if err == nil {
+			conn.Close()
+		}
+		return finishProxyTest(result, start, err)
+	}
+
+	result.Target = proxyTestTarget(settings, channel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, result.Target, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	// The test client is built for this call only and not cached.
+	client := &http.Client{Timeout: proxyDialTimeout}
+	if cfg != nil {
+		client = proxyClient(cfg, client)
+		defer client.CloseIdleConnections()
+	}
+	resp, err := client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return finishProxyTest(result, start, err)
+}
+
+func finishProxyTest(result ProxyTestResult, start time.Time, err error) ProxyTestResult {
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.OK = true
+	return result
+}
+
+// proxyTestTarget picks the URL a channel is checked against. Any HTTP answer
+// counts as reachable; only transport errors fail the check.
+func proxyTestTarget(settings *store.Settings, channel string) string {
+	switch channel {
+	case store.ProxyChannelLLM:
+		if base := strings.TrimSpace(settings.LLMBaseURL); base != "" {
+			return base
+		}
+		return "https://api.openai.com/v1/models"
+	case store.ProxyChannelSearch:
+		if settings.SearchProvider == store.SearchProviderPlaywrightBing {
+			return "https://www.bing.com/"
+		}
+		return googleConnectivityProbe
+	default:
+		return "https://www.example.com/"
+	}
+}
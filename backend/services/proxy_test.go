@@ -0,0 +1,252 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gomail "gopkg.in/gomail.v2"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+func TestResolveProxyChannels(t *testing.T) {
+	settings := &store.Settings{
+		ProxyURL:      "http://proxy.lan:3128",
+		ProxyUsername: "alice",
+		ProxyPassword: "s3cret",
+		ProxyBypass:   "*.corp.local, 10.0.0.0/8\nintranet",
+		ProxyChannels: map[string]string{
+			store.ProxyChannelLLM:   store.ProxyModeDirect,
+			store.ProxyChannelFetch: "socks5h://10.1.1.1:1080",
+		},
+	}
+
+	if cfg, err := resolveProxy(settings, store.ProxyChannelLLM); err != nil || cfg != nil {
+		t.Fatalf("expected direct llm channel, got %#v, %v", cfg, err)
+	}
+	search, err := resolveProxy(settings, store.ProxyChannelSearch)
+	if err != nil || search == nil || search.display() != "http://proxy.lan:3128" {
+		t.Fatalf("expected search to use the global proxy, got %#v, %v", search, err)
+	}
+	fetch, err := resolveProxy(settings, store.ProxyChannelFetch)
+	if err != nil || fetch == nil || fetch.display() != "socks5h://10.1.1.1:1080" {
+		t.Fatalf("expected fetch override, got %#v, %v", fetch, err)
+	}
+	if user := fetch.server.User.Username(); user != "alice" {
+		t.Fatalf("expected shared credentials on the channel proxy, got %q", user)
+	}
+
+	for addr, want := range map[string]bool{
+		"api.corp.local:443": true,
+		"corp.local":         true,
+		"10.2.3.4:25":        true,
+		"intranet:80":        true,
+		"127.0.0.1:8080":     true,
+		"example.com:443":    false,
+		"notcorp.local":      false,
+	} {
+		if got := search.bypassed(addr); got != want {
+			t.Fatalf("bypassed(%s) = %v, want %v", addr, got, want)
+		}
+	}
+
+	browser := fetch.playwright()
+	if browser.Server != "socks5://10.1.1.1:1080" || browser.Bypass == nil || *browser.Bypass != "*.corp.local,10.0.0.0/8,intranet" {
+		t.Fatalf("unexpected playwright proxy %#v", browser)
+	}
+
+	settings.ProxyURL = ""
+	if cfg, _ := resolveProxy(settings, store.ProxyChannelSearch); cfg != nil {
+		t.Fatalf("expected no proxy without a global proxy, got %#v", cfg)
+	}
+}
+
+func TestProxyRouterRoutesHTTPClients(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		seenURL  string
+		seenAuth string
+	)
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seenURL = r.URL.String()
+		seenAuth = r.Header.Get("Proxy-Authorization")
+		mu.Unlock()
+		_, _ = io.WriteString(w, "via proxy")
+	}))
+	defer proxyServer.Close()
+
+	settings := &store.Settings{
+		ProxyURL:      proxyServer.URL,
+		ProxyUsername: "alice",
+		ProxyPassword: "s3cret",
+		ProxyChannels: map[string]string{store.ProxyChannelLLM: store.ProxyModeDirect, store.ProxyChannelSearch: "ftp://bad:21"},
+	}
+	router := NewProxyRouter(nil)
+	base := &http.Client{Timeout: 5 * time.Second}
+
+	if client := router.httpClient(settings, store.ProxyChannelLLM, base); client != base {
+		t.Fatalf("expected the direct channel to reuse the base client")
+	}
+	client := router.httpClient(settings, store.ProxyChannelFetch, base)
+	if again := router.httpClient(settings, store.ProxyChannelFetch, base); again != client {
+		t.Fatalf("expected proxied clients to be cached")
+	}
+	resp, err := client.Get("http://upstream.example/hello")
+	if err != nil {
+		t.Fatalf("proxied request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "via proxy" {
+		t.Fatalf("unexpected body %q", body)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if seenURL != "http://upstream.example/hello" {
+		t.Fatalf("proxy saw %q", seenURL)
+	}
+	if want := "Basic " + base64.StdEncoding.EncodeToString([]byte("alice:s3cret")); seenAuth != want {
+		t.Fatalf("expected proxy credentials, got %q", seenAuth)
+	}
+
+	if _, err := router.httpClient(settings, store.ProxyChannelSearch, base).Get("http://upstream.example/"); err == nil || !strings.Contains(err.Error(), "代理配置无效") {
+		t.Fatalf("expected invalid channel proxy to fail closed, got %v", err)
+	}
+}
+
+func TestProxyRouterKeepsOneClientPerChannel(t *testing.T) {
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "via proxy")
+	}))
+	defer proxyServer.Close()
+
+	settings := &store.Settings{ProxyURL: proxyServer.URL}
+	router := NewProxyRouter(nil)
+	for i := 0; i < 3; i++ {
+		router.testChannel(context.Background(), settings, store.ProxyChannelFetch)
+		router.httpClient(settings, store.ProxyChannelSearch, &http.Client{})
+	}
+	router.mu.Lock()
+	defer router.mu.Unlock()
+	if len(router.clients) != 1 {
+		t.Fatalf("expected one cached client, got %d", len(router.clients))
+	}
+	if _, ok := router.clients[store.ProxyChannelSearch]; !ok {
+		t.Fatalf("expected the search channel client to be cached")
+	}
+}
+
+func TestSMTPDeliveryThroughConnectProxy(t *testing.T) {
+	smtpLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen smtp: %v", err)
+	}
+	defer smtpLn.Close()
+	received := make(chan string, 1)
+	go serveFakeSMTP(smtpLn, received)
+
+	proxyLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen proxy: %v", err)
+	}
+	defer proxyLn.Close()
+	tunnelTarget := make(chan string, 1)
+	go serveConnectProxy(proxyLn, smtpLn.Addr().String(), tunnelTarget)
+
+	settings := &store.Settings{ProxyURL: proxyLn.Addr().String()}
+	mailer := &SMTPMailer{proxies: NewProxyRouter(nil)}
+	inputs := &smtpInputs{host: "smtp.example.test", port: 587, username: "sales@example.com", security: "tls"}
+	msg := gomail.NewMessage()
+	msg.SetHeader("From", "sales@example.com")
+	msg.SetHeader("To", "buyer@example.org")
+	msg.SetHeader("Subject", "Hello")
+	msg.SetBody("text/plain", "Quote attached")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := mailer.deliver(ctx, settings, inputs, msg); err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+	if target := <-tunnelTarget; target != "smtp.example.test:587" {
+		t.Fatalf("expected a tunnel to the SMTP host, got %q", target)
+	}
+	transcript := <-received
+	for _, want := range []string{"MAIL FROM:<sales@example.com>", "RCPT TO:<buyer@example.org>", "Quote attached"} {
+		if !strings.Contains(transcript, want) {
+			t.Fatalf("expected %q in SMTP transcript:\n%s", want, transcript)
+		}
+	}
+}
+
+// serveConnectProxy accepts one CONNECT request and tunnels it to upstream.
+func serveConnectProxy(ln net.Listener, upstream string, target chan<- string) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	req, err := http.ReadRequest(bufio.NewReader(conn))
+	if err != nil || req.Method != http.MethodConnect {
+		return
+	}
+	target <- req.Host
+	backend, err := net.Dial("tcp", upstream)
+	if err != nil {
+		return
+	}
+	defer backend.Close()
+	_, _ = io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	go func() { _, _ = io.Copy(backend, conn) }()
+	_, _ = io.Copy(conn, backend)
+}
+
+// serveFakeSMTP speaks just enough SMTP for one message without TLS or AUTH.
+func serveFakeSMTP(ln net.Listener, received chan<- string) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	var transcript strings.Builder
+	reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+	reply("220 fake ESMTP")
+	inData := false
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			received <- transcript.String()
+			return
+		}
+		transcript.WriteString(line)
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case inData:
+			if strings.TrimSpace(line) == "." {
+				inData = false
+				reply("250 queued")
+			}
+		case strings.HasPrefix(cmd, "EHLO"):
+			reply("250 fake")
+		case cmd == "DATA":
+			inData = true
+			reply("354 go ahead")
+		case cmd == "QUIT":
+			reply("221 bye")
+			received <- transcript.String()
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
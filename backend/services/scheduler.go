@@ -20,13 +20,22 @@ type SchedulerServiceImpl struct {
 	store    *store.Store
 	composer EmailComposerService
 	mailer   MailService
+	budget   budgetGate
 }
 
 // NewSchedulerService constructs a scheduler instance.
 func NewSchedulerService(st *store.Store, composer EmailComposerService, mailer MailService) *SchedulerServiceImpl {
-	return &SchedulerServiceImpl{store: st, composer: composer, mailer: mailer}
+	return &SchedulerServiceImpl{store: st, composer: composer, mailer: mailer, budget: budgetGate{scope: "scheduler"}}
 }
 
+// followupBudgetDelay is how long a follow-up waits when the LLM token budget
+// is used up. Deferring does not count as a failed attempt.
+const followupBudgetDelay = time.Hour
+
+// followupPersistTimeout bounds the bookkeeping after a follow-up was sent,
+// which runs even when the caller has gone away.
+const followupPersistTimeout = 10 * time.Second
+
 // ErrNoAdminEmail indicates there is no configured admin inbox for outbound emails.
 var ErrNoAdminEmail = errors.New("admin email not configured")
 
@@ -125,7 +134,7 @@ func (s *SchedulerServiceImpl) RunNow(ctx context.Context, taskID int64) error {
 		return err
 	}
 
-	finalize := func(status string, emailID sql.NullInt64, errMsg string) error {
+	finalize := func(ctx context.Context, status string, emailID sql.NullInt64, errMsg string) error {
 		var lastErr sql.NullString
 		if errMsg != "" {
 			lastErr = sql.NullString{String: errMsg, Valid: true}
@@ -146,15 +155,19 @@ func (s *SchedulerServiceImpl) RunNow(ctx context.Context, taskID int64) error {
 		}
 	}
 	reschedule := func(curAttempts int, errMsg string) error {
+		// Leave an interrupted task running; it is requeued on the next start.
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		nextAttempts := curAttempts + 1
 		if nextAttempts > maxAttempts {
-			return finalize("failed", sql.NullInt64{}, errMsg)
+			return finalize(ctx, "failed", sql.NullInt64{}, errMsg)
 		}
 		nextDue := time.Now().Add(backoff(nextAttempts))
 		return s.store.RescheduleTaskAfterFailure(ctx, taskID, nextDue, nextAttempts, errMsg)
 	}
 
-	scheduleNextCron := func() {
+	scheduleNextCron := func(ctx context.Context) {
 		if task.Mode == "cron" && strings.TrimSpace(task.CronExpression) != "" {
 			if schedule, err := parseCronExpression(task.CronExpression); err != nil {
 				log.Printf("reschedule cron task %d parse error: %v", task.ID, err)
@@ -185,10 +198,10 @@ func (s *SchedulerServiceImpl) RunNow(ctx context.Context, taskID int64) error {
 
 	if customer.FollowupSent {
 		msg := "客户已标记为“仅发送一次”，本次自动邮件已跳过。如需继续发送，请在客户详情中选择“继续发送”。"
-		if err := finalize("skipped", sql.NullInt64{}, msg); err != nil {
+		if err := finalize(ctx, "skipped", sql.NullInt64{}, msg); err != nil {
 			return err
 		}
-		scheduleNextCron()
+		scheduleNextCron(ctx)
 		return nil
 	}
 
@@ -199,6 +212,18 @@ func (s *SchedulerServiceImpl) RunNow(ctx context.Context, taskID int64) error {
 	}
 	recipients := []string{adminRecipient}
 
+	if ok, err := s.budget.allow(ctx, s.store); err != nil {
+		_ = reschedule(task.Attempts, err.Error())
+		return err
+	} else if !ok {
+		nextDue := time.Now().Add(followupBudgetDelay)
+		msg := "LLM 预算已用尽，跟进邮件延后生成"
+		if err := s.store.RescheduleTaskAfterFailure(ctx, taskID, nextDue, task.Attempts, msg); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: 任务已延后至 %s", store.ErrLLMBudgetExceeded, nextDue.Format(time.RFC3339))
+	}
+
 	draft, err := s.composer.DraftFollowup(ctx, task.CustomerID, task.ContextEmailID)
 	if err != nil {
 		_ = reschedule(task.Attempts, err.Error())
@@ -217,21 +242,25 @@ func (s *SchedulerServiceImpl) RunNow(ctx context.Context, taskID int64) error {
 		return err
 	}
 
+	// The message is out. Record that even if ctx is cancelled now: a task left
+	// running is requeued on the next start and would send the same follow-up
+	// again, so a failed status update is logged rather than retried.
+	persistCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), followupPersistTimeout)
+	defer cancel()
 	now := time.Now()
-	if err := s.store.UpdateEmailStatus(ctx, emailID, "sent", &now, messageID); err != nil {
-		_ = reschedule(task.Attempts, err.Error())
-		return err
+	if err := s.store.UpdateEmailStatus(persistCtx, emailID, "sent", &now, messageID); err != nil {
+		log.Printf("update email status failed task=%d email=%d: %v", task.ID, emailID, err)
 	}
 
-	if err := s.store.UpdateFollowupSent(ctx, task.CustomerID, true); err != nil {
+	if err := s.store.UpdateFollowupSent(persistCtx, task.CustomerID, true); err != nil {
 		log.Printf("update followup flag failed customer=%d: %v", task.CustomerID, err)
 	}
 
-	if err := finalize("sent", sql.NullInt64{Int64: emailID, Valid: true}, ""); err != nil {
+	if err := finalize(persistCtx, "sent", sql.NullInt64{Int64: emailID, Valid: true}, ""); err != nil {
 		return err
 	}
 
-	scheduleNextCron()
+	scheduleNextCron(persistCtx)
 	return nil
 }
 
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+type fakeFollowupComposer struct {
+	stubEmailComposer
+	drafts int
+}
+
+func (c *fakeFollowupComposer) DraftFollowup(ctx context.Context, customerID int64, contextEmailID int64) (*domain.EmailDraft, error) {
+	c.drafts++
+	return &domain.EmailDraft{Subject: "Following up", Body: "Any news?"}, nil
+}
+
+type fakeMailer struct {
+	stubMailer
+	sent   int
+	onSend func()
+}
+
+func (m *fakeMailer) Send(ctx context.Context, to []string, subject, body string) (string, error) {
+	m.sent++
+	if m.onSend != nil {
+		m.onSend()
+	}
+	return "<followup@test>", nil
+}
+
+// newSchedulerFixture stores a customer with an initial email and one due
+// follow-up task.
+func newSchedulerFixture(t *testing.T, settingsJSON string) (*store.Store, int64) {
+	t.Helper()
+	st := setupTestStore(t)
+	t.Cleanup(func() { st.Close() })
+	ctx := context.Background()
+	if err := st.SaveSettings(ctx, strings.NewReader(settingsJSON)); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+	customerID, err := st.CreateCustomer(ctx, &domain.CreateCompanyRequest{Name: "Acme"})
+	if err != nil {
+		t.Fatalf("create customer: %v", err)
+	}
+	emailID, err := st.InsertEmailDraft(ctx, customerID, "initial", domain.EmailDraft{Subject: "Hello", Body: "Hi"}, "sent")
+	if err != nil {
+		t.Fatalf("insert email: %v", err)
+	}
+	taskID, err := st.CreateScheduledTask(ctx, &store.ScheduledTaskInput{
+		CustomerID:     customerID,
+		ContextEmailID: emailID,
+		DueAt:          time.Now().Add(-time.Minute),
+		Mode:           "simple",
+		DelayValue:     3,
+		DelayUnit:      "days",
+	})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	return st, taskID
+}
+
+func TestRunNowDefersFollowupWhileBudgetExhausted(t *testing.T) {
+	st, taskID := newSchedulerFixture(t, `{"admin_email": "me@seller.test", "llm_daily_token_budget": 100}`)
+	ctx := context.Background()
+	if err := st.InsertLLMUsage(ctx, store.LLMUsageEntry{Task: "email", TotalTokens: 150}); err != nil {
+		t.Fatalf("insert usage: %v", err)
+	}
+
+	composer := &fakeFollowupComposer{}
+	mailer := &fakeMailer{}
+	err := NewSchedulerService(st, composer, mailer).RunNow(ctx, taskID)
+	if !errors.Is(err, store.ErrLLMBudgetExceeded) {
+		t.Fatalf("RunNow error = %v, want budget exceeded", err)
+	}
+	if composer.drafts != 0 || mailer.sent != 0 {
+		t.Fatalf("drafted %d and sent %d follow-ups over budget", composer.drafts, mailer.sent)
+	}
+	task, err := st.GetTask(ctx, taskID)
+	if err != nil {
+		t.Fatalf("get task: %v", err)
+	}
+	due, _ := time.Parse(time.RFC3339, task.DueAt)
+	if task.Status != "scheduled" || task.Attempts != 0 || !due.After(time.Now().Add(30*time.Minute)) {
+		t.Fatalf("task = %+v, want it deferred without counting an attempt", task)
+	}
+}
+
+func TestRunNowRecordsSendAfterCancellation(t *testing.T) {
+	st, taskID := newSchedulerFixture(t, `{"admin_email": "me@seller.test"}`)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Shutdown arrives while the message is on its way out.
+	mailer := &fakeMailer{onSend: cancel}
+	if err := NewSchedulerService(st, &fakeFollowupComposer{}, mailer).RunNow(ctx, taskID); err != nil {
+		t.Fatalf("RunNow: %v", err)
+	}
+
+	bg := context.Background()
+	task, err := st.GetTask(bg, taskID)
+	if err != nil {
+		t.Fatalf("get task: %v", err)
+	}
+	if task.Status != "sent" || task.GeneratedEmailID == 0 {
+		t.Fatalf("task = %+v, want it recorded as sent", task)
+	}
+	email, err := st.GetEmail(bg, task.GeneratedEmailID)
+	if err != nil {
+		t.Fatalf("get email: %v", err)
+	}
+	if email.Status != "sent" {
+		t.Fatalf("email status = %q, want sent", email.Status)
+	}
+
+	requeued, err := st.RequeueInterruptedJobs(bg)
+	if err != nil {
+		t.Fatalf("requeue: %v", err)
+	}
+	if requeued.Scheduled != 0 {
+		t.Fatalf("sent follow-up requeued on restart: %+v", requeued)
+	}
+	if due, _ := st.FetchDueTasks(bg, 5); len(due) != 0 || mailer.sent != 1 {
+		t.Fatalf("due tasks after restart = %+v, sent = %d", due, mailer.sent)
+	}
+}
@@ -18,6 +18,7 @@ import (
 
 const (
 	defaultSearchTimeout    = 25 * time.Second
+	searchUserAgent         = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
 	googleConnectivityProbe = "https://www.google.com/generate_204"
 )
 
@@ -58,7 +59,8 @@ func (p searchProvider) modeDescription() string {
 	}
 }
 
-// SearchStage enumerates the predefined concurrent search tracks.
+// SearchStage identifies a search track of the plan. The constants are the
+// keys of the built-in stages; configured plans may add their own.
 type SearchStage string
 
 const (
@@ -75,51 +77,24 @@ type searchTaskSpec struct {
 	Limit        int
 }
 
-var defaultSearchPlan = []searchTaskSpec{
-	{
-		Stage: SearchStageBroad,
-		Label: "任务A：基础信息搜索",
-		Limit: 10, // 增加到10个结果
-		QueryBuilder: func(name string) string {
-			return strings.TrimSpace(name)
-		},
-	},
-	{
-		Stage: SearchStageWebsite,
-		Label: "任务B：官网及业务搜索",
-		Limit: 10, // 增加到10个结果
-		QueryBuilder: func(name string) string {
-			name = strings.TrimSpace(name)
-			if name == "" {
-				return ""
-			}
-			return fmt.Sprintf(`%s official website`, name)
-		},
-	},
-	{
-		Stage: SearchStageContacts,
-		Label: "任务C：关键联系人搜索",
-		Limit: 10, // 增加到10个结果
-		QueryBuilder: func(name string) string {
-			name = strings.TrimSpace(name)
-			if name == "" {
-				return ""
-			}
-			return fmt.Sprintf(`%s CEO founder owner purchasing manager`, name)
-		},
-	},
-	{
-		Stage: SearchStageLinkedIn,
-		Label: "任务D：社交与职业背景搜索",
-		Limit: 10, // 增加到10个结果
-		QueryBuilder: func(name string) string {
-			name = strings.TrimSpace(name)
-			if name == "" {
-				return ""
-			}
-			return fmt.Sprintf(`site:linkedin.com %s`, name)
-		},
-	},
+// defaultSearchPlan is the built-in plan used when settings carry none.
+var defaultSearchPlan = searchPlanSpecs(store.DefaultSearchPlan())
+
+// searchPlanSpecs turns the enabled stages of a configured plan into runnable specs.
+func searchPlanSpecs(stages []store.SearchPlanStage) []searchTaskSpec {
+	specs := make([]searchTaskSpec, 0, len(stages))
+	for _, stage := range stages {
+		if !stage.Enabled {
+			continue
+		}
+		specs = append(specs, searchTaskSpec{
+			Stage:        SearchStage(stage.Key),
+			Label:        stage.Label,
+			Limit:        stage.Limit,
+			QueryBuilder: stage.BuildQuery,
+		})
+	}
+	return specs
 }
 
 func stageLabel(stage SearchStage) string {
@@ -184,25 +159,77 @@ func (r *SearchPlanResult) Items(stage SearchStage) []SearchItem {
 	return res.Items
 }
 
-// SearchClient invokes search engines using Playwright.
+// SearchClient runs web searches through the providers chosen in settings.
 type SearchClient struct {
-	store    *store.Store
-	provider searchProvider
+	store      *store.Store
+	httpClient *http.Client
+	cache      *ResponseCache
+	browsers   *BrowserPool // shared headless browsers for the Playwright backends
+	health     *SearchHealth
+	proxies    *ProxyRouter
+
+	engineOnce sync.Once
+	engine     searchProvider // Playwright engine picked by the connectivity check
 }
 
-// NewSearchClient constructs a search client and determines which provider to use.
+// NewSearchClient constructs a search client. The provider chain is read from
+// settings on every search, so changes apply without a restart.
 func NewSearchClient(st *store.Store, httpClient *http.Client) *SearchClient {
-	provider, err := detectSearchProvider(httpClient)
-	if err != nil {
-		log.Printf("[search] Google 连通性检测失败，切换至 Bing 搜索: %v", err)
-	} else {
-		log.Printf("[search] Google 连通性检测通过，使用谷歌搜索")
-	}
-	log.Printf("[search] 启动搜索模式：%s", provider.modeDescription())
 	return &SearchClient{
-		store:    st,
-		provider: provider,
+		store:      st,
+		httpClient: httpClient,
+		browsers:   NewBrowserPool(defaultBrowserPoolSize, defaultBrowserMaxUses),
+		health:     NewSearchHealth(),
+	}
+}
+
+// ProviderHealth reports the health of every search provider used since start-up.
+func (c *SearchClient) ProviderHealth() []SearchProviderHealth {
+	if c == nil {
+		return nil
+	}
+	return c.health.Snapshot()
+}
+
+// Close shuts down the pooled Playwright browsers.
+func (c *SearchClient) Close() error {
+	if c == nil {
+		return nil
 	}
+	return c.browsers.Close()
+}
+
+func (c *SearchClient) http() *http.Client {
+	if c.httpClient == nil {
+		return &http.Client{Timeout: defaultSearchTimeout}
+	}
+	return c.httpClient
+}
+
+// playwrightEngine picks the engine automatic Playwright mode tries first,
+// using a connectivity check on first use. The other engine stays in the chain
+// as a fallback and provider health decides when to skip either one.
+func (c *SearchClient) playwrightEngine(settings *store.Settings) searchProvider {
+	c.engineOnce.Do(func() {
+		var (
+			engine searchProvider
+			err    error
+		)
+		// A ping says nothing about the proxied route, so only probe over HTTP.
+		if cfg, _ := resolveProxy(settings, store.ProxyChannelSearch); cfg != nil {
+			engine, err = probeGoogle(c.proxies.httpClient(settings, store.ProxyChannelSearch, c.http()))
+		} else {
+			engine, err = detectSearchProvider(c.httpClient)
+		}
+		if err != nil {
+			log.Printf("[search] Google 连通性检测失败，切换至 Bing 搜索: %v", err)
+		} else {
+			log.Printf("[search] Google 连通性检测通过，使用谷歌搜索")
+		}
+		log.Printf("[search] 启动搜索模式：%s", engine.modeDescription())
+		c.engine = engine
+	})
+	return c.engine
 }
 
 func detectSearchProvider(httpClient *http.Client) (searchProvider, error) {
@@ -213,6 +240,11 @@ func detectSearchProvider(httpClient *http.Client) (searchProvider, error) {
 	log.Printf("[search] google.com ping 失败，尝试 HTTP 探测")
 
 	// Step 2: HTTP probe as a fallback to reduce false negatives.
+	return probeGoogle(httpClient)
+}
+
+// probeGoogle requests the Google connectivity endpoint over HTTP.
+func probeGoogle(httpClient *http.Client) (searchProvider, error) {
 	if httpClient == nil {
 		httpClient = &http.Client{Timeout: 5 * time.Second}
 	}
@@ -259,7 +291,8 @@ func pingGoogle() error {
 	return nil
 }
 
-// ExecutePlan runs the predefined parallel search plan using Playwright.
+// ExecutePlan runs the search plan configured in settings, one concurrent
+// search per enabled stage.
 func (c *SearchClient) ExecutePlan(ctx context.Context, customerName string) (*SearchPlanResult, error) {
 	if c == nil || c.store == nil {
 		return nil, fmt.Errorf("search client not initialized")
@@ -269,16 +302,35 @@ func (c *SearchClient) ExecutePlan(ctx context.Context, customerName string) (*S
 		return nil, fmt.Errorf("搜索关键词不能为空")
 	}
 
+	settings, err := c.store.GetSettings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("读取搜索配置失败: %w", err)
+	}
+	specs := searchPlanSpecs(settings.SearchPlan)
+	if len(specs) == 0 {
+		specs = defaultSearchPlan
+	}
+	result := c.runPlan(ctx, settings, specs, customerName)
+	if len(result.combined) == 0 {
+		return nil, fmt.Errorf("所有搜索任务均未返回有效结果")
+	}
+
+	return result, nil
+}
+
+// runPlan executes specs concurrently and merges their results in plan order.
+func (c *SearchClient) runPlan(ctx context.Context, settings *store.Settings, specs []searchTaskSpec, customerName string) *SearchPlanResult {
+	backends := c.searchBackends(settings)
 	result := &SearchPlanResult{
 		Customer:     customerName,
-		Provider:     string(c.provider),
+		Provider:     backends[0].Name(),
 		StageResults: make(map[SearchStage]*SearchTaskResult),
 	}
 
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 
-	for _, spec := range defaultSearchPlan {
+	for _, spec := range specs {
 		query := spec.QueryBuilder(customerName)
 		if strings.TrimSpace(query) == "" {
 			continue
@@ -287,7 +339,7 @@ func (c *SearchClient) ExecutePlan(ctx context.Context, customerName string) (*S
 		go func(spec searchTaskSpec, query string) {
 			defer wg.Done()
 			start := time.Now()
-			items, err := c.searchWithPlaywright(ctx, query, spec.Limit)
+			items, err := c.cachedSearch(ctx, settings, backends, query, spec.Limit)
 			if err != nil {
 				log.Printf("[search] stage=%s query=\"%s\" error=%v", spec.Stage, truncateForLog(query, 80), err)
 			} else {
@@ -309,7 +361,7 @@ func (c *SearchClient) ExecutePlan(ctx context.Context, customerName string) (*S
 	wg.Wait()
 
 	seen := map[string]struct{}{}
-	for _, spec := range defaultSearchPlan {
+	for _, spec := range specs {
 		res, ok := result.StageResults[spec.Stage]
 		if !ok || len(res.Items) == 0 {
 			continue
@@ -317,15 +369,81 @@ func (c *SearchClient) ExecutePlan(ctx context.Context, customerName string) (*S
 		result.Order = append(result.Order, spec.Stage)
 		result.combined = appendDedup(result.combined, res.Items, seen, 0)
 	}
+	return result
+}
 
-	if len(result.combined) == 0 {
-		return nil, fmt.Errorf("所有搜索任务均未返回有效结果")
+// SearchPlanPreview is the dry-run view of a search plan.
+type SearchPlanPreview struct {
+	Customer string               `json:"customer"`
+	Provider string               `json:"provider"`
+	Stages   []SearchStagePreview `json:"stages"`
+	Combined []SearchItem         `json:"combined"`
+}
+
+// SearchStagePreview reports one stage of a dry run. Disabled stages are listed
+// with their rendered query but not executed.
+type SearchStagePreview struct {
+	Stage      SearchStage  `json:"stage"`
+	Label      string       `json:"label"`
+	Query      string       `json:"query"`
+	Limit      int          `json:"limit"`
+	Enabled    bool         `json:"enabled"`
+	Items      []SearchItem `json:"items"`
+	Error      string       `json:"error,omitempty"`
+	DurationMS int64        `json:"duration_ms"`
+}
+
+// DryRunPlan runs a search plan for customerName and reports every stage,
+// including failed and empty ones. A nil stages list uses the saved plan, so
+// an edited plan can be tried before it is saved.
+func (c *SearchClient) DryRunPlan(ctx context.Context, customerName string, stages []store.SearchPlanStage) (*SearchPlanPreview, error) {
+	if c == nil || c.store == nil {
+		return nil, fmt.Errorf("search client not initialized")
+	}
+	customerName = strings.TrimSpace(customerName)
+	if customerName == "" {
+		return nil, fmt.Errorf("搜索关键词不能为空")
+	}
+	settings, err := c.store.GetSettings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("读取搜索配置失败: %w", err)
+	}
+	if stages == nil {
+		stages = settings.SearchPlan
+	}
+	stages, err = store.NormalizeSearchPlan(stages)
+	if err != nil {
+		return nil, err
 	}
 
-	return result, nil
+	result := c.runPlan(ctx, settings, searchPlanSpecs(stages), customerName)
+	preview := &SearchPlanPreview{
+		Customer: customerName,
+		Provider: result.Provider,
+		Stages:   make([]SearchStagePreview, 0, len(stages)),
+		Combined: result.Combined(),
+	}
+	for _, stage := range stages {
+		entry := SearchStagePreview{
+			Stage:   SearchStage(stage.Key),
+			Label:   stage.Label,
+			Query:   stage.BuildQuery(customerName),
+			Limit:   stage.Limit,
+			Enabled: stage.Enabled,
+		}
+		if res := result.Result(entry.Stage); res != nil {
+			entry.Items = res.Items
+			entry.DurationMS = res.Duration.Milliseconds()
+			if res.Error != nil {
+				entry.Error = res.Error.Error()
+			}
+		}
+		preview.Stages = append(preview.Stages, entry)
+	}
+	return preview, nil
 }
 
-// Search executes a single ad-hoc query using Playwright.
+// Search executes a single ad-hoc query through the configured provider chain.
 func (c *SearchClient) Search(ctx context.Context, query string, limit int) ([]SearchItem, error) {
 	if c == nil || c.store == nil {
 		return nil, fmt.Errorf("search client not initialized")
@@ -333,7 +451,58 @@ func (c *SearchClient) Search(ctx context.Context, query string, limit int) ([]S
 	if limit <= 0 {
 		limit = 10
 	}
-	return c.searchWithPlaywright(ctx, query, limit)
+	settings, err := c.store.GetSettings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("读取搜索配置失败: %w", err)
+	}
+	return c.cachedSearch(ctx, settings, c.searchBackends(settings), query, limit)
+}
+
+// cachedSearch serves (provider chain, query, limit) from the response cache when
+// possible and stores fresh non-empty results for the configured TTL.
+func (c *SearchClient) cachedSearch(ctx context.Context, settings *store.Settings, backends []SearchBackend, query string, limit int) ([]SearchItem, error) {
+	var ttl time.Duration
+	if c.cache != nil {
+		ttl = hoursTTL(settings.SearchCacheTTLHours)
+	}
+	if ttl <= 0 {
+		items, _, err := runSearchChain(ctx, c.health, backends, query, limit)
+		return exhaustedChainFallback(backends, query, items, err), err
+	}
+
+	names := make([]string, 0, len(backends))
+	for _, backend := range backends {
+		names = append(names, backend.Name())
+	}
+	key := cacheKey(names, strings.TrimSpace(query), limit)
+	if !cacheBypassed(ctx) {
+		var items []SearchItem
+		if c.cache.Get(CacheNamespaceSearch, key, &items) {
+			log.Printf("[search] query=\"%s\" limit=%d status=cache-hit", truncateForLog(query, 80), limit)
+			return items, nil
+		}
+	}
+	items, _, err := runSearchChain(ctx, c.health, backends, query, limit)
+	if err == nil && len(items) > 0 {
+		c.cache.Put(CacheNamespaceSearch, key, items, ttl)
+	}
+	return exhaustedChainFallback(backends, query, items, err), err
+}
+
+// exhaustedChainFallback answers with the query itself once every backend of a
+// browser-scraping chain came up empty, the way a single browser search used
+// to. It is never cached, so the next query tries the real backends again.
+func exhaustedChainFallback(backends []SearchBackend, query string, items []SearchItem, err error) []SearchItem {
+	if err != nil || len(items) > 0 {
+		return items
+	}
+	for _, backend := range backends {
+		if _, ok := backend.(browserSearchBackend); ok {
+			log.Printf("[search] query=\"%s\" status=exhausted fallback=direct", truncateForLog(query, 80))
+			return directMode(query)
+		}
+	}
+	return items
 }
 
 // TestSearch validates that Playwright can return at least one result.
@@ -352,8 +521,8 @@ func (c *SearchClient) TestSearch(ctx context.Context) error {
 	return nil
 }
 
-// searchWithPlaywright performs a Google search using Playwright and returns top results.
-func (c *SearchClient) searchWithPlaywright(ctx context.Context, query string, limit int) ([]SearchItem, error) {
+// searchWithPlaywright scrapes the given engine's result page with Playwright and returns top results.
+func (c *SearchClient) searchWithPlaywright(ctx context.Context, engine searchProvider, browserProxy *playwright.Proxy, query string, limit int) ([]SearchItem, error) {
 	if strings.TrimSpace(query) == "" {
 		return nil, fmt.Errorf("搜索查询不能为空")
 	}
@@ -361,66 +530,23 @@ func (c *SearchClient) searchWithPlaywright(ctx context.Context, query string, l
 		limit = 10
 	}
 
-	log.Printf("[search] provider=%s playwright query=\"%s\" limit=%d", c.provider.displayName(), truncateForLog(query, 80), limit)
+	log.Printf("[search] provider=%s playwright query=\"%s\" limit=%d", engine.displayName(), truncateForLog(query, 80), limit)
 
 	// Run Playwright search
-	items, err := runPlaywrightSearch(ctx, query, limit, c.provider)
+	items, err := runPlaywrightSearch(ctx, c.browsers, browserProxy, query, limit, engine)
 	if err != nil {
 		return nil, fmt.Errorf("Playwright search failed: %w", err)
 	}
 
-	// If no results, try fallback direct mode
+	// An empty page falls through to the next backend in the chain.
 	if len(items) == 0 {
-		log.Printf("[search] no results from Playwright, using fallback")
-		return directMode(query), nil
+		log.Printf("[search] provider=%s playwright query=\"%s\" status=empty", engine.displayName(), truncateForLog(query, 80))
 	}
-
 	return items, nil
 }
 
-// runPlaywrightSearch executes search using Playwright for the configured provider.
-func runPlaywrightSearch(ctx context.Context, query string, limit int, provider searchProvider) ([]SearchItem, error) {
-	// Start Playwright
-	pw, err := playwright.Run()
-	if err != nil {
-		return nil, fmt.Errorf("failed to start Playwright: %w", err)
-	}
-	defer pw.Stop()
-
-	// Create browser context with anti-detection measures
-	userAgent := "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
-
-	browser, err := pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
-		Headless: playwright.Bool(true),
-		Args: []string{
-			"--disable-blink-features=AutomationControlled",
-			"--no-sandbox",
-			"--disable-setuid-sandbox",
-			"--disable-dev-shm-usage",
-			"--disable-web-security",
-			"--disable-features=VizDisplayCompositor",
-		},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to launch browser: %w", err)
-	}
-	defer browser.Close()
-
-	// Create context with custom user agent
-	ctxPW, err := browser.NewContext(playwright.BrowserNewContextOptions{
-		UserAgent: playwright.String(userAgent),
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create context: %w", err)
-	}
-	defer ctxPW.Close()
-
-	// Create page and search
-	page, err := ctxPW.NewPage()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create page: %w", err)
-	}
-
+// runPlaywrightSearch executes search using a pooled Playwright browser for the configured provider.
+func runPlaywrightSearch(ctx context.Context, pool *BrowserPool, browserProxy *playwright.Proxy, query string, limit int, provider searchProvider) ([]SearchItem, error) {
 	var searchURL string
 	var waitSelector string
 	switch provider {
@@ -432,30 +558,49 @@ func runPlaywrightSearch(ctx context.Context, query string, limit int, provider
 		waitSelector = "#search"
 	}
 
-	if _, err := page.Goto(searchURL, playwright.PageGotoOptions{
-		WaitUntil: playwright.WaitUntilStateNetworkidle,
-		Timeout:   playwright.Float(30000),
-	}); err != nil {
-		return nil, fmt.Errorf("failed to navigate to %s: %w", provider.displayName(), err)
-	}
+	var results []SearchItem
+	err := pool.WithPage(ctx, playwright.BrowserNewContextOptions{
+		UserAgent: playwright.String(searchUserAgent),
+		Proxy:     browserProxy,
+	}, func(page playwright.Page) error {
+		if _, err := page.Goto(searchURL, playwright.PageGotoOptions{
+			WaitUntil: playwright.WaitUntilStateNetworkidle,
+			Timeout:   playwright.Float(30000),
+		}); err != nil {
+			return fmt.Errorf("failed to navigate to %s: %w", provider.displayName(), err)
+		}
 
-	// Wait for search results to load
-	page.WaitForSelector(waitSelector, playwright.PageWaitForSelectorOptions{
-		Timeout: playwright.Float(15000),
-	})
+		// Wait for search results to load
+		page.WaitForSelector(waitSelector, playwright.PageWaitForSelectorOptions{
+			Timeout: playwright.Float(15000),
+		})
 
-	// Additional wait to ensure page is fully loaded
-	time.Sleep(2 * time.Second)
+		// Additional wait to ensure page is fully loaded
+		if err := sleepContext(ctx, 2*time.Second); err != nil {
+			return err
+		}
 
-	// Extract search results
-	results, err := extractSearchResults(page, limit, provider)
+		var err error
+		results, err = extractSearchResults(page, limit, provider)
+		if err != nil || len(results) > 0 {
+			return err
+		}
+		// An empty result page is often a CAPTCHA or consent wall; report it
+		// so the provider is degraded instead of silently returning nothing.
+		html, _ := page.Content()
+		if reason := detectSearchBlock(page.URL(), html); reason != "" {
+			return fmt.Errorf("%s %s: %w", provider.displayName(), reason, errSearchBlocked)
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// If we have results, fetch page contents in parallel for better snippets
+	// The result page lease is returned first so snippet fetches never wait on
+	// a slot held by their own search.
 	if len(results) > 0 {
-		results = fetchPageContentsParallel(ctx, results, limit)
+		results = fetchPageContentsParallel(ctx, pool, browserProxy, results, limit)
 	}
 
 	return results, nil
@@ -604,7 +749,7 @@ func buildExtractionScript(provider searchProvider, limit int) string {
 }
 
 // fetchPageContentsParallel fetches page contents in parallel for better snippets.
-func fetchPageContentsParallel(ctx context.Context, items []SearchItem, limit int) []SearchItem {
+func fetchPageContentsParallel(ctx context.Context, pool *BrowserPool, browserProxy *playwright.Proxy, items []SearchItem, limit int) []SearchItem {
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 
@@ -614,6 +759,13 @@ func fetchPageContentsParallel(ctx context.Context, items []SearchItem, limit in
 	results := make([]SearchItem, 0, len(items))
 
 	for _, item := range items {
+		if ctx.Err() != nil {
+			// Keep the remaining results as they are instead of opening more pages.
+			mu.Lock()
+			results = append(results, item)
+			mu.Unlock()
+			continue
+		}
 		sem <- struct{}{}
 		wg.Add(1)
 
@@ -621,61 +773,23 @@ func fetchPageContentsParallel(ctx context.Context, items []SearchItem, limit in
 			defer wg.Done()
 			defer func() { <-sem }()
 
-			// Create new browser for each page to avoid conflicts
-			pw, err := playwright.Run()
-			if err != nil {
-				mu.Lock()
-				results = append(results, item)
-				mu.Unlock()
-				return
-			}
-			defer pw.Stop()
-
-			browser, err := pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
-				Headless: playwright.Bool(true),
-			})
-			if err != nil {
-				mu.Lock()
-				results = append(results, item)
-				mu.Unlock()
-				return
-			}
-			defer browser.Close()
-
-			ctxPW, err := browser.NewContext()
-			if err != nil {
-				mu.Lock()
-				results = append(results, item)
-				mu.Unlock()
-				return
-			}
-			defer ctxPW.Close()
-
-			page, err := ctxPW.NewPage()
-			if err != nil {
-				mu.Lock()
-				results = append(results, item)
-				mu.Unlock()
-				return
-			}
-
-			// Try to fetch the page with timeout
+			// Try to fetch the page with timeout; the pool closes the page's
+			// context when the timeout fires.
 			ctxTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
 			defer cancel()
 
-			go func() {
-				<-ctxTimeout.Done()
-				if ctxTimeout.Err() != nil {
-					page.Close()
+			_ = pool.WithPage(ctxTimeout, playwright.BrowserNewContextOptions{Proxy: browserProxy}, func(page playwright.Page) error {
+				resp, err := page.Goto(item.URL, playwright.PageGotoOptions{
+					WaitUntil: playwright.WaitUntilStateDomcontentloaded,
+					Timeout:   playwright.Float(10000),
+				})
+				if err != nil || resp == nil || resp.Status() >= 400 {
+					return err
 				}
-			}()
-
-			if resp, err := page.Goto(item.URL, playwright.PageGotoOptions{
-				WaitUntil: playwright.WaitUntilStateDomcontentloaded,
-				Timeout:   playwright.Float(10000),
-			}); err == nil && resp != nil && resp.Status() < 400 {
 				// Wait a bit for page to load
-				time.Sleep(1 * time.Second)
+				if err := sleepContext(ctxTimeout, time.Second); err != nil {
+					return err
+				}
 
 				// Get page title if missing
 				if item.Title == "" {
@@ -712,7 +826,8 @@ func fetchPageContentsParallel(ctx context.Context, items []SearchItem, limit in
 						}
 					}
 				}
-			}
+				return nil
+			})
 
 			mu.Lock()
 			results = append(results, item)
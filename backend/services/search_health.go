@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	searchHealthWindow          = 20 // recent outcomes used for the success rate
+	searchHealthMinSamples      = 5
+	searchHealthMinSuccessRate  = 0.5
+	searchHealthMaxConsecutive  = 3
+	searchHealthInitialCooldown = 5 * time.Minute
+	searchHealthMaxCooldown     = time.Hour
+)
+
+// Provider health states reported by SearchHealth.
+const (
+	SearchHealthHealthy  = "healthy"
+	SearchHealthDegraded = "degraded" // cooling down, skipped while others work
+	SearchHealthProbing  = "probing"  // cooldown over, one trial query in flight
+)
+
+// errSearchBlocked marks a result page that is a CAPTCHA or consent wall rather
+// than search results. It degrades the provider immediately.
+var errSearchBlocked = errors.New("搜索引擎返回验证码或同意页面")
+
+// errSearchEmpty records an empty result page from a browser engine. It counts
+// as an ordinary failure, so an engine that keeps returning nothing is
+// degraded after repeated misses instead of looking healthy.
+var errSearchEmpty = errors.New("浏览器搜索未返回结果")
+
+// SearchProviderHealth is the reported health of one search provider.
+type SearchProviderHealth struct {
+	Provider            string     `json:"provider"`
+	State               string     `json:"state"`
+	SuccessRate         float64    `json:"success_rate"` // over the recent window; 1 when unused
+	Successes           int        `json:"successes"`
+	Failures            int        `json:"failures"`
+	Blocked             int        `json:"blocked"` // CAPTCHA or consent pages seen
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	CooldownUntil       *time.Time `json:"cooldown_until,omitempty"`
+}
+
+type providerHealth struct {
+	recent        []bool
+	successes     int
+	failures      int
+	blocked       int
+	consecutive   int
+	lastError     string
+	lastErrorAt   time.Time
+	lastSuccessAt time.Time
+	cooldown      time.Duration
+	cooldownUntil time.Time
+	probing       bool
+}
+
+// SearchHealth tracks search outcomes per provider. A provider that keeps
+// failing, falls below the success rate threshold or serves a CAPTCHA is
+// degraded for a cooldown that doubles on every relapse. Once the cooldown
+// ends a single real query is let through as a probe; success restores it.
+type SearchHealth struct {
+	mu        sync.Mutex
+	providers map[string]*providerHealth
+	now       func() time.Time
+}
+
+// NewSearchHealth creates an empty tracker; unseen providers count as healthy.
+func NewSearchHealth() *SearchHealth {
+	return &SearchHealth{providers: make(map[string]*providerHealth), now: time.Now}
+}
+
+// allow reports whether a query may use the provider now. After the cooldown
+// it admits one probe at a time.
+func (h *SearchHealth) allow(name string) bool {
+	if h == nil {
+		return true
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	p := h.providers[name]
+	if p == nil || p.cooldownUntil.IsZero() {
+		return true
+	}
+	if p.probing || h.now().Before(p.cooldownUntil) {
+		return false
+	}
+	p.probing = true
+	log.Printf("[search] provider=%s status=probe", name)
+	return true
+}
+
+// record stores the outcome of one query. Empty result sets count as success;
+// runSearchChain reports empty pages from browser engines as errSearchEmpty.
+// Queries cut short by the caller say nothing about the provider; they only
+// free the probe slot.
+func (h *SearchHealth) record(name string, err error) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	p := h.providers[name]
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		if p != nil {
+			p.probing = false
+		}
+		return
+	}
+	if p == nil {
+		p = &providerHealth{}
+		h.providers[name] = p
+	}
+	now := h.now()
+	p.recent = append(p.recent, err == nil)
+	if len(p.recent) > searchHealthWindow {
+		p.recent = p.recent[len(p.recent)-searchHealthWindow:]
+	}
+
+	if err == nil {
+		p.successes++
+		p.consecutive = 0
+		p.lastSuccessAt = now
+		if !p.cooldownUntil.IsZero() {
+			log.Printf("[search] provider=%s status=recovered", name)
+		}
+		p.cooldown, p.cooldownUntil, p.probing = 0, time.Time{}, false
+		return
+	}
+
+	p.failures++
+	p.consecutive++
+	p.lastError = err.Error()
+	p.lastErrorAt = now
+	blocked := errors.Is(err, errSearchBlocked)
+	if blocked {
+		p.blocked++
+	}
+	if blocked || p.probing || p.consecutive >= searchHealthMaxConsecutive || lowSuccessRate(p.recent) {
+		p.cooldown *= 2
+		if p.cooldown < searchHealthInitialCooldown {
+			p.cooldown = searchHealthInitialCooldown
+		}
+		if p.cooldown > searchHealthMaxCooldown {
+			p.cooldown = searchHealthMaxCooldown
+		}
+		p.cooldownUntil = now.Add(p.cooldown)
+		p.probing = false
+		log.Printf("[search] provider=%s status=degraded cooldown=%s error=%v", name, p.cooldown, err)
+	}
+}
+
+func lowSuccessRate(recent []bool) bool {
+	return len(recent) >= searchHealthMinSamples && successRate(recent) < searchHealthMinSuccessRate
+}
+
+func successRate(recent []bool) float64 {
+	if len(recent) == 0 {
+		return 1
+	}
+	ok := 0
+	for _, success := range recent {
+		if success {
+			ok++
+		}
+	}
+	return float64(ok) / float64(len(recent))
+}
+
+// Snapshot reports every provider seen so far, sorted by name.
+func (h *SearchHealth) Snapshot() []SearchProviderHealth {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := h.now()
+	out := make([]SearchProviderHealth, 0, len(h.providers))
+	for name, p := range h.providers {
+		entry := SearchProviderHealth{
+			Provider:            name,
+			State:               SearchHealthHealthy,
+			SuccessRate:         successRate(p.recent),
+			Successes:           p.successes,
+			Failures:            p.failures,
+			Blocked:             p.blocked,
+			ConsecutiveFailures: p.consecutive,
+			LastError:           p.lastError,
+			LastErrorAt:         timePtr(p.lastErrorAt),
+			LastSuccessAt:       timePtr(p.lastSuccessAt),
+			CooldownUntil:       timePtr(p.cooldownUntil),
+		}
+		switch {
+		case p.probing || (!p.cooldownUntil.IsZero() && !now.Before(p.cooldownUntil)):
+			entry.State = SearchHealthProbing
+		case !p.cooldownUntil.IsZero():
+			entry.State = SearchHealthDegraded
+		}
+		out = append(out, entry)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Provider < out[j].Provider })
+	return out
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// detectSearchBlock inspects a result page for CAPTCHA and consent walls and
+// returns a short reason, or "" for a normal page.
+func detectSearchBlock(pageURL, html string) string {
+	lowerURL := strings.ToLower(pageURL)
+	switch {
+	case strings.Contains(lowerURL, "google.com/sorry/"):
+		return "Google 异常流量验证"
+	case strings.Contains(lowerURL, "consent.google.") || strings.Contains(lowerURL, "consent.youtube."):
+		return "Google Cookie 同意页面"
+	}
+	lowerHTML := strings.ToLower(html)
+	markers := []struct{ marker, reason string }{
+		{"unusual traffic from your computer network", "Google 异常流量验证"},
+		{"before you continue to google", "Google Cookie 同意页面"},
+		{"g-recaptcha", "reCAPTCHA 验证"},
+		{"hcaptcha.com", "hCaptcha 验证"},
+		{"challenges.cloudflare.com", "Cloudflare 验证"},
+		{"please solve the challenge below to continue", "Bing 人机验证"},
+		{"one last step", "Bing 人机验证"},
+	}
+	for _, item := range markers {
+		if strings.Contains(lowerHTML, item.marker) {
+			return item.reason
+		}
+	}
+	return ""
+}
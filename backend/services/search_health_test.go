@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type scriptedBackend struct {
+	name  string
+	err   error
+	items []SearchItem
+	calls int
+}
+
+func (b *scriptedBackend) Name() string { return b.name }
+
+func (b *scriptedBackend) Search(context.Context, string, int) ([]SearchItem, error) {
+	b.calls++
+	return b.items, b.err
+}
+
+func newTestSearchHealth(now *time.Time) *SearchHealth {
+	health := NewSearchHealth()
+	health.now = func() time.Time { return *now }
+	return health
+}
+
+func TestSearchHealthFailsOverWhenProviderIsBlocked(t *testing.T) {
+	now := time.Date(2024, 5, 1, 15, 0, 0, 0, time.UTC)
+	health := newTestSearchHealth(&now)
+	google := &scriptedBackend{name: "playwright_google", err: fmt.Errorf("Google 异常流量验证: %w", errSearchBlocked)}
+	bing := &scriptedBackend{name: "playwright_bing", items: []SearchItem{{URL: "https://acme.example"}}}
+	backends := []SearchBackend{google, bing}
+
+	if _, name, err := runSearchChain(context.Background(), health, backends, "acme", 5); err != nil || name != "playwright_bing" {
+		t.Fatalf("expected bing to answer, got %s %v", name, err)
+	}
+	if _, name, _ := runSearchChain(context.Background(), health, backends, "acme", 5); name != "playwright_bing" || google.calls != 1 {
+		t.Fatalf("blocked provider should be skipped during cooldown, google calls=%d", google.calls)
+	}
+
+	snapshot := health.Snapshot()
+	if len(snapshot) != 2 || snapshot[1].Provider != "playwright_google" || snapshot[1].State != SearchHealthDegraded || snapshot[1].Blocked != 1 {
+		t.Fatalf("unexpected snapshot %#v", snapshot)
+	}
+
+	// After the cooldown one probe goes through; success restores the provider.
+	now = now.Add(searchHealthInitialCooldown + time.Second)
+	google.err = nil
+	google.items = []SearchItem{{URL: "https://acme.example/google"}}
+	if items, name, _ := runSearchChain(context.Background(), health, backends, "acme", 5); name != "playwright_google" || len(items) != 1 {
+		t.Fatalf("expected google probe to answer, got %s", name)
+	}
+	if state := health.Snapshot()[1].State; state != SearchHealthHealthy {
+		t.Fatalf("expected google to recover, got %s", state)
+	}
+}
+
+func TestSearchHealthBacksOffOnRepeatedFailures(t *testing.T) {
+	now := time.Date(2024, 5, 1, 15, 0, 0, 0, time.UTC)
+	health := newTestSearchHealth(&now)
+	boom := errors.New("boom")
+
+	for i := 0; i < searchHealthMaxConsecutive-1; i++ {
+		health.record("brave", boom)
+	}
+	if !health.allow("brave") {
+		t.Fatalf("provider should stay in use below the failure threshold")
+	}
+	health.record("brave", boom)
+	if health.allow("brave") {
+		t.Fatalf("provider should be degraded after %d consecutive failures", searchHealthMaxConsecutive)
+	}
+
+	now = now.Add(searchHealthInitialCooldown)
+	if !health.allow("brave") {
+		t.Fatalf("expected a probe after the cooldown")
+	}
+	if health.allow("brave") {
+		t.Fatalf("only one probe may be in flight")
+	}
+	health.record("brave", boom)
+	if until := health.Snapshot()[0].CooldownUntil; until == nil || !until.Equal(now.Add(2*searchHealthInitialCooldown)) {
+		t.Fatalf("failed probe should double the cooldown, got %v", until)
+	}
+
+	now = now.Add(2 * searchHealthInitialCooldown)
+	if !health.allow("brave") {
+		t.Fatalf("expected a second probe")
+	}
+	health.record("brave", context.Canceled)
+	if !health.allow("brave") {
+		t.Fatalf("a cancelled probe should free the probe slot")
+	}
+}
+
+func TestSearchHealthDegradesOnLowSuccessRate(t *testing.T) {
+	now := time.Now()
+	health := newTestSearchHealth(&now)
+	boom := errors.New("boom")
+	for _, ok := range []bool{true, false, true, false, false, true, false, false} {
+		if ok {
+			health.record("serpapi", nil)
+		} else {
+			health.record("serpapi", boom)
+		}
+	}
+	if health.allow("serpapi") {
+		t.Fatalf("provider below %.0f%% success should be degraded, snapshot %#v", searchHealthMinSuccessRate*100, health.Snapshot())
+	}
+}
+
+func TestSearchChainUsesDegradedProvidersAsLastResort(t *testing.T) {
+	now := time.Now()
+	health := newTestSearchHealth(&now)
+	only := &scriptedBackend{name: "brave", items: []SearchItem{{URL: "https://acme.example"}}}
+	health.record("brave", fmt.Errorf("blocked: %w", errSearchBlocked))
+
+	items, name, err := runSearchChain(context.Background(), health, []SearchBackend{only}, "acme", 5)
+	if err != nil || name != "brave" || len(items) != 1 {
+		t.Fatalf("degraded provider should still answer when nothing else can, got %s %v", name, err)
+	}
+}
+
+func TestDetectSearchBlock(t *testing.T) {
+	cases := []struct {
+		url, html string
+		blocked   bool
+	}{
+		{"https://www.google.com/sorry/index?continue=x", "", true},
+		{"https://consent.google.com/ml?continue=x", "", true},
+		{"https://www.google.com/search?q=a", `<div>Our systems have detected unusual traffic from your computer network.</div>`, true},
+		{"https://www.bing.com/search?q=a", `<h1>One last step</h1><p>Please solve the challenge below to continue</p>`, true},
+		{"https://www.google.com/search?q=a", `<div class="g-recaptcha" data-sitekey="x"></div>`, true},
+		{"https://www.google.com/search?q=a", `<div id="search"><a href="https://acme.example">Acme</a></div>`, false},
+	}
+	for _, tc := range cases {
+		if got := detectSearchBlock(tc.url, tc.html) != ""; got != tc.blocked {
+			t.Fatalf("detectSearchBlock(%q) = %v, want %v", tc.url, got, tc.blocked)
+		}
+	}
+}
+
+func TestSearchHealthDegradesBrowserReturningNothing(t *testing.T) {
+	now := time.Date(2024, 5, 1, 15, 0, 0, 0, time.UTC)
+	health := newTestSearchHealth(&now)
+	google := scriptedBrowserBackend{&scriptedBackend{name: "playwright_google"}}
+	brave := &scriptedBackend{name: "brave", items: []SearchItem{{URL: "https://acme.example"}}}
+	searxng := &scriptedBackend{name: "searxng"}
+	backends := []SearchBackend{google, brave}
+
+	for i := 0; i < searchHealthMaxConsecutive; i++ {
+		if _, name, err := runSearchChain(context.Background(), health, backends, "acme", 5); err != nil || name != "brave" {
+			t.Fatalf("expected brave to answer, got %s %v", name, err)
+		}
+	}
+	runSearchChain(context.Background(), health, backends, "acme", 5)
+	if google.calls != searchHealthMaxConsecutive {
+		t.Fatalf("empty browser engine should be skipped during cooldown, calls=%d", google.calls)
+	}
+	snapshot := health.Snapshot()
+	if snapshot[1].Provider != "playwright_google" || snapshot[1].State != SearchHealthDegraded || snapshot[1].LastError != errSearchEmpty.Error() {
+		t.Fatalf("unexpected snapshot %#v", snapshot)
+	}
+
+	// An API provider with no matches is still healthy.
+	for i := 0; i < searchHealthMaxConsecutive; i++ {
+		runSearchChain(context.Background(), health, []SearchBackend{searxng}, "nothing", 5)
+	}
+	if !health.allow("searxng") {
+		t.Fatalf("empty API results should not degrade the provider")
+	}
+}
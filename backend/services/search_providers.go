@@ -0,0 +1,468 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+// Default endpoints of the hosted search APIs; settings may override them.
+const (
+	defaultBingAPIEndpoint = "https://api.bing.microsoft.com/v7.0/search"
+	defaultBraveEndpoint   = "https://api.search.brave.com/res/v1/web/search"
+	defaultSerpAPIEndpoint = "https://serpapi.com/search.json"
+)
+
+// SearchBackend is one web search implementation selectable in settings.
+type SearchBackend interface {
+	// Name returns the settings identifier of the backend.
+	Name() string
+	Search(ctx context.Context, query string, limit int) ([]SearchItem, error)
+}
+
+// errSearchNotConfigured marks a backend that lacks its API key or endpoint.
+var errSearchNotConfigured = errors.New("搜索服务未配置")
+
+// searchBackends builds the ordered failover chain from settings: the primary
+// provider followed by the fallback list. Automatic Playwright mode expands to
+// both engines, the reachable one first, so a blocked engine fails over to the
+// other. Unknown names are skipped; when nothing usable remains the direct mode
+// is used so a search never hard-fails on configuration alone.
+func (c *SearchClient) searchBackends(settings *store.Settings) []SearchBackend {
+	route := searchRoute{http: c.proxies.httpClient(settings, store.ProxyChannelSearch, c.http())}
+	if cfg, err := resolveProxy(settings, store.ProxyChannelSearch); err == nil {
+		route.browserProxy = cfg.playwright()
+	}
+	var names []string
+	for _, name := range append([]string{settings.SearchProvider}, settings.SearchFallbackProviders...) {
+		name = store.NormalizeSearchProvider(name)
+		if name == store.SearchProviderPlaywright {
+			if c.playwrightEngine(settings) == searchProviderBing {
+				names = append(names, store.SearchProviderPlaywrightBing, store.SearchProviderPlaywrightGoogle)
+			} else {
+				names = append(names, store.SearchProviderPlaywrightGoogle, store.SearchProviderPlaywrightBing)
+			}
+			continue
+		}
+		names = append(names, name)
+	}
+	var backends []SearchBackend
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		backend := c.newSearchBackend(name, scopedSearchValue(settings.SearchAPIKey, name), scopedSearchValue(settings.SearchEndpoint, name), route)
+		if backend == nil {
+			log.Printf("[search] 不支持的搜索服务 %q，已忽略", name)
+			continue
+		}
+		backends = append(backends, backend)
+	}
+	if len(backends) == 0 {
+		backends = append(backends, directBackend{})
+	}
+	return backends
+}
+
+// searchRoute carries the proxied HTTP client and browser proxy for the
+// search channel.
+type searchRoute struct {
+	http         *http.Client
+	browserProxy *playwright.Proxy
+}
+
+func (c *SearchClient) newSearchBackend(name, apiKey, endpoint string, route searchRoute) SearchBackend {
+	switch name {
+	case store.SearchProviderPlaywrightGoogle:
+		return playwrightBackend{client: c, engine: searchProviderGoogle, proxy: route.browserProxy}
+	case store.SearchProviderPlaywrightBing:
+		return playwrightBackend{client: c, engine: searchProviderBing, proxy: route.browserProxy}
+	case store.SearchProviderSearXNG:
+		return &searxngBackend{http: route.http, endpoint: endpoint, apiKey: apiKey}
+	case store.SearchProviderBingAPI:
+		return &bingAPIBackend{http: route.http, endpoint: firstNonEmpty(endpoint, defaultBingAPIEndpoint), apiKey: apiKey}
+	case store.SearchProviderBrave:
+		return &braveBackend{http: route.http, endpoint: firstNonEmpty(endpoint, defaultBraveEndpoint), apiKey: apiKey}
+	case store.SearchProviderSerpAPI:
+		return &serpAPIBackend{http: route.http, endpoint: firstNonEmpty(endpoint, defaultSerpAPIEndpoint), apiKey: apiKey}
+	case store.SearchProviderDirect:
+		return directBackend{}
+	default:
+		return nil
+	}
+}
+
+// scopedSearchValue resolves a per-provider value from the search_api_key or
+// search_endpoint setting. Entries are separated by ";" or new lines and may be
+// scoped as "brave=KEY"; the first unscoped entry applies to every provider
+// without a scoped entry.
+func scopedSearchValue(raw, provider string) string {
+	var plain string
+	for _, entry := range strings.FieldsFunc(raw, func(r rune) bool { return r == ';' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if name, value, ok := strings.Cut(entry, "="); ok {
+			if name = store.NormalizeSearchProvider(name); isSearchProviderName(name) {
+				if name == provider {
+					return strings.TrimSpace(value)
+				}
+				continue
+			}
+		}
+		if plain == "" {
+			plain = entry
+		}
+	}
+	return plain
+}
+
+func isSearchProviderName(name string) bool {
+	for _, item := range store.SearchProviders {
+		if item == name {
+			return true
+		}
+	}
+	return false
+}
+
+// runSearchChain tries each backend in order until one returns results and
+// reports which backend answered. Empty result sets also fall through so a later
+// backend gets a chance. When none has results, an empty answer from any backend
+// wins over errors; otherwise the last error is returned.
+//
+// Backends that health marks as degraded are skipped and only tried as a last
+// resort, after every healthy backend came up empty. Each outcome is recorded,
+// so a provider that starts failing mid-run is dropped for later queries; an
+// empty page from a browser engine counts as a failure there.
+func runSearchChain(ctx context.Context, health *SearchHealth, backends []SearchBackend, query string, limit int) ([]SearchItem, string, error) {
+	var (
+		lastErr  error
+		anyEmpty bool
+		deferred []SearchBackend
+	)
+	try := func(backend SearchBackend) ([]SearchItem, bool) {
+		items, err := backend.Search(ctx, query, limit)
+		if ctx.Err() != nil {
+			health.record(backend.Name(), ctx.Err())
+			return nil, false
+		}
+		outcome := err
+		if _, browser := backend.(browserSearchBackend); browser && err == nil && len(items) == 0 {
+			outcome = errSearchEmpty
+		}
+		health.record(backend.Name(), outcome)
+		if err == nil && len(items) > 0 {
+			return items, true
+		}
+		if err == nil {
+			anyEmpty = true
+		} else {
+			lastErr = err
+		}
+		log.Printf("[search] provider=%s status=failover results=%d error=%v", backend.Name(), len(items), err)
+		return nil, false
+	}
+	for _, backend := range backends {
+		if !health.allow(backend.Name()) {
+			deferred = append(deferred, backend)
+			continue
+		}
+		if items, ok := try(backend); ok {
+			return items, backend.Name(), nil
+		}
+		if ctx.Err() != nil {
+			return nil, backend.Name(), ctx.Err()
+		}
+	}
+	for _, backend := range deferred {
+		log.Printf("[search] provider=%s status=last-resort", backend.Name())
+		if items, ok := try(backend); ok {
+			return items, backend.Name(), nil
+		}
+		if ctx.Err() != nil {
+			return nil, backend.Name(), ctx.Err()
+		}
+	}
+	last := backends[len(backends)-1].Name()
+	if anyEmpty {
+		return nil, last, nil
+	}
+	return nil, last, lastErr
+}
+
+// browserSearchBackend is implemented by backends that scrape result pages in
+// a browser. An empty page from them more often means a block or a changed
+// layout than a query without matches.
+type browserSearchBackend interface {
+	SearchBackend
+	scrapesResultPages()
+}
+
+// playwrightBackend scrapes Google or Bing result pages in a headless browser.
+type playwrightBackend struct {
+	client *SearchClient
+	engine searchProvider
+	proxy  *playwright.Proxy
+}
+
+func (b playwrightBackend) Name() string {
+	if b.engine == searchProviderBing {
+		return store.SearchProviderPlaywrightBing
+	}
+	return store.SearchProviderPlaywrightGoogle
+}
+
+func (b playwrightBackend) Search(ctx context.Context, query string, limit int) ([]SearchItem, error) {
+	return b.client.searchWithPlaywright(ctx, b.engine, b.proxy, query, limit)
+}
+
+func (playwrightBackend) scrapesResultPages() {}
+
+// directBackend returns the query itself as the only result; it needs no network.
+type directBackend struct{}
+
+func (directBackend) Name() string { return store.SearchProviderDirect }
+
+func (directBackend) Search(_ context.Context, query string, _ int) ([]SearchItem, error) {
+	return directMode(query), nil
+}
+
+// searxngBackend queries a self-hosted SearXNG instance with format=json enabled.
+type searxngBackend struct {
+	http     *http.Client
+	endpoint string
+	apiKey   string
+}
+
+func (b *searxngBackend) Name() string { return store.SearchProviderSearXNG }
+
+func (b *searxngBackend) Search(ctx context.Context, query string, limit int) ([]SearchItem, error) {
+	if strings.TrimSpace(b.endpoint) == "" {
+		return nil, fmt.Errorf("SearXNG 地址未配置: %w", errSearchNotConfigured)
+	}
+	endpoint := strings.TrimRight(strings.TrimSpace(b.endpoint), "/")
+	if !strings.HasSuffix(endpoint, "/search") {
+		joined, err := url.JoinPath(endpoint, "search")
+		if err != nil {
+			return nil, fmt.Errorf("拼接 SearXNG 地址失败: %w", err)
+		}
+		endpoint = joined
+	}
+	params := url.Values{"q": {query}, "format": {"json"}, "pageno": {"1"}}
+	headers := map[string]string{}
+	if b.apiKey != "" {
+		headers["Authorization"] = "Bearer " + b.apiKey
+	}
+	var parsed struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := getSearchJSON(ctx, b.http, b.Name(), endpoint, params, headers, &parsed); err != nil {
+		return nil, err
+	}
+	items := make([]SearchItem, 0, len(parsed.Results))
+	for _, r := range parsed.Results {
+		items = append(items, SearchItem{Title: r.Title, URL: r.URL, Snippet: r.Content})
+	}
+	return trimSearchItems(items, limit), nil
+}
+
+// bingAPIBackend calls the Bing Web Search v7 API.
+type bingAPIBackend struct {
+	http     *http.Client
+	endpoint string
+	apiKey   string
+}
+
+func (b *bingAPIBackend) Name() string { return store.SearchProviderBingAPI }
+
+func (b *bingAPIBackend) Search(ctx context.Context, query string, limit int) ([]SearchItem, error) {
+	if b.apiKey == "" {
+		return nil, fmt.Errorf("Bing Search API Key 未配置: %w", errSearchNotConfigured)
+	}
+	params := url.Values{"q": {query}, "count": {strconv.Itoa(clampSearchLimit(limit, 50))}, "mkt": {"en-US"}, "responseFilter": {"Webpages"}}
+	headers := map[string]string{"Ocp-Apim-Subscription-Key": b.apiKey}
+	var parsed struct {
+		WebPages struct {
+			Value []struct {
+				Name    string `json:"name"`
+				URL     string `json:"url"`
+				Snippet string `json:"snippet"`
+			} `json:"value"`
+		} `json:"webPages"`
+	}
+	if err := getSearchJSON(ctx, b.http, b.Name(), b.endpoint, params, headers, &parsed); err != nil {
+		return nil, err
+	}
+	items := make([]SearchItem, 0, len(parsed.WebPages.Value))
+	for _, r := range parsed.WebPages.Value {
+		items = append(items, SearchItem{Title: r.Name, URL: r.URL, Snippet: r.Snippet})
+	}
+	return trimSearchItems(items, limit), nil
+}
+
+// braveBackend calls the Brave Search web API.
+type braveBackend struct {
+	http     *http.Client
+	endpoint string
+	apiKey   string
+}
+
+func (b *braveBackend) Name() string { return store.SearchProviderBrave }
+
+func (b *braveBackend) Search(ctx context.Context, query string, limit int) ([]SearchItem, error) {
+	if b.apiKey == "" {
+		return nil, fmt.Errorf("Brave Search API Key 未配置: %w", errSearchNotConfigured)
+	}
+	params := url.Values{"q": {query}, "count": {strconv.Itoa(clampSearchLimit(limit, 20))}}
+	headers := map[string]string{"X-Subscription-Token": b.apiKey}
+	var parsed struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := getSearchJSON(ctx, b.http, b.Name(), b.endpoint, params, headers, &parsed); err != nil {
+		return nil, err
+	}
+	items := make([]SearchItem, 0, len(parsed.Web.Results))
+	for _, r := range parsed.Web.Results {
+		items = append(items, SearchItem{Title: r.Title, URL: r.URL, Snippet: stripSearchMarkup(r.Description)})
+	}
+	return trimSearchItems(items, limit), nil
+}
+
+// serpAPIBackend calls SerpAPI or any endpoint returning the same
+// organic_results shape, such as self-hosted proxies.
+type serpAPIBackend struct {
+	http     *http.Client
+	endpoint string
+	apiKey   string
+}
+
+func (b *serpAPIBackend) Name() string { return store.SearchProviderSerpAPI }
+
+func (b *serpAPIBackend) Search(ctx context.Context, query string, limit int) ([]SearchItem, error) {
+	if b.apiKey == "" {
+		return nil, fmt.Errorf("SerpAPI Key 未配置: %w", errSearchNotConfigured)
+	}
+	params := url.Values{"engine": {"google"}, "q": {query}, "num": {strconv.Itoa(clampSearchLimit(limit, 100))}, "api_key": {b.apiKey}}
+	var parsed struct {
+		Error          string `json:"error"`
+		OrganicResults []struct {
+			Title   string `json:"title"`
+			Link    string `json:"link"`
+			Snippet string `json:"snippet"`
+		} `json:"organic_results"`
+	}
+	if err := getSearchJSON(ctx, b.http, b.Name(), b.endpoint, params, nil, &parsed); err != nil {
+		return nil, err
+	}
+	if parsed.Error != "" && len(parsed.OrganicResults) == 0 {
+		if strings.Contains(strings.ToLower(parsed.Error), "hasn't returned any results") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("serpapi 返回错误: %s", parsed.Error)
+	}
+	items := make([]SearchItem, 0, len(parsed.OrganicResults))
+	for _, r := range parsed.OrganicResults {
+		items = append(items, SearchItem{Title: r.Title, URL: r.Link, Snippet: r.Snippet})
+	}
+	return trimSearchItems(items, limit), nil
+}
+
+// getSearchJSON issues a GET request against a search API and decodes the JSON body into out.
+func getSearchJSON(ctx context.Context, client *http.Client, provider, endpoint string, params url.Values, headers map[string]string, out any) error {
+	target, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("%s 地址无效: %w", provider, err)
+	}
+	query := target.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	target.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return fmt.Errorf("构建 %s 请求失败: %w", provider, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// Some providers take the API key in the query string; keep it out of
+		// logs and the search health report.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = (&url.URL{Scheme: target.Scheme, Host: target.Host, Path: target.Path}).String()
+		}
+		return fmt.Errorf("请求 %s 失败: %w", provider, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s 返回状态码 %d: %s", provider, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(out); err != nil {
+		return fmt.Errorf("解析 %s 响应失败: %w", provider, err)
+	}
+	return nil
+}
+
+func clampSearchLimit(limit, max int) int {
+	if limit <= 0 {
+		return 10
+	}
+	if limit > max {
+		return max
+	}
+	return limit
+}
+
+// trimSearchItems drops results without a URL and caps the list at limit.
+func trimSearchItems(items []SearchItem, limit int) []SearchItem {
+	cleaned := make([]SearchItem, 0, len(items))
+	for _, item := range items {
+		item.URL = strings.TrimSpace(item.URL)
+		if item.URL == "" {
+			continue
+		}
+		item.Title = strings.TrimSpace(item.Title)
+		item.Snippet = strings.TrimSpace(item.Snippet)
+		cleaned = append(cleaned, item)
+		if limit > 0 && len(cleaned) >= limit {
+			break
+		}
+	}
+	return cleaned
+}
+
+// stripSearchMarkup removes the <strong> highlighting some APIs put in snippets.
+func stripSearchMarkup(text string) string {
+	replacer := strings.NewReplacer("<strong>", "", "</strong>", "", "<b>", "", "</b>", "")
+	return replacer.Replace(text)
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+func TestSearchBackendsAgainstFakeAPIs(t *testing.T) {
+	cases := []struct {
+		name      string
+		path      string
+		header    string
+		body      string
+		newClient func(endpoint string, client *http.Client) SearchBackend
+	}{
+		{
+			name: "searxng",
+			path: "/search",
+			body: `{"results":[{"title":"Acme","url":"https://acme.example","content":"Widgets"},{"title":"No URL"}]}`,
+			newClient: func(endpoint string, client *http.Client) SearchBackend {
+				return &searxngBackend{http: client, endpoint: endpoint}
+			},
+		},
+		{
+			name:   "bing_api",
+			path:   "/v7.0/search",
+			header: "Ocp-Apim-Subscription-Key",
+			body:   `{"webPages":{"value":[{"name":"Acme","url":"https://acme.example","snippet":"Widgets"}]}}`,
+			newClient: func(endpoint string, client *http.Client) SearchBackend {
+				return &bingAPIBackend{http: client, endpoint: endpoint + "/v7.0/search", apiKey: "k"}
+			},
+		},
+		{
+			name:   "brave",
+			path:   "/res/v1/web/search",
+			header: "X-Subscription-Token",
+			body:   `{"web":{"results":[{"title":"Acme","url":"https://acme.example","description":"<strong>Widgets</strong>"}]}}`,
+			newClient: func(endpoint string, client *http.Client) SearchBackend {
+				return &braveBackend{http: client, endpoint: endpoint + "/res/v1/web/search", apiKey: "k"}
+			},
+		},
+		{
+			name: "serpapi",
+			path: "/search.json",
+			body: `{"organic_results":[{"title":"Acme","link":"https://acme.example","snippet":"Widgets"}]}`,
+			newClient: func(endpoint string, client *http.Client) SearchBackend {
+				return &serpAPIBackend{http: client, endpoint: endpoint + "/search.json", apiKey: "k"}
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tc.path {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				if r.URL.Query().Get("q") != "acme widgets" {
+					t.Errorf("query not forwarded: %s", r.URL.RawQuery)
+				}
+				if tc.header != "" && r.Header.Get(tc.header) != "k" {
+					t.Errorf("missing %s header", tc.header)
+				}
+				if tc.name == "serpapi" && r.URL.Query().Get("api_key") != "k" {
+					t.Errorf("missing api_key parameter")
+				}
+				fmt.Fprint(w, tc.body)
+			}))
+			defer server.Close()
+
+			backend := tc.newClient(server.URL, server.Client())
+			items, err := backend.Search(context.Background(), "acme widgets", 5)
+			if err != nil {
+				t.Fatalf("search: %v", err)
+			}
+			if backend.Name() != tc.name {
+				t.Fatalf("unexpected name %s", backend.Name())
+			}
+			if len(items) != 1 || items[0].URL != "https://acme.example" || items[0].Title != "Acme" || items[0].Snippet != "Widgets" {
+				t.Fatalf("unexpected items %#v", items)
+			}
+		})
+	}
+}
+
+func TestSearchFailsOverAlongConfiguredChain(t *testing.T) {
+	var braveCalls, serpCalls int
+	brave := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		braveCalls++
+		if r.Header.Get("X-Subscription-Token") != "brave-key" {
+			t.Errorf("brave got wrong key %q", r.Header.Get("X-Subscription-Token"))
+		}
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"error":"rate limited"}`)
+	}))
+	defer brave.Close()
+	serp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serpCalls++
+		if r.URL.Query().Get("api_key") != "shared-key" {
+			t.Errorf("serpapi got wrong key %q", r.URL.Query().Get("api_key"))
+		}
+		fmt.Fprint(w, `{"organic_results":[{"title":"Acme","link":"https://acme.example"}]}`)
+	}))
+	defer serp.Close()
+
+	st := setupTestStore(t)
+	defer st.Close()
+	data, _ := json.Marshal(store.Settings{
+		SearchProvider:          "brave",
+		SearchAPIKey:            "brave=brave-key; shared-key",
+		SearchEndpoint:          fmt.Sprintf("brave=%s\nserpapi=%s", brave.URL, serp.URL),
+		SearchFallbackProviders: []string{"unknown-engine", "serpapi", "brave"},
+	})
+	if err := st.SaveSettings(context.Background(), bytes.NewReader(data)); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	settings, err := st.GetSettings(context.Background())
+	if err != nil {
+		t.Fatalf("get settings: %v", err)
+	}
+	if len(settings.SearchFallbackProviders) != 2 || settings.SearchFallbackProviders[1] != "serpapi" {
+		t.Fatalf("fallback list not normalised: %v", settings.SearchFallbackProviders)
+	}
+
+	client := NewSearchClient(st, http.DefaultClient)
+	items, err := client.Search(context.Background(), "acme", 5)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(items) != 1 || items[0].URL != "https://acme.example" {
+		t.Fatalf("unexpected items %#v", items)
+	}
+	if braveCalls != 1 || serpCalls != 1 {
+		t.Fatalf("expected one call per provider, got brave=%d serpapi=%d", braveCalls, serpCalls)
+	}
+
+	plan, err := client.ExecutePlan(context.Background(), "Acme")
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if plan.Provider != "brave" || len(plan.Combined()) != 1 {
+		t.Fatalf("unexpected plan %#v", plan)
+	}
+}
+
+func TestSearchChainReportsLastError(t *testing.T) {
+	backends := []SearchBackend{&braveBackend{}, &bingAPIBackend{}}
+	if _, name, err := runSearchChain(context.Background(), nil, backends, "acme", 5); err == nil || name != "bing_api" {
+		t.Fatalf("expected configuration error from last backend, got %s %v", name, err)
+	}
+	backends = append(backends, directBackend{})
+	items, name, err := runSearchChain(context.Background(), nil, backends, "acme.example", 5)
+	if err != nil || name != "direct" || len(items) != 1 {
+		t.Fatalf("expected direct mode to answer, got %s %v %v", name, items, err)
+	}
+}
+
+func TestSearchRequestErrorsHideQueryString(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	endpoint := server.URL + "/search.json"
+	server.Close()
+
+	var out map[string]any
+	err := getSearchJSON(context.Background(), server.Client(), "SerpAPI", endpoint, url.Values{"api_key": {"serp-secret"}, "q": {"acme"}}, nil, &out)
+	if err == nil {
+		t.Fatal("expected a connection error")
+	}
+	if strings.Contains(err.Error(), "serp-secret") || !strings.Contains(err.Error(), "/search.json") {
+		t.Fatalf("error = %v", err)
+	}
+}
+
+// scriptedBrowserBackend is a scripted backend that scrapes result pages.
+type scriptedBrowserBackend struct{ *scriptedBackend }
+
+func (scriptedBrowserBackend) scrapesResultPages() {}
+
+func TestEmptyBrowserSearchFallsThroughChain(t *testing.T) {
+	google := scriptedBrowserBackend{&scriptedBackend{name: "playwright_google"}}
+	brave := &scriptedBackend{name: "brave"}
+	client := &SearchClient{health: NewSearchHealth(), cache: NewResponseCache(t.TempDir())}
+	settings := &store.Settings{SearchCacheTTLHours: 1}
+	backends := []SearchBackend{google, brave}
+
+	// Every backend empty: the query itself stands in, but is not cached.
+	items, err := client.cachedSearch(context.Background(), settings, backends, "acme.example", 5)
+	if err != nil || len(items) != 1 || items[0].Title != "acme.example" {
+		t.Fatalf("exhausted chain = %#v, %v; want the direct-mode placeholder", items, err)
+	}
+
+	brave.items = []SearchItem{{URL: "https://acme.example"}}
+	items, err = client.cachedSearch(context.Background(), settings, backends, "acme.example", 5)
+	if err != nil || len(items) != 1 || items[0].URL != "https://acme.example" {
+		t.Fatalf("empty browser page should fall through to brave, got %#v, %v", items, err)
+	}
+	if google.calls != 2 || brave.calls != 2 {
+		t.Fatalf("placeholder was served from cache: google=%d brave=%d", google.calls, brave.calls)
+	}
+
+	// Chains without a browser backend keep reporting nothing.
+	empty := &scriptedBackend{name: "serpapi"}
+	if items, err := client.cachedSearch(context.Background(), settings, []SearchBackend{empty}, "nothing", 5); err != nil || len(items) != 0 {
+		t.Fatalf("API chain = %#v, %v; want no results", items, err)
+	}
+}
@@ -1,12 +1,17 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/anner/ai-foreign-trade-assistant/backend/store"
@@ -313,3 +318,69 @@ func testCompanyWebsite(t *testing.T, ctx context.Context, client *SearchClient,
 
 	return false
 }
+
+func TestSearchDryRunPlanReportsEveryStage(t *testing.T) {
+	var mu sync.Mutex
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("q")
+		mu.Lock()
+		queries = append(queries, query)
+		mu.Unlock()
+		if strings.Contains(query, "importer") {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprintf(w, `{"results":[{"title":%q,"url":"https://acme.example/%d"}]}`, query, len(query))
+	}))
+	defer server.Close()
+
+	st := setupTestStore(t)
+	defer st.Close()
+	data, _ := json.Marshal(store.Settings{
+		SearchProvider: store.SearchProviderSearXNG,
+		SearchEndpoint: server.URL,
+		SearchPlan: []store.SearchPlanStage{
+			{Key: "broad", Label: "Broad", Query: "{company}", Enabled: true},
+			{Key: "distributors", Label: "Distributors", Query: "{company} distributor", Limit: 3, Enabled: true},
+			{Key: "importers", Label: "Importers", Query: "importer", Enabled: true},
+			{Key: "linkedin", Label: "LinkedIn", Query: "site:linkedin.com {company}", Enabled: false},
+		},
+	})
+	if err := st.SaveSettings(context.Background(), bytes.NewReader(data)); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	client := NewSearchClient(st, server.Client())
+	preview, err := client.DryRunPlan(context.Background(), "Acme", nil)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(preview.Stages) != 4 || len(queries) != 3 {
+		t.Fatalf("expected 4 stages and 3 executed queries, got %d stages %v", len(preview.Stages), queries)
+	}
+	if s := preview.Stages[1]; s.Query != "Acme distributor" || s.Limit != 3 || len(s.Items) != 1 {
+		t.Fatalf("unexpected distributor stage %#v", s)
+	}
+	if s := preview.Stages[2]; s.Query != "Acme importer" || s.Error == "" {
+		t.Fatalf("failed stage should report its error, got %#v", s)
+	}
+	if s := preview.Stages[3]; s.Enabled || s.Query != "site:linkedin.com Acme" || s.Items != nil {
+		t.Fatalf("disabled stage should be listed but not run, got %#v", s)
+	}
+	if len(preview.Combined) != 2 {
+		t.Fatalf("expected combined results from the two healthy stages, got %v", preview.Combined)
+	}
+
+	plan, err := client.ExecutePlan(context.Background(), "Acme")
+	if err != nil {
+		t.Fatalf("execute plan: %v", err)
+	}
+	if len(plan.Order) != 2 || plan.Order[0] != "broad" || plan.Result("distributors").Label != "Distributors" {
+		t.Fatalf("configured plan not used: %#v", plan)
+	}
+
+	if _, err := client.DryRunPlan(context.Background(), "Acme", []store.SearchPlanStage{{Key: "off"}}); err == nil {
+		t.Fatalf("expected an override without enabled stages to be rejected")
+	}
+}
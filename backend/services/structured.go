@@ -0,0 +1,270 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// schemaField describes one expected key of a structured LLM reply. Nested keys
+// use dotted paths such as "reasoning.positive_signals".
+type schemaField struct {
+	Path     string
+	Kind     string   // "string", "number", "bool", "array" or "object"
+	Required bool     // must be present; required strings must also be non-blank
+	Enum     []string // allowed string values, compared case-insensitively
+}
+
+// outputSchema is the contract a task's JSON reply is validated against.
+type outputSchema struct {
+	Name   string
+	Fields []schemaField
+}
+
+var gradingSchema = outputSchema{
+	Name: "grading",
+	Fields: []schemaField{
+		{Path: "suggested_grade", Kind: "string", Required: true, Enum: []string{"A", "B", "C"}},
+		{Path: "confidence_score", Kind: "number"},
+		{Path: "reasoning", Kind: "object"},
+		{Path: "reasoning.positive_signals", Kind: "array"},
+		{Path: "reasoning.negative_signals", Kind: "array"},
+	},
+}
+
+var analysisSchema = outputSchema{
+	Name: "analysis",
+	Fields: []schemaField{
+		{Path: "core_business", Kind: "string", Required: true},
+		{Path: "pain_points", Kind: "string", Required: true},
+		{Path: "my_entry_points", Kind: "string", Required: true},
+		{Path: "full_report", Kind: "string", Required: true},
+	},
+}
+
+var emailDraftSchema = outputSchema{
+	Name: "email",
+	Fields: []schemaField{
+		{Path: "subject", Kind: "string"},
+		{Path: "body", Kind: "string", Required: true},
+	},
+}
+
+var enrichmentSchema = outputSchema{
+	Name: "enrichment",
+	Fields: []schemaField{
+		{Path: "website", Kind: "string"},
+		{Path: "website_confidence", Kind: "number"},
+		{Path: "country", Kind: "string"},
+		{Path: "contacts", Kind: "array"},
+		{Path: "summary", Kind: "string", Required: true},
+		{Path: "candidates", Kind: "array"},
+	},
+}
+
+const structuredRepairPrompt = `你上一次的回复未通过格式校验：%s
+请修正后重新输出。只返回一个完整的 JSON 对象，不要使用 Markdown 代码块，也不要附加任何说明文字。`
+
+// ChatJSON runs a chat completion and decodes the reply into out after checking it
+// against schema. A reply that fails extraction or validation is retried once with
+// a repair prompt carrying the validation error.
+func (c *LLMClient) ChatJSON(ctx context.Context, messages []ChatMessage, opts ChatOptions, schema outputSchema, out any) error {
+	content, _, err := c.Chat(ctx, messages, opts)
+	if err != nil {
+		return err
+	}
+	return c.DecodeJSON(ctx, messages, opts, content, schema, out)
+}
+
+// DecodeJSON decodes an already received reply (e.g. a finished stream) into out,
+// issuing a single repair request when the reply does not satisfy schema. Replies
+// that fail the check are dropped from the response cache.
+func (c *LLMClient) DecodeJSON(ctx context.Context, messages []ChatMessage, opts ChatOptions, content string, schema outputSchema, out any) error {
+	cause := decodeStructured(content, schema, out)
+	if cause == nil {
+		return nil
+	}
+	c.forgetCompletion(ctx, messages, opts)
+	log.Printf("[llm] task=%s schema=%s status=repair error=%v", opts.Task, schema.Name, cause)
+
+	repair := make([]ChatMessage, 0, len(messages)+2)
+	repair = append(repair, messages...)
+	repair = append(repair,
+		ChatMessage{Role: "assistant", Content: content},
+		ChatMessage{Role: "user", Content: fmt.Sprintf(structuredRepairPrompt, cause)},
+	)
+	fixed, _, err := c.Chat(ctx, repair, opts)
+	if err != nil {
+		return fmt.Errorf("修复结构化输出失败: %w", err)
+	}
+	if err := decodeStructured(fixed, schema, out); err != nil {
+		c.forgetCompletion(ctx, repair, opts)
+		return fmt.Errorf("结构化输出校验失败: %w", err)
+	}
+	return nil
+}
+
+// decodeStructured extracts the JSON object from content, validates it and unmarshals it into out.
+func decodeStructured(content string, schema outputSchema, out any) error {
+	raw, err := extractJSONObject(content)
+	if err != nil {
+		return err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		return fmt.Errorf("JSON 语法错误: %w", err)
+	}
+	if err := schema.validate(doc); err != nil {
+		return err
+	}
+	normalized, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("JSON 编码失败: %w", err)
+	}
+	if err := json.Unmarshal(normalized, out); err != nil {
+		return fmt.Errorf("JSON 字段类型不匹配: %w", err)
+	}
+	return nil
+}
+
+// extractJSONObject strips markdown fences and surrounding prose, returning the
+// first balanced top-level JSON object in content.
+func extractJSONObject(content string) (string, error) {
+	text := strings.TrimSpace(content)
+	if idx := strings.Index(text, "```"); idx >= 0 {
+		inner := text[idx+3:]
+		if nl := strings.IndexByte(inner, '\n'); nl >= 0 && !strings.Contains(inner[:nl], "{") {
+			inner = inner[nl+1:]
+		}
+		if end := strings.Index(inner, "```"); end >= 0 {
+			inner = inner[:end]
+		}
+		if strings.Contains(inner, "{") {
+			text = inner
+		}
+	}
+
+	start := strings.IndexByte(text, '{')
+	if start < 0 {
+		return "", fmt.Errorf("回复中未找到 JSON 对象")
+	}
+	depth := 0
+	inString := false
+	escaped := false
+	for i := start; i < len(text); i++ {
+		ch := text[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == '"':
+				inString = false
+			}
+			continue
+		}
+		switch ch {
+		case '"':
+			inString = true
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return text[start : i+1], nil
+			}
+		}
+	}
+	return "", fmt.Errorf("JSON 对象不完整")
+}
+
+// validate checks required keys, value kinds and enum values. Enum values are
+// normalised in place so callers see the canonical spelling.
+func (s outputSchema) validate(doc map[string]any) error {
+	var problems []string
+	for _, field := range s.Fields {
+		parent, key, value, ok := lookupPath(doc, field.Path)
+		if !ok || value == nil {
+			if field.Required {
+				problems = append(problems, fmt.Sprintf("缺少字段 %s", field.Path))
+			}
+			continue
+		}
+		if !matchesKind(value, field.Kind) {
+			problems = append(problems, fmt.Sprintf("字段 %s 应为 %s 类型", field.Path, field.Kind))
+			continue
+		}
+		text, isString := value.(string)
+		if !isString {
+			continue
+		}
+		if field.Required && strings.TrimSpace(text) == "" {
+			problems = append(problems, fmt.Sprintf("字段 %s 不能为空", field.Path))
+			continue
+		}
+		if len(field.Enum) == 0 {
+			continue
+		}
+		canonical := ""
+		for _, allowed := range field.Enum {
+			if strings.EqualFold(strings.TrimSpace(text), allowed) {
+				canonical = allowed
+				break
+			}
+		}
+		if canonical == "" {
+			problems = append(problems, fmt.Sprintf("字段 %s 的值 %q 不在允许范围 %s 内", field.Path, text, strings.Join(field.Enum, "/")))
+			continue
+		}
+		parent[key] = canonical
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "；"))
+	}
+	return nil
+}
+
+func lookupPath(doc map[string]any, path string) (map[string]any, string, any, bool) {
+	parts := strings.Split(path, ".")
+	current := doc
+	for i, part := range parts {
+		value, ok := current[part]
+		if !ok {
+			return nil, "", nil, false
+		}
+		if i == len(parts)-1 {
+			return current, part, value, true
+		}
+		next, ok := value.(map[string]any)
+		if !ok {
+			return nil, "", nil, false
+		}
+		current = next
+	}
+	return nil, "", nil, false
+}
+
+func matchesKind(value any, kind string) bool {
+	switch kind {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "bool":
+		_, ok := value.(bool)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	default:
+		return true
+	}
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+func TestExtractJSONObject(t *testing.T) {
+	cases := map[string]string{
+		"plain":          `{"a":1}`,
+		"fenced":         "```json\n{\"a\":1}\n```",
+		"fence no lang":  "```\n{\"a\":1}\n```",
+		"leading prose":  "Here is the result:\n{\"a\":1}",
+		"trailing prose": "{\"a\":1}\nLet me know if you need more.",
+		"brace in value": `{"a":"}{"} trailing`,
+	}
+	for name, input := range cases {
+		got, err := extractJSONObject(input)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var doc map[string]any
+		if err := json.Unmarshal([]byte(got), &doc); err != nil {
+			t.Fatalf("%s: extracted invalid JSON %q", name, got)
+		}
+	}
+	if _, err := extractJSONObject("no json here"); err == nil {
+		t.Fatalf("expected error without an object")
+	}
+	if _, err := extractJSONObject(`{"a": {"b": 1}`); err == nil {
+		t.Fatalf("expected error for truncated object")
+	}
+}
+
+func TestDecodeStructuredValidatesSchema(t *testing.T) {
+	var grade struct {
+		SuggestedGrade string `json:"suggested_grade"`
+	}
+	if err := decodeStructured("```json\n{\"suggested_grade\":\" b \"}\n```", gradingSchema, &grade); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if grade.SuggestedGrade != "B" {
+		t.Fatalf("enum should be normalised, got %q", grade.SuggestedGrade)
+	}
+
+	err := decodeStructured(`{"suggested_grade":"S","confidence_score":"high"}`, gradingSchema, &grade)
+	if err == nil || !strings.Contains(err.Error(), "suggested_grade") || !strings.Contains(err.Error(), "confidence_score") {
+		t.Fatalf("expected enum and type errors, got %v", err)
+	}
+
+	var analysis struct{}
+	if err := decodeStructured(`{"core_business":"x","pain_points":"","my_entry_points":"y"}`, analysisSchema, &analysis); err == nil ||
+		!strings.Contains(err.Error(), "pain_points") || !strings.Contains(err.Error(), "full_report") {
+		t.Fatalf("expected missing field errors, got %v", err)
+	}
+}
+
+func TestChatJSONRepairsInvalidReply(t *testing.T) {
+	var requests [][]map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Messages []map[string]any `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		requests = append(requests, payload.Messages)
+		reply := `Sure! {"suggested_grade":"S"}`
+		if len(requests) > 1 {
+			reply = `{"suggested_grade":"a","confidence_score":0.8}`
+		}
+		data, _ := json.Marshal(reply)
+		fmt.Fprintf(w, `{"choices":[{"message":{"content":%s}}]}`, data)
+	}))
+	defer server.Close()
+
+	st := setupTestStore(t)
+	defer st.Close()
+	settings, _ := json.Marshal(store.Settings{LLMBaseURL: server.URL, LLMAPIKey: "test", LLMModel: "m"})
+	if err := st.SaveSettings(context.Background(), bytes.NewReader(settings)); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	client := NewLLMClient(st, server.Client())
+
+	var parsed struct {
+		SuggestedGrade string  `json:"suggested_grade"`
+		Confidence     float64 `json:"confidence_score"`
+	}
+	messages := []ChatMessage{{Role: "user", Content: "grade"}}
+	if err := client.ChatJSON(context.Background(), messages, ChatOptions{Task: LLMTaskGrading}, gradingSchema, &parsed); err != nil {
+		t.Fatalf("chat json: %v", err)
+	}
+	if parsed.SuggestedGrade != "A" || parsed.Confidence != 0.8 {
+		t.Fatalf("unexpected parsed result %+v", parsed)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected one repair request, got %d requests", len(requests))
+	}
+	repair := requests[1]
+	if len(repair) != 3 || repair[1]["role"] != "assistant" || !strings.Contains(fmt.Sprint(repair[2]["content"]), "suggested_grade") {
+		t.Fatalf("repair prompt should include the previous reply and validation error: %v", repair)
+	}
+
+	requests = nil
+	var again struct{}
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, nil)
+		fmt.Fprint(w, `{"choices":[{"message":{"content":"still not json"}}]}`)
+	})
+	if err := client.ChatJSON(context.Background(), messages, ChatOptions{}, gradingSchema, &again); err == nil {
+		t.Fatalf("expected failure after a single repair attempt")
+	}
+	if len(requests) != 2 {
+		t.Fatalf("repair should be attempted exactly once, got %d requests", len(requests))
+	}
+}
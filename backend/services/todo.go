@@ -19,10 +19,11 @@ type TodoServiceImpl struct {
     store     *store.Store
     enricher  EnrichmentService
     automation AutomationService
+    budget     budgetGate
 }
 
 func NewTodoService(st *store.Store, enricher EnrichmentService, automation AutomationService) *TodoServiceImpl {
-    return &TodoServiceImpl{store: st, enricher: enricher, automation: automation}
+    return &TodoServiceImpl{store: st, enricher: enricher, automation: automation, budget: budgetGate{scope: "todo"}}
 }
 
 func (s *TodoServiceImpl) Enqueue(ctx context.Context, query string) (*domain.TodoTask, error) {
@@ -34,6 +35,10 @@ func (s *TodoServiceImpl) Enqueue(ctx context.Context, query string) (*domain.To
 }
 
 func (s *TodoServiceImpl) ProcessNext(ctx context.Context) (bool, error) {
+    // 预算用尽时保持队列原样，等待额度恢复后继续。
+    if ok, err := s.budget.allow(ctx, s.store); err != nil || !ok {
+        return false, err
+    }
     task, err := s.store.ClaimNextTodo(ctx)
     if err != nil || task == nil {
         return false, err
@@ -42,7 +47,7 @@ func (s *TodoServiceImpl) ProcessNext(ctx context.Context) (bool, error) {
     // Resolve company information
     result, err := s.enricher.ResolveCompany(ctx, &domain.ResolveCompanyRequest{Query: task.Query})
     if err != nil {
-        _ = s.store.MarkTodoFailed(ctx, task.ID, err.Error())
+        s.markFailed(ctx, task.ID, err)
         return true, err
     }
 
@@ -58,6 +63,7 @@ func (s *TodoServiceImpl) ProcessNext(ctx context.Context) (bool, error) {
         Country:  strings.TrimSpace(result.Country),
         Summary:  strings.TrimSpace(result.Summary),
         Contacts: result.Contacts,
+        WebsiteSignals: result.WebsiteSignals,
     }
     customerID, err := s.store.CreateCustomer(ctx, createReq)
     if err != nil {
@@ -67,7 +73,7 @@ func (s *TodoServiceImpl) ProcessNext(ctx context.Context) (bool, error) {
         } else if existing, _, ferr := s.store.FindCustomerByQuery(ctx, result.Website); ferr == nil && existing != nil {
             customerID = existing.ID
         } else {
-            _ = s.store.MarkTodoFailed(ctx, task.ID, err.Error())
+            s.markFailed(ctx, task.ID, err)
             return true, err
         }
     }
@@ -87,3 +93,12 @@ func (s *TodoServiceImpl) ProcessNext(ctx context.Context) (bool, error) {
     return true, nil
 }
 
+// markFailed records a failure unless ctx was cancelled; interrupted tasks stay
+// running and are requeued on the next start.
+func (s *TodoServiceImpl) markFailed(ctx context.Context, id int64, err error) {
+    if ctx.Err() != nil {
+        log.Printf("[todo] task=%d interrupted: %v", id, err)
+        return
+    }
+    _ = s.store.MarkTodoFailed(ctx, id, err.Error())
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/playwright-community/playwright-go"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+// Fetch modes recorded on WebPageSummary.
+const (
+	FetchModeHTTP    = "http"    // plain HTTP response parsed as-is
+	FetchModeBrowser = "browser" // DOM rendered by headless Chromium
+)
+
+// A page with less visible text than this is treated as a client-rendered shell.
+const minVisibleTextRunes = 200
+
+const browserRenderTimeout = 25 * time.Second
+
+// spaMarkers are selectors of the mount points and bootstrapping data that
+// single-page app frameworks leave in the server HTML.
+var spaMarkers = []string{
+	"#root", "#app", "#__next", "#__nuxt", "#___gatsby", "[ng-app]", "[ng-version]",
+	"app-root", "[data-reactroot]", "script#__NEXT_DATA__", "script#__NUXT_DATA__",
+}
+
+// visibleText returns the text a reader would see, without scripts and styles.
+func visibleText(doc *goquery.Document) string {
+	body := doc.Find("body").Clone()
+	body.Find("script, style, noscript, template, svg").Remove()
+	return strings.TrimSpace(compactWhitespace(body.Text()))
+}
+
+// needsBrowserRender reports whether a server response looks like an empty
+// client-rendered shell: little visible text, plus either an SPA mount point or
+// a noscript notice asking for JavaScript. Very short pages without markers
+// still qualify, since many builders inject all content with scripts.
+func needsBrowserRender(doc *goquery.Document) bool {
+	text := visibleText(doc)
+	if utf8.RuneCountInString(text) >= minVisibleTextRunes {
+		return false
+	}
+	if utf8.RuneCountInString(text) < minVisibleTextRunes/4 {
+		return true
+	}
+	for _, marker := range spaMarkers {
+		if doc.Find(marker).Length() > 0 {
+			return true
+		}
+	}
+	noscript := strings.ToLower(doc.Find("noscript").Text())
+	return strings.Contains(noscript, "javascript")
+}
+
+// fetchPage downloads a page over HTTP and, when the response is a
+// JavaScript-rendered shell, loads it again in a pooled headless browser and
+// uses the rendered DOM. Render failures keep the HTTP result.
+func (w *WebFetcher) fetchPage(ctx context.Context, pageURL string) (*goquery.Document, string, string, error) {
+	doc, finalURL, err := w.fetchDocument(ctx, pageURL)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if !needsBrowserRender(doc) {
+		return doc, finalURL, FetchModeHTTP, nil
+	}
+	render := w.render
+	if render == nil {
+		if w.browsers == nil {
+			return doc, finalURL, FetchModeHTTP, nil
+		}
+		render = w.renderWithBrowser
+	}
+	html, renderedURL, err := render(ctx, finalURL)
+	if err != nil {
+		log.Printf("[fetch] 浏览器渲染失败，使用原始页面 %s: %v", finalURL, err)
+		return doc, finalURL, FetchModeHTTP, nil
+	}
+	rendered, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return doc, finalURL, FetchModeHTTP, nil
+	}
+	if utf8.RuneCountInString(visibleText(rendered)) <= utf8.RuneCountInString(visibleText(doc)) {
+		return doc, finalURL, FetchModeHTTP, nil
+	}
+	if renderedURL == "" {
+		renderedURL = finalURL
+	}
+	return rendered, renderedURL, FetchModeBrowser, nil
+}
+
+// renderWithBrowser loads pageURL in the shared browser pool through the fetch
+// proxy channel and returns the rendered HTML and final URL. With a guard set,
+// all browser traffic goes through a guardProxy in front of that channel.
+func (w *WebFetcher) renderWithBrowser(ctx context.Context, pageURL string) (string, string, error) {
+	upstream, err := w.proxies.channelProxy(ctx, store.ProxyChannelFetch)
+	if err != nil {
+		return "", "", err
+	}
+	browserProxy := upstream.playwright()
+	if w.guard != nil {
+		guarded, err := startGuardProxy(w.guard, upstream)
+		if err != nil {
+			return "", "", err
+		}
+		defer guarded.Close()
+		// Chromium sends loopback requests around a proxy unless told not to.
+		browserProxy = &playwright.Proxy{Server: guarded.url(), Bypass: playwright.String("<-loopback>")}
+	}
+	ctx, cancel := context.WithTimeout(ctx, browserRenderTimeout)
+	defer cancel()
+
+	var html, finalURL string
+	err = w.browsers.WithPage(ctx, playwright.BrowserNewContextOptions{
+		UserAgent: playwright.String(searchUserAgent),
+		Proxy:     browserProxy,
+		// Rendering one page never needs service workers.
+		ServiceWorkers: playwright.ServiceWorkerPolicyBlock,
+	}, func(page playwright.Page) error {
+		if w.guard != nil {
+			// Rendering never needs WebSockets, so every socket is closed
+			// before it connects.
+			if err := page.RouteWebSocket("**/*", blockWebSocket); err != nil {
+				return fmt.Errorf("设置 WebSocket 拦截失败: %w", err)
+			}
+		}
+		resp, err := page.Goto(pageURL, playwright.PageGotoOptions{
+			WaitUntil: playwright.WaitUntilStateNetworkidle,
+			Timeout:   playwright.Float(float64(browserRenderTimeout.Milliseconds())),
+		})
+		if err != nil {
+			return fmt.Errorf("打开页面失败: %w", err)
+		}
+		if resp != nil && resp.Status() >= 400 {
+			return fmt.Errorf("页面返回状态码 %d", resp.Status())
+		}
+		finalURL = page.URL()
+		html, err = page.Content()
+		if err != nil {
+			return fmt.Errorf("读取渲染结果失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return html, finalURL, nil
+}
+
+// blockWebSocket closes a page's WebSocket without connecting to the server.
+func blockWebSocket(route playwright.WebSocketRoute) {
+	route.Close()
+}
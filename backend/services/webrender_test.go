@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const staticCompanyPage = `<html><body><h1>Acme Valves</h1>
+<p>Acme Valves has manufactured industrial ball valves, gate valves and actuators since 1962.
+Our plants in Ohio and Texas supply refineries, chemical plants and water utilities across North America.
+Contact our export team for OEM projects, distributor enquiries and custom engineering.</p></body></html>`
+
+const spaShellPage = `<html><head><script src="/static/js/main.js"></script></head>
+<body><noscript>You need to enable JavaScript to run this app.</noscript><div id="root"></div></body></html>`
+
+func TestNeedsBrowserRender(t *testing.T) {
+	cases := map[string]struct {
+		html string
+		want bool
+	}{
+		"static page":  {staticCompanyPage, false},
+		"react shell":  {spaShellPage, true},
+		"next.js":      {`<html><body><div id="__next">Loading…</div><script id="__NEXT_DATA__" type="application/json">{"props":{"pageProps":{"company":"` + strings.Repeat("x", 500) + `"}}}</script></body></html>`, true},
+		"script text":  {`<html><body><script>var copy = "` + strings.Repeat("lorem ipsum ", 60) + `";</script></body></html>`, true},
+		"short markup": {`<html><body><div id="app"><p>Welcome to Acme. We make valves for refineries and water utilities worldwide.</p></div></body></html>`, true},
+	}
+	for name, tc := range cases {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(tc.html))
+		if err != nil {
+			t.Fatalf("%s: parse: %v", name, err)
+		}
+		if got := needsBrowserRender(doc); got != tc.want {
+			t.Errorf("%s: needsBrowserRender = %v, want %v", name, got, tc.want)
+		}
+	}
+}
+
+func TestFetchRendersJavaScriptPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/static":
+			fmt.Fprint(w, staticCompanyPage)
+		default:
+			fmt.Fprint(w, spaShellPage)
+		}
+	}))
+	defer server.Close()
+
+	var rendered []string
+	fetcher := NewWebFetcher(server.Client())
+	fetcher.render = func(ctx context.Context, pageURL string) (string, string, error) {
+		rendered = append(rendered, pageURL)
+		return `<html><body><div id="root">` + staticCompanyPage + `<a href="mailto:export@acme.test">Email</a></div></body></html>`, pageURL, nil
+	}
+
+	summary, err := fetcher.Fetch(context.Background(), server.URL+"/spa")
+	if err != nil {
+		t.Fatalf("Fetch spa: %v", err)
+	}
+	if summary.FetchMode != FetchModeBrowser {
+		t.Errorf("spa fetch mode = %q, want %q", summary.FetchMode, FetchModeBrowser)
+	}
+	if !strings.Contains(summary.Text, "ball valves") || !containsString(summary.Emails, "export@acme.test") {
+		t.Errorf("rendered summary = %+v", summary)
+	}
+
+	summary, err = fetcher.Fetch(context.Background(), server.URL+"/static")
+	if err != nil {
+		t.Fatalf("Fetch static: %v", err)
+	}
+	if summary.FetchMode != FetchModeHTTP || len(rendered) != 1 {
+		t.Errorf("static page should not be rendered: mode=%q rendered=%v", summary.FetchMode, rendered)
+	}
+
+	fetcher.render = func(ctx context.Context, pageURL string) (string, string, error) {
+		return "", "", errors.New("chromium unavailable")
+	}
+	summary, err = fetcher.Fetch(context.Background(), server.URL+"/spa")
+	if err != nil {
+		t.Fatalf("Fetch with failing renderer: %v", err)
+	}
+	if summary.FetchMode != FetchModeHTTP {
+		t.Errorf("failed render should keep the HTTP result, got %q", summary.FetchMode)
+	}
+}
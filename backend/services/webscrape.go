@@ -1,6 +1,7 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -12,6 +13,9 @@ import (
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
 )
 
 var emailRegex = regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)
@@ -23,11 +27,24 @@ type WebPageSummary struct {
 	Text   string
 	Emails []string
 	Phones []string
+	Pages  []string // pages merged into the summary when crawled
+	// Profile and SocialLinks come from structured data the site publishes.
+	Profile     *domain.CompanyProfile
+	SocialLinks []domain.SocialLink
+	// FetchMode is FetchModeHTTP or FetchModeBrowser for the start page.
+	FetchMode string
+	// Signals are the technology and commerce signals seen on the pages.
+	Signals *domain.WebsiteSignals
 }
 
 // WebFetcher retrieves and summarises webpages.
 type WebFetcher struct {
-	client *http.Client
+	client   *http.Client
+	proxies  *ProxyRouter
+	browsers *BrowserPool // renders JavaScript-only pages; nil disables the fallback
+	guard    *fetchGuard  // blocks internal addresses; nil allows any host
+	// render overrides the browser fallback, returning the rendered HTML and final URL.
+	render func(ctx context.Context, pageURL string) (string, string, error)
 }
 
 // NewWebFetcher builds a new WebFetcher.
@@ -38,6 +55,8 @@ func NewWebFetcher(client *http.Client) *WebFetcher {
 	return &WebFetcher{client: client}
 }
 
+const fetchUserAgent = "Mozilla/5.0 (compatible; AI-Trade-Assistant/1.0)"
+
 // Fetch retrieves a page and returns condensed text plus discovered emails.
 func (w *WebFetcher) Fetch(ctx context.Context, rawURL string) (*WebPageSummary, error) {
 	if rawURL == "" {
@@ -47,34 +66,73 @@ func (w *WebFetcher) Fetch(ctx context.Context, rawURL string) (*WebPageSummary,
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed, nil)
+	doc, _, mode, err := w.fetchPage(ctx, parsed)
+	if err != nil {
+		return nil, err
+	}
+	summary := summarizeDocument(doc, parsed, 4000)
+	summary.FetchMode = mode
+	return summary, nil
+}
+
+// get requests a URL through the fetch proxy channel after checking it with
+// the guard. The caller closes the body.
+func (w *WebFetcher) get(ctx context.Context, pageURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("构建请求失败: %w", err)
 	}
-	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; AI-Trade-Assistant/1.0)")
-	resp, err := w.client.Do(req)
+	if err := checkFetchScheme(req.URL); err != nil {
+		return nil, err
+	}
+	if w.guard != nil {
+		if err := w.guard.checkURL(ctx, req.URL); err != nil {
+			return nil, err
+		}
+	}
+	req.Header.Set("User-Agent", fetchUserAgent)
+	return w.proxies.channelClient(ctx, store.ProxyChannelFetch, w.client).Do(req)
+}
+
+// fetchDocument downloads and parses an HTML page, returning the URL reached
+// after redirects.
+func (w *WebFetcher) fetchDocument(ctx context.Context, pageURL string) (*goquery.Document, string, error) {
+	resp, err := w.get(ctx, pageURL)
 	if err != nil {
-		return nil, fmt.Errorf("请求官网失败: %w", err)
+		return nil, "", fmt.Errorf("请求官网失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("官网返回状态码 %d", resp.StatusCode)
+		return nil, "", fmt.Errorf("官网返回状态码 %d", resp.StatusCode)
 	}
 
-	limited := io.LimitReader(resp.Body, 1<<20) // 1MB
-	doc, err := goquery.NewDocumentFromReader(limited)
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20)) // 1MB
+	if err != nil {
+		return nil, "", fmt.Errorf("读取网页失败: %w", err)
+	}
+	body, _ := decodeHTML(raw, resp.Header.Get("Content-Type"))
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
 	if err != nil {
-		return nil, fmt.Errorf("解析网页失败: %w", err)
+		return nil, "", fmt.Errorf("解析网页失败: %w", err)
 	}
+	finalURL := pageURL
+	if resp.Request != nil && resp.Request.URL != nil {
+		finalURL = resp.Request.URL.String()
+	}
+	return doc, finalURL, nil
+}
 
+// summarizeDocument extracts key text sections (capped at textLimit runes),
+// emails and phone numbers from a parsed page.
+func summarizeDocument(doc *goquery.Document, pageURL string, textLimit int) *WebPageSummary {
 	bodyPlain := strings.TrimSpace(compactWhitespace(doc.Find("body").Text()))
 	sections := collectKeySections(doc, bodyPlain)
 	bodyText := strings.Join(sections, "\n---\n")
 	if strings.TrimSpace(bodyText) == "" {
 		bodyText = bodyPlain
 	}
-	bodyText = truncateRunes(bodyText, 4000)
+	bodyText = truncateRunes(bodyText, textLimit)
 
 	var emails []string
 	var phones []string
@@ -83,8 +141,7 @@ func (w *WebFetcher) Fetch(ctx context.Context, rawURL string) (*WebPageSummary,
 		if !exists {
 			return
 		}
-		address := strings.TrimPrefix(href, "mailto:")
-		address = strings.TrimSpace(address)
+		address := cleanMailto(href)
 		if address == "" {
 			return
 		}
@@ -109,9 +166,22 @@ func (w *WebFetcher) Fetch(ctx context.Context, rawURL string) (*WebPageSummary,
 		}
 	})
 
-	matches := emailRegex.FindAllString(bodyPlain, -1)
+	profile := extractCompanyProfile(doc)
+	if profile != nil {
+		if number := sanitizePhone(profile.Phone); number != "" && !containsString(phones, number) {
+			phones = append(phones, number)
+		}
+	}
+
+	var matches []string
+	if profile != nil && profile.Email != "" {
+		matches = append(matches, profile.Email)
+	}
+	matches = append(matches, emailRegex.FindAllString(bodyPlain, -1)...)
+	matches = append(matches, deobfuscateEmails(doc)...)
 	for _, m := range matches {
-		if !containsString(emails, m) {
+		m = cleanEmail(m)
+		if m != "" && !containsString(emails, m) {
 			emails = append(emails, m)
 		}
 	}
@@ -126,7 +196,15 @@ func (w *WebFetcher) Fetch(ctx context.Context, rawURL string) (*WebPageSummary,
 		}
 	}
 
-	return &WebPageSummary{URL: parsed, Text: bodyText, Emails: emails, Phones: phones}, nil
+	return &WebPageSummary{
+		URL:         pageURL,
+		Text:        bodyText,
+		Emails:      emails,
+		Phones:      phones,
+		Profile:     profile,
+		SocialLinks: extractSocialLinks(doc),
+		Signals:     detectWebsiteSignals(doc, pageURL),
+	}
 }
 
 func compactWhitespace(input string) string {
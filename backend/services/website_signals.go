@@ -0,0 +1,421 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+	"github.com/anner/ai-foreign-trade-assistant/backend/store"
+)
+
+// maxSignalEvidence caps the evidence notes kept for one website.
+const maxSignalEvidence = 12
+
+// signalFingerprint identifies a platform by markers in the lower-cased page
+// source. Markers starting with "generator:" match the meta generator tag.
+type signalFingerprint struct {
+	name    string
+	markers []string
+}
+
+var ecommerceFingerprints = []signalFingerprint{
+	{"shopify", []string{"cdn.shopify.com", "shopify.theme", ".myshopify.com", "generator:shopify"}},
+	{"magento", []string{"text/x-magento-init", "data-mage-init", "mage/cookies", "generator:magento"}},
+	{"woocommerce", []string{"/plugins/woocommerce/", "woocommerce-page", "wc-ajax=", "generator:woocommerce"}},
+	{"bigcommerce", []string{"cdn11.bigcommerce.com", "bigcommerce.com/s-", "generator:bigcommerce"}},
+	{"prestashop", []string{"var prestashop", "/modules/ps_", "generator:prestashop"}},
+	{"shopware", []string{"/bundles/storefront/", "generator:shopware"}},
+	{"opencart", []string{"index.php?route=product/", "catalog/view/theme/", "generator:opencart"}},
+	{"salesforce commerce cloud", []string{"demandware.static", "/on/demandware.store/"}},
+}
+
+var technologyFingerprints = []signalFingerprint{
+	{"wordpress", []string{"/wp-content/", "/wp-includes/", "generator:wordpress"}},
+	{"joomla", []string{"/media/jui/", "generator:joomla"}},
+	{"drupal", []string{"drupal.settings", "/sites/default/files/", "generator:drupal"}},
+	{"typo3", []string{"/typo3conf/", "/typo3temp/", "generator:typo3"}},
+	{"wix", []string{"static.wixstatic.com", "generator:wix"}},
+	{"squarespace", []string{"static1.squarespace.com", "generator:squarespace"}},
+	{"webflow", []string{"assets.website-files.com", "generator:webflow"}},
+	{"hubspot", []string{"js.hs-scripts.com", "generator:hubspot"}},
+}
+
+// rfqKeywords mark quote and inquiry forms, as opposed to newsletter or login forms.
+var rfqKeywords = []string{
+	"rfq", "request a quote", "request for quot", "get a quote", "quotation", "inquiry", "enquiry",
+	"angebot anfordern", "anfrage", "devis", "cotiza", "preventivo", "询价", "询盘", "報價", "见积", "見積",
+}
+
+// distributorKeywords mark dealer, reseller and partner network pages.
+var distributorKeywords = []string{
+	"distributor", "dealer", "reseller", "where-to-buy", "where to buy", "sales partner", "sales-partner",
+	"vertriebspartner", "händler", "haendler", "distributeur", "revendeur", "distribuidor", "distributore",
+	"经销商", "代理商", "經銷商", "販売店", "代理店",
+}
+
+var (
+	productPathRegex  = regexp.MustCompile(`(?i)/(?:products?|produkte?|produits?|productos?|prodotti|items?|p|sku|shop/product)/[^/?#]+`)
+	productCountRegex = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\bof\s+([\d,.]{1,9})\s+(?:results|products|items)\b`),
+		regexp.MustCompile(`(?i)\b([\d,.]{2,9})\+?\s+(?:products|items|skus|articles|produkte|artikel|produits|productos|prodotti)\b`),
+		regexp.MustCompile(`共\s*([\d,]{1,9})\s*(?:个|款|种|件)?\s*(?:产品|商品|結果|结果)`),
+	}
+)
+
+// certificationPatterns map certificate names to their usual spellings. Short
+// acronyms are matched case-sensitively to avoid words like "reach".
+var certificationPatterns = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"ISO", regexp.MustCompile(`\bISO[\s-]?(9001|14001|45001|13485|22000|27001|50001|17025|3834|TS\s?16949)\b`)},
+	{"IATF 16949", regexp.MustCompile(`\bIATF[\s-]?16949\b`)},
+	{"CE", regexp.MustCompile(`\bCE[\s-]?(?i:mark|marked|marking|certified|certificate|certification|conformity|approved|compliant|zertifiziert|kennzeichnung)\b|(?i:\bce[-_ ](?:mark|logo|certificate)\b)`)},
+	{"UL", regexp.MustCompile(`\bUL[\s-]?(?i:listed|certified|approved)\b`)},
+	{"RoHS", regexp.MustCompile(`(?i)\brohs\b`)},
+	{"REACH", regexp.MustCompile(`\bREACH\b`)},
+	{"FDA", regexp.MustCompile(`\bFDA[\s-]?(?i:registered|approved|cleared|certified|compliant|registration)\b`)},
+	{"GMP", regexp.MustCompile(`\bc?GMP\b`)},
+	{"HACCP", regexp.MustCompile(`\bHACCP\b`)},
+	{"BRCGS", regexp.MustCompile(`\bBRC(?:GS)?\b`)},
+	{"FSSC 22000", regexp.MustCompile(`\bFSSC[\s-]?22000\b`)},
+	{"TÜV", regexp.MustCompile(`\bT(?:Ü|UE|U)V\b`)},
+	{"API", regexp.MustCompile(`\bAPI[\s-]?(?:6A|6D|5L|Q1|Spec)\b`)},
+}
+
+// detectWebsiteSignals reports the technology and commerce signals visible on
+// one page. Crawl merges the signals of all pages it visits.
+func detectWebsiteSignals(doc *goquery.Document, pageURL string) *domain.WebsiteSignals {
+	signals := &domain.WebsiteSignals{DetectedAt: store.Now()}
+	pagePath := "/"
+	if parsed, err := url.Parse(pageURL); err == nil && parsed.Path != "" {
+		pagePath = parsed.Path
+	}
+	note := func(format string, args ...any) {
+		signals.Evidence = append(signals.Evidence, fmt.Sprintf(format, args...))
+	}
+
+	source, _ := doc.Html()
+	source = strings.ToLower(source)
+	if generator, ok := doc.Find(`meta[name="generator"], meta[name="Generator"]`).Attr("content"); ok {
+		source += "\ngenerator:" + strings.ToLower(strings.TrimSpace(generator))
+	}
+	signals.Ecommerce = matchFingerprints(ecommerceFingerprints, source)
+	signals.Technologies = matchFingerprints(technologyFingerprints, source)
+	if len(signals.Ecommerce) > 0 {
+		note("e-commerce platform %s on %s", strings.Join(signals.Ecommerce, ", "), pagePath)
+	}
+
+	signals.Languages = pageLanguages(doc)
+	if len(signals.Languages) > 1 {
+		note("%d language versions (hreflang) on %s", len(signals.Languages), pagePath)
+	}
+
+	if hasRFQForm(doc) {
+		signals.RFQForm = true
+		note("quote/inquiry form on %s", pagePath)
+	}
+
+	if size, how := catalogSize(doc); size > 0 {
+		signals.CatalogSize = size
+		note("catalog of about %d products (%s) on %s", size, how, pagePath)
+	}
+
+	signals.Certifications = pageCertifications(doc)
+	if len(signals.Certifications) > 0 {
+		note("certifications %s mentioned on %s", strings.Join(signals.Certifications, ", "), pagePath)
+	}
+
+	if link := distributorLink(doc, pagePath); link != "" {
+		signals.Distributors = true
+		note("distributor/dealer page %s", link)
+	}
+	return signals
+}
+
+func matchFingerprints(fingerprints []signalFingerprint, source string) []string {
+	var names []string
+	for _, fp := range fingerprints {
+		for _, marker := range fp.markers {
+			if strings.Contains(source, marker) {
+				names = append(names, fp.name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// pageLanguages returns the primary language subtags of the page and its
+// hreflang alternates.
+func pageLanguages(doc *goquery.Document) []string {
+	var languages []string
+	add := func(tag string) {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "x-default" {
+			return
+		}
+		primary, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+		if len(primary) >= 2 && len(primary) <= 3 && !containsString(languages, primary) {
+			languages = append(languages, primary)
+		}
+	}
+	if lang, ok := doc.Find("html").Attr("lang"); ok {
+		add(lang)
+	}
+	doc.Find("link[hreflang], a[hreflang]").Each(func(_ int, sel *goquery.Selection) {
+		lang, _ := sel.Attr("hreflang")
+		add(lang)
+	})
+	return languages
+}
+
+// hasRFQForm looks for a form mentioning quotes or inquiries with room for a
+// message, which separates it from newsletter sign-ups.
+func hasRFQForm(doc *goquery.Document) bool {
+	found := false
+	doc.Find("form").EachWithBreak(func(_ int, form *goquery.Selection) bool {
+		if form.Find("textarea").Length() == 0 && form.Find("input:not([type=hidden]):not([type=submit])").Length() < 4 {
+			return true
+		}
+		var text strings.Builder
+		text.WriteString(form.Text())
+		for _, attr := range []string{"action", "id", "class", "name"} {
+			value, _ := form.Attr(attr)
+			text.WriteString(" " + value)
+		}
+		form.Find("input, textarea, select, button").Each(func(_ int, field *goquery.Selection) {
+			for _, attr := range []string{"name", "placeholder", "value", "id"} {
+				value, _ := field.Attr(attr)
+				text.WriteString(" " + value)
+			}
+		})
+		// The heading just before the form usually names it.
+		text.WriteString(" " + form.PrevAllFiltered("h1, h2, h3, h4").First().Text())
+		lower := strings.ToLower(text.String())
+		for _, word := range rfqKeywords {
+			if strings.Contains(lower, word) {
+				found = true
+				return false
+			}
+		}
+		return true
+	})
+	return found
+}
+
+// catalogSize estimates the number of products from product links, schema.org
+// Product data and counters such as "Showing 1–12 of 348 results". The largest
+// estimate wins, since each one only sees part of the catalog.
+func catalogSize(doc *goquery.Document) (int, string) {
+	best, how := 0, ""
+	consider := func(n int, source string) {
+		if n > best && n < 1000000 {
+			best, how = n, source
+		}
+	}
+
+	links := map[string]struct{}{}
+	doc.Find("a[href]").Each(func(_ int, sel *goquery.Selection) {
+		href, _ := sel.Attr("href")
+		parsed, err := url.Parse(strings.TrimSpace(href))
+		if err != nil {
+			return
+		}
+		if match := productPathRegex.FindString(parsed.Path); match != "" {
+			links[strings.ToLower(match)] = struct{}{}
+		}
+	})
+	if len(links) >= 3 {
+		consider(len(links), "product links")
+	}
+
+	products, listed := 0, 0
+	doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, sel *goquery.Selection) {
+		var data any
+		if err := json.Unmarshal([]byte(strings.TrimSpace(sel.Text())), &data); err != nil {
+			return
+		}
+		p, l := countJSONLDProducts(data)
+		products += p
+		if l > listed {
+			listed = l
+		}
+	})
+	products += doc.Find(`[itemtype$="schema.org/Product"]`).Length()
+	consider(products, "schema.org products")
+	consider(listed, "schema.org item list")
+
+	text := compactWhitespace(doc.Find("body").Text())
+	for _, pattern := range productCountRegex {
+		for _, m := range pattern.FindAllStringSubmatch(text, -1) {
+			digits := strings.NewReplacer(",", "", ".", "").Replace(m[1])
+			if n, err := strconv.Atoi(digits); err == nil {
+				consider(n, fmt.Sprintf("%q", strings.TrimSpace(m[0])))
+			}
+		}
+	}
+	return best, how
+}
+
+// countJSONLDProducts counts Product nodes and returns the largest ItemList
+// numberOfItems.
+func countJSONLDProducts(data any) (int, int) {
+	products, listed := 0, 0
+	switch v := data.(type) {
+	case []any:
+		for _, item := range v {
+			p, l := countJSONLDProducts(item)
+			products += p
+			listed = max(listed, l)
+		}
+	case map[string]any:
+		for _, t := range jsonLDStrings(v["@type"]) {
+			switch t {
+			case "Product", "ProductGroup":
+				products++
+			case "ItemList", "OfferCatalog":
+				if n, err := strconv.Atoi(jsonLDText(v["numberOfItems"])); err == nil {
+					listed = max(listed, n)
+				}
+			}
+		}
+		for key, child := range v {
+			if key == "@type" || key == "@context" {
+				continue
+			}
+			p, l := countJSONLDProducts(child)
+			products += p
+			listed = max(listed, l)
+		}
+	}
+	return products, listed
+}
+
+// pageCertifications finds certificates named in the text and in image alt,
+// title and file names, where certification logos live.
+func pageCertifications(doc *goquery.Document) []string {
+	var haystack strings.Builder
+	haystack.WriteString(compactWhitespace(doc.Find("body").Text()))
+	doc.Find("img").Each(func(_ int, img *goquery.Selection) {
+		for _, attr := range []string{"alt", "title", "src"} {
+			value, _ := img.Attr(attr)
+			haystack.WriteString(" " + value)
+		}
+	})
+	text := haystack.String()
+	var found []string
+	for _, cert := range certificationPatterns {
+		matches := cert.pattern.FindAllStringSubmatch(text, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		if cert.name != "ISO" {
+			found = append(found, cert.name)
+			continue
+		}
+		for _, m := range matches {
+			name := "ISO " + strings.Join(strings.Fields(m[1]), " ")
+			if !containsString(found, name) {
+				found = append(found, name)
+			}
+		}
+	}
+	return found
+}
+
+// distributorLink returns the path of a distributor or dealer page linked
+// from, or equal to, the current page.
+func distributorLink(doc *goquery.Document, pagePath string) string {
+	if containsKeyword(strings.ToLower(pagePath), distributorKeywords) {
+		return pagePath
+	}
+	link := ""
+	doc.Find("a[href]").EachWithBreak(func(_ int, sel *goquery.Selection) bool {
+		href, _ := sel.Attr("href")
+		target := strings.ToLower(href + " " + sel.Text())
+		if !containsKeyword(target, distributorKeywords) {
+			return true
+		}
+		link = strings.TrimSpace(href)
+		if parsed, err := url.Parse(link); err == nil && parsed.Path != "" {
+			link = parsed.Path
+		}
+		return false
+	})
+	return link
+}
+
+func containsKeyword(text string, keywords []string) bool {
+	for _, word := range keywords {
+		if strings.Contains(text, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeWebsiteSignals folds the signals of another page of the same site into dst.
+func mergeWebsiteSignals(dst, src *domain.WebsiteSignals) {
+	if dst == nil || src == nil {
+		return
+	}
+	dst.Ecommerce = mergeUnique(dst.Ecommerce, src.Ecommerce)
+	dst.Technologies = mergeUnique(dst.Technologies, src.Technologies)
+	dst.Languages = mergeUnique(dst.Languages, src.Languages)
+	dst.RFQForm = dst.RFQForm || src.RFQForm
+	dst.CatalogSize = max(dst.CatalogSize, src.CatalogSize)
+	dst.Certifications = mergeUnique(dst.Certifications, src.Certifications)
+	dst.Distributors = dst.Distributors || src.Distributors
+	dst.Evidence = mergeUnique(dst.Evidence, src.Evidence)
+	if len(dst.Evidence) > maxSignalEvidence {
+		dst.Evidence = dst.Evidence[:maxSignalEvidence]
+	}
+}
+
+// formatWebsiteSignals renders signals as evidence lines for the grading
+// prompt. Absent signals are stated too, since they are evidence as well;
+// a failed detection renders nothing.
+func formatWebsiteSignals(signals *domain.WebsiteSignals) string {
+	if signals == nil || signals.Failed {
+		return ""
+	}
+	orNone := func(items []string) string {
+		if len(items) == 0 {
+			return "none detected"
+		}
+		return strings.Join(items, ", ")
+	}
+	yesNo := func(ok bool) string {
+		if ok {
+			return "yes"
+		}
+		return "not found"
+	}
+	catalog := "unknown"
+	if signals.CatalogSize > 0 {
+		catalog = fmt.Sprintf("about %d products", signals.CatalogSize)
+	}
+	languages := append([]string(nil), signals.Languages...)
+	sort.Strings(languages)
+	lines := []string{
+		"- E-commerce platform: " + orNone(signals.Ecommerce),
+		"- Site technology: " + orNone(signals.Technologies),
+		fmt.Sprintf("- Languages: %d (%s)", len(languages), orNone(languages)),
+		"- B2B quote/inquiry (RFQ) form: " + yesNo(signals.RFQForm),
+		"- Catalog size: " + catalog,
+		"- Certifications: " + orNone(signals.Certifications),
+		"- Distributor/dealer pages: " + yesNo(signals.Distributors),
+	}
+	if len(signals.Evidence) > 0 {
+		lines = append(lines, "- Evidence: "+strings.Join(signals.Evidence, "; "))
+	}
+	return strings.Join(lines, "\n")
+}
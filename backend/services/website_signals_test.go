@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+)
+
+func readSignalsFixture(t *testing.T, name string) string {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join("testdata", "signals", name))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return string(raw)
+}
+
+func TestDetectWebsiteSignals(t *testing.T) {
+	cases := []struct {
+		file string
+		want domain.WebsiteSignals
+	}{
+		{"b2b_manufacturer.html", domain.WebsiteSignals{
+			Technologies:   []string{"wordpress"},
+			Languages:      []string{"de", "en", "fr"},
+			RFQForm:        true,
+			CatalogSize:    1200,
+			Certifications: []string{"ISO 9001", "ISO 14001", "CE", "TÜV"},
+			Distributors:   true,
+		}},
+		{"consumer_shop.html", domain.WebsiteSignals{
+			Ecommerce:   []string{"shopify"},
+			Languages:   []string{"en"},
+			CatalogSize: 48,
+		}},
+		{"catalog_jsonld.html", domain.WebsiteSignals{
+			Ecommerce:      []string{"magento"},
+			Languages:      []string{"en"},
+			CatalogSize:    864,
+			Certifications: []string{"UL", "RoHS"},
+			Distributors:   true,
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.file, func(t *testing.T) {
+			doc := parseTestDocument(t, readSignalsFixture(t, tc.file))
+			got := detectWebsiteSignals(doc, "https://example.com/")
+			if got.DetectedAt == "" || (got.RFQForm || got.CatalogSize > 0) && len(got.Evidence) == 0 {
+				t.Errorf("missing timestamp or evidence: %+v", got)
+			}
+			got.DetectedAt, got.Evidence = "", nil
+			if !reflect.DeepEqual(*got, tc.want) {
+				t.Errorf("signals = %+v\nwant      %+v", *got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMergeAndFormatWebsiteSignals(t *testing.T) {
+	root := detectWebsiteSignals(parseTestDocument(t, readSignalsFixture(t, "consumer_shop.html")), "https://example.com/")
+	page := detectWebsiteSignals(parseTestDocument(t, readSignalsFixture(t, "catalog_jsonld.html")), "https://example.com/catalog")
+	mergeWebsiteSignals(root, page)
+	if root.CatalogSize != 864 || !root.Distributors || len(root.Ecommerce) != 2 {
+		t.Fatalf("merged signals = %+v", root)
+	}
+
+	text := formatWebsiteSignals(root)
+	for _, want := range []string{
+		"- E-commerce platform: shopify, magento",
+		"- Languages: 1 (en)",
+		"- B2B quote/inquiry (RFQ) form: not found",
+		"- Catalog size: about 864 products",
+		"- Certifications: UL, RoHS",
+		"- Distributor/dealer pages: yes",
+		"/where-to-buy",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("formatted signals missing %q:\n%s", want, text)
+		}
+	}
+	if formatWebsiteSignals(nil) != "" {
+		t.Errorf("nil signals should format as empty")
+	}
+}
+
+func TestGradingPromptIncludesWebsiteSignals(t *testing.T) {
+	prompts := NewPromptService(nil)
+	customer := &domain.Customer{Name: "Hansa Armaturen", WebsiteSignals: &domain.WebsiteSignals{RFQForm: true, CatalogSize: 1200}}
+	messages, err := prompts.Messages(context.Background(), PromptGrading, PromptData{
+		Customer:       customer,
+		WebsiteSignals: formatWebsiteSignals(customer.WebsiteSignals),
+	})
+	if err != nil {
+		t.Fatalf("render grading prompt: %v", err)
+	}
+	user := messages[len(messages)-1].Content
+	if !strings.Contains(user, "### Website Signals") || !strings.Contains(user, "Catalog size: about 1200 products") {
+		t.Fatalf("grading prompt lacks signals:\n%s", user)
+	}
+
+	messages, err = prompts.Messages(context.Background(), PromptGrading, PromptData{Customer: &domain.Customer{Name: "Unknown"}})
+	if err != nil {
+		t.Fatalf("render grading prompt: %v", err)
+	}
+	if strings.Contains(messages[len(messages)-1].Content, "### Website Signals") {
+		t.Errorf("signals section should be omitted when nothing was detected")
+	}
+}
+
+func TestGradingRemembersFailedSignalDetection(t *testing.T) {
+	var siteHits atomic.Int32
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siteHits.Add(1)
+		http.Error(w, "down", http.StatusInternalServerError)
+	}))
+	defer site.Close()
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"choices":[{"message":{"content":"{\"suggested_grade\":\"B\",\"confidence_score\":0.5,\"reasoning\":{\"positive_signals\":[],\"negative_signals\":[]}}"}}]}`)
+	}))
+	defer llm.Close()
+
+	ctx := context.Background()
+	st := setupTestStore(t)
+	defer st.Close()
+	if err := st.SaveSettings(ctx, strings.NewReader(fmt.Sprintf(`{"llm_base_url": %q, "llm_api_key": "test", "llm_model": "m"}`, llm.URL))); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+	customerID, err := st.CreateCustomer(ctx, &domain.CreateCompanyRequest{Name: "Acme", Website: site.URL})
+	if err != nil {
+		t.Fatalf("create customer: %v", err)
+	}
+	grader := NewGradingService(st, NewLLMClient(st, llm.Client()), NewPromptService(st))
+	grader.fetcher = NewWebFetcher(site.Client())
+
+	for i := 0; i < 2; i++ {
+		if _, err := grader.Suggest(ctx, customerID); err != nil {
+			t.Fatalf("Suggest: %v", err)
+		}
+	}
+	if hits := siteHits.Load(); hits != 1 {
+		t.Fatalf("website fetched %d times, want once", hits)
+	}
+	customer, err := st.GetCustomer(ctx, customerID)
+	if err != nil {
+		t.Fatalf("get customer: %v", err)
+	}
+	if customer.WebsiteSignals == nil || !customer.WebsiteSignals.Failed || customer.WebsiteSignals.DetectedAt == "" {
+		t.Fatalf("stored signals = %+v, want a failed detection", customer.WebsiteSignals)
+	}
+
+	now := time.Now()
+	stale := &domain.WebsiteSignals{Failed: true, DetectedAt: now.Add(-25 * time.Hour).UTC().Format(time.RFC3339)}
+	if !signalsDue(stale, now) || signalsDue(customer.WebsiteSignals, now) || signalsDue(&domain.WebsiteSignals{DetectedAt: stale.DetectedAt}, now) {
+		t.Errorf("failed detections should be retried only once stale")
+	}
+}
@@ -451,18 +451,19 @@ func (s *Store) GetCustomerDetail(ctx context.Context, customerID int64) (*domai
 	}
 
 	detail := &domain.CustomerDetail{
-		ID:           customer.ID,
-		Name:         customer.Name,
-		Website:      customer.Website,
-		Country:      customer.Country,
-		Summary:      customer.Summary,
-		Grade:        strings.ToUpper(strings.TrimSpace(customer.Grade)),
-		GradeReason:  customer.GradeReason,
-		FollowupSent: customer.FollowupSent,
-		Contacts:     contacts,
-		SourceJSON:   customer.SourceJSON,
-		CreatedAt:    customer.CreatedAt,
-		UpdatedAt:    customer.UpdatedAt,
+		ID:             customer.ID,
+		Name:           customer.Name,
+		Website:        customer.Website,
+		Country:        customer.Country,
+		Summary:        customer.Summary,
+		Grade:          strings.ToUpper(strings.TrimSpace(customer.Grade)),
+		GradeReason:    customer.GradeReason,
+		FollowupSent:   customer.FollowupSent,
+		Contacts:       contacts,
+		SourceJSON:     customer.SourceJSON,
+		WebsiteSignals: customer.WebsiteSignals,
+		CreatedAt:      customer.CreatedAt,
+		UpdatedAt:      customer.UpdatedAt,
 	}
 
 	if detail.Grade == "" {
@@ -499,6 +500,12 @@ func (s *Store) GetCustomerDetail(ctx context.Context, customerID int64) (*domai
 		return nil, err
 	}
 
+	activities, err := s.ListCustomerActivities(ctx, customerID, customerDetailActivityLimit)
+	if err != nil {
+		return nil, err
+	}
+	detail.Activities = activities
+
 	return detail, nil
 }
 
@@ -693,18 +700,23 @@ func (s *Store) CreateCustomer(ctx context.Context, req *domain.CreateCompanyReq
 	if len(source) == 0 {
 		source = []byte("{}")
 	}
+	signals, err := encodeWebsiteSignals(req.WebsiteSignals)
+	if err != nil {
+		return 0, err
+	}
 
 	now := Now()
 	var customerID int64
-	err := s.WithTx(ctx, func(tx *sql.Tx) error {
+	err = s.WithTx(ctx, func(tx *sql.Tx) error {
 		res, err := tx.ExecContext(ctx,
-			`INSERT INTO customers (name, website, country, grade, grade_reason, summary, source_json, created_at, updated_at)
-             VALUES (?, ?, ?, 'unknown', '', ?, ?, ?, ?)`,
+			`INSERT INTO customers (name, website, country, grade, grade_reason, summary, source_json, website_signals, created_at, updated_at)
+             VALUES (?, ?, ?, 'unknown', '', ?, ?, ?, ?, ?)`,
 			req.Name,
 			req.Website,
 			req.Country,
 			req.Summary,
 			string(source),
+			signals,
 			now,
 			now,
 		)
@@ -768,6 +780,11 @@ func (s *Store) UpdateCustomer(ctx context.Context, customerID int64, req *domai
 		if affected == 0 {
 			return fmt.Errorf("客户不存在或未更新")
 		}
+		if req.WebsiteSignals != nil {
+			if err := updateWebsiteSignalsTx(ctx, tx, customerID, req.WebsiteSignals); err != nil {
+				return err
+			}
+		}
 		if _, err := tx.ExecContext(ctx, `DELETE FROM contacts WHERE customer_id = ?`, customerID); err != nil {
 			return fmt.Errorf("清理旧联系人失败: %w", err)
 		}
@@ -775,6 +792,53 @@ func (s *Store) UpdateCustomer(ctx context.Context, customerID int64, req *domai
 	})
 }
 
+// UpdateCustomerWebsiteSignals stores the signals detected on a customer's website.
+func (s *Store) UpdateCustomerWebsiteSignals(ctx context.Context, customerID int64, signals *domain.WebsiteSignals) error {
+	if s == nil || s.DB == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	if customerID <= 0 {
+		return fmt.Errorf("invalid customer id")
+	}
+	return s.WithTx(ctx, func(tx *sql.Tx) error {
+		return updateWebsiteSignalsTx(ctx, tx, customerID, signals)
+	})
+}
+
+func updateWebsiteSignalsTx(ctx context.Context, tx *sql.Tx, customerID int64, signals *domain.WebsiteSignals) error {
+	encoded, err := encodeWebsiteSignals(signals)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE customers SET website_signals = ? WHERE id = ?`, encoded, customerID); err != nil {
+		return fmt.Errorf("保存网站信号失败: %w", err)
+	}
+	return nil
+}
+
+// encodeWebsiteSignals returns the JSON column value, NULL for nil signals.
+func encodeWebsiteSignals(signals *domain.WebsiteSignals) (sql.NullString, error) {
+	if signals == nil {
+		return sql.NullString{}, nil
+	}
+	raw, err := json.Marshal(signals)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("编码网站信号失败: %w", err)
+	}
+	return sql.NullString{String: string(raw), Valid: true}, nil
+}
+
+func decodeWebsiteSignals(raw sql.NullString) *domain.WebsiteSignals {
+	if !raw.Valid || strings.TrimSpace(raw.String) == "" {
+		return nil
+	}
+	var signals domain.WebsiteSignals
+	if err := json.Unmarshal([]byte(raw.String), &signals); err != nil {
+		return nil
+	}
+	return &signals
+}
+
 // ReplaceContacts rewrites the contacts for a customer.
 func (s *Store) ReplaceContacts(ctx context.Context, customerID int64, contacts []domain.Contact) error {
 	if s == nil || s.DB == nil {
@@ -796,10 +860,11 @@ func (s *Store) GetCustomer(ctx context.Context, id int64) (*domain.Customer, er
 	if s == nil || s.DB == nil {
 		return nil, fmt.Errorf("store not initialized")
 	}
-	row := s.DB.QueryRowContext(ctx, `SELECT id, name, website, country, grade, grade_reason, summary, followup_sent, source_json, created_at, updated_at FROM customers WHERE id = ?`, id)
+	row := s.DB.QueryRowContext(ctx, `SELECT id, name, website, country, grade, grade_reason, summary, followup_sent, source_json, website_signals, created_at, updated_at FROM customers WHERE id = ?`, id)
 	var (
 		customer domain.Customer
 		source   sql.NullString
+		signals  sql.NullString
 		sent     sql.NullInt64
 	)
 	if err := row.Scan(
@@ -812,6 +877,7 @@ func (s *Store) GetCustomer(ctx context.Context, id int64) (*domain.Customer, er
 		&customer.Summary,
 		&sent,
 		&source,
+		&signals,
 		&customer.CreatedAt,
 		&customer.UpdatedAt,
 	); err != nil {
@@ -823,6 +889,7 @@ func (s *Store) GetCustomer(ctx context.Context, id int64) (*domain.Customer, er
 	if source.Valid {
 		customer.SourceJSON = json.RawMessage(source.String)
 	}
+	customer.WebsiteSignals = decodeWebsiteSignals(signals)
 	if sent.Valid {
 		customer.FollowupSent = sent.Int64 == 1
 	}
@@ -835,7 +902,11 @@ func (s *Store) ListContacts(ctx context.Context, customerID int64) ([]domain.Co
 		return nil, fmt.Errorf("store not initialized")
 	}
 	rows, err := s.DB.QueryContext(ctx,
-		`SELECT name, title, email, phone, source, is_key FROM contacts WHERE customer_id = ? ORDER BY is_key DESC, id ASC`,
+		`SELECT c.name, c.title, c.email, c.phone, c.source, c.is_key,
+		        COALESCE(v.status, ''), COALESCE(v.reason, ''), COALESCE(v.role, 0), COALESCE(v.checked_at, '')
+		 FROM contacts c
+		 LEFT JOIN email_verifications v ON v.email = LOWER(TRIM(c.email))
+		 WHERE c.customer_id = ? ORDER BY c.is_key DESC, c.id ASC`,
 		customerID,
 	)
 	if err != nil {
@@ -846,11 +917,13 @@ func (s *Store) ListContacts(ctx context.Context, customerID int64) ([]domain.Co
 	contacts := make([]domain.Contact, 0)
 	for rows.Next() {
 		var c domain.Contact
-		var isKey int
-		if err := rows.Scan(&c.Name, &c.Title, &c.Email, &c.Phone, &c.Source, &isKey); err != nil {
+		var isKey, role int
+		if err := rows.Scan(&c.Name, &c.Title, &c.Email, &c.Phone, &c.Source, &isKey,
+			&c.EmailStatus, &c.EmailStatusReason, &role, &c.EmailCheckedAt); err != nil {
 			return nil, fmt.Errorf("解析联系人失败: %w", err)
 		}
 		c.IsKey = isKey == 1
+		c.EmailRole = role == 1
 		contacts = append(contacts, c)
 	}
 	if err := rows.Err(); err != nil {
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+)
+
+func TestCustomerWebsiteSignals(t *testing.T) {
+	st, err := Open(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer st.Close()
+	ctx := context.Background()
+	if err := st.InitSchema(ctx); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+
+	signals := &domain.WebsiteSignals{
+		Ecommerce:      []string{"magento"},
+		Languages:      []string{"de", "en"},
+		RFQForm:        true,
+		CatalogSize:    320,
+		Certifications: []string{"ISO 9001"},
+		Evidence:       []string{"quote/inquiry form on /kontakt"},
+	}
+	customerID, err := st.CreateCustomer(ctx, &domain.CreateCompanyRequest{Name: "Acme", Website: "https://acme.example", WebsiteSignals: signals})
+	if err != nil {
+		t.Fatalf("create customer: %v", err)
+	}
+	customer, err := st.GetCustomer(ctx, customerID)
+	if err != nil {
+		t.Fatalf("get customer: %v", err)
+	}
+	if !reflect.DeepEqual(customer.WebsiteSignals, signals) {
+		t.Fatalf("signals = %+v, want %+v", customer.WebsiteSignals, signals)
+	}
+
+	// Edits that do not carry signals keep the stored ones.
+	if err := st.UpdateCustomer(ctx, customerID, &domain.CreateCompanyRequest{Name: "Acme GmbH", Website: "https://acme.example"}); err != nil {
+		t.Fatalf("update customer: %v", err)
+	}
+	detail, err := st.GetCustomerDetail(ctx, customerID)
+	if err != nil {
+		t.Fatalf("get detail: %v", err)
+	}
+	if detail.WebsiteSignals == nil || detail.WebsiteSignals.CatalogSize != 320 {
+		t.Fatalf("signals lost on update: %+v", detail.WebsiteSignals)
+	}
+
+	if err := st.UpdateCustomerWebsiteSignals(ctx, customerID, &domain.WebsiteSignals{Distributors: true}); err != nil {
+		t.Fatalf("update signals: %v", err)
+	}
+	customer, err = st.GetCustomer(ctx, customerID)
+	if err != nil {
+		t.Fatalf("get customer: %v", err)
+	}
+	if !customer.WebsiteSignals.Distributors || customer.WebsiteSignals.RFQForm {
+		t.Fatalf("signals not replaced: %+v", customer.WebsiteSignals)
+	}
+}
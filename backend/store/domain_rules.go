@@ -0,0 +1,245 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Domain rule kinds. Blocked domains never win website resolution; allowed
+// (preferred) domains get a ranking bonus and override broader block rules.
+const (
+	DomainRuleBlock = "block"
+	DomainRuleAllow = "allow"
+)
+
+// DomainRule is one entry of the website resolution block/allow list. A pattern
+// containing a dot matches the domain and its subdomains ("alibaba.com" matches
+// "m.alibaba.com"); a bare word matches any domain label ("kompass" matches
+// "fr.kompass.com" and "kompass.de").
+type DomainRule struct {
+	ID        int64  `json:"id"`
+	Pattern   string `json:"pattern"`
+	Kind      string `json:"kind"`
+	Note      string `json:"note,omitempty"`
+	BuiltIn   bool   `json:"built_in"`
+	Enabled   bool   `json:"enabled"`
+	CreatedAt string `json:"created_at,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+// ErrDomainRuleNotFound is returned when a rule id does not exist.
+var ErrDomainRuleNotFound = errors.New("域名规则不存在")
+
+// DefaultDomainRules returns the built-in block list: social networks, news and
+// job sites, search engines, encyclopedias and B2B marketplaces that list many
+// companies but are never a company's own website.
+func DefaultDomainRules() []DomainRule {
+	groups := []struct {
+		note     string
+		patterns []string
+	}{
+		{"社交网络", []string{"linkedin.com", "linkedin.cn", "facebook.com", "twitter.com", "x.com", "instagram.com", "youtube.com"}},
+		{"企业信息与新闻", []string{"crunchbase.com", "bloomberg.com", "reuters.com", "zoominfo.com", "dnb.com", "qcc.com", "tianyancha.com"}},
+		{"招聘与分类信息", []string{"glassdoor.com", "indeed.com", "fang.com", "58.com", "ganji.com"}},
+		{"百科与问答", []string{"zhihu.com", "baike.com", "wiki", "wikipedia.org"}},
+		{"搜索引擎", []string{"baidu.com", "google.com", "bing.com"}},
+		{"B2B 平台", []string{"alibaba.com", "1688.com", "made-in-china.com", "globalsources.com", "kompass", "europages", "indiamart.com", "tradeindia.com", "ec21.com", "tradekey.com", "dhgate.com", "amazon.com", "ebay.com"}},
+	}
+	var rules []DomainRule
+	for _, group := range groups {
+		for _, pattern := range group.patterns {
+			rules = append(rules, DomainRule{Pattern: pattern, Kind: DomainRuleBlock, Note: group.note, BuiltIn: true, Enabled: true})
+		}
+	}
+	return rules
+}
+
+// NormalizeDomainPattern lowercases a pattern and strips any scheme, path, port
+// and leading "www." or "*." so URLs can be pasted as-is.
+func NormalizeDomainPattern(raw string) (string, error) {
+	pattern := strings.ToLower(strings.TrimSpace(raw))
+	if strings.Contains(pattern, "://") {
+		if parsed, err := url.Parse(pattern); err == nil {
+			pattern = parsed.Host
+		}
+	}
+	if idx := strings.IndexAny(pattern, "/?#"); idx >= 0 {
+		pattern = pattern[:idx]
+	}
+	if idx := strings.LastIndex(pattern, ":"); idx >= 0 {
+		pattern = pattern[:idx]
+	}
+	pattern = strings.TrimPrefix(pattern, "*.")
+	pattern = strings.TrimPrefix(pattern, "www.")
+	pattern = strings.Trim(pattern, ".")
+	if pattern == "" {
+		return "", fmt.Errorf("域名规则不能为空")
+	}
+	for _, r := range pattern {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r > 127) {
+			return "", fmt.Errorf("域名规则包含非法字符: %s", raw)
+		}
+	}
+	return pattern, nil
+}
+
+func normalizeDomainRuleKind(kind string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case DomainRuleBlock, "":
+		return DomainRuleBlock, nil
+	case DomainRuleAllow:
+		return DomainRuleAllow, nil
+	default:
+		return "", fmt.Errorf("不支持的域名规则类型: %s", kind)
+	}
+}
+
+// seedDomainRules inserts missing built-in rules. Built-ins the user disabled
+// keep their state because existing patterns are left untouched.
+func (s *Store) seedDomainRules(ctx context.Context) error {
+	now := Now()
+	for _, rule := range DefaultDomainRules() {
+		if _, err := s.DB.ExecContext(ctx,
+			`INSERT OR IGNORE INTO domain_rules (pattern, kind, note, builtin, enabled, created_at, updated_at)
+			 VALUES (?, ?, ?, 1, 1, ?, ?)`,
+			rule.Pattern, rule.Kind, rule.Note, now, now,
+		); err != nil {
+			return fmt.Errorf("seed domain rule %s: %w", rule.Pattern, err)
+		}
+	}
+	return nil
+}
+
+const domainRuleColumns = `id, pattern, kind, COALESCE(note, ''), builtin, enabled, created_at, updated_at`
+
+func scanDomainRule(row rowScanner) (*DomainRule, error) {
+	var (
+		rule             DomainRule
+		builtin, enabled int
+	)
+	if err := row.Scan(&rule.ID, &rule.Pattern, &rule.Kind, &rule.Note, &builtin, &enabled, &rule.CreatedAt, &rule.UpdatedAt); err != nil {
+		return nil, err
+	}
+	rule.BuiltIn = builtin == 1
+	rule.Enabled = enabled == 1
+	return &rule, nil
+}
+
+// ListDomainRules returns every rule, allow rules first, then by pattern.
+func (s *Store) ListDomainRules(ctx context.Context) ([]DomainRule, error) {
+	if s == nil || s.DB == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	rows, err := s.DB.QueryContext(ctx,
+		`SELECT `+domainRuleColumns+` FROM domain_rules ORDER BY CASE kind WHEN 'allow' THEN 0 ELSE 1 END, pattern`)
+	if err != nil {
+		return nil, fmt.Errorf("查询域名规则失败: %w", err)
+	}
+	defer rows.Close()
+
+	rules := make([]DomainRule, 0)
+	for rows.Next() {
+		rule, err := scanDomainRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("解析域名规则失败: %w", err)
+		}
+		rules = append(rules, *rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历域名规则失败: %w", err)
+	}
+	return rules, nil
+}
+
+// GetDomainRule loads a single rule.
+func (s *Store) GetDomainRule(ctx context.Context, id int64) (*DomainRule, error) {
+	if s == nil || s.DB == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	rule, err := scanDomainRule(s.DB.QueryRowContext(ctx, `SELECT `+domainRuleColumns+` FROM domain_rules WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDomainRuleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询域名规则失败: %w", err)
+	}
+	return rule, nil
+}
+
+// CreateDomainRule adds a user-defined rule. Patterns must be unique.
+func (s *Store) CreateDomainRule(ctx context.Context, rule DomainRule) (*DomainRule, error) {
+	if s == nil || s.DB == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	pattern, err := NormalizeDomainPattern(rule.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	kind, err := normalizeDomainRuleKind(rule.Kind)
+	if err != nil {
+		return nil, err
+	}
+	now := Now()
+	res, err := s.DB.ExecContext(ctx,
+		`INSERT INTO domain_rules (pattern, kind, note, builtin, enabled, created_at, updated_at)
+		 VALUES (?, ?, ?, 0, 1, ?, ?)`,
+		pattern, kind, strings.TrimSpace(rule.Note), now, now,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, fmt.Errorf("域名规则已存在: %s", pattern)
+		}
+		return nil, fmt.Errorf("保存域名规则失败: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("保存域名规则失败: %w", err)
+	}
+	return s.GetDomainRule(ctx, id)
+}
+
+// UpdateDomainRule changes the kind, note and enabled flag of a rule. The
+// pattern itself is immutable; delete and recreate the rule to change it.
+func (s *Store) UpdateDomainRule(ctx context.Context, id int64, kind, note string, enabled bool) (*DomainRule, error) {
+	if s == nil || s.DB == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	kind, err := normalizeDomainRuleKind(kind)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.DB.ExecContext(ctx,
+		`UPDATE domain_rules SET kind = ?, note = ?, enabled = ?, updated_at = ? WHERE id = ?`,
+		kind, strings.TrimSpace(note), boolToInt(enabled), Now(), id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("更新域名规则失败: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrDomainRuleNotFound
+	}
+	return s.GetDomainRule(ctx, id)
+}
+
+// DeleteDomainRule removes a user-defined rule. Built-in rules are re-seeded on
+// startup, so they can only be disabled.
+func (s *Store) DeleteDomainRule(ctx context.Context, id int64) error {
+	if s == nil || s.DB == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	rule, err := s.GetDomainRule(ctx, id)
+	if err != nil {
+		return err
+	}
+	if rule.BuiltIn {
+		return fmt.Errorf("内置域名规则不能删除，可以停用")
+	}
+	if _, err := s.DB.ExecContext(ctx, `DELETE FROM domain_rules WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("删除域名规则失败: %w", err)
+	}
+	return nil
+}
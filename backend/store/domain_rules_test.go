@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestDomainRulesSeedAndCRUD(t *testing.T) {
+	st, err := Open(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer st.Close()
+	ctx := context.Background()
+	if err := st.InitSchema(ctx); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+
+	rules, err := st.ListDomainRules(ctx)
+	if err != nil {
+		t.Fatalf("list rules: %v", err)
+	}
+	if len(rules) != len(DefaultDomainRules()) {
+		t.Fatalf("expected %d built-in rules, got %d", len(DefaultDomainRules()), len(rules))
+	}
+	var alibaba *DomainRule
+	for i := range rules {
+		if rules[i].Pattern == "alibaba.com" {
+			alibaba = &rules[i]
+		}
+	}
+	if alibaba == nil || !alibaba.BuiltIn || !alibaba.Enabled || alibaba.Kind != DomainRuleBlock {
+		t.Fatalf("expected alibaba.com to be a built-in block rule, got %#v", alibaba)
+	}
+
+	if _, err := st.UpdateDomainRule(ctx, alibaba.ID, DomainRuleBlock, "", false); err != nil {
+		t.Fatalf("disable rule: %v", err)
+	}
+	if err := st.DeleteDomainRule(ctx, alibaba.ID); err == nil {
+		t.Fatalf("expected built-in rule deletion to fail")
+	}
+	if err := st.InitSchema(ctx); err != nil {
+		t.Fatalf("re-init schema: %v", err)
+	}
+	reloaded, err := st.GetDomainRule(ctx, alibaba.ID)
+	if err != nil {
+		t.Fatalf("get rule: %v", err)
+	}
+	if reloaded.Enabled {
+		t.Fatalf("re-seeding should keep a disabled built-in disabled")
+	}
+
+	created, err := st.CreateDomainRule(ctx, DomainRule{Pattern: "https://www.Example-Supplier.com/about", Kind: "allow", Note: " 老客户 "})
+	if err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+	if created.Pattern != "example-supplier.com" || created.Kind != DomainRuleAllow || created.Note != "老客户" || created.BuiltIn {
+		t.Fatalf("unexpected created rule %#v", created)
+	}
+	if _, err := st.CreateDomainRule(ctx, DomainRule{Pattern: "example-supplier.com"}); err == nil {
+		t.Fatalf("expected duplicate pattern to fail")
+	}
+	if _, err := st.CreateDomainRule(ctx, DomainRule{Pattern: "bad domain"}); err == nil {
+		t.Fatalf("expected invalid pattern to fail")
+	}
+	if _, err := st.CreateDomainRule(ctx, DomainRule{Pattern: "ok.com", Kind: "prefer"}); err == nil {
+		t.Fatalf("expected unknown kind to fail")
+	}
+	if err := st.DeleteDomainRule(ctx, created.ID); err != nil {
+		t.Fatalf("delete rule: %v", err)
+	}
+	if _, err := st.GetDomainRule(ctx, created.ID); !errors.Is(err, ErrDomainRuleNotFound) {
+		t.Fatalf("expected not found after delete, got %v", err)
+	}
+}
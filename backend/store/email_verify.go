@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+)
+
+// NormalizeDNSServer validates the resolver used for email verification. A
+// missing port means 53; an empty value (the system resolver) is returned
+// unchanged.
+func NormalizeDNSServer(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	host, port, err := net.SplitHostPort(raw)
+	if err != nil {
+		host, port = strings.Trim(raw, "[]"), "53"
+	}
+	if host == "" || strings.ContainsAny(host, "/ ") {
+		return "", fmt.Errorf("DNS 服务器地址无效: %s", raw)
+	}
+	if p, err := net.LookupPort("udp", port); err != nil || p <= 0 {
+		return "", fmt.Errorf("DNS 服务器端口无效: %s", raw)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// SaveEmailVerification stores the latest verification of an address,
+// replacing the previous one.
+func (s *Store) SaveEmailVerification(ctx context.Context, v *domain.EmailVerification) error {
+	if s == nil || s.DB == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	if v == nil || strings.TrimSpace(v.Email) == "" {
+		return fmt.Errorf("邮箱地址不能为空")
+	}
+	if v.CheckedAt == "" {
+		v.CheckedAt = Now()
+	}
+	_, err := s.DB.ExecContext(ctx,
+		`INSERT INTO email_verifications (email, status, reason, role, disposable, mx_host, smtp_checked, checked_at)
+         VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+         ON CONFLICT(email) DO UPDATE SET
+           status = excluded.status,
+           reason = excluded.reason,
+           role = excluded.role,
+           disposable = excluded.disposable,
+           mx_host = excluded.mx_host,
+           smtp_checked = excluded.smtp_checked,
+           checked_at = excluded.checked_at`,
+		strings.ToLower(strings.TrimSpace(v.Email)),
+		v.Status,
+		v.Reason,
+		boolToInt(v.Role),
+		boolToInt(v.Disposable),
+		v.MXHost,
+		boolToInt(v.SMTPChecked),
+		v.CheckedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("保存邮箱验证结果失败: %w", err)
+	}
+	return nil
+}
+
+// GetEmailVerification returns the stored verification of an address, or nil
+// if it was never checked.
+func (s *Store) GetEmailVerification(ctx context.Context, email string) (*domain.EmailVerification, error) {
+	if s == nil || s.DB == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	row := s.DB.QueryRowContext(ctx,
+		`SELECT email, status, COALESCE(reason, ''), role, disposable, COALESCE(mx_host, ''), smtp_checked, checked_at
+         FROM email_verifications WHERE email = ?`,
+		strings.ToLower(strings.TrimSpace(email)),
+	)
+	var (
+		v                             domain.EmailVerification
+		role, disposable, smtpChecked int
+	)
+	if err := row.Scan(&v.Email, &v.Status, &v.Reason, &role, &disposable, &v.MXHost, &smtpChecked, &v.CheckedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询邮箱验证结果失败: %w", err)
+	}
+	v.Role = role == 1
+	v.Disposable = disposable == 1
+	v.SMTPChecked = smtpChecked == 1
+	return &v, nil
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+)
+
+func TestNormalizeDNSServer(t *testing.T) {
+	cases := map[string]string{
+		"":                "",
+		" 1.1.1.1 ":       "1.1.1.1:53",
+		"127.0.0.1:5353":  "127.0.0.1:5353",
+		"2606:4700::1111": "[2606:4700::1111]:53",
+		"[::1]:5353":      "[::1]:5353",
+		"dns.example.com": "dns.example.com:53",
+	}
+	for raw, want := range cases {
+		got, err := NormalizeDNSServer(raw)
+		if err != nil || got != want {
+			t.Errorf("NormalizeDNSServer(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"https://dns.example/query", "1.1.1.1:dns-over-nothing"} {
+		if _, err := NormalizeDNSServer(raw); err == nil {
+			t.Errorf("NormalizeDNSServer(%q) accepted", raw)
+		}
+	}
+}
+
+func TestEmailVerificationStorage(t *testing.T) {
+	st, err := Open(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer st.Close()
+	ctx := context.Background()
+	if err := st.InitSchema(ctx); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+
+	if v, err := st.GetEmailVerification(ctx, "john@acme.example"); err != nil || v != nil {
+		t.Fatalf("unchecked address = %+v, %v", v, err)
+	}
+	if err := st.SaveEmailVerification(ctx, &domain.EmailVerification{Email: "John@Acme.example", Status: domain.EmailStatusRisky, Reason: "catch-all"}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := st.SaveEmailVerification(ctx, &domain.EmailVerification{Email: "john@acme.example", Status: domain.EmailStatusValid, MXHost: "mx.acme.example", SMTPChecked: true}); err != nil {
+		t.Fatalf("save again: %v", err)
+	}
+	v, err := st.GetEmailVerification(ctx, " JOHN@acme.example")
+	if err != nil || v == nil || v.Status != domain.EmailStatusValid || !v.SMTPChecked || v.Reason != "" || v.CheckedAt == "" {
+		t.Fatalf("stored = %+v, %v", v, err)
+	}
+
+	id, err := st.CreateCustomer(ctx, &domain.CreateCompanyRequest{
+		Name:     "Acme",
+		Contacts: []domain.Contact{{Name: "John", Email: "John@acme.example"}, {Name: "Jane", Email: "jane@acme.example"}},
+	})
+	if err != nil {
+		t.Fatalf("create customer: %v", err)
+	}
+	contacts, err := st.ListContacts(ctx, id)
+	if err != nil || len(contacts) != 2 {
+		t.Fatalf("contacts = %+v, %v", contacts, err)
+	}
+	for _, c := range contacts {
+		switch c.Name {
+		case "John":
+			if c.EmailStatus != domain.EmailStatusValid || c.EmailCheckedAt != v.CheckedAt {
+				t.Errorf("John = %+v", c)
+			}
+		case "Jane":
+			if c.EmailStatus != "" || c.EmailCheckedAt != "" {
+				t.Errorf("unchecked contact has a status: %+v", c)
+			}
+		}
+	}
+}
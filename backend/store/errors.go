@@ -4,3 +4,6 @@ import "errors"
 
 // ErrNotImplemented is returned by store functions not yet available in the MVP skeleton.
 var ErrNotImplemented = errors.New("not implemented")
+
+// ErrLLMBudgetExceeded indicates the configured daily or monthly LLM token budget is used up.
+var ErrLLMBudgetExceeded = errors.New("llm token budget exceeded")
@@ -0,0 +1,243 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+)
+
+// LLM usage report groupings.
+const (
+	LLMUsageGroupDay      = "day"
+	LLMUsageGroupStep     = "step"
+	LLMUsageGroupCustomer = "customer"
+)
+
+// LLMUsageEntry is one recorded LLM call attempt.
+type LLMUsageEntry struct {
+	Task             string
+	Provider         string
+	Model            string
+	CustomerID       int64
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	LatencyMs        int64
+	Error            string
+}
+
+// LLMUsageReportFilter selects the grouping and date range (YYYY-MM-DD, inclusive, UTC) of a report.
+type LLMUsageReportFilter struct {
+	GroupBy string
+	From    string
+	To      string
+}
+
+// InsertLLMUsage appends a call to the usage ledger.
+func (s *Store) InsertLLMUsage(ctx context.Context, entry LLMUsageEntry) error {
+	if s == nil || s.DB == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	var customerID sql.NullInt64
+	if entry.CustomerID > 0 {
+		customerID = sql.NullInt64{Int64: entry.CustomerID, Valid: true}
+	}
+	_, err := s.DB.ExecContext(ctx, `
+		INSERT INTO llm_usage (task, provider, model, customer_id, prompt_tokens, completion_tokens, total_tokens, latency_ms, error, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		strings.TrimSpace(entry.Task),
+		strings.TrimSpace(entry.Provider),
+		strings.TrimSpace(entry.Model),
+		customerID,
+		entry.PromptTokens,
+		entry.CompletionTokens,
+		entry.TotalTokens,
+		entry.LatencyMs,
+		strings.TrimSpace(entry.Error),
+		Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("记录 LLM 用量失败: %w", err)
+	}
+	return nil
+}
+
+// SumLLMTokensSince returns the total tokens consumed since the given instant.
+func (s *Store) SumLLMTokensSince(ctx context.Context, since time.Time) (int, error) {
+	if s == nil || s.DB == nil {
+		return 0, fmt.Errorf("store not initialized")
+	}
+	var total int
+	if err := s.DB.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(total_tokens), 0) FROM llm_usage WHERE created_at >= ?`,
+		since.UTC().Format(time.RFC3339),
+	).Scan(&total); err != nil {
+		return 0, fmt.Errorf("统计 LLM 用量失败: %w", err)
+	}
+	return total, nil
+}
+
+// GetLLMBudgetStatus compares today's and this month's usage (UTC) with the configured caps.
+func (s *Store) GetLLMBudgetStatus(ctx context.Context) (*domain.LLMBudgetStatus, error) {
+	settings, err := s.GetSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	status := &domain.LLMBudgetStatus{
+		DailyLimit:   settings.LLMDailyTokenBudget,
+		MonthlyLimit: settings.LLMMonthlyTokenBudget,
+	}
+	if status.DailyUsed, err = s.SumLLMTokensSince(ctx, dayStart); err != nil {
+		return nil, err
+	}
+	if status.MonthlyUsed, err = s.SumLLMTokensSince(ctx, monthStart); err != nil {
+		return nil, err
+	}
+	status.Exceeded = (status.DailyLimit > 0 && status.DailyUsed >= status.DailyLimit) ||
+		(status.MonthlyLimit > 0 && status.MonthlyUsed >= status.MonthlyLimit)
+	return status, nil
+}
+
+// CheckLLMBudget returns ErrLLMBudgetExceeded when a configured cap has been reached.
+func (s *Store) CheckLLMBudget(ctx context.Context) error {
+	status, err := s.GetLLMBudgetStatus(ctx)
+	if err != nil {
+		return err
+	}
+	if !status.Exceeded {
+		return nil
+	}
+	if status.DailyLimit > 0 && status.DailyUsed >= status.DailyLimit {
+		return fmt.Errorf("%w: 今日已用 %d / %d tokens", ErrLLMBudgetExceeded, status.DailyUsed, status.DailyLimit)
+	}
+	return fmt.Errorf("%w: 本月已用 %d / %d tokens", ErrLLMBudgetExceeded, status.MonthlyUsed, status.MonthlyLimit)
+}
+
+// LLMUsageReport aggregates the usage ledger by day, step or customer.
+func (s *Store) LLMUsageReport(ctx context.Context, filter LLMUsageReportFilter) (*domain.LLMUsageReport, error) {
+	if s == nil || s.DB == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+
+	groupBy := strings.ToLower(strings.TrimSpace(filter.GroupBy))
+	var keyExpr, labelExpr, orderBy string
+	switch groupBy {
+	case "", LLMUsageGroupDay:
+		groupBy = LLMUsageGroupDay
+		keyExpr, labelExpr, orderBy = "substr(u.created_at, 1, 10)", "''", "bucket_key ASC"
+	case LLMUsageGroupStep:
+		keyExpr, labelExpr, orderBy = "COALESCE(NULLIF(u.task, ''), 'other')", "''", "token_sum DESC"
+	case LLMUsageGroupCustomer:
+		keyExpr, labelExpr, orderBy = "CAST(COALESCE(u.customer_id, 0) AS TEXT)", "COALESCE(MAX(c.name), '')", "token_sum DESC"
+	default:
+		return nil, fmt.Errorf("不支持的分组方式: %s", filter.GroupBy)
+	}
+
+	now := time.Now().UTC()
+	from, err := parseReportDate(filter.From, now.AddDate(0, 0, -29))
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseReportDate(filter.To, now)
+	if err != nil {
+		return nil, err
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("结束日期不能早于开始日期")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s AS bucket_key, %s AS label,
+		       COUNT(*),
+		       SUM(CASE WHEN COALESCE(u.error, '') <> '' THEN 1 ELSE 0 END),
+		       COALESCE(SUM(u.prompt_tokens), 0),
+		       COALESCE(SUM(u.completion_tokens), 0),
+		       COALESCE(SUM(u.total_tokens), 0) AS token_sum,
+		       COALESCE(AVG(u.latency_ms), 0)
+		FROM llm_usage u
+		LEFT JOIN customers c ON c.id = u.customer_id
+		WHERE u.created_at >= ? AND u.created_at < ?
+		GROUP BY bucket_key
+		ORDER BY %s`, keyExpr, labelExpr, orderBy)
+
+	rows, err := s.DB.QueryContext(ctx, query,
+		from.Format(time.RFC3339),
+		to.AddDate(0, 0, 1).Format(time.RFC3339),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("查询 LLM 用量失败: %w", err)
+	}
+	defer rows.Close()
+
+	report := &domain.LLMUsageReport{
+		GroupBy: groupBy,
+		From:    from.Format("2006-01-02"),
+		To:      to.Format("2006-01-02"),
+		Buckets: make([]domain.LLMUsageBucket, 0),
+		Total:   domain.LLMUsageBucket{Key: "total"},
+	}
+	var latencyWeighted float64
+	for rows.Next() {
+		var (
+			bucket     domain.LLMUsageBucket
+			avgLatency float64
+		)
+		if err := rows.Scan(
+			&bucket.Key,
+			&bucket.Label,
+			&bucket.Calls,
+			&bucket.Failures,
+			&bucket.PromptTokens,
+			&bucket.CompletionTokens,
+			&bucket.TotalTokens,
+			&avgLatency,
+		); err != nil {
+			return nil, fmt.Errorf("解析 LLM 用量失败: %w", err)
+		}
+		bucket.AvgLatencyMs = int64(avgLatency)
+		if groupBy == LLMUsageGroupCustomer && bucket.Key == "0" {
+			bucket.Label = "未关联客户"
+		}
+		report.Buckets = append(report.Buckets, bucket)
+
+		report.Total.Calls += bucket.Calls
+		report.Total.Failures += bucket.Failures
+		report.Total.PromptTokens += bucket.PromptTokens
+		report.Total.CompletionTokens += bucket.CompletionTokens
+		report.Total.TotalTokens += bucket.TotalTokens
+		latencyWeighted += avgLatency * float64(bucket.Calls)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历 LLM 用量失败: %w", err)
+	}
+	if report.Total.Calls > 0 {
+		report.Total.AvgLatencyMs = int64(latencyWeighted / float64(report.Total.Calls))
+	}
+
+	budget, err := s.GetLLMBudgetStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.Budget = *budget
+	return report, nil
+}
+
+func parseReportDate(value string, fallback time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Date(fallback.Year(), fallback.Month(), fallback.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("日期格式应为 YYYY-MM-DD: %s", value)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestLLMUsageReportAndBudget(t *testing.T) {
+	st, err := Open(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer st.Close()
+	ctx := context.Background()
+	if err := st.InitSchema(ctx); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+
+	entries := []LLMUsageEntry{
+		{Task: "grading", Model: "fast", CustomerID: 1, PromptTokens: 60, CompletionTokens: 40, TotalTokens: 100, LatencyMs: 200},
+		{Task: "analysis", Model: "strong", CustomerID: 1, PromptTokens: 200, CompletionTokens: 100, TotalTokens: 300, LatencyMs: 600},
+		{Task: "analysis", Model: "strong", CustomerID: 2, LatencyMs: 50, Error: "LLM 接口返回错误(503)"},
+	}
+	for _, entry := range entries {
+		if err := st.InsertLLMUsage(ctx, entry); err != nil {
+			t.Fatalf("insert usage: %v", err)
+		}
+	}
+
+	report, err := st.LLMUsageReport(ctx, LLMUsageReportFilter{GroupBy: LLMUsageGroupStep})
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if len(report.Buckets) != 2 || report.Buckets[0].Key != "analysis" {
+		t.Fatalf("unexpected buckets %#v", report.Buckets)
+	}
+	if report.Buckets[0].Calls != 2 || report.Buckets[0].Failures != 1 || report.Buckets[0].TotalTokens != 300 {
+		t.Fatalf("unexpected analysis bucket %#v", report.Buckets[0])
+	}
+	if report.Total.TotalTokens != 400 || report.Total.Calls != 3 {
+		t.Fatalf("unexpected totals %#v", report.Total)
+	}
+
+	byCustomer, err := st.LLMUsageReport(ctx, LLMUsageReportFilter{GroupBy: LLMUsageGroupCustomer})
+	if err != nil {
+		t.Fatalf("customer report: %v", err)
+	}
+	if len(byCustomer.Buckets) != 2 {
+		t.Fatalf("expected 2 customer buckets, got %#v", byCustomer.Buckets)
+	}
+
+	if _, err := st.LLMUsageReport(ctx, LLMUsageReportFilter{GroupBy: "model"}); err == nil {
+		t.Fatalf("expected unsupported grouping to fail")
+	}
+
+	if err := st.CheckLLMBudget(ctx); err != nil {
+		t.Fatalf("unlimited budget should pass: %v", err)
+	}
+	data, _ := json.Marshal(Settings{LLMDailyTokenBudget: 350})
+	if err := st.SaveSettings(ctx, bytes.NewReader(data)); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+	if err := st.CheckLLMBudget(ctx); !errors.Is(err, ErrLLMBudgetExceeded) {
+		t.Fatalf("expected budget exceeded, got %v", err)
+	}
+}
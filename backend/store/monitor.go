@@ -0,0 +1,197 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+)
+
+// Website monitor check intervals, in hours.
+const (
+	DefaultMonitorIntervalHours = 168
+	MinMonitorIntervalHours     = 6
+)
+
+// customerDetailActivityLimit caps the activities returned with a customer detail.
+const customerDetailActivityLimit = 20
+
+// NormalizeMonitorGrades keeps the valid grades (A, B, C) of a comma separated
+// list, upper-cased and de-duplicated. An empty result falls back to "A".
+func NormalizeMonitorGrades(raw string) string {
+	seen := make(map[string]bool)
+	var grades []string
+	for _, part := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == '，' || r == ' ' }) {
+		grade := strings.ToUpper(strings.TrimSpace(part))
+		switch grade {
+		case "A", "B", "C":
+		default:
+			continue
+		}
+		if !seen[grade] {
+			seen[grade] = true
+			grades = append(grades, grade)
+		}
+	}
+	if len(grades) == 0 {
+		return "A"
+	}
+	return strings.Join(grades, ",")
+}
+
+// MonitorTarget is a customer whose website is due for a change check.
+type MonitorTarget struct {
+	CustomerID    int64
+	Name          string
+	Website       string
+	Grade         string
+	LastCheckedAt string
+}
+
+// CreateCustomerActivity appends an entry to a customer's timeline.
+func (s *Store) CreateCustomerActivity(ctx context.Context, activity *domain.CustomerActivity) (int64, error) {
+	if s == nil || s.DB == nil {
+		return 0, fmt.Errorf("store not initialized")
+	}
+	if activity == nil || activity.CustomerID <= 0 {
+		return 0, fmt.Errorf("invalid customer id")
+	}
+	if strings.TrimSpace(activity.Kind) == "" {
+		return 0, fmt.Errorf("活动类型不能为空")
+	}
+	if activity.CreatedAt == "" {
+		activity.CreatedAt = Now()
+	}
+	res, err := s.DB.ExecContext(ctx,
+		`INSERT INTO customer_activities (customer_id, kind, title, detail, created_at)
+         VALUES (?, ?, ?, ?, ?)`,
+		activity.CustomerID,
+		activity.Kind,
+		activity.Title,
+		activity.Detail,
+		activity.CreatedAt,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("保存客户动态失败: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("读取客户动态 ID 失败: %w", err)
+	}
+	activity.ID = id
+	return id, nil
+}
+
+// ListCustomerActivities returns a customer's latest activities, newest first.
+func (s *Store) ListCustomerActivities(ctx context.Context, customerID int64, limit int) ([]domain.CustomerActivity, error) {
+	if s == nil || s.DB == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	if limit <= 0 {
+		limit = customerDetailActivityLimit
+	}
+	rows, err := s.DB.QueryContext(ctx,
+		`SELECT id, customer_id, kind, COALESCE(title, ''), COALESCE(detail, ''), created_at
+         FROM customer_activities
+         WHERE customer_id = ?
+         ORDER BY created_at DESC, id DESC
+         LIMIT ?`,
+		customerID,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("查询客户动态失败: %w", err)
+	}
+	defer rows.Close()
+
+	var activities []domain.CustomerActivity
+	for rows.Next() {
+		var activity domain.CustomerActivity
+		if err := rows.Scan(&activity.ID, &activity.CustomerID, &activity.Kind, &activity.Title, &activity.Detail, &activity.CreatedAt); err != nil {
+			return nil, fmt.Errorf("读取客户动态失败: %w", err)
+		}
+		activities = append(activities, activity)
+	}
+	return activities, rows.Err()
+}
+
+// ListMonitorDueCustomers returns customers with a website and one of the
+// given grades that were never checked or last checked before checkedBefore,
+// least recently checked first.
+func (s *Store) ListMonitorDueCustomers(ctx context.Context, grades []string, checkedBefore string, limit int) ([]MonitorTarget, error) {
+	if s == nil || s.DB == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	if len(grades) == 0 {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	placeholders := make([]string, len(grades))
+	args := make([]any, 0, len(grades)+2)
+	for i, grade := range grades {
+		placeholders[i] = "?"
+		args = append(args, strings.ToUpper(strings.TrimSpace(grade)))
+	}
+	args = append(args, checkedBefore, limit)
+	rows, err := s.DB.QueryContext(ctx,
+		`SELECT c.id, COALESCE(c.name, ''), c.website, UPPER(TRIM(c.grade)), COALESCE(m.last_checked_at, '')
+         FROM customers c
+         LEFT JOIN website_monitors m ON m.customer_id = c.id
+         WHERE TRIM(COALESCE(c.website, '')) <> ''
+           AND UPPER(TRIM(COALESCE(c.grade, ''))) IN (`+strings.Join(placeholders, ", ")+`)
+           AND (m.last_checked_at IS NULL OR m.last_checked_at < ?)
+         ORDER BY COALESCE(m.last_checked_at, '') ASC, c.id ASC
+         LIMIT ?`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("查询待监控客户失败: %w", err)
+	}
+	defer rows.Close()
+
+	var targets []MonitorTarget
+	for rows.Next() {
+		var target MonitorTarget
+		if err := rows.Scan(&target.CustomerID, &target.Name, &target.Website, &target.Grade, &target.LastCheckedAt); err != nil {
+			return nil, fmt.Errorf("读取待监控客户失败: %w", err)
+		}
+		targets = append(targets, target)
+	}
+	return targets, rows.Err()
+}
+
+// RecordMonitorCheck stores the outcome of a website check. A non-empty
+// checkErr is kept until the next successful check.
+func (s *Store) RecordMonitorCheck(ctx context.Context, customerID int64, changed bool, checkErr string) error {
+	if s == nil || s.DB == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	if customerID <= 0 {
+		return fmt.Errorf("invalid customer id")
+	}
+	now := Now()
+	var changedAt sql.NullString
+	if changed {
+		changedAt = sql.NullString{String: now, Valid: true}
+	}
+	_, err := s.DB.ExecContext(ctx,
+		`INSERT INTO website_monitors (customer_id, last_checked_at, last_changed_at, last_error)
+         VALUES (?, ?, ?, ?)
+         ON CONFLICT(customer_id) DO UPDATE SET
+           last_checked_at = excluded.last_checked_at,
+           last_changed_at = COALESCE(excluded.last_changed_at, website_monitors.last_changed_at),
+           last_error = excluded.last_error`,
+		customerID,
+		now,
+		changedAt,
+		checkErr,
+	)
+	if err != nil {
+		return fmt.Errorf("记录网站监控结果失败: %w", err)
+	}
+	return nil
+}
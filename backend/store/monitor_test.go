@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+)
+
+func TestNormalizeMonitorGrades(t *testing.T) {
+	cases := map[string]string{
+		"":           "A",
+		"a, b":       "A,B",
+		"B，c,b":      "B,C",
+		"x, unknown": "A",
+	}
+	for raw, want := range cases {
+		if got := NormalizeMonitorGrades(raw); got != want {
+			t.Errorf("NormalizeMonitorGrades(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestMonitorDueCustomersAndActivities(t *testing.T) {
+	st, err := Open(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer st.Close()
+	ctx := context.Background()
+	if err := st.InitSchema(ctx); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+
+	create := func(name, website, grade string) int64 {
+		id, err := st.CreateCustomer(ctx, &domain.CreateCompanyRequest{Name: name, Website: website})
+		if err != nil {
+			t.Fatalf("create customer: %v", err)
+		}
+		if grade != "" {
+			if err := st.UpdateCustomerGrade(ctx, id, grade, ""); err != nil {
+				t.Fatalf("grade customer: %v", err)
+			}
+		}
+		return id
+	}
+	acme := create("Acme", "https://acme.example", "A")
+	create("Beta", "https://beta.example", "B")
+	create("NoSite", "", "A")
+
+	future := time.Now().UTC().Add(time.Hour).Format(time.RFC3339)
+	due, err := st.ListMonitorDueCustomers(ctx, []string{"A"}, future, 10)
+	if err != nil {
+		t.Fatalf("list due: %v", err)
+	}
+	if len(due) != 1 || due[0].CustomerID != acme || due[0].LastCheckedAt != "" {
+		t.Fatalf("due = %+v", due)
+	}
+
+	if err := st.RecordMonitorCheck(ctx, acme, false, ""); err != nil {
+		t.Fatalf("record check: %v", err)
+	}
+	past := time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)
+	if due, _ := st.ListMonitorDueCustomers(ctx, []string{"A", "B"}, past, 10); len(due) != 1 || due[0].Name != "Beta" {
+		t.Fatalf("recently checked customer still due: %+v", due)
+	}
+
+	for _, title := range []string{"官网更新：新增 1 个页面", "官网更新：6 处内容变化"} {
+		if _, err := st.CreateCustomerActivity(ctx, &domain.CustomerActivity{CustomerID: acme, Kind: domain.ActivityWebsiteChange, Title: title}); err != nil {
+			t.Fatalf("create activity: %v", err)
+		}
+	}
+	detail, err := st.GetCustomerDetail(ctx, acme)
+	if err != nil {
+		t.Fatalf("get detail: %v", err)
+	}
+	if len(detail.Activities) != 2 || detail.Activities[0].Title != "官网更新：6 处内容变化" {
+		t.Fatalf("activities = %+v", detail.Activities)
+	}
+
+	if err := st.DeleteCustomer(ctx, acme); err != nil {
+		t.Fatalf("delete customer: %v", err)
+	}
+	if activities, _ := st.ListCustomerActivities(ctx, acme, 0); len(activities) != 0 {
+		t.Fatalf("activities survived customer deletion: %+v", activities)
+	}
+}
@@ -0,0 +1,155 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+)
+
+const promptTemplateColumns = `id, name, version, COALESCE(system_prompt, ''), COALESCE(user_prompt, ''), COALESCE(note, ''), created_at`
+
+// GetActivePromptTemplate returns the newest stored version of a prompt, or nil when none exists.
+func (s *Store) GetActivePromptTemplate(ctx context.Context, name string) (*domain.PromptTemplate, error) {
+	if s == nil || s.DB == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	row := s.DB.QueryRowContext(ctx,
+		`SELECT `+promptTemplateColumns+` FROM prompt_templates WHERE name = ? ORDER BY version DESC LIMIT 1`,
+		strings.TrimSpace(name),
+	)
+	tpl, err := scanPromptTemplate(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询提示词模板失败: %w", err)
+	}
+	return tpl, nil
+}
+
+// GetPromptTemplateVersion returns a specific version of a prompt.
+func (s *Store) GetPromptTemplateVersion(ctx context.Context, name string, version int) (*domain.PromptTemplate, error) {
+	if s == nil || s.DB == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	row := s.DB.QueryRowContext(ctx,
+		`SELECT `+promptTemplateColumns+` FROM prompt_templates WHERE name = ? AND version = ?`,
+		strings.TrimSpace(name),
+		version,
+	)
+	tpl, err := scanPromptTemplate(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("未找到提示词模板 %s 的版本 %d", name, version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询提示词模板失败: %w", err)
+	}
+	return tpl, nil
+}
+
+// ListPromptTemplateVersions returns every stored version of a prompt, newest first.
+func (s *Store) ListPromptTemplateVersions(ctx context.Context, name string) ([]domain.PromptTemplate, error) {
+	if s == nil || s.DB == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	rows, err := s.DB.QueryContext(ctx,
+		`SELECT `+promptTemplateColumns+` FROM prompt_templates WHERE name = ? ORDER BY version DESC`,
+		strings.TrimSpace(name),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("查询提示词版本失败: %w", err)
+	}
+	defer rows.Close()
+
+	versions := make([]domain.PromptTemplate, 0)
+	for rows.Next() {
+		tpl, err := scanPromptTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("解析提示词版本失败: %w", err)
+		}
+		versions = append(versions, *tpl)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历提示词版本失败: %w", err)
+	}
+	return versions, nil
+}
+
+// CreatePromptTemplateVersion appends a new version, which immediately becomes active.
+func (s *Store) CreatePromptTemplateVersion(ctx context.Context, name, systemPrompt, userPrompt, note string) (*domain.PromptTemplate, error) {
+	if s == nil || s.DB == nil {
+		return nil, fmt.Errorf("store not initialized")
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("模板名称不能为空")
+	}
+
+	var id int64
+	var version int
+	now := Now()
+	err := s.WithTx(ctx, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx,
+			`SELECT COALESCE(MAX(version), 0) + 1 FROM prompt_templates WHERE name = ?`, name,
+		).Scan(&version); err != nil {
+			return fmt.Errorf("计算模板版本失败: %w", err)
+		}
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO prompt_templates (name, version, system_prompt, user_prompt, note, created_at)
+			 VALUES (?, ?, ?, ?, ?, ?)`,
+			name, version, systemPrompt, userPrompt, strings.TrimSpace(note), now,
+		)
+		if err != nil {
+			return fmt.Errorf("保存提示词模板失败: %w", err)
+		}
+		id, err = res.LastInsertId()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &domain.PromptTemplate{
+		ID:           id,
+		Name:         name,
+		Version:      version,
+		SystemPrompt: systemPrompt,
+		UserPrompt:   userPrompt,
+		Note:         strings.TrimSpace(note),
+		CreatedAt:    now,
+	}, nil
+}
+
+// DeletePromptTemplates removes all stored versions so the built-in default applies again.
+func (s *Store) DeletePromptTemplates(ctx context.Context, name string) error {
+	if s == nil || s.DB == nil {
+		return fmt.Errorf("store not initialized")
+	}
+	if _, err := s.DB.ExecContext(ctx, `DELETE FROM prompt_templates WHERE name = ?`, strings.TrimSpace(name)); err != nil {
+		return fmt.Errorf("删除提示词模板失败: %w", err)
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPromptTemplate(row rowScanner) (*domain.PromptTemplate, error) {
+	var tpl domain.PromptTemplate
+	if err := row.Scan(
+		&tpl.ID,
+		&tpl.Name,
+		&tpl.Version,
+		&tpl.SystemPrompt,
+		&tpl.UserPrompt,
+		&tpl.Note,
+		&tpl.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &tpl, nil
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Proxy channels. Each outbound traffic type can use the global proxy, connect
+// directly or use its own proxy server.
+const (
+	ProxyChannelLLM    = "llm"
+	ProxyChannelSearch = "search"
+	ProxyChannelFetch  = "fetch"
+	ProxyChannelSMTP   = "smtp"
+)
+
+// ProxyChannels lists the channels accepted in proxy_channels.
+var ProxyChannels = []string{ProxyChannelLLM, ProxyChannelSearch, ProxyChannelFetch, ProxyChannelSMTP}
+
+// Per-channel proxy modes. Any other value is a proxy URL for that channel.
+const (
+	ProxyModeGlobal = "global"
+	ProxyModeDirect = "direct"
+)
+
+// NormalizeProxyURL validates a proxy server address. A missing scheme means
+// HTTP; http, https, socks5 and socks5h are accepted. Credentials belong in the
+// proxy username and password settings, which are stored encrypted, so URLs
+// carrying them are rejected. An empty value is returned unchanged.
+func NormalizeProxyURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("代理地址无效: %w", err)
+	}
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	switch parsed.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return "", fmt.Errorf("不支持的代理协议: %s", parsed.Scheme)
+	}
+	if parsed.User != nil {
+		return "", fmt.Errorf("请在代理用户名和密码中填写认证信息，不要写在代理地址里")
+	}
+	host, port, err := net.SplitHostPort(parsed.Host)
+	if err != nil || host == "" || port == "" {
+		return "", fmt.Errorf("代理地址需要包含主机和端口，例如 socks5://127.0.0.1:1080")
+	}
+	if strings.Trim(parsed.Path, "/") != "" || parsed.RawQuery != "" {
+		return "", fmt.Errorf("代理地址不能包含路径: %s", raw)
+	}
+	return parsed.Scheme + "://" + parsed.Host, nil
+}
+
+// encodeProxyChannels validates the per-channel overrides and serialises them
+// for storage. Channels using the global proxy are left out.
+func encodeProxyChannels(channels map[string]string) (string, error) {
+	cleaned := make(map[string]string, len(channels))
+	for channel, value := range channels {
+		channel = strings.ToLower(strings.TrimSpace(channel))
+		if !isProxyChannel(channel) {
+			return "", fmt.Errorf("未知的代理通道: %s", channel)
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(value) {
+		case "", ProxyModeGlobal:
+			continue
+		case ProxyModeDirect:
+			cleaned[channel] = ProxyModeDirect
+			continue
+		}
+		normalized, err := NormalizeProxyURL(value)
+		if err != nil {
+			return "", fmt.Errorf("%s 通道: %w", channel, err)
+		}
+		cleaned[channel] = normalized
+	}
+	data, err := json.Marshal(cleaned)
+	if err != nil {
+		return "", fmt.Errorf("encode proxy channels: %w", err)
+	}
+	return string(data), nil
+}
+
+func isProxyChannel(name string) bool {
+	for _, channel := range ProxyChannels {
+		if channel == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+)
+
+// RequeuedJobs counts background work moved from running back to the queue.
+type RequeuedJobs struct {
+	Automation int64
+	Todo       int64
+	Scheduled  int64
+}
+
+// Total returns the number of requeued rows across all queues.
+func (r RequeuedJobs) Total() int64 {
+	return r.Automation + r.Todo + r.Scheduled
+}
+
+// RequeueInterruptedJobs puts work left in the running state by a shutdown or
+// crash back into its queue. It must run before the background runners start,
+// while nothing can legitimately be running.
+func (s *Store) RequeueInterruptedJobs(ctx context.Context) (RequeuedJobs, error) {
+	var result RequeuedJobs
+	if s == nil || s.DB == nil {
+		return result, fmt.Errorf("store not initialized")
+	}
+	now := Now()
+	err := s.WithTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE automation_jobs
+             SET status = ?, stage = ?, updated_at = ?
+             WHERE status = ?`,
+			domain.AutomationStatusQueued,
+			domain.AutomationStagePending,
+			now,
+			domain.AutomationStatusRunning,
+		)
+		if err != nil {
+			return fmt.Errorf("恢复自动化任务失败: %w", err)
+		}
+		result.Automation, _ = res.RowsAffected()
+
+		res, err = tx.ExecContext(ctx,
+			`UPDATE todo_tasks SET status = 'queued', updated_at = ? WHERE status = 'running'`,
+			now,
+		)
+		if err != nil {
+			return fmt.Errorf("恢复待处理任务失败: %w", err)
+		}
+		result.Todo, _ = res.RowsAffected()
+
+		res, err = tx.ExecContext(ctx,
+			`UPDATE scheduled_tasks SET status = 'scheduled', updated_at = ? WHERE status = 'running'`,
+			now,
+		)
+		if err != nil {
+			return fmt.Errorf("恢复定时任务失败: %w", err)
+		}
+		result.Scheduled, _ = res.RowsAffected()
+		return nil
+	})
+	if err != nil {
+		return RequeuedJobs{}, err
+	}
+	return result, nil
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/domain"
+)
+
+func TestRequeueInterruptedJobs(t *testing.T) {
+	st, err := Open(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer st.Close()
+	ctx := context.Background()
+	if err := st.InitSchema(ctx); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+
+	customerID, err := st.CreateCustomer(ctx, &domain.CreateCompanyRequest{Name: "Acme", Website: "https://acme.example"})
+	if err != nil {
+		t.Fatalf("create customer: %v", err)
+	}
+	if _, err := st.CreateAutomationJob(ctx, customerID); err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	job, err := st.ClaimNextAutomationJob(ctx)
+	if err != nil || job == nil {
+		t.Fatalf("claim job: %v %v", job, err)
+	}
+	if _, err := st.CreateTodoTask(ctx, "acme"); err != nil {
+		t.Fatalf("create todo: %v", err)
+	}
+	if _, err := st.CreateTodoTask(ctx, "globex"); err != nil {
+		t.Fatalf("create todo: %v", err)
+	}
+	todo, err := st.ClaimNextTodo(ctx)
+	if err != nil || todo == nil {
+		t.Fatalf("claim todo: %v %v", todo, err)
+	}
+
+	requeued, err := st.RequeueInterruptedJobs(ctx)
+	if err != nil {
+		t.Fatalf("requeue: %v", err)
+	}
+	if requeued.Automation != 1 || requeued.Todo != 1 || requeued.Total() != 2 {
+		t.Fatalf("unexpected requeue counts %#v", requeued)
+	}
+
+	job, err = st.GetAutomationJob(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("get job: %v", err)
+	}
+	if job.Status != domain.AutomationStatusQueued || job.Stage != domain.AutomationStagePending {
+		t.Fatalf("job not requeued: %#v", job)
+	}
+	again, err := st.ClaimNextTodo(ctx)
+	if err != nil || again == nil || again.ID != todo.ID {
+		t.Fatalf("expected interrupted todo %d to be claimed first, got %v %v", todo.ID, again, err)
+	}
+
+	requeued, err = st.RequeueInterruptedJobs(ctx)
+	if err != nil {
+		t.Fatalf("second requeue: %v", err)
+	}
+	if requeued.Automation != 0 || requeued.Todo != 1 {
+		t.Fatalf("unexpected second requeue counts %#v", requeued)
+	}
+}
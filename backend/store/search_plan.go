@@ -0,0 +1,116 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SearchPlanCompanyPlaceholder is replaced with the company name in stage query
+// templates. Templates without it get the company name prepended.
+const SearchPlanCompanyPlaceholder = "{company}"
+
+const (
+	defaultSearchStageLimit = 10
+	maxSearchStageLimit     = 20
+	maxSearchPlanStages     = 8
+)
+
+// SearchPlanStage configures one concurrent search track of the company
+// research plan.
+type SearchPlanStage struct {
+	Key     string `json:"key"`
+	Label   string `json:"label"`
+	Query   string `json:"query"`
+	Limit   int    `json:"limit"`
+	Enabled bool   `json:"enabled"`
+}
+
+// DefaultSearchPlan returns the built-in four-stage plan used when no plan has
+// been configured.
+func DefaultSearchPlan() []SearchPlanStage {
+	return []SearchPlanStage{
+		{Key: "broad_discovery", Label: "任务A：基础信息搜索", Query: "{company}", Limit: 10, Enabled: true},
+		{Key: "website_focus", Label: "任务B：官网及业务搜索", Query: "{company} official website", Limit: 10, Enabled: true},
+		{Key: "decision_makers", Label: "任务C：关键联系人搜索", Query: "{company} CEO founder owner purchasing manager", Limit: 10, Enabled: true},
+		{Key: "linkedin_audit", Label: "任务D：社交与职业背景搜索", Query: "site:linkedin.com {company}", Limit: 10, Enabled: true},
+	}
+}
+
+// BuildQuery renders the stage template for a company name. It returns an
+// empty string when the name is blank.
+func (s SearchPlanStage) BuildQuery(company string) string {
+	company = strings.TrimSpace(company)
+	if company == "" {
+		return ""
+	}
+	template := strings.TrimSpace(s.Query)
+	if !strings.Contains(template, SearchPlanCompanyPlaceholder) {
+		return strings.TrimSpace(company + " " + template)
+	}
+	return strings.TrimSpace(strings.ReplaceAll(template, SearchPlanCompanyPlaceholder, company))
+}
+
+// NormalizeSearchPlan trims the stages, fills in keys, labels and limits, and
+// rejects plans that cannot run. An empty plan is returned as the default.
+func NormalizeSearchPlan(stages []SearchPlanStage) ([]SearchPlanStage, error) {
+	if len(stages) == 0 {
+		return DefaultSearchPlan(), nil
+	}
+	if len(stages) > maxSearchPlanStages {
+		return nil, fmt.Errorf("搜索计划最多 %d 个阶段", maxSearchPlanStages)
+	}
+	seen := make(map[string]bool, len(stages))
+	cleaned := make([]SearchPlanStage, 0, len(stages))
+	enabled := 0
+	for i, stage := range stages {
+		stage.Key = strings.ToLower(strings.TrimSpace(stage.Key))
+		if stage.Key == "" {
+			stage.Key = fmt.Sprintf("stage_%d", i+1)
+		}
+		if seen[stage.Key] {
+			return nil, fmt.Errorf("搜索阶段标识重复: %s", stage.Key)
+		}
+		seen[stage.Key] = true
+		stage.Label = strings.TrimSpace(stage.Label)
+		if stage.Label == "" {
+			stage.Label = stage.Key
+		}
+		stage.Query = strings.TrimSpace(stage.Query)
+		if stage.Query == "" {
+			stage.Query = SearchPlanCompanyPlaceholder
+		}
+		if stage.Limit <= 0 {
+			stage.Limit = defaultSearchStageLimit
+		}
+		if stage.Limit > maxSearchStageLimit {
+			stage.Limit = maxSearchStageLimit
+		}
+		if stage.Enabled {
+			enabled++
+		}
+		cleaned = append(cleaned, stage)
+	}
+	if enabled == 0 {
+		return nil, fmt.Errorf("搜索计划至少需要启用一个阶段")
+	}
+	return cleaned, nil
+}
+
+// encodeSearchPlan normalises the plan and serialises it for storage. The
+// default plan is stored as an empty value so later default changes apply.
+func encodeSearchPlan(stages []SearchPlanStage) (string, error) {
+	cleaned, err := NormalizeSearchPlan(stages)
+	if err != nil {
+		return "", err
+	}
+	defaults, _ := json.Marshal(DefaultSearchPlan())
+	data, err := json.Marshal(cleaned)
+	if err != nil {
+		return "", fmt.Errorf("encode search plan: %w", err)
+	}
+	if string(data) == string(defaults) {
+		return "", nil
+	}
+	return string(data), nil
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func TestSearchPlanStageBuildQuery(t *testing.T) {
+	cases := map[string]string{
+		"{company}":                      "Acme GmbH",
+		"site:linkedin.com {company}":    "site:linkedin.com Acme GmbH",
+		"distributor importer":           "Acme GmbH distributor importer",
+		"\"{company}\" OR {company} ltd": "\"Acme GmbH\" OR Acme GmbH ltd",
+	}
+	for template, want := range cases {
+		if got := (SearchPlanStage{Query: template}).BuildQuery(" Acme GmbH "); got != want {
+			t.Fatalf("template %q: got %q want %q", template, got, want)
+		}
+	}
+	if got := (SearchPlanStage{Query: "{company} distributor"}).BuildQuery("  "); got != "" {
+		t.Fatalf("blank company should produce no query, got %q", got)
+	}
+}
+
+func TestNormalizeSearchPlan(t *testing.T) {
+	stages, err := NormalizeSearchPlan([]SearchPlanStage{
+		{Key: " Importers ", Query: " {company} importer ", Limit: 50, Enabled: true},
+		{Limit: -1},
+	})
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	if stages[0].Key != "importers" || stages[0].Label != "importers" || stages[0].Limit != maxSearchStageLimit || stages[0].Query != "{company} importer" {
+		t.Fatalf("unexpected first stage %#v", stages[0])
+	}
+	if stages[1].Key != "stage_2" || stages[1].Query != SearchPlanCompanyPlaceholder || stages[1].Limit != defaultSearchStageLimit {
+		t.Fatalf("unexpected second stage %#v", stages[1])
+	}
+
+	if _, err := NormalizeSearchPlan([]SearchPlanStage{{Key: "a"}}); err == nil {
+		t.Fatalf("expected an error when no stage is enabled")
+	}
+	if _, err := NormalizeSearchPlan([]SearchPlanStage{{Key: "a", Enabled: true}, {Key: "A", Enabled: true}}); err == nil {
+		t.Fatalf("expected an error for duplicate keys")
+	}
+}
+
+func TestSearchPlanPersistence(t *testing.T) {
+	st, err := Open(filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer st.Close()
+	ctx := context.Background()
+	if err := st.InitSchema(ctx); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+
+	settings, err := st.GetSettings(ctx)
+	if err != nil {
+		t.Fatalf("get settings: %v", err)
+	}
+	if len(settings.SearchPlan) != len(DefaultSearchPlan()) {
+		t.Fatalf("expected the default plan, got %#v", settings.SearchPlan)
+	}
+
+	plan := []SearchPlanStage{
+		{Key: "broad", Label: "Broad", Query: "{company}", Limit: 5, Enabled: true},
+		{Key: "distributors", Label: "Distributors", Query: "{company} distributor", Limit: 8, Enabled: false},
+	}
+	data, _ := json.Marshal(Settings{SearchPlan: plan})
+	if err := st.SaveSettings(ctx, bytes.NewReader(data)); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+	settings, err = st.GetSettings(ctx)
+	if err != nil {
+		t.Fatalf("get settings: %v", err)
+	}
+	if len(settings.SearchPlan) != 2 || settings.SearchPlan[1] != plan[1] {
+		t.Fatalf("plan not persisted: %#v", settings.SearchPlan)
+	}
+
+	var stored string
+	data, _ = json.Marshal(Settings{SearchPlan: DefaultSearchPlan()})
+	if err := st.SaveSettings(ctx, bytes.NewReader(data)); err != nil {
+		t.Fatalf("save settings: %v", err)
+	}
+	if err := st.DB.QueryRowContext(ctx, `SELECT COALESCE(search_plan, '') FROM settings WHERE id = 1`).Scan(&stored); err != nil {
+		t.Fatalf("read column: %v", err)
+	}
+	if stored != "" {
+		t.Fatalf("default plan should be stored as empty, got %q", stored)
+	}
+}
@@ -11,24 +11,51 @@ import (
 
 // Settings represents the persisted global configuration.
 type Settings struct {
-	LLMBaseURL              string `json:"llm_base_url"`
-	LLMAPIKey               string `json:"llm_api_key"`
-	LLMModel                string `json:"llm_model"`
-	MyCompanyName           string `json:"my_company_name"`
-	MyProduct               string `json:"my_product_profile"`
-	SMTPHost                string `json:"smtp_host"`
-	SMTPPort                int    `json:"smtp_port"`
-	SMTPUsername            string `json:"smtp_username"`
-	SMTPPassword            string `json:"smtp_password"`
-	SMTPSecurity            string `json:"smtp_security"`
-	AdminEmail              string `json:"admin_email"`
-	RatingGuideline         string `json:"rating_guideline"`
-	AutomationEnabled       bool   `json:"automation_enabled"`
-	AutomationFollowupDays  int    `json:"automation_followup_days"`
-	AutomationRequiredGrade string `json:"automation_required_grade"`
-	LoginPassword           string `json:"login_password,omitempty"`
-	LoginPasswordHash       string `json:"-"`
-	LoginPasswordVersion    int    `json:"-"`
+	LLMBaseURL              string            `json:"llm_base_url"`
+	LLMAPIKey               string            `json:"llm_api_key"`
+	LLMModel                string            `json:"llm_model"`
+	LLMProvider             string            `json:"llm_provider"`
+	LLMAPIVersion           string            `json:"llm_api_version"`
+	LLMTaskModels           map[string]string `json:"llm_task_models"`
+	LLMFallbackModels       []string          `json:"llm_fallback_models"`
+	LLMDailyTokenBudget     int               `json:"llm_daily_token_budget"`
+	LLMMonthlyTokenBudget   int               `json:"llm_monthly_token_budget"`
+	LLMMaxConcurrency       int               `json:"llm_max_concurrency"`
+	LLMRequestsPerMinute    int               `json:"llm_requests_per_minute"`
+	LLMCacheTTLHours        int               `json:"llm_cache_ttl_hours"`
+	SearchCacheTTLHours     int               `json:"search_cache_ttl_hours"`
+	SearchProvider          string            `json:"search_provider"`
+	SearchAPIKey            string            `json:"search_api_key"`
+	SearchEndpoint          string            `json:"search_endpoint"`
+	SearchFallbackProviders []string          `json:"search_fallback_providers"`
+	SearchPlan              []SearchPlanStage `json:"search_plan"`
+	MyCompanyName           string            `json:"my_company_name"`
+	MyProduct               string            `json:"my_product_profile"`
+	SMTPHost                string            `json:"smtp_host"`
+	SMTPPort                int               `json:"smtp_port"`
+	SMTPUsername            string            `json:"smtp_username"`
+	SMTPPassword            string            `json:"smtp_password"`
+	SMTPSecurity            string            `json:"smtp_security"`
+	AdminEmail              string            `json:"admin_email"`
+	RatingGuideline         string            `json:"rating_guideline"`
+	AutomationEnabled       bool              `json:"automation_enabled"`
+	AutomationFollowupDays  int               `json:"automation_followup_days"`
+	AutomationRequiredGrade string            `json:"automation_required_grade"`
+	MonitorEnabled          bool              `json:"monitor_enabled"`
+	MonitorGrades           string            `json:"monitor_grades"`
+	MonitorIntervalHours    int               `json:"monitor_interval_hours"`
+	MonitorLLMSummary       bool              `json:"monitor_llm_summary"`
+	EmailVerifyDNSServer    string            `json:"email_verify_dns_server"`
+	EmailVerifySMTPProbe    bool              `json:"email_verify_smtp_probe"`
+	ProxyURL                string            `json:"proxy_url"`
+	ProxyUsername           string            `json:"proxy_username"`
+	ProxyPassword           string            `json:"proxy_password"`
+	ProxyBypass             string            `json:"proxy_bypass"`
+	FetchAllowedHosts       string            `json:"fetch_allowed_hosts"`
+	ProxyChannels           map[string]string `json:"proxy_channels"`
+	LoginPassword           string            `json:"login_password,omitempty"`
+	LoginPasswordHash       string            `json:"-"`
+	LoginPasswordVersion    int               `json:"-"`
 }
 
 // GetSettings fetches the single settings row.
@@ -38,6 +65,21 @@ func (s *Store) GetSettings(ctx context.Context) (*Settings, error) {
 	  COALESCE(llm_base_url, ''),
 	  COALESCE(llm_api_key, ''),
 	  COALESCE(llm_model, ''),
+	  COALESCE(llm_provider, ''),
+	  COALESCE(llm_api_version, ''),
+	  COALESCE(llm_task_models, ''),
+	  COALESCE(llm_fallback_models, ''),
+	  COALESCE(llm_daily_token_budget, 0),
+	  COALESCE(llm_monthly_token_budget, 0),
+	  COALESCE(llm_max_concurrency, 0),
+	  COALESCE(llm_requests_per_minute, 0),
+	  COALESCE(llm_cache_ttl_hours, 0),
+	  COALESCE(search_cache_ttl_hours, 24),
+	  COALESCE(search_provider, ''),
+	  COALESCE(search_api_key, ''),
+	  COALESCE(search_endpoint, ''),
+	  COALESCE(search_fallback_providers, ''),
+	  COALESCE(search_plan, ''),
 	  COALESCE(my_company_name, ''),
 	  COALESCE(my_product_profile, ''),
 	  COALESCE(smtp_host, ''),
@@ -50,16 +92,44 @@ func (s *Store) GetSettings(ctx context.Context) (*Settings, error) {
 	  COALESCE(automation_enabled, 0),
 	  COALESCE(automation_followup_days, 0),
 	  COALESCE(automation_required_grade, ''),
+	  COALESCE(monitor_enabled, 0),
+	  COALESCE(monitor_grades, ''),
+	  COALESCE(monitor_interval_hours, 0),
+	  COALESCE(monitor_llm_summary, 0),
+	  COALESCE(email_verify_dns_server, ''),
+	  COALESCE(email_verify_smtp_probe, 0),
+	  COALESCE(proxy_url, ''),
+	  COALESCE(proxy_username, ''),
+	  COALESCE(proxy_password, ''),
+	  COALESCE(proxy_bypass, ''),
+	  COALESCE(fetch_allowed_hosts, ''),
+	  COALESCE(proxy_channels, ''),
 	  COALESCE(login_password_hash, ''),
 	  COALESCE(login_password_version, 1)
 	FROM settings WHERE id = 1;
 `)
 	var settings Settings
-	var automationEnabledInt int
+	var automationEnabledInt, monitorEnabledInt, monitorLLMSummaryInt, emailVerifySMTPProbeInt int
+	var taskModelsJSON, fallbackModelsJSON, searchFallbacksJSON, searchPlanJSON, proxyChannelsJSON string
 	if err := row.Scan(
 		&settings.LLMBaseURL,
 		&settings.LLMAPIKey,
 		&settings.LLMModel,
+		&settings.LLMProvider,
+		&settings.LLMAPIVersion,
+		&taskModelsJSON,
+		&fallbackModelsJSON,
+		&settings.LLMDailyTokenBudget,
+		&settings.LLMMonthlyTokenBudget,
+		&settings.LLMMaxConcurrency,
+		&settings.LLMRequestsPerMinute,
+		&settings.LLMCacheTTLHours,
+		&settings.SearchCacheTTLHours,
+		&settings.SearchProvider,
+		&settings.SearchAPIKey,
+		&settings.SearchEndpoint,
+		&searchFallbacksJSON,
+		&searchPlanJSON,
 		&settings.MyCompanyName,
 		&settings.MyProduct,
 		&settings.SMTPHost,
@@ -72,6 +142,18 @@ func (s *Store) GetSettings(ctx context.Context) (*Settings, error) {
 		&automationEnabledInt,
 		&settings.AutomationFollowupDays,
 		&settings.AutomationRequiredGrade,
+		&monitorEnabledInt,
+		&settings.MonitorGrades,
+		&settings.MonitorIntervalHours,
+		&monitorLLMSummaryInt,
+		&settings.EmailVerifyDNSServer,
+		&emailVerifySMTPProbeInt,
+		&settings.ProxyURL,
+		&settings.ProxyUsername,
+		&settings.ProxyPassword,
+		&settings.ProxyBypass,
+		&settings.FetchAllowedHosts,
+		&proxyChannelsJSON,
 		&settings.LoginPasswordHash,
 		&settings.LoginPasswordVersion,
 	); err != nil {
@@ -86,12 +168,49 @@ func (s *Store) GetSettings(ctx context.Context) (*Settings, error) {
 	if settings.AutomationFollowupDays <= 0 {
 		settings.AutomationFollowupDays = 3
 	}
+	settings.MonitorEnabled = monitorEnabledInt == 1
+	settings.MonitorLLMSummary = monitorLLMSummaryInt == 1
+	settings.EmailVerifySMTPProbe = emailVerifySMTPProbeInt == 1
+	settings.MonitorGrades = NormalizeMonitorGrades(settings.MonitorGrades)
+	if settings.MonitorIntervalHours <= 0 {
+		settings.MonitorIntervalHours = DefaultMonitorIntervalHours
+	}
 	if strings.TrimSpace(settings.SMTPSecurity) == "" {
 		settings.SMTPSecurity = "auto"
 	}
 	if settings.LoginPasswordVersion <= 0 {
 		settings.LoginPasswordVersion = 1
 	}
+	settings.LLMProvider = NormalizeLLMProvider(settings.LLMProvider)
+	if strings.TrimSpace(taskModelsJSON) != "" {
+		if err := json.Unmarshal([]byte(taskModelsJSON), &settings.LLMTaskModels); err != nil {
+			return nil, fmt.Errorf("decode llm task models: %w", err)
+		}
+	}
+	if strings.TrimSpace(fallbackModelsJSON) != "" {
+		if err := json.Unmarshal([]byte(fallbackModelsJSON), &settings.LLMFallbackModels); err != nil {
+			return nil, fmt.Errorf("decode llm fallback models: %w", err)
+		}
+	}
+	settings.SearchProvider = NormalizeSearchProvider(settings.SearchProvider)
+	if strings.TrimSpace(searchFallbacksJSON) != "" {
+		if err := json.Unmarshal([]byte(searchFallbacksJSON), &settings.SearchFallbackProviders); err != nil {
+			return nil, fmt.Errorf("decode search fallback providers: %w", err)
+		}
+	}
+	if strings.TrimSpace(searchPlanJSON) != "" {
+		if err := json.Unmarshal([]byte(searchPlanJSON), &settings.SearchPlan); err != nil {
+			return nil, fmt.Errorf("decode search plan: %w", err)
+		}
+	}
+	if len(settings.SearchPlan) == 0 {
+		settings.SearchPlan = DefaultSearchPlan()
+	}
+	if strings.TrimSpace(proxyChannelsJSON) != "" {
+		if err := json.Unmarshal([]byte(proxyChannelsJSON), &settings.ProxyChannels); err != nil {
+			return nil, fmt.Errorf("decode proxy channels: %w", err)
+		}
+	}
 	return &settings, nil
 }
 
@@ -104,30 +223,107 @@ func (s *Store) SaveSettings(ctx context.Context, body io.Reader) error {
 	if payload.AutomationFollowupDays <= 0 {
 		payload.AutomationFollowupDays = 3
 	}
+	payload.MonitorGrades = NormalizeMonitorGrades(payload.MonitorGrades)
+	if payload.MonitorIntervalHours <= 0 {
+		payload.MonitorIntervalHours = DefaultMonitorIntervalHours
+	} else if payload.MonitorIntervalHours < MinMonitorIntervalHours {
+		payload.MonitorIntervalHours = MinMonitorIntervalHours
+	}
 	payload.SMTPSecurity = strings.TrimSpace(payload.SMTPSecurity)
 	if payload.SMTPSecurity == "" {
 		payload.SMTPSecurity = "auto"
 	}
+	payload.LLMProvider = NormalizeLLMProvider(payload.LLMProvider)
+	payload.LLMAPIVersion = strings.TrimSpace(payload.LLMAPIVersion)
+	if payload.LLMDailyTokenBudget < 0 {
+		payload.LLMDailyTokenBudget = 0
+	}
+	if payload.LLMMonthlyTokenBudget < 0 {
+		payload.LLMMonthlyTokenBudget = 0
+	}
+	if payload.LLMMaxConcurrency < 0 {
+		payload.LLMMaxConcurrency = 0
+	}
+	if payload.LLMRequestsPerMinute < 0 {
+		payload.LLMRequestsPerMinute = 0
+	}
+	if payload.LLMCacheTTLHours < 0 {
+		payload.LLMCacheTTLHours = 0
+	}
+	if payload.SearchCacheTTLHours < 0 {
+		payload.SearchCacheTTLHours = 0
+	}
+	taskModelsJSON, fallbackModelsJSON, err := encodeLLMRouting(payload.LLMTaskModels, payload.LLMFallbackModels)
+	if err != nil {
+		return err
+	}
+	payload.SearchProvider = NormalizeSearchProvider(payload.SearchProvider)
+	payload.SearchEndpoint = strings.TrimSpace(payload.SearchEndpoint)
+	searchFallbacksJSON, err := encodeSearchFallbacks(payload.SearchProvider, payload.SearchFallbackProviders)
+	if err != nil {
+		return err
+	}
+	searchPlanJSON, err := encodeSearchPlan(payload.SearchPlan)
+	if err != nil {
+		return err
+	}
+	if payload.ProxyURL, err = NormalizeProxyURL(payload.ProxyURL); err != nil {
+		return err
+	}
+	payload.ProxyUsername = strings.TrimSpace(payload.ProxyUsername)
+	payload.ProxyBypass = strings.TrimSpace(payload.ProxyBypass)
+	payload.FetchAllowedHosts = strings.TrimSpace(payload.FetchAllowedHosts)
+	if payload.EmailVerifyDNSServer, err = NormalizeDNSServer(payload.EmailVerifyDNSServer); err != nil {
+		return err
+	}
+	proxyChannelsJSON, err := encodeProxyChannels(payload.ProxyChannels)
+	if err != nil {
+		return err
+	}
 
 	toStore := payload
 	if err := encryptSettingsSecrets(&toStore); err != nil {
 		return fmt.Errorf("encrypt settings secrets: %w", err)
 	}
 
-	_, err := s.DB.ExecContext(ctx, `
+	_, err = s.DB.ExecContext(ctx, `
 		UPDATE settings
 		SET llm_base_url = ?, llm_api_key = ?, llm_model = ?,
+		    llm_provider = ?, llm_api_version = ?, llm_task_models = ?, llm_fallback_models = ?,
+		    llm_daily_token_budget = ?, llm_monthly_token_budget = ?,
+		    llm_max_concurrency = ?, llm_requests_per_minute = ?,
+		    llm_cache_ttl_hours = ?, search_cache_ttl_hours = ?,
+		    search_provider = ?, search_api_key = ?, search_endpoint = ?, search_fallback_providers = ?,
+		    search_plan = ?,
 		    my_company_name = ?, my_product_profile = ?,
 		    smtp_host = ?, smtp_port = ?, smtp_username = ?, smtp_password = ?,
 		    smtp_security = ?,
 		    admin_email = ?, rating_guideline = ?,
 		    automation_enabled = ?, automation_followup_days = ?, automation_required_grade = ?,
+		    monitor_enabled = ?, monitor_grades = ?, monitor_interval_hours = ?, monitor_llm_summary = ?,
+		    email_verify_dns_server = ?, email_verify_smtp_probe = ?,
+		    proxy_url = ?, proxy_username = ?, proxy_password = ?, proxy_bypass = ?, fetch_allowed_hosts = ?, proxy_channels = ?,
 		    updated_at = datetime('now')
 		WHERE id = 1;
 	`,
 		toStore.LLMBaseURL,
 		toStore.LLMAPIKey,
 		toStore.LLMModel,
+		toStore.LLMProvider,
+		toStore.LLMAPIVersion,
+		taskModelsJSON,
+		fallbackModelsJSON,
+		toStore.LLMDailyTokenBudget,
+		toStore.LLMMonthlyTokenBudget,
+		toStore.LLMMaxConcurrency,
+		toStore.LLMRequestsPerMinute,
+		toStore.LLMCacheTTLHours,
+		toStore.SearchCacheTTLHours,
+		toStore.SearchProvider,
+		toStore.SearchAPIKey,
+		toStore.SearchEndpoint,
+		searchFallbacksJSON,
+		searchPlanJSON,
 		toStore.MyCompanyName,
 		toStore.MyProduct,
 		toStore.SMTPHost,
@@ -140,6 +336,18 @@ func (s *Store) SaveSettings(ctx context.Context, body io.Reader) error {
 		boolToInt(toStore.AutomationEnabled),
 		toStore.AutomationFollowupDays,
 		toStore.AutomationRequiredGrade,
+		boolToInt(toStore.MonitorEnabled),
+		toStore.MonitorGrades,
+		toStore.MonitorIntervalHours,
+		boolToInt(toStore.MonitorLLMSummary),
+		toStore.EmailVerifyDNSServer,
+		boolToInt(toStore.EmailVerifySMTPProbe),
+		toStore.ProxyURL,
+		toStore.ProxyUsername,
+		toStore.ProxyPassword,
+		toStore.ProxyBypass,
+		toStore.FetchAllowedHosts,
+		proxyChannelsJSON,
 	)
 	if err != nil {
 		return fmt.Errorf("update settings: %w", err)
@@ -147,6 +355,125 @@ func (s *Store) SaveSettings(ctx context.Context, body io.Reader) error {
 	return nil
 }
 
+// Supported LLM provider identifiers.
+const (
+	LLMProviderOpenAI    = "openai"
+	LLMProviderAnthropic = "anthropic"
+	LLMProviderOllama    = "ollama"
+	LLMProviderAzure     = "azure"
+)
+
+// NormalizeLLMProvider maps user input onto a supported provider, defaulting to OpenAI-compatible.
+func NormalizeLLMProvider(value string) string {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case LLMProviderAnthropic:
+		return LLMProviderAnthropic
+	case LLMProviderOllama:
+		return LLMProviderOllama
+	case LLMProviderAzure, "azure_openai", "azure-openai":
+		return LLMProviderAzure
+	default:
+		return LLMProviderOpenAI
+	}
+}
+
+// encodeLLMRouting trims the per-task and fallback model lists and serialises them for storage.
+func encodeLLMRouting(taskModels map[string]string, fallbacks []string) (string, string, error) {
+	cleanedTasks := make(map[string]string, len(taskModels))
+	for task, model := range taskModels {
+		task = strings.TrimSpace(task)
+		model = strings.TrimSpace(model)
+		if task == "" || model == "" {
+			continue
+		}
+		cleanedTasks[task] = model
+	}
+	cleanedFallbacks := make([]string, 0, len(fallbacks))
+	for _, model := range fallbacks {
+		if model = strings.TrimSpace(model); model != "" {
+			cleanedFallbacks = append(cleanedFallbacks, model)
+		}
+	}
+	tasksJSON, err := json.Marshal(cleanedTasks)
+	if err != nil {
+		return "", "", fmt.Errorf("encode llm task models: %w", err)
+	}
+	fallbacksJSON, err := json.Marshal(cleanedFallbacks)
+	if err != nil {
+		return "", "", fmt.Errorf("encode llm fallback models: %w", err)
+	}
+	return string(tasksJSON), string(fallbacksJSON), nil
+}
+
+// Supported search provider identifiers. The Playwright providers scrape result
+// pages in a headless browser; the others call JSON search APIs.
+const (
+	SearchProviderPlaywright       = "playwright"
+	SearchProviderPlaywrightGoogle = "playwright_google"
+	SearchProviderPlaywrightBing   = "playwright_bing"
+	SearchProviderSearXNG          = "searxng"
+	SearchProviderBingAPI          = "bing_api"
+	SearchProviderBrave            = "brave"
+	SearchProviderSerpAPI          = "serpapi"
+	SearchProviderDirect           = "direct"
+)
+
+// SearchProviders lists the provider identifiers accepted in settings.
+var SearchProviders = []string{
+	SearchProviderPlaywright,
+	SearchProviderPlaywrightGoogle,
+	SearchProviderPlaywrightBing,
+	SearchProviderSearXNG,
+	SearchProviderBingAPI,
+	SearchProviderBrave,
+	SearchProviderSerpAPI,
+	SearchProviderDirect,
+}
+
+// NormalizeSearchProvider lowercases the provider name, mapping aliases and an
+// empty value (Playwright with automatic engine choice). Unknown names are kept
+// so the search client can report them.
+func NormalizeSearchProvider(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "":
+		return SearchProviderPlaywright
+	case "google":
+		return SearchProviderPlaywrightGoogle
+	case "bing":
+		return SearchProviderPlaywrightBing
+	case "bing-api", "bingapi":
+		return SearchProviderBingAPI
+	case "serp_api", "serp-api":
+		return SearchProviderSerpAPI
+	default:
+		return value
+	}
+}
+
+// encodeSearchFallbacks normalises the failover list, dropping blanks,
+// duplicates and the primary provider, and serialises it for storage.
+func encodeSearchFallbacks(primary string, fallbacks []string) (string, error) {
+	seen := map[string]bool{primary: true}
+	cleaned := make([]string, 0, len(fallbacks))
+	for _, name := range fallbacks {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		name = NormalizeSearchProvider(name)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		cleaned = append(cleaned, name)
+	}
+	data, err := json.Marshal(cleaned)
+	if err != nil {
+		return "", fmt.Errorf("encode search fallback providers: %w", err)
+	}
+	return string(data), nil
+}
+
 func boolToInt(value bool) int {
 	if value {
 		return 1
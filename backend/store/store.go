@@ -47,6 +47,21 @@ func (s *Store) InitSchema(ctx context.Context) error {
         llm_base_url TEXT,
         llm_api_key TEXT,
         llm_model TEXT,
+        llm_provider TEXT DEFAULT 'openai',
+        llm_api_version TEXT,
+        llm_task_models TEXT,
+        llm_fallback_models TEXT,
+        llm_daily_token_budget INTEGER DEFAULT 0,
+        llm_monthly_token_budget INTEGER DEFAULT 0,
+        llm_max_concurrency INTEGER DEFAULT 0,
+        llm_requests_per_minute INTEGER DEFAULT 0,
+        llm_cache_ttl_hours INTEGER DEFAULT 0,
+        search_cache_ttl_hours INTEGER DEFAULT 24,
+        search_provider TEXT,
+        search_api_key TEXT,
+        search_endpoint TEXT,
+        search_fallback_providers TEXT,
+        search_plan TEXT,
         my_company_name TEXT,
         my_product_profile TEXT,
         smtp_host TEXT,
@@ -56,11 +71,21 @@ func (s *Store) InitSchema(ctx context.Context) error {
         smtp_security TEXT DEFAULT 'auto',
         admin_email TEXT,
         rating_guideline TEXT,
-        search_provider TEXT,
-        search_api_key TEXT,
         automation_enabled INTEGER DEFAULT 0,
         automation_followup_days INTEGER DEFAULT 3,
         automation_required_grade TEXT DEFAULT 'A',
+        monitor_enabled INTEGER DEFAULT 0,
+        monitor_grades TEXT DEFAULT 'A',
+        monitor_interval_hours INTEGER DEFAULT 168,
+        monitor_llm_summary INTEGER DEFAULT 0,
+        email_verify_dns_server TEXT,
+        email_verify_smtp_probe INTEGER DEFAULT 0,
+        proxy_url TEXT,
+        proxy_username TEXT,
+        proxy_password TEXT,
+        proxy_bypass TEXT,
+        fetch_allowed_hosts TEXT,
+        proxy_channels TEXT,
         login_password_hash TEXT,
         login_password_version INTEGER DEFAULT 1,
         created_at TEXT,
@@ -79,6 +104,7 @@ func (s *Store) InitSchema(ctx context.Context) error {
 			summary TEXT,
 			followup_sent INTEGER DEFAULT 0,
 			source_json TEXT,
+			website_signals TEXT,
 			created_at TEXT,
 			updated_at TEXT
 		);`,
@@ -171,6 +197,67 @@ func (s *Store) InitSchema(ctx context.Context) error {
 			created_at TEXT NOT NULL,
 			updated_at TEXT NOT NULL
 		);`,
+		`CREATE TABLE IF NOT EXISTS llm_usage (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			task TEXT,
+			provider TEXT,
+			model TEXT,
+			customer_id INTEGER,
+			prompt_tokens INTEGER DEFAULT 0,
+			completion_tokens INTEGER DEFAULT 0,
+			total_tokens INTEGER DEFAULT 0,
+			latency_ms INTEGER DEFAULT 0,
+			error TEXT,
+			created_at TEXT NOT NULL
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_llm_usage_created_at ON llm_usage(created_at);`,
+		`CREATE TABLE IF NOT EXISTS prompt_templates (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			version INTEGER NOT NULL,
+			system_prompt TEXT,
+			user_prompt TEXT,
+			note TEXT,
+			created_at TEXT NOT NULL,
+			UNIQUE(name, version)
+		);`,
+		`CREATE TABLE IF NOT EXISTS domain_rules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			pattern TEXT NOT NULL UNIQUE,
+			kind TEXT NOT NULL DEFAULT 'block',
+			note TEXT,
+			builtin INTEGER DEFAULT 0,
+			enabled INTEGER DEFAULT 1,
+			created_at TEXT NOT NULL,
+			updated_at TEXT NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS customer_activities (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			customer_id INTEGER NOT NULL,
+			kind TEXT NOT NULL,
+			title TEXT,
+			detail TEXT,
+			created_at TEXT NOT NULL,
+			FOREIGN KEY(customer_id) REFERENCES customers(id) ON DELETE CASCADE
+		);`,
+		`CREATE INDEX IF NOT EXISTS idx_customer_activities_customer ON customer_activities(customer_id, created_at);`,
+		`CREATE TABLE IF NOT EXISTS website_monitors (
+			customer_id INTEGER PRIMARY KEY,
+			last_checked_at TEXT,
+			last_changed_at TEXT,
+			last_error TEXT,
+			FOREIGN KEY(customer_id) REFERENCES customers(id) ON DELETE CASCADE
+		);`,
+		`CREATE TABLE IF NOT EXISTS email_verifications (
+			email TEXT PRIMARY KEY,
+			status TEXT NOT NULL,
+			reason TEXT,
+			role INTEGER DEFAULT 0,
+			disposable INTEGER DEFAULT 0,
+			mx_host TEXT,
+			smtp_checked INTEGER DEFAULT 0,
+			checked_at TEXT NOT NULL
+		);`,
 		`CREATE TABLE IF NOT EXISTS logs (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			level TEXT,
@@ -193,6 +280,12 @@ func (s *Store) InitSchema(ctx context.Context) error {
 		}
 	}
 
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE customers ADD COLUMN website_signals TEXT`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure website_signals column: %w", err)
+		}
+	}
+
 	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN automation_enabled INTEGER DEFAULT 0`); err != nil {
 		if !strings.Contains(err.Error(), "duplicate column name") {
 			return fmt.Errorf("ensure automation_enabled column: %w", err)
@@ -229,6 +322,156 @@ func (s *Store) InitSchema(ctx context.Context) error {
 		}
 	}
 
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN llm_provider TEXT DEFAULT 'openai'`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure llm_provider column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN llm_api_version TEXT`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure llm_api_version column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN llm_task_models TEXT`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure llm_task_models column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN llm_fallback_models TEXT`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure llm_fallback_models column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN llm_daily_token_budget INTEGER DEFAULT 0`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure llm_daily_token_budget column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN llm_monthly_token_budget INTEGER DEFAULT 0`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure llm_monthly_token_budget column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN llm_max_concurrency INTEGER DEFAULT 0`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure llm_max_concurrency column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN llm_requests_per_minute INTEGER DEFAULT 0`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure llm_requests_per_minute column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN llm_cache_ttl_hours INTEGER DEFAULT 0`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure llm_cache_ttl_hours column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN search_cache_ttl_hours INTEGER DEFAULT 24`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure search_cache_ttl_hours column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN search_endpoint TEXT`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure search_endpoint column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN search_fallback_providers TEXT`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure search_fallback_providers column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN search_plan TEXT`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure search_plan column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN proxy_url TEXT`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure proxy_url column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN proxy_username TEXT`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure proxy_username column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN proxy_password TEXT`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure proxy_password column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN proxy_bypass TEXT`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure proxy_bypass column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN proxy_channels TEXT`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure proxy_channels column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN fetch_allowed_hosts TEXT`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure fetch_allowed_hosts column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN monitor_enabled INTEGER DEFAULT 0`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure monitor_enabled column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN monitor_grades TEXT DEFAULT 'A'`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure monitor_grades column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN monitor_interval_hours INTEGER DEFAULT 168`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure monitor_interval_hours column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN monitor_llm_summary INTEGER DEFAULT 0`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure monitor_llm_summary column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN email_verify_dns_server TEXT`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure email_verify_dns_server column: %w", err)
+		}
+	}
+
+	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE settings ADD COLUMN email_verify_smtp_probe INTEGER DEFAULT 0`); err != nil {
+		if !strings.Contains(err.Error(), "duplicate column name") {
+			return fmt.Errorf("ensure email_verify_smtp_probe column: %w", err)
+		}
+	}
+
 	if _, err := s.DB.ExecContext(ctx, `ALTER TABLE scheduled_tasks ADD COLUMN schedule_mode TEXT DEFAULT 'simple'`); err != nil {
 		if !strings.Contains(err.Error(), "duplicate column name") {
 			return fmt.Errorf("ensure schedule_mode column: %w", err)
@@ -265,6 +508,10 @@ func (s *Store) InitSchema(ctx context.Context) error {
 		}
 	}
 
+	if err := s.seedDomainRules(ctx); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -12,7 +12,8 @@ import (
 type AutomationRunner struct {
 	automation services.AutomationService
 	interval   time.Duration
-	stopCh     chan struct{}
+	cancel     context.CancelFunc
+	done       chan struct{}
 }
 
 // NewAutomationRunner constructs an automation runner.
@@ -23,16 +24,18 @@ func NewAutomationRunner(automation services.AutomationService) *AutomationRunne
 	return &AutomationRunner{
 		automation: automation,
 		interval:   3 * time.Second,
-		stopCh:     make(chan struct{}),
+		done:       make(chan struct{}),
 	}
 }
 
-// Start launches the background polling loop.
+// Start launches the background polling loop. Work in flight is cancelled together with ctx.
 func (r *AutomationRunner) Start(ctx context.Context) {
 	if r == nil {
 		return
 	}
+	ctx, r.cancel = context.WithCancel(services.WithBackgroundPriority(ctx))
 	go func() {
+		defer close(r.done)
 		ticker := time.NewTicker(r.interval)
 		defer ticker.Stop()
 		for {
@@ -40,27 +43,27 @@ func (r *AutomationRunner) Start(ctx context.Context) {
 			select {
 			case <-ctx.Done():
 				return
-			case <-r.stopCh:
-				return
 			case <-ticker.C:
 			}
 		}
 	}()
 }
 
-// Stop stops the runner.
+// Stop cancels the job in flight and waits for the loop to exit. An interrupted
+// job stays running and is requeued on the next start.
 func (r *AutomationRunner) Stop() {
-	if r == nil {
+	if r == nil || r.cancel == nil {
 		return
 	}
-	close(r.stopCh)
+	r.cancel()
+	<-r.done
 }
 
 func (r *AutomationRunner) drain(ctx context.Context) {
 	if r == nil || r.automation == nil {
 		return
 	}
-	for {
+	for ctx.Err() == nil {
 		processed, err := r.automation.ProcessNext(ctx)
 		if err != nil {
 			log.Printf("[automation] 处理任务失败: %v", err)
@@ -0,0 +1,64 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/anner/ai-foreign-trade-assistant/backend/services"
+)
+
+// MonitorRunner periodically checks the websites of tracked customers. The
+// service decides which customers are due, so the poll interval only bounds
+// how late a check can start.
+type MonitorRunner struct {
+	monitor  services.WebsiteMonitorService
+	interval time.Duration
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewMonitorRunner constructs a website monitor runner.
+func NewMonitorRunner(monitor services.WebsiteMonitorService) *MonitorRunner {
+	if monitor == nil {
+		return nil
+	}
+	return &MonitorRunner{
+		monitor:  monitor,
+		interval: 10 * time.Minute,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start launches the background polling loop. Checks in flight are cancelled together with ctx.
+func (r *MonitorRunner) Start(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	ctx, r.cancel = context.WithCancel(services.WithBackgroundPriority(ctx))
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			if _, err := r.monitor.CheckDue(ctx); err != nil && !errors.Is(err, services.ErrNotImplemented) {
+				log.Printf("[monitor] 检查客户官网失败: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop cancels the checks in flight and waits for the loop to exit.
+func (r *MonitorRunner) Stop() {
+	if r == nil || r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+}
@@ -14,7 +14,8 @@ type Runner struct {
 	store     *store.Store
 	scheduler services.SchedulerService
 	interval  time.Duration
-	stopCh    chan struct{}
+	cancel    context.CancelFunc
+	done      chan struct{}
 }
 
 // NewRunner constructs a new follow-up runner.
@@ -23,21 +24,21 @@ func NewRunner(st *store.Store, scheduler services.SchedulerService) *Runner {
 		store:     st,
 		scheduler: scheduler,
 		interval:  time.Minute,
-		stopCh:    make(chan struct{}),
+		done:      make(chan struct{}),
 	}
 }
 
-// Start launches the background loop.
+// Start launches the background loop. Work in flight is cancelled together with ctx.
 func (r *Runner) Start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(services.WithBackgroundPriority(ctx))
 	go func() {
+		defer close(r.done)
 		ticker := time.NewTicker(r.interval)
 		defer ticker.Stop()
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case <-r.stopCh:
-				return
 			case <-ticker.C:
 				r.process(ctx)
 			}
@@ -45,9 +46,14 @@ func (r *Runner) Start(ctx context.Context) {
 	}()
 }
 
-// Stop stops the runner.
+// Stop cancels the task in flight and waits for the loop to exit. A task cut
+// short stays running and is requeued on the next start.
 func (r *Runner) Stop() {
-	close(r.stopCh)
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
 }
 
 func (r *Runner) process(ctx context.Context) {
@@ -57,6 +63,9 @@ func (r *Runner) process(ctx context.Context) {
 		return
 	}
 	for _, task := range tasks {
+		if ctx.Err() != nil {
+			return
+		}
 		if err := r.scheduler.RunNow(ctx, task.ID); err != nil {
 			log.Printf("[scheduler] 执行任务 %d 失败: %v", task.ID, err)
 		} else {
@@ -12,21 +12,25 @@ import (
 type TodoRunner struct {
     todo     services.TodoService
     interval time.Duration
-    stopCh   chan struct{}
+    cancel   context.CancelFunc
+    done     chan struct{}
 }
 
 func NewTodoRunner(todo services.TodoService) *TodoRunner {
     if todo == nil {
         return nil
     }
-    return &TodoRunner{todo: todo, interval: 2 * time.Second, stopCh: make(chan struct{})}
+    return &TodoRunner{todo: todo, interval: 2 * time.Second, done: make(chan struct{})}
 }
 
+// Start launches the polling loop. Work in flight is cancelled together with ctx.
 func (r *TodoRunner) Start(ctx context.Context) {
     if r == nil {
         return
     }
+    ctx, r.cancel = context.WithCancel(services.WithBackgroundPriority(ctx))
     go func() {
+        defer close(r.done)
         ticker := time.NewTicker(r.interval)
         defer ticker.Stop()
         for {
@@ -34,26 +38,27 @@ func (r *TodoRunner) Start(ctx context.Context) {
             select {
             case <-ctx.Done():
                 return
-            case <-r.stopCh:
-                return
             case <-ticker.C:
             }
         }
     }()
 }
 
+// Stop cancels the task in flight and waits for the loop to exit. An
+// interrupted task stays running and is requeued on the next start.
 func (r *TodoRunner) Stop() {
-    if r == nil {
+    if r == nil || r.cancel == nil {
         return
     }
-    close(r.stopCh)
+    r.cancel()
+    <-r.done
 }
 
 func (r *TodoRunner) drain(ctx context.Context) {
     if r == nil || r.todo == nil {
         return
     }
-    for {
+    for ctx.Err() == nil {
         processed, err := r.todo.ProcessNext(ctx)
         if err != nil {
             log.Printf("[todo] 处理任务失败: %v", err)
@@ -26,3 +26,13 @@ export const updateFollowupStatus = async (customerId, followupSent) => {
   })
   return data
 }
+
+export const checkCustomerWebsite = async (customerId) => {
+  const { data } = await http.post(`/customers/${customerId}/website-check`)
+  return data
+}
+
+export const verifyCustomerContacts = async (customerId) => {
+  const { data } = await http.post(`/customers/${customerId}/contacts/verify`)
+  return data
+}
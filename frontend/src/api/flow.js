@@ -1,7 +1,12 @@
-import http from './http'
+import http, { postStream } from './http'
 
-export const resolveCompany = async (query) => {
-  const { data } = await http.post('/companies/resolve', { query })
+// noCache 为 true 时跳过后端的 LLM / 搜索结果缓存，强制重新生成。
+const cacheParams = ({ noCache } = {}) => (noCache ? { no_cache: 1 } : undefined)
+
+const withCacheQuery = (url, options) => (options?.noCache ? `${url}?no_cache=1` : url)
+
+export const resolveCompany = async (query, options) => {
+  const { data } = await http.post('/companies/resolve', { query }, { params: cacheParams(options) })
   return data
 }
 
@@ -22,8 +27,10 @@ export const replaceContacts = async (customerId, contacts) => {
   return data
 }
 
-export const suggestGrade = async (customerId) => {
-  const { data } = await http.post(`/companies/${customerId}/grade/suggest`)
+export const suggestGrade = async (customerId, options) => {
+  const { data } = await http.post(`/companies/${customerId}/grade/suggest`, undefined, {
+    params: cacheParams(options),
+  })
   return data
 }
 
@@ -37,6 +44,9 @@ export const generateAnalysis = async (customerId) => {
   return data
 }
 
+export const streamAnalysis = (customerId, onDelta, options) =>
+  postStream(withCacheQuery(`/companies/${customerId}/analysis/stream`, options), { onDelta })
+
 export const updateAnalysis = async (customerId, payload) => {
   const { data } = await http.put(`/companies/${customerId}/analysis`, payload)
   return data
@@ -47,6 +57,9 @@ export const generateEmailDraft = async (customerId) => {
   return data
 }
 
+export const streamEmailDraft = (customerId, onDelta, options) =>
+  postStream(withCacheQuery(`/companies/${customerId}/email-draft/stream`, options), { onDelta })
+
 export const updateEmailDraft = async (emailId, payload) => {
   const { data } = await http.put(`/emails/${emailId}`, payload)
   return data
@@ -46,4 +46,74 @@ http.interceptors.response.use(
   }
 )
 
+const parseEventBlock = (block) => {
+  let event = 'message'
+  const dataLines = []
+  block.split('\n').forEach((line) => {
+    if (line.startsWith('event:')) {
+      event = line.slice(6).trim()
+    } else if (line.startsWith('data:')) {
+      dataLines.push(line.slice(5).trim())
+    }
+  })
+  if (!dataLines.length) return null
+  try {
+    return { event, data: JSON.parse(dataLines.join('\n')) }
+  } catch (error) {
+    return null
+  }
+}
+
+// postStream 以 SSE 方式调用流式接口，逐段回调增量文本，最终返回与普通接口一致的响应包。
+export const postStream = async (url, { onDelta } = {}) => {
+  const headers = { Accept: 'text/event-stream' }
+  const token = getToken()
+  if (token) {
+    headers.Authorization = `Bearer ${token}`
+  }
+  const response = await fetch(`/api${url}`, { method: 'POST', headers })
+  if (response.status === 401) {
+    clearToken()
+    triggerLoginRedirect()
+  }
+  if (!response.ok || !response.body) {
+    let message = '请求失败，请稍后再试。'
+    try {
+      const payload = await response.json()
+      message = payload?.error || message
+    } catch (error) {
+      // ignore non-JSON error bodies
+    }
+    throw new Error(message)
+  }
+
+  const reader = response.body.getReader()
+  const decoder = new TextDecoder()
+  let buffer = ''
+  let result = null
+  for (;;) {
+    const { value, done } = await reader.read()
+    if (done) break
+    buffer += decoder.decode(value, { stream: true })
+    let boundary = buffer.indexOf('\n\n')
+    while (boundary >= 0) {
+      const parsed = parseEventBlock(buffer.slice(0, boundary))
+      buffer = buffer.slice(boundary + 2)
+      boundary = buffer.indexOf('\n\n')
+      if (!parsed) continue
+      if (parsed.event === 'delta') {
+        onDelta?.(parsed.data?.text || '')
+      } else if (parsed.event === 'done') {
+        result = parsed.data
+      } else if (parsed.event === 'error') {
+        throw new Error(parsed.data?.error || '生成失败，请稍后再试。')
+      }
+    }
+  }
+  if (!result) {
+    throw new Error('流式响应意外中断，请重试。')
+  }
+  return result
+}
+
 export default http
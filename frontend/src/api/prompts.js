@@ -0,0 +1,31 @@
+import http from './http'
+
+export const listPrompts = async () => {
+  const { data } = await http.get('/prompts')
+  return data
+}
+
+export const listPromptVersions = async (name) => {
+  const { data } = await http.get(`/prompts/${name}/versions`)
+  return data
+}
+
+export const savePrompt = async (name, payload) => {
+  const { data } = await http.put(`/prompts/${name}`, payload)
+  return data
+}
+
+export const rollbackPrompt = async (name, version) => {
+  const { data } = await http.post(`/prompts/${name}/rollback`, { version })
+  return data
+}
+
+export const resetPrompt = async (name) => {
+  const { data } = await http.delete(`/prompts/${name}`)
+  return data
+}
+
+export const previewPrompt = async (name, payload) => {
+  const { data } = await http.post(`/prompts/${name}/preview`, payload)
+  return data
+}
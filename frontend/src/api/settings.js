@@ -38,3 +38,53 @@ export const testSearch = async () => {
   const { data } = await http.post('/settings/test-search')
   return data
 }
+
+export const testProxy = async () => {
+  const { data } = await http.post('/settings/test-proxy')
+  return data
+}
+
+export const fetchHealth = async () => {
+  const { data } = await http.get('/health')
+  return data
+}
+
+export const dryRunSearchPlan = async (payload) => {
+  const { data } = await http.post('/search/plan/dry-run', payload)
+  return data
+}
+
+export const fetchCacheStats = async () => {
+  const { data } = await http.get('/cache')
+  return data
+}
+
+export const purgeCache = async (namespace = '') => {
+  const { data } = await http.delete('/cache', { params: namespace ? { namespace } : undefined })
+  return data
+}
+
+export const fetchLLMUsage = async (params = {}) => {
+  const { data } = await http.get('/llm/usage', { params })
+  return data
+}
+
+export const listDomainRules = async () => {
+  const { data } = await http.get('/domain-rules')
+  return data
+}
+
+export const createDomainRule = async (payload) => {
+  const { data } = await http.post('/domain-rules', payload)
+  return data
+}
+
+export const updateDomainRule = async (id, payload) => {
+  const { data } = await http.put(`/domain-rules/${id}`, payload)
+  return data
+}
+
+export const deleteDomainRule = async (id) => {
+  const { data } = await http.delete(`/domain-rules/${id}`)
+  return data
+}
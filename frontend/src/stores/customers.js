@@ -5,6 +5,8 @@ import {
   deleteCustomer as deleteCustomerRequest,
   triggerAutomation,
   updateFollowupStatus as updateFollowupStatusRequest,
+  checkCustomerWebsite,
+  verifyCustomerContacts,
 } from '../api/customers'
 import { useUiStore } from './ui'
 
@@ -201,5 +203,69 @@ export const useCustomersStore = defineStore('customers', {
         this.followupUpdating = nextState
       }
     },
+    async checkWebsite(customerId) {
+      const ui = useUiStore()
+      if (!customerId) {
+        ui.pushToast('无效的客户 ID', 'error')
+        return null
+      }
+      try {
+        const payload = await checkCustomerWebsite(customerId)
+        if (!payload.ok) {
+          ui.pushToast(payload.error || '检查官网失败', 'error')
+          return null
+        }
+        const result = payload.data || {}
+        if (result.activity && this.detail && this.detail.id === customerId) {
+          this.detail = {
+            ...this.detail,
+            activities: [result.activity, ...(this.detail.activities || [])],
+          }
+        }
+        if (result.baseline) {
+          ui.pushToast('已保存官网快照，之后的检查会与它对比', 'success')
+        } else if (result.partial) {
+          ui.pushToast('本次只抓取到少量页面，已跳过对比', 'info')
+        } else if (result.significant) {
+          ui.pushToast('发现官网重要变化，已记录到客户动态', 'success')
+        } else {
+          ui.pushToast(result.changed ? '官网只有少量变化' : '官网没有变化', 'info')
+        }
+        return result
+      } catch (error) {
+        console.error('Failed to check customer website', error)
+        ui.pushToast(error.message, 'error')
+        return null
+      }
+    },
+    async verifyContacts(customerId) {
+      const ui = useUiStore()
+      if (!customerId) {
+        ui.pushToast('无效的客户 ID', 'error')
+        return null
+      }
+      try {
+        const payload = await verifyCustomerContacts(customerId)
+        if (!payload.ok) {
+          ui.pushToast(payload.error || '验证邮箱失败', 'error')
+          return null
+        }
+        const contacts = payload.data || []
+        if (this.detail && this.detail.id === customerId) {
+          this.detail = { ...this.detail, contacts }
+        }
+        const invalid = contacts.filter((item) => item.email_status === 'invalid').length
+        if (invalid) {
+          ui.pushToast(`邮箱验证完成，${invalid} 个邮箱无效`, 'error')
+        } else {
+          ui.pushToast('邮箱验证完成', 'success')
+        }
+        return contacts
+      } catch (error) {
+        console.error('Failed to verify contact emails', error)
+        ui.pushToast(error.message, 'error')
+        return null
+      }
+    },
   },
 })
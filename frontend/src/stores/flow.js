@@ -6,9 +6,9 @@ import {
   replaceContacts,
   suggestGrade,
   confirmGrade,
-  generateAnalysis,
+  streamAnalysis,
   updateAnalysis,
-  generateEmailDraft,
+  streamEmailDraft,
   updateEmailDraft,
   saveFirstFollowup,
   scheduleFollowup,
@@ -71,6 +71,10 @@ export const useFlowStore = defineStore('flow', {
     gradeFinal: null,
     analysis: null,
     emailDraft: null,
+    streamingText: {
+      analysis: '',
+      email: '',
+    },
     followupId: null,
     scheduledTask: null,
     automationJob: null,
@@ -296,6 +300,9 @@ export const useFlowStore = defineStore('flow', {
       }
       if (this.resolveResult && typeof this.resolveResult === 'object') {
         payload.source_json = this.resolveResult
+        if (this.resolveResult.website_signals) {
+          payload.website_signals = this.resolveResult.website_signals
+        }
       }
       try {
         let response
@@ -462,12 +469,13 @@ export const useFlowStore = defineStore('flow', {
         ui.pushToast(error.message, 'error')
       }
     },
-    async fetchGrade() {
+    // options.noCache 用于“重新生成”类操作，跳过后端缓存。
+    async fetchGrade(options) {
       if (!this.customerId) return
       const ui = useUiStore()
       this.loading.grade = true
       try {
-        const payload = await suggestGrade(this.customerId)
+        const payload = await suggestGrade(this.customerId, options)
         this.gradeSuggestion = payload.data
       } catch (error) {
         ui.pushToast(error.message, 'error')
@@ -491,18 +499,26 @@ export const useFlowStore = defineStore('flow', {
         ui.pushToast(error.message, 'error')
       }
     },
-    async fetchAnalysis() {
+    async fetchAnalysis(options) {
       if (!this.customerId) return
       const ui = useUiStore()
       this.loading.analysis = true
+      this.streamingText.analysis = ''
       try {
-        const payload = await generateAnalysis(this.customerId)
+        const payload = await streamAnalysis(
+          this.customerId,
+          (text) => {
+            this.streamingText.analysis += text
+          },
+          options
+        )
         this.analysis = payload.data
         this.step = 3
       } catch (error) {
         ui.pushToast(error.message, 'error')
       } finally {
         this.loading.analysis = false
+        this.streamingText.analysis = ''
       }
     },
     async persistAnalysis() {
@@ -520,18 +536,26 @@ export const useFlowStore = defineStore('flow', {
         ui.pushToast(error.message, 'error')
       }
     },
-    async generateEmail() {
+    async generateEmail(options) {
       if (!this.customerId) return
       const ui = useUiStore()
       this.loading.email = true
+      this.streamingText.email = ''
       try {
-        const payload = await generateEmailDraft(this.customerId)
+        const payload = await streamEmailDraft(
+          this.customerId,
+          (text) => {
+            this.streamingText.email += text
+          },
+          options
+        )
         this.emailDraft = payload.data
         this.step = 4
       } catch (error) {
         ui.pushToast(error.message, 'error')
       } finally {
         this.loading.email = false
+        this.streamingText.email = ''
       }
     },
     async saveInitialFollowup(notes = '') {
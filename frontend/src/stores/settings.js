@@ -9,6 +9,21 @@ const defaultState = () => ({
     llm_base_url: '',
     llm_api_key: '',
     llm_model: '',
+    llm_provider: 'openai',
+    llm_api_version: '',
+    llm_task_models: {},
+    llm_fallback_models: [],
+    llm_daily_token_budget: 0,
+    llm_monthly_token_budget: 0,
+    llm_max_concurrency: 0,
+    llm_requests_per_minute: 0,
+    llm_cache_ttl_hours: 0,
+    search_cache_ttl_hours: 24,
+    search_provider: 'playwright',
+    search_api_key: '',
+    search_endpoint: '',
+    search_fallback_providers: [],
+    search_plan: [],
     my_company_name: '',
     my_product_profile: '',
     smtp_host: '',
@@ -22,6 +37,18 @@ const defaultState = () => ({
     automation_enabled: false,
     automation_followup_days: 3,
     automation_required_grade: 'A',
+    monitor_enabled: false,
+    monitor_grades: 'A',
+    monitor_interval_hours: 168,
+    monitor_llm_summary: false,
+    email_verify_dns_server: '',
+    email_verify_smtp_probe: false,
+    proxy_url: '',
+    proxy_username: '',
+    proxy_password: '',
+    proxy_bypass: '',
+    fetch_allowed_hosts: '',
+    proxy_channels: {},
   },
 })
 